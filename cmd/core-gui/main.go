@@ -33,6 +33,10 @@ func main() {
 	// This provides WebView access, console capture, window control, and process management
 	mcpBridge := NewMCPBridge(mcpPort, rt.Display)
 
+	// Publish config and file changes to the frontend over the bridge's hub
+	rt.Config.SetHub(mcpBridge.wsHub, "")
+	rt.Watch.SetHub(mcpBridge.wsHub, "")
+
 	// Collect all services including plugins
 	// Display service registered separately so Wails calls its Startup() for tray/window
 	services := []application.Service{
@@ -12,22 +12,45 @@
 //	    }
 //	  }
 //	}
+//
+// To serve files from a build box instead of the local filesystem, pass an
+// SFTP URL:
+//
+//	core-mcp -sftp 'sftp://dev@build1/~/src?key=/home/dev/.ssh/id_ed25519'
 package main
 
 import (
 	"context"
+	"flag"
 	"log"
 	"os"
 	"os/signal"
 	"syscall"
 
+	"github.com/host-uk/core/pkg/io/sftp"
 	"github.com/host-uk/core/pkg/mcp"
 )
 
 func main() {
+	sftpURL := flag.String("sftp", "", "serve files from a remote host, e.g. sftp://user@host/path?agent=true")
+	flag.Parse()
+
 	// Create standalone MCP service (no Core instance needed)
 	svc := mcp.NewStandalone()
 
+	if *sftpURL != "" {
+		cfg, err := sftp.ParseURL(*sftpURL)
+		if err != nil {
+			log.Fatalf("Invalid SFTP URL: %v", err)
+		}
+		medium, err := sftp.New(cfg)
+		if err != nil {
+			log.Fatalf("Failed to create SFTP medium: %v", err)
+		}
+		defer medium.Close()
+		svc.SetMedium(medium)
+	}
+
 	// Set up graceful shutdown
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/host-uk/core/pkg/cache"
+	"github.com/host-uk/core/pkg/repos"
+	"github.com/leaanthony/clir"
+)
+
+const (
+	// cacheMaxSize is the size the workspace cache is kept under.
+	cacheMaxSize = 100 << 20
+	// cacheStaleTime is how long issues and reviews are shown from an expired
+	// cache entry while it is refreshed in the background, one entry at a time.
+	cacheStaleTime = 24 * time.Hour
+)
+
+// AddCacheCommand adds the 'cache' command to the given parent command.
+func AddCacheCommand(parent *clir.Cli) {
+	cacheCmd := parent.NewSubCommand("cache", "Inspect and manage the workspace cache")
+	cacheCmd.LongDescription("Manages the cache of GitHub responses used by search, issues and reviews.\n" +
+		"The cache lives in .core/cache next to repos.yaml, or in the current directory.\n\n" +
+		"Examples:\n" +
+		"  core cache stats\n" +
+		"  core cache prune\n" +
+		"  core cache clear --bucket issues")
+
+	statsCmd := cacheCmd.NewSubCommand("stats", "Show cache size and entries per bucket")
+	statsCmd.Action(func() error {
+		return runCacheStats()
+	})
+
+	pruneCmd := cacheCmd.NewSubCommand("prune", "Remove expired entries and shrink the cache to its size limit")
+	pruneCmd.Action(func() error {
+		return runCachePrune()
+	})
+
+	var bucket string
+	clearCmd := cacheCmd.NewSubCommand("clear", "Remove cached entries")
+	clearCmd.StringFlag("bucket", "Only clear this bucket (github, issues, reviews)", &bucket)
+	clearCmd.Action(func() error {
+		return runCacheClear(bucket)
+	})
+}
+
+// workspaceCacheDir returns .core/cache next to repos.yaml, or "" to use
+// .core/cache in the current directory.
+func workspaceCacheDir() string {
+	if regPath, err := repos.FindRegistry(); err == nil {
+		return filepath.Join(filepath.Dir(regPath), ".core", "cache")
+	}
+	return ""
+}
+
+// openCache opens the workspace cache with opts. If it can't be opened, an
+// in-memory cache is returned so commands still work, just without caching
+// between runs.
+func openCache(opts cache.Options) *cache.Cache {
+	opts.MaxSize = cacheMaxSize
+	c, err := cache.NewDir(workspaceCacheDir(), opts)
+	if err != nil {
+		return cache.NewMemory(opts)
+	}
+	return c
+}
+
+func runCacheStats() error {
+	c, err := cache.NewDir(workspaceCacheDir(), cache.Options{MaxSize: cacheMaxSize})
+	if err != nil {
+		return fmt.Errorf("failed to open cache: %w", err)
+	}
+	stats, err := c.Stats()
+	if err != nil {
+		return fmt.Errorf("failed to read cache: %w", err)
+	}
+
+	if stats.Entries == 0 {
+		fmt.Println("Cache is empty.")
+		return nil
+	}
+	for _, b := range stats.Buckets {
+		name := b.Name
+		if name == "" {
+			name = "(root)"
+		}
+		fmt.Printf("  %s %5d entries  %s\n", repoNameStyle.Render(fmt.Sprintf("%-12s", name)), b.Entries, dimStyle.Render(formatBytes(b.Size)))
+	}
+	fmt.Println()
+	fmt.Printf("%d entries, %s of %s", stats.Entries, formatBytes(stats.Size), formatBytes(cacheMaxSize))
+	if stats.Expired > 0 {
+		fmt.Printf(" %s", dimStyle.Render(fmt.Sprintf("(%d expired)", stats.Expired)))
+	}
+	fmt.Println()
+	return nil
+}
+
+func runCachePrune() error {
+	c, err := cache.NewDir(workspaceCacheDir(), cache.Options{MaxSize: cacheMaxSize, StaleWhileRevalidate: cacheStaleTime})
+	if err != nil {
+		return fmt.Errorf("failed to open cache: %w", err)
+	}
+	removed, err := c.Prune()
+	if err != nil {
+		return fmt.Errorf("failed to prune cache: %w", err)
+	}
+	fmt.Printf("%s Removed %d entries\n", successStyle.Render("✓"), removed)
+	return nil
+}
+
+func runCacheClear(bucket string) error {
+	c, err := cache.NewDir(workspaceCacheDir(), cache.Options{})
+	if err != nil {
+		return fmt.Errorf("failed to open cache: %w", err)
+	}
+	if bucket != "" {
+		c = c.Bucket(bucket)
+	}
+	if err := c.Clear(); err != nil {
+		return fmt.Errorf("failed to clear cache: %w", err)
+	}
+	fmt.Printf("%s Cache cleared\n", successStyle.Render("✓"))
+	return nil
+}
+
+// formatBytes formats a size for display, e.g. "1.5 MiB".
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
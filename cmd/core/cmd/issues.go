@@ -2,18 +2,26 @@ package cmd
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/url"
 	"os"
 	"os/exec"
+	"path"
 	"sort"
 	"strings"
 	"time"
 
-	"github.com/host-uk/core/pkg/repos"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/host-uk/core/pkg/cache"
+	"github.com/host-uk/core/pkg/repos"
 	"github.com/leaanthony/clir"
 )
 
+// issuesCacheTTL is how long fetched issues and PRs are used without
+// refreshing them.
+const issuesCacheTTL = 5 * time.Minute
+
 var (
 	issueRepoStyle = lipgloss.NewStyle().
 			Foreground(lipgloss.Color("#6b7280")) // gray-500
@@ -35,26 +43,24 @@ var (
 			Foreground(lipgloss.Color("#6b7280")) // gray-500
 )
 
-// GitHubIssue represents a GitHub issue from the API.
+// GitHubIssue represents a GitHub issue from the REST API.
 type GitHubIssue struct {
 	Number    int       `json:"number"`
 	Title     string    `json:"title"`
 	State     string    `json:"state"`
-	CreatedAt time.Time `json:"createdAt"`
+	CreatedAt time.Time `json:"created_at"`
 	Author    struct {
 		Login string `json:"login"`
-	} `json:"author"`
-	Assignees struct {
-		Nodes []struct {
-			Login string `json:"login"`
-		} `json:"nodes"`
+	} `json:"user"`
+	Assignees []struct {
+		Login string `json:"login"`
 	} `json:"assignees"`
-	Labels struct {
-		Nodes []struct {
-			Name string `json:"name"`
-		} `json:"nodes"`
+	Labels []struct {
+		Name string `json:"name"`
 	} `json:"labels"`
-	URL string `json:"url"`
+	URL string `json:"html_url"`
+	// PullRequest is set for pull requests, which the issues API also lists.
+	PullRequest json.RawMessage `json:"pull_request,omitempty"`
 
 	// Added by us
 	RepoName string `json:"-"`
@@ -65,24 +71,29 @@ func AddIssuesCommand(parent *clir.Cli) {
 	var registryPath string
 	var limit int
 	var assignee string
+	var refresh bool
 
 	issuesCmd := parent.NewSubCommand("issues", "List open issues across all repos")
 	issuesCmd.LongDescription("Fetches open issues from GitHub for all repos in the registry.\n" +
+		"Results are cached for 5 minutes, and shown from the cache for a day\n" +
+		"after that while they are revalidated with GitHub, which is cheap if\n" +
+		"nothing has changed.\n" +
 		"Requires the 'gh' CLI to be installed and authenticated.")
 
 	issuesCmd.StringFlag("registry", "Path to repos.yaml (auto-detected if not specified)", &registryPath)
-	issuesCmd.IntFlag("limit", "Max issues per repo (default 10)", &limit)
+	issuesCmd.IntFlag("limit", "Max issues per repo (default 10, at most 100)", &limit)
 	issuesCmd.StringFlag("assignee", "Filter by assignee (use @me for yourself)", &assignee)
+	issuesCmd.BoolFlag("refresh", "Bypass cache and fetch fresh data", &refresh)
 
 	issuesCmd.Action(func() error {
 		if limit == 0 {
 			limit = 10
 		}
-		return runIssues(registryPath, limit, assignee)
+		return runIssues(registryPath, limit, assignee, refresh)
 	})
 }
 
-func runIssues(registryPath string, limit int, assignee string) error {
+func runIssues(registryPath string, limit int, assignee string, refresh bool) error {
 	// Check gh is available
 	if _, err := exec.LookPath("gh"); err != nil {
 		return fmt.Errorf("'gh' CLI not found. Install from https://cli.github.com/")
@@ -114,7 +125,21 @@ func runIssues(registryPath string, limit int, assignee string) error {
 		}
 	}
 
-	// Fetch issues sequentially (avoid GitHub rate limits)
+	// The REST API doesn't know @me, so look up who that is.
+	if assignee == "@me" {
+		if assignee, err = ghLogin(); err != nil {
+			return fmt.Errorf("failed to look up the gh user: %w", err)
+		}
+	}
+
+	c := openCache(cache.Options{StaleWhileRevalidate: cacheStaleTime}).Bucket("issues").WithTTL(issuesCacheTTL)
+	if refresh {
+		_ = c.Clear()
+	}
+	defer c.Wait()
+
+	// Fetch issues sequentially (avoid GitHub rate limits). Stale entries are
+	// shown straight away and revalidated by the cache one at a time.
 	var allIssues []GitHubIssue
 	var fetchErrors []error
 
@@ -123,7 +148,7 @@ func runIssues(registryPath string, limit int, assignee string) error {
 		repoFullName := fmt.Sprintf("%s/%s", reg.Org, repo.Name)
 		fmt.Printf("\033[2K\r%s %d/%d %s", dimStyle.Render("Fetching"), i+1, len(repoList), repo.Name)
 
-		issues, err := fetchIssues(repoFullName, repo.Name, limit, assignee)
+		issues, err := fetchIssues(c, repoFullName, repo.Name, limit, assignee)
 		if err != nil {
 			fetchErrors = append(fetchErrors, fmt.Errorf("%s: %w", repo.Name, err))
 			continue
@@ -160,46 +185,57 @@ func runIssues(registryPath string, limit int, assignee string) error {
 	return nil
 }
 
-func fetchIssues(repoFullName, repoName string, limit int, assignee string) ([]GitHubIssue, error) {
-	args := []string{
-		"issue", "list",
-		"--repo", repoFullName,
-		"--state", "open",
-		"--limit", fmt.Sprintf("%d", limit),
-		"--json", "number,title,state,createdAt,author,assignees,labels,url",
-	}
-
+// fetchIssues returns the newest open issues of a repo, up to limit. The
+// first page of the repo's issues is cached whatever the limit, so that it
+// can be revalidated with a 304.
+func fetchIssues(c *cache.Cache, repoFullName, repoName string, limit int, assignee string) ([]GitHubIssue, error) {
+	endpoint := fmt.Sprintf("repos/%s/issues?state=open&per_page=%d", repoFullName, ghPageSize)
 	if assignee != "" {
-		args = append(args, "--assignee", assignee)
+		endpoint += "&assignee=" + url.QueryEscape(assignee)
 	}
 
-	cmd := exec.Command("gh", args...)
-	output, err := cmd.Output()
-	if err != nil {
-		// Check if it's just "no issues" vs actual error
-		if exitErr, ok := err.(*exec.ExitError); ok {
-			stderr := string(exitErr.Stderr)
-			if strings.Contains(stderr, "no issues") || strings.Contains(stderr, "Could not resolve") {
-				return nil, nil
-			}
-			return nil, fmt.Errorf("%s", stderr)
+	var all []GitHubIssue
+	key := path.Join(repoFullName, "open-"+assignee)
+	err := c.Fetch(key, &all, func(v cache.Validators) (*cache.Response, error) {
+		resp, err := ghAPI(endpoint, v)
+		if errors.Is(err, errGHNotFound) {
+			// The repo doesn't exist on GitHub, so it has no issues.
+			return &cache.Response{Data: []GitHubIssue{}}, nil
 		}
+		return resp, err
+	})
+	if err != nil {
 		return nil, err
 	}
 
+	// Tag with repo name, leaving out pull requests
 	var issues []GitHubIssue
-	if err := json.Unmarshal(output, &issues); err != nil {
-		return nil, err
-	}
-
-	// Tag with repo name
-	for i := range issues {
-		issues[i].RepoName = repoName
+	for _, issue := range all {
+		if issue.PullRequest != nil {
+			continue
+		}
+		issue.RepoName = repoName
+		issues = append(issues, issue)
+		if len(issues) == limit {
+			break
+		}
 	}
 
 	return issues, nil
 }
 
+// ghLogin returns the login of the user gh is authenticated as.
+func ghLogin() (string, error) {
+	output, err := exec.Command("gh", "api", "user", "--jq", ".login").Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return "", fmt.Errorf("%s", strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return "", err
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
 func printIssue(issue GitHubIssue) {
 	// #42 [core-bio] Fix avatar upload
 	num := issueNumberStyle.Render(fmt.Sprintf("#%d", issue.Number))
@@ -209,18 +245,18 @@ func printIssue(issue GitHubIssue) {
 	line := fmt.Sprintf("  %s %s %s", num, repo, title)
 
 	// Add labels if any
-	if len(issue.Labels.Nodes) > 0 {
+	if len(issue.Labels) > 0 {
 		var labels []string
-		for _, l := range issue.Labels.Nodes {
+		for _, l := range issue.Labels {
 			labels = append(labels, l.Name)
 		}
 		line += " " + issueLabelStyle.Render("["+strings.Join(labels, ", ")+"]")
 	}
 
 	// Add assignee if any
-	if len(issue.Assignees.Nodes) > 0 {
+	if len(issue.Assignees) > 0 {
 		var assignees []string
-		for _, a := range issue.Assignees.Nodes {
+		for _, a := range issue.Assignees {
 			assignees = append(assignees, "@"+a.Login)
 		}
 		line += " " + issueAssigneeStyle.Render(strings.Join(assignees, ", "))
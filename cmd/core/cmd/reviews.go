@@ -5,12 +5,14 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"path"
 	"sort"
 	"strings"
 	"time"
 
-	"github.com/host-uk/core/pkg/repos"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/host-uk/core/pkg/cache"
+	"github.com/host-uk/core/pkg/repos"
 	"github.com/leaanthony/clir"
 )
 
@@ -70,22 +72,27 @@ func AddReviewsCommand(parent *clir.Cli) {
 	var registryPath string
 	var author string
 	var showAll bool
+	var refresh bool
 
 	reviewsCmd := parent.NewSubCommand("reviews", "List PRs needing review across all repos")
 	reviewsCmd.LongDescription("Fetches open PRs from GitHub for all repos in the registry.\n" +
 		"Shows review status (approved, changes requested, pending).\n" +
+		"Results are cached for 5 minutes, and shown from the cache for a day\n" +
+		"after that while they are fetched again in full. Review status comes\n" +
+		"from GitHub's GraphQL API, which can't tell us nothing has changed.\n" +
 		"Requires the 'gh' CLI to be installed and authenticated.")
 
 	reviewsCmd.StringFlag("registry", "Path to repos.yaml (auto-detected if not specified)", &registryPath)
 	reviewsCmd.StringFlag("author", "Filter by PR author", &author)
 	reviewsCmd.BoolFlag("all", "Show all PRs including drafts", &showAll)
+	reviewsCmd.BoolFlag("refresh", "Bypass cache and fetch fresh data", &refresh)
 
 	reviewsCmd.Action(func() error {
-		return runReviews(registryPath, author, showAll)
+		return runReviews(registryPath, author, showAll, refresh)
 	})
 }
 
-func runReviews(registryPath string, author string, showAll bool) error {
+func runReviews(registryPath string, author string, showAll bool, refresh bool) error {
 	// Check gh is available
 	if _, err := exec.LookPath("gh"); err != nil {
 		return fmt.Errorf("'gh' CLI not found. Install from https://cli.github.com/")
@@ -117,7 +124,14 @@ func runReviews(registryPath string, author string, showAll bool) error {
 		}
 	}
 
-	// Fetch PRs sequentially (avoid GitHub rate limits)
+	c := openCache(cache.Options{StaleWhileRevalidate: cacheStaleTime}).Bucket("reviews").WithTTL(issuesCacheTTL)
+	if refresh {
+		_ = c.Clear()
+	}
+	defer c.Wait()
+
+	// Fetch PRs sequentially (avoid GitHub rate limits). Stale entries are
+	// shown straight away and revalidated by the cache one at a time.
 	var allPRs []GitHubPR
 	var fetchErrors []error
 
@@ -126,7 +140,7 @@ func runReviews(registryPath string, author string, showAll bool) error {
 		repoFullName := fmt.Sprintf("%s/%s", reg.Org, repo.Name)
 		fmt.Printf("\033[2K\r%s %d/%d %s", dimStyle.Render("Fetching"), i+1, len(repoList), repo.Name)
 
-		prs, err := fetchPRs(repoFullName, repo.Name, author)
+		prs, err := fetchPRs(c, repoFullName, repo.Name, author)
 		if err != nil {
 			fetchErrors = append(fetchErrors, fmt.Errorf("%s: %w", repo.Name, err))
 			continue
@@ -201,7 +215,33 @@ func runReviews(registryPath string, author string, showAll bool) error {
 	return nil
 }
 
-func fetchPRs(repoFullName, repoName string, author string) ([]GitHubPR, error) {
+// fetchPRs returns the open pull requests of a repo. Unlike issues, they
+// aren't revalidated: the review decision is only available through GraphQL,
+// which has no conditional requests, so expired entries are fetched in full.
+func fetchPRs(c *cache.Cache, repoFullName, repoName string, author string) ([]GitHubPR, error) {
+	var prs []GitHubPR
+	key := path.Join(repoFullName, author+"-open")
+	err := c.Fetch(key, &prs, func(cache.Validators) (*cache.Response, error) {
+		output, err := listPRs(repoFullName, author)
+		if err != nil {
+			return nil, err
+		}
+		return &cache.Response{Data: json.RawMessage(output)}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Tag with repo name
+	for i := range prs {
+		prs[i].RepoName = repoName
+	}
+
+	return prs, nil
+}
+
+// listPRs returns the open pull requests of a repo as JSON.
+func listPRs(repoFullName string, author string) ([]byte, error) {
 	args := []string{
 		"pr", "list",
 		"--repo", repoFullName,
@@ -219,24 +259,13 @@ func fetchPRs(repoFullName, repoName string, author string) ([]GitHubPR, error)
 		if exitErr, ok := err.(*exec.ExitError); ok {
 			stderr := string(exitErr.Stderr)
 			if strings.Contains(stderr, "no pull requests") || strings.Contains(stderr, "Could not resolve") {
-				return nil, nil
+				return []byte("[]"), nil
 			}
 			return nil, fmt.Errorf("%s", stderr)
 		}
 		return nil, err
 	}
-
-	var prs []GitHubPR
-	if err := json.Unmarshal(output, &prs); err != nil {
-		return nil, err
-	}
-
-	// Tag with repo name
-	for i := range prs {
-		prs[i].RepoName = repoName
-	}
-
-	return prs, nil
+	return output, nil
 }
 
 func printPR(pr GitHubPR) {
@@ -84,6 +84,7 @@ func Execute() error {
 	AddDoctorCommand(app)
 	AddSearchCommand(app)
 	AddInstallCommand(app)
+	AddCacheCommand(app)
 	// Run the application
 	return app.Run()
 }
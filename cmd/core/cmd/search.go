@@ -1,17 +1,21 @@
 package cmd
 
 import (
+	"bufio"
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"net/textproto"
 	"os"
 	"os/exec"
-	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/host-uk/core/pkg/cache"
-	"github.com/host-uk/core/pkg/repos"
 	"github.com/leaanthony/clir"
 )
 
@@ -25,13 +29,14 @@ func AddSearchCommand(parent *clir.Cli) {
 
 	searchCmd := parent.NewSubCommand("search", "Search GitHub for repos by pattern")
 	searchCmd.LongDescription("Searches GitHub for repositories matching a pattern.\n" +
-		"Uses gh CLI for authenticated search. Results are cached for 1 hour.\n\n" +
+		"Uses gh CLI for authenticated search. Results are cached for 1 hour,\n" +
+		"then revalidated with GitHub, which is cheap if nothing has changed.\n\n" +
 		"Examples:\n" +
 		"  core search --org host-uk --pattern 'core-*'\n" +
 		"  core search --org mycompany --pattern '*-mod-*'\n" +
 		"  core search --org LetheanNetwork --refresh")
 
-	searchCmd.StringFlag("org", "GitHub organization to search (required)", &org)
+	searchCmd.StringFlag("org", "GitHub organization or user to search (required)", &org)
 	searchCmd.StringFlag("pattern", "Repo name pattern (* for wildcard)", &pattern)
 	searchCmd.StringFlag("type", "Filter by type in name (mod, services, plug, website)", &repoType)
 	searchCmd.IntFlag("limit", "Max results (default 50)", &limit)
@@ -61,70 +66,23 @@ type ghRepo struct {
 }
 
 func runSearch(org, pattern, repoType string, limit int, refresh bool) error {
-	// Initialize cache in workspace .core/ directory
-	var cacheDir string
-	if regPath, err := repos.FindRegistry(); err == nil {
-		cacheDir = filepath.Join(filepath.Dir(regPath), ".core", "cache")
+	// Cache in workspace .core/ directory; unchanged results are revalidated
+	// with their ETag, which doesn't count against the rate limit.
+	c := openCache(cache.Options{}).Bucket(cache.GitHubReposKey(org))
+	if refresh {
+		_ = c.Clear()
 	}
 
-	c, err := cache.New(cacheDir, 0)
+	repos, fetched, err := fetchRepos(c, org, limit)
 	if err != nil {
-		// Cache init failed, continue without cache
-		c = nil
+		return err
 	}
-
-	cacheKey := cache.GitHubReposKey(org)
-	var repos []ghRepo
-	var fromCache bool
-
-	// Try cache first (unless refresh requested)
-	if c != nil && !refresh {
-		if found, err := c.Get(cacheKey, &repos); found && err == nil {
-			fromCache = true
-			age := c.Age(cacheKey)
-			fmt.Printf("%s %s %s\n", dimStyle.Render("Cache:"), org, dimStyle.Render(fmt.Sprintf("(%s ago)", age.Round(time.Second))))
-		}
+	if !fetched {
+		age := c.Age("1")
+		fmt.Printf("%s %s %s\n", dimStyle.Render("Cache:"), org, dimStyle.Render(fmt.Sprintf("(%s ago)", age.Round(time.Second))))
 	}
-
-	// Fetch from GitHub if not cached
-	if !fromCache {
-		if !ghAuthenticated() {
-			return fmt.Errorf("gh CLI not authenticated. Run: gh auth login")
-		}
-
-		// Check for bad GH_TOKEN which can override keyring auth
-		if os.Getenv("GH_TOKEN") != "" {
-			fmt.Printf("%s GH_TOKEN env var is set - this may cause auth issues\n", dimStyle.Render("Note:"))
-			fmt.Printf("%s Unset it with: unset GH_TOKEN\n\n", dimStyle.Render(""))
-		}
-
-		fmt.Printf("%s %s... ", dimStyle.Render("Fetching:"), org)
-
-		// Always use gh repo list (more reliable than gh search repos)
-		cmd := exec.Command("gh", "repo", "list", org,
-			"--json", "name,description,visibility,updatedAt,primaryLanguage",
-			"--limit", fmt.Sprintf("%d", limit))
-		output, err := cmd.CombinedOutput()
-
-		if err != nil {
-			fmt.Println()
-			errStr := strings.TrimSpace(string(output))
-			if strings.Contains(errStr, "401") || strings.Contains(errStr, "Bad credentials") {
-				return fmt.Errorf("authentication failed - try: unset GH_TOKEN && gh auth login")
-			}
-			return fmt.Errorf("search failed: %s", errStr)
-		}
-
-		if err := json.Unmarshal(output, &repos); err != nil {
-			return fmt.Errorf("failed to parse results: %w", err)
-		}
-
-		// Cache the results
-		if c != nil {
-			_ = c.Set(cacheKey, repos)
-		}
-
-		fmt.Printf("%s\n", successStyle.Render("✓"))
+	if len(repos) > limit {
+		repos = repos[:limit]
 	}
 
 	// Filter by glob pattern and type
@@ -178,6 +136,109 @@ func runSearch(org, pattern, repoType string, limit int, refresh bool) error {
 	return nil
 }
 
+// fetchRepos returns the first limit repos of an org, or of a user if there
+// is no org by that name. Each page of the list is cached under its number
+// with its own validators, so every page can be revalidated with a 304.
+// fetched reports whether any page was fetched rather than taken from the
+// cache.
+func fetchRepos(c *cache.Cache, org string, limit int) (repos []ghRepo, fetched bool, err error) {
+	owner := "orgs"
+	for page := 1; len(repos) < limit; page++ {
+		var items []ghRepo
+		err := c.Fetch(strconv.Itoa(page), &items, func(v cache.Validators) (*cache.Response, error) {
+			if !fetched {
+				fetched = true
+				if !ghAuthenticated() {
+					return nil, fmt.Errorf("gh CLI not authenticated. Run: gh auth login")
+				}
+
+				// Check for bad GH_TOKEN which can override keyring auth
+				if os.Getenv("GH_TOKEN") != "" {
+					fmt.Printf("%s GH_TOKEN env var is set - this may cause auth issues\n", dimStyle.Render("Note:"))
+					fmt.Printf("%s Unset it with: unset GH_TOKEN\n\n", dimStyle.Render(""))
+				}
+				fmt.Printf("%s %s... ", dimStyle.Render("Fetching:"), org)
+			}
+
+			endpoint := func() string {
+				return fmt.Sprintf("%s/%s/repos?per_page=%d&page=%d", owner, org, ghPageSize, page)
+			}
+			resp, err := ghAPI(endpoint(), v)
+			if errors.Is(err, errGHNotFound) && owner == "orgs" {
+				// Not an org, so list the repos of the user instead.
+				owner = "users"
+				resp, err = ghAPI(endpoint(), v)
+			}
+			if err != nil {
+				fmt.Println()
+				if strings.Contains(err.Error(), "401") || strings.Contains(err.Error(), "Bad credentials") {
+					return nil, fmt.Errorf("authentication failed - try: unset GH_TOKEN && gh auth login")
+				}
+				return nil, fmt.Errorf("search failed: %w", err)
+			}
+			return resp, nil
+		})
+		if err != nil {
+			return nil, fetched, err
+		}
+		repos = append(repos, items...)
+		if len(items) < ghPageSize {
+			break
+		}
+	}
+	if fetched {
+		fmt.Printf("%s\n", successStyle.Render("✓"))
+	}
+	return repos, fetched, nil
+}
+
+// errGHNotFound is returned by ghAPI for an endpoint that doesn't exist.
+var errGHNotFound = errors.New("not found")
+
+// ghAPI GETs a GitHub REST endpoint with the gh CLI. The cached validators
+// are sent as conditional headers, so an unchanged response comes back as a
+// 304 with no body.
+func ghAPI(endpoint string, v cache.Validators) (*cache.Response, error) {
+	args := []string{"api", "--include", endpoint}
+	if v.ETag != "" {
+		args = append(args, "-H", "If-None-Match: "+v.ETag)
+	}
+	if v.LastModified != "" {
+		args = append(args, "-H", "If-Modified-Since: "+v.LastModified)
+	}
+
+	// gh exits non-zero for a 304, but still prints the response headers.
+	output, err := exec.Command("gh", args...).Output()
+	reader := bufio.NewReader(bytes.NewReader(output))
+	tp := textproto.NewReader(reader)
+	statusLine, _ := tp.ReadLine()
+	header, _ := tp.ReadMIMEHeader()
+	body, _ := io.ReadAll(reader)
+
+	if fields := strings.Fields(statusLine); len(fields) > 1 {
+		switch fields[1] {
+		case "304":
+			return &cache.Response{NotModified: true}, nil
+		case "404":
+			return nil, fmt.Errorf("%s: %w", endpoint, errGHNotFound)
+		}
+	}
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return nil, fmt.Errorf("%s", strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return nil, err
+	}
+	return &cache.Response{
+		Data:         json.RawMessage(body),
+		ETag:         header.Get("ETag"),
+		LastModified: header.Get("Last-Modified"),
+	}, nil
+}
+
+// ghPageSize is the most items GitHub returns in one page.
+const ghPageSize = 100
+
 // matchGlob does simple glob matching with * wildcards
 func matchGlob(pattern, name string) bool {
 	if pattern == "*" || pattern == "" {
@@ -25,6 +25,7 @@ require (
 	github.com/charmbracelet/x/term v0.2.1 // indirect
 	github.com/gdamore/encoding v1.0.1 // indirect
 	github.com/gdamore/tcell/v2 v2.8.1 // indirect
+	github.com/host-uk/core/pkg/config/schema v0.0.0 // indirect
 	github.com/kr/text v0.2.0 // indirect
 	github.com/lucasb-eyer/go-colorful v1.2.0 // indirect
 	github.com/mattn/go-isatty v0.0.20 // indirect
@@ -41,6 +42,7 @@ require (
 replace (
 	github.com/host-uk/core => ../../
 	github.com/host-uk/core/pkg/build => ../../pkg/build
+	github.com/host-uk/core/pkg/config/schema => ../../pkg/config/schema
 	github.com/host-uk/core/pkg/git => ../../pkg/git
 	github.com/host-uk/core/pkg/repos => ../../pkg/repos
 )
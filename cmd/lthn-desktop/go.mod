@@ -18,6 +18,7 @@ require (
 replace (
 	github.com/host-uk/core => ../../
 	github.com/host-uk/core/pkg/config => ../../pkg/config
+	github.com/host-uk/core/pkg/config/schema => ../../pkg/config/schema
 	github.com/host-uk/core/pkg/core => ../../pkg/core
 	github.com/host-uk/core/pkg/crypt => ../../pkg/crypt
 	github.com/host-uk/core/pkg/display => ../../pkg/display
@@ -44,6 +45,7 @@ require (
 	github.com/Microsoft/go-winio v0.6.2 // indirect
 	github.com/ProtonMail/go-crypto v1.3.0 // indirect
 	github.com/host-uk/core/pkg/config v0.0.0-00010101000000-000000000000 // indirect
+	github.com/host-uk/core/pkg/config/schema v0.0.0 // indirect
 	github.com/host-uk/core/pkg/core v0.0.0 // indirect
 	github.com/host-uk/core/pkg/docs v0.0.0-00010101000000-000000000000 // indirect
 	github.com/host-uk/core/pkg/help v0.0.0-00010101000000-000000000000 // indirect
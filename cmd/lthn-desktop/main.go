@@ -33,6 +33,10 @@ func main() {
 	// This provides WebView access, console capture, window control, and process management
 	mcpBridge := NewMCPBridge(mcpPort, rt.Display)
 
+	// Publish config and file changes to the frontend over the bridge's hub
+	rt.Config.SetHub(mcpBridge.wsHub, "")
+	rt.Watch.SetHub(mcpBridge.wsHub, "")
+
 	// Create the Mining bridge for native miner management
 	miningBridge := NewMiningBridge()
 
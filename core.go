@@ -29,7 +29,7 @@ func NewRuntime() (*Runtime, error) {
 
 	// Register built-in plugins
 	ctx := context.Background()
-	if err := plugins.Register(ctx, system.New()); err != nil {
+	if err := plugins.Register(ctx, system.New().WithCore(rt.Core)); err != nil {
 		return nil, err
 	}
 
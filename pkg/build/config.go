@@ -7,7 +7,7 @@ import (
 	"os"
 	"path/filepath"
 
-	"gopkg.in/yaml.v3"
+	"github.com/host-uk/core/pkg/config/schema"
 )
 
 // ConfigFileName is the name of the build configuration file.
@@ -16,6 +16,13 @@ const ConfigFileName = "build.yaml"
 // ConfigDir is the directory where build configuration is stored.
 const ConfigDir = ".core"
 
+// ConfigVersion is the current build.yaml format version. Files with an
+// older version are upgraded by configMigrations when they are loaded.
+const ConfigVersion = 1
+
+// configMigrations upgrade build.yaml files to ConfigVersion.
+var configMigrations = schema.NewMigrator(ConfigVersion)
+
 // BuildConfig holds the complete build configuration loaded from .core/build.yaml.
 // This is distinct from Config which holds runtime build parameters.
 type BuildConfig struct {
@@ -36,7 +43,7 @@ type Project struct {
 	// Description is a brief description of the project.
 	Description string `yaml:"description"`
 	// Main is the path to the main package (e.g., ./cmd/core).
-	Main string `yaml:"main"`
+	Main string `yaml:"main" validate:"required"`
 	// Binary is the output binary name.
 	Binary string `yaml:"binary"`
 }
@@ -57,14 +64,16 @@ type Build struct {
 // This is separate from Target to allow for additional config-specific fields.
 type TargetConfig struct {
 	// OS is the target operating system (e.g., "linux", "darwin", "windows").
-	OS string `yaml:"os"`
+	OS string `yaml:"os" validate:"required"`
 	// Arch is the target architecture (e.g., "amd64", "arm64").
-	Arch string `yaml:"arch"`
+	Arch string `yaml:"arch" validate:"required"`
 }
 
 // LoadConfig loads build configuration from the .core/build.yaml file in the given directory.
 // If the config file does not exist, it returns DefaultConfig().
-// Returns an error if the file exists but cannot be parsed.
+// Files from older versions are upgraded in place, keeping a backup.
+// Returns an error if the file exists but cannot be parsed, has unknown fields,
+// is newer than ConfigVersion, or breaks the schema in the validate tags.
 func LoadConfig(dir string) (*BuildConfig, error) {
 	configPath := filepath.Join(dir, ConfigDir, ConfigFileName)
 
@@ -76,14 +85,23 @@ func LoadConfig(dir string) (*BuildConfig, error) {
 		return nil, fmt.Errorf("build.LoadConfig: failed to read config file: %w", err)
 	}
 
+	data, err = configMigrations.Upgrade(configPath, data)
+	if err != nil {
+		return nil, fmt.Errorf("build.LoadConfig: %w", err)
+	}
+
 	var cfg BuildConfig
-	if err := yaml.Unmarshal(data, &cfg); err != nil {
+	if err := schema.Unmarshal(configPath, data, &cfg); err != nil {
 		return nil, fmt.Errorf("build.LoadConfig: failed to parse config file: %w", err)
 	}
 
 	// Apply defaults for any missing fields
 	applyDefaults(&cfg)
 
+	if err := schema.Validate(&cfg); err != nil {
+		return nil, fmt.Errorf("build.LoadConfig: invalid config file %s: %w", configPath, err)
+	}
+
 	return &cfg, nil
 }
 
@@ -5,6 +5,7 @@ import (
 	"path/filepath"
 	"testing"
 
+	"github.com/host-uk/core/pkg/config/schema"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -92,7 +93,7 @@ targets:
 
 	t.Run("applies defaults for missing fields", func(t *testing.T) {
 		content := `
-version: 2
+version: 1
 project:
   name: partial
 `
@@ -103,7 +104,7 @@ project:
 		require.NotNil(t, cfg)
 
 		// Explicit values preserved
-		assert.Equal(t, 2, cfg.Version)
+		assert.Equal(t, 1, cfg.Version)
 		assert.Equal(t, "partial", cfg.Project.Name)
 
 		// Defaults applied
@@ -155,6 +156,45 @@ project:
 		assert.Contains(t, err.Error(), "failed to parse config file")
 	})
 
+	t.Run("returns error for unknown fields", func(t *testing.T) {
+		content := `
+version: 1
+project:
+  name: typo
+  bianry: core
+`
+		dir := setupConfigTestDir(t, content)
+
+		cfg, err := LoadConfig(dir)
+		assert.Error(t, err)
+		assert.Nil(t, cfg)
+		assert.Contains(t, err.Error(), "bianry")
+	})
+
+	t.Run("returns error for newer versions", func(t *testing.T) {
+		dir := setupConfigTestDir(t, "version: 2\n")
+
+		cfg, err := LoadConfig(dir)
+		assert.ErrorIs(t, err, schema.ErrUnsupportedVersion)
+		assert.Nil(t, cfg)
+	})
+
+	t.Run("returns error for targets without an arch", func(t *testing.T) {
+		content := `
+version: 1
+targets:
+  - os: linux
+  - os: darwin
+    arch: arm64
+`
+		dir := setupConfigTestDir(t, content)
+
+		cfg, err := LoadConfig(dir)
+		assert.ErrorIs(t, err, schema.ErrInvalid)
+		assert.Nil(t, cfg)
+		assert.Contains(t, err.Error(), "targets[0].arch: is required")
+	})
+
 	t.Run("returns error for unreadable file", func(t *testing.T) {
 		dir := t.TempDir()
 		coreDir := filepath.Join(dir, ConfigDir)
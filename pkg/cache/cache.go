@@ -1,20 +1,59 @@
-// Package cache provides a file-based cache for GitHub API responses.
+// Package cache provides a size-bounded cache for GitHub API responses and
+// other JSON values, stored in an io.Medium.
+//
+// Entries expire after a TTL. Fetch revalidates expired entries with the
+// ETag and Last-Modified validators stored alongside them, so an unchanged
+// response costs a 304 rather than a full download. When the cache grows past
+// its size or entry limits, the least recently used entries are evicted.
+// Processes may share a cache directory; each merges its index with the one
+// on disk when it saves it.
 package cache
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
 	"time"
+
+	"github.com/host-uk/core/pkg/io"
+	"github.com/host-uk/core/pkg/io/local"
 )
 
 // DefaultTTL is the default cache expiry time.
 const DefaultTTL = 1 * time.Hour
 
-// Cache represents a file-based cache.
+// indexName is the file that records the size and last use of every entry.
+// Entries always end in ".json", so no key can collide with it.
+const indexName = ".index"
+
+// Options configures a Cache.
+type Options struct {
+	// TTL is how long entries stay fresh. Defaults to DefaultTTL.
+	TTL time.Duration
+	// StaleWhileRevalidate is how long after expiry Fetch still returns an
+	// entry straight away, revalidating it in the background. Zero
+	// revalidates before returning.
+	StaleWhileRevalidate time.Duration
+	// MaxSize is the total size in bytes the cache is kept under. Zero means
+	// no limit.
+	MaxSize int64
+	// MaxEntries is the number of entries the cache is kept under. Zero means
+	// no limit.
+	MaxEntries int
+}
+
+// Cache is a cache of JSON values, or a named bucket of one.
 type Cache struct {
-	baseDir string
-	ttl     time.Duration
+	store  *store
+	bucket string
+	ttl    time.Duration
 }
 
 // Entry represents a cached item with metadata.
@@ -22,11 +61,64 @@ type Entry struct {
 	Data      json.RawMessage `json:"data"`
 	CachedAt  time.Time       `json:"cached_at"`
 	ExpiresAt time.Time       `json:"expires_at"`
+	// ETag and LastModified are the validators of the response the data came
+	// from, used to revalidate it.
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+}
+
+// Validators identify the version of a response that is cached, for a
+// conditional request.
+type Validators struct {
+	ETag         string
+	LastModified string
+}
+
+// Response is the result of a FetchFunc.
+type Response struct {
+	// Data is the value to cache. A json.RawMessage is stored as it is.
+	Data any
+	// NotModified reports that the cached value is still current, as for an
+	// HTTP 304. Data is ignored.
+	NotModified bool
+	// ETag and LastModified are the validators of the response, if any.
+	ETag         string
+	LastModified string
+}
+
+// FetchFunc fetches the value for a key. If the cache has an expired value, v
+// holds its validators, to be sent as If-None-Match and If-Modified-Since.
+type FetchFunc func(v Validators) (*Response, error)
+
+// store is the state shared by a cache and its buckets.
+type store struct {
+	medium io.Medium
+	opts   Options
+	now    func() time.Time
+
+	mu      sync.Mutex
+	index   map[string]*indexEntry // entry path -> metadata; nil until loaded
+	removed map[string]bool        // entries deleted since the index was saved
+	wg      sync.WaitGroup
+	queue   []func() // background revalidations not yet started
+	busy    bool     // a worker is running the queue
+	errors  []error  // from background revalidation
+}
+
+type indexEntry struct {
+	Size     int64     `json:"size"`
+	Accessed time.Time `json:"accessed"`
 }
 
-// New creates a new cache instance.
+// New creates a new cache in a directory.
 // If baseDir is empty, uses .core/cache in current directory
 func New(baseDir string, ttl time.Duration) (*Cache, error) {
+	return NewDir(baseDir, Options{TTL: ttl})
+}
+
+// NewDir creates a cache in a directory, configured by opts. If baseDir is
+// empty, uses .core/cache in the current directory.
+func NewDir(baseDir string, opts Options) (*Cache, error) {
 	if baseDir == "" {
 		// Use .core/cache in current working directory
 		cwd, err := os.Getwd()
@@ -36,126 +128,629 @@ func New(baseDir string, ttl time.Duration) (*Cache, error) {
 		baseDir = filepath.Join(cwd, ".core", "cache")
 	}
 
-	if ttl == 0 {
-		ttl = DefaultTTL
-	}
-
 	// Ensure cache directory exists
 	if err := os.MkdirAll(baseDir, 0755); err != nil {
 		return nil, err
 	}
+	m, err := local.New(baseDir)
+	if err != nil {
+		return nil, err
+	}
+	return Open(m, opts), nil
+}
 
+// Open creates a cache that stores its entries in m.
+func Open(m io.Medium, opts Options) *Cache {
+	if opts.TTL <= 0 {
+		opts.TTL = DefaultTTL
+	}
 	return &Cache{
-		baseDir: baseDir,
-		ttl:     ttl,
-	}, nil
+		store: &store{medium: m, opts: opts, now: time.Now},
+		ttl:   opts.TTL,
+	}
 }
 
-// Path returns the full path for a cache key.
+// NewMemory creates a cache that is kept in memory, for tests.
+func NewMemory(opts Options) *Cache {
+	return Open(io.NewMockMedium(), opts)
+}
+
+// Bucket returns a view of the cache whose keys are kept apart from those of
+// other buckets. Buckets share the size and entry limits of the cache.
+func (c *Cache) Bucket(name string) *Cache {
+	return &Cache{store: c.store, bucket: c.Path(name), ttl: c.ttl}
+}
+
+// WithTTL returns a view of the cache whose new entries expire after ttl.
+func (c *Cache) WithTTL(ttl time.Duration) *Cache {
+	return &Cache{store: c.store, bucket: c.bucket, ttl: ttl}
+}
+
+// Path returns where a key is stored, relative to the cache's medium. Keys
+// may contain slashes; ".." cannot leave the bucket.
 func (c *Cache) Path(key string) string {
-	return filepath.Join(c.baseDir, key+".json")
+	key = strings.TrimPrefix(path.Clean("/"+filepath.ToSlash(key)), "/")
+	return path.Join(c.bucket, key)
+}
+
+// entryPath returns the path of the file a key's entry is stored in.
+func (c *Cache) entryPath(key string) string {
+	return c.Path(key) + ".json"
 }
 
 // Get retrieves a cached item if it exists and hasn't expired.
 func (c *Cache) Get(key string, dest interface{}) (bool, error) {
-	path := c.Path(key)
-
-	data, err := os.ReadFile(path)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return false, nil
-		}
-		return false, err
-	}
-
-	var entry Entry
-	if err := json.Unmarshal(data, &entry); err != nil {
-		// Invalid cache file, treat as miss
-		return false, nil
-	}
+	s := c.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-	// Check expiry
-	if time.Now().After(entry.ExpiresAt) {
+	entry, ok := s.read(c.entryPath(key))
+	if !ok || !s.now().Before(entry.ExpiresAt) {
 		return false, nil
 	}
-
-	// Unmarshal the actual data
 	if err := json.Unmarshal(entry.Data, dest); err != nil {
 		return false, err
 	}
-
-	return true, nil
+	return true, s.touch(c.entryPath(key))
 }
 
-// Set stores an item in the cache.
+// Set stores an item in the cache, evicting the least recently used entries if
+// the cache grows past its limits.
 func (c *Cache) Set(key string, data interface{}) error {
-	path := c.Path(key)
+	s := c.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return c.set(key, &Response{Data: data})
+}
 
-	// Ensure parent directory exists
-	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+// set stores the data of a response under key. The caller holds s.mu.
+func (c *Cache) set(key string, resp *Response) error {
+	dataBytes, err := json.Marshal(resp.Data)
+	if err != nil {
 		return err
 	}
+	now := c.store.now()
+	return c.store.write(c.entryPath(key), &Entry{
+		Data:         dataBytes,
+		CachedAt:     now,
+		ExpiresAt:    now.Add(c.ttl),
+		ETag:         resp.ETag,
+		LastModified: resp.LastModified,
+	})
+}
 
-	// Marshal the data
-	dataBytes, err := json.Marshal(data)
+// Fetch gets the value for key from the cache, or from fetch if it isn't
+// cached or has expired, and decodes it into dest.
+//
+// An expired entry is revalidated by calling fetch with its validators; if
+// fetch reports it NotModified, it is used again for another TTL. Within the
+// StaleWhileRevalidate window, the expired entry is used straight away and
+// revalidated in the background; call Wait before exiting to let that finish.
+// Background revalidations run one at a time, in the order they were started,
+// so a loop over many stale keys doesn't call the source all at once.
+func (c *Cache) Fetch(key string, dest any, fetch FetchFunc) error {
+	s := c.store
+	p := c.entryPath(key)
+
+	s.mu.Lock()
+	entry, ok := s.read(p)
+	now := s.now()
+	fresh := ok && now.Before(entry.ExpiresAt)
+	stale := ok && !fresh && now.Before(entry.ExpiresAt.Add(s.opts.StaleWhileRevalidate))
+	var err error
+	if fresh || stale {
+		err = s.touch(p)
+	}
+	s.mu.Unlock()
 	if err != nil {
 		return err
 	}
 
-	entry := Entry{
-		Data:      dataBytes,
-		CachedAt:  time.Now(),
-		ExpiresAt: time.Now().Add(c.ttl),
+	if stale {
+		s.background(func() {
+			if _, err := c.revalidate(key, entry, fetch); err != nil {
+				s.mu.Lock()
+				s.errors = append(s.errors, fmt.Errorf("revalidating %s: %w", key, err))
+				s.mu.Unlock()
+			}
+		})
+	}
+	if !fresh && !stale {
+		if !ok {
+			entry = nil
+		}
+		if entry, err = c.revalidate(key, entry, fetch); err != nil {
+			return err
+		}
 	}
+	return json.Unmarshal(entry.Data, dest)
+}
 
-	entryBytes, err := json.MarshalIndent(entry, "", "  ")
+// background queues fn to run after the background work already queued,
+// starting a worker if none is running.
+func (s *store) background(fn func()) {
+	s.wg.Add(1)
+	s.mu.Lock()
+	s.queue = append(s.queue, fn)
+	start := !s.busy
+	s.busy = true
+	s.mu.Unlock()
+	if start {
+		go s.work()
+	}
+}
+
+// work runs queued background work until the queue is empty.
+func (s *store) work() {
+	for {
+		s.mu.Lock()
+		if len(s.queue) == 0 {
+			s.busy = false
+			s.mu.Unlock()
+			return
+		}
+		fn := s.queue[0]
+		s.queue = s.queue[1:]
+		s.mu.Unlock()
+
+		fn()
+		s.wg.Done()
+	}
+}
+
+// revalidate calls fetch for key, with the validators of entry if it isn't
+// nil, and stores the result. fetch is called without holding s.mu, so other
+// keys can be used while it runs.
+func (c *Cache) revalidate(key string, entry *Entry, fetch FetchFunc) (*Entry, error) {
+	var v Validators
+	if entry != nil {
+		v = Validators{ETag: entry.ETag, LastModified: entry.LastModified}
+	}
+	resp, err := fetch(v)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	s := c.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p := c.entryPath(key)
+	if resp.NotModified {
+		if entry == nil {
+			return nil, fmt.Errorf("cache.Fetch: %s reported not modified, but is not cached", key)
+		}
+		now := s.now()
+		refreshed := *entry
+		refreshed.CachedAt, refreshed.ExpiresAt = now, now.Add(c.ttl)
+		return &refreshed, s.write(p, &refreshed)
 	}
 
-	return os.WriteFile(path, entryBytes, 0644)
+	if err := c.set(key, resp); err != nil {
+		return nil, err
+	}
+	refreshed, ok := s.read(p)
+	if !ok {
+		return nil, fmt.Errorf("cache.Fetch: %s could not be read back", key)
+	}
+	return refreshed, nil
+}
+
+// Wait waits for background revalidation started by Fetch to finish, and
+// returns any errors it had.
+func (c *Cache) Wait() error {
+	s := c.store
+	s.wg.Wait()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err := errors.Join(s.errors...)
+	s.errors = nil
+	return err
 }
 
 // Delete removes an item from the cache.
 func (c *Cache) Delete(key string) error {
-	path := c.Path(key)
-	err := os.Remove(path)
-	if os.IsNotExist(err) {
-		return nil
-	}
-	return err
+	s := c.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.remove(c.entryPath(key))
 }
 
-// Clear removes all cached items.
+// Clear removes all cached items, or all items in the bucket.
 func (c *Cache) Clear() error {
-	return os.RemoveAll(c.baseDir)
+	s := c.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.load(); err != nil {
+		return err
+	}
+	for p := range s.index {
+		if inBucket(p, c.bucket) {
+			s.forget(p)
+		}
+	}
+	if c.bucket != "" {
+		if err := s.medium.DeleteAll(c.bucket); err != nil {
+			return err
+		}
+		return s.save()
+	}
+
+	entries, err := s.medium.List("")
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	for _, e := range entries {
+		if err := s.medium.DeleteAll(e.Name()); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // Age returns how old a cached item is, or -1 if not cached.
 func (c *Cache) Age(key string) time.Duration {
-	path := c.Path(key)
+	s := c.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.read(c.entryPath(key))
+	if !ok {
+		return -1
+	}
+	return s.now().Sub(entry.CachedAt)
+}
+
+// Stats describes the contents of a cache.
+type Stats struct {
+	Entries int
+	Size    int64
+	// Expired is the number of entries past their TTL, including those that
+	// can still be served while they are revalidated.
+	Expired int
+	// Buckets breaks the totals down by top-level bucket. Entries outside a
+	// bucket are counted under "".
+	Buckets []BucketStats
+}
+
+// BucketStats describes the contents of one bucket.
+type BucketStats struct {
+	Name    string
+	Entries int
+	Size    int64
+}
+
+// Stats returns the number and size of the entries in the cache, or in the
+// bucket.
+func (c *Cache) Stats() (Stats, error) {
+	s := c.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.load(); err != nil {
+		return Stats{}, err
+	}
+
+	var stats Stats
+	buckets := make(map[string]*BucketStats)
+	now := s.now()
+	for p, ie := range s.index {
+		if !inBucket(p, c.bucket) {
+			continue
+		}
+		stats.Entries++
+		stats.Size += ie.Size
+		if entry, ok := s.read(p); ok && !now.Before(entry.ExpiresAt) {
+			stats.Expired++
+		}
+
+		name, _, found := strings.Cut(strings.TrimPrefix(strings.TrimPrefix(p, c.bucket), "/"), "/")
+		if !found {
+			name = ""
+		}
+		b, ok := buckets[name]
+		if !ok {
+			b = &BucketStats{Name: name}
+			buckets[name] = b
+		}
+		b.Entries++
+		b.Size += ie.Size
+	}
+	for _, b := range buckets {
+		stats.Buckets = append(stats.Buckets, *b)
+	}
+	sort.Slice(stats.Buckets, func(i, j int) bool { return stats.Buckets[i].Name < stats.Buckets[j].Name })
+	return stats, nil
+}
+
+// Prune removes entries that have expired and can no longer be served while
+// they are revalidated, then evicts the least recently used entries until the
+// cache is within its limits. It returns the number of entries removed.
+func (c *Cache) Prune() (int, error) {
+	s := c.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.load(); err != nil {
+		return 0, err
+	}
+	if err := s.sync(); err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	now := s.now()
+	for p := range s.index {
+		if !inBucket(p, c.bucket) {
+			continue
+		}
+		entry, ok := s.read(p)
+		if ok && now.Before(entry.ExpiresAt.Add(s.opts.StaleWhileRevalidate)) {
+			continue
+		}
+		if err := s.medium.Delete(p); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return removed, err
+		}
+		s.forget(p)
+		removed++
+	}
 
-	data, err := os.ReadFile(path)
+	evicted, err := s.evict("")
+	removed += evicted
 	if err != nil {
-		return -1
+		return removed, err
 	}
+	return removed, s.save()
+}
 
+// inBucket reports whether the entry path p is in bucket.
+func inBucket(p, bucket string) bool {
+	return bucket == "" || strings.HasPrefix(p, bucket+"/")
+}
+
+// read returns the entry stored at p, if there is a valid one.
+func (s *store) read(p string) (*Entry, bool) {
+	data, err := s.medium.Read(p)
+	if err != nil {
+		return nil, false
+	}
 	var entry Entry
-	if err := json.Unmarshal(data, &entry); err != nil {
-		return -1
+	if err := json.Unmarshal([]byte(data), &entry); err != nil {
+		// Invalid cache file, treat as miss
+		return nil, false
 	}
+	return &entry, true
+}
 
-	return time.Since(entry.CachedAt)
+// write stores entry at p and evicts other entries if the cache is over its
+// limits.
+func (s *store) write(p string, entry *Entry) error {
+	if err := s.load(); err != nil {
+		return err
+	}
+	entryBytes, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := s.medium.Write(p, string(entryBytes)); err != nil {
+		return err
+	}
+	s.index[p] = &indexEntry{Size: int64(len(entryBytes)), Accessed: s.now()}
+	s.merge()
+	if _, err := s.evict(p); err != nil {
+		return err
+	}
+	return s.flush()
+}
+
+// touch marks the entry at p as just used. The index isn't saved until the
+// next change to the cache, so a hit doesn't rewrite it.
+func (s *store) touch(p string) error {
+	if err := s.load(); err != nil {
+		return err
+	}
+	ie, ok := s.index[p]
+	if !ok {
+		info, err := s.medium.Stat(p)
+		if err != nil {
+			return nil
+		}
+		ie = &indexEntry{Size: info.Size()}
+		s.index[p] = ie
+	}
+	ie.Accessed = s.now()
+	return nil
+}
+
+// remove deletes the entry at p.
+func (s *store) remove(p string) error {
+	if err := s.load(); err != nil {
+		return err
+	}
+	err := s.medium.Delete(p)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	s.forget(p)
+	return s.save()
+}
+
+// forget drops p from the index, and keeps it from coming back when the
+// index is merged with the one on disk.
+func (s *store) forget(p string) {
+	delete(s.index, p)
+	if s.removed == nil {
+		s.removed = make(map[string]bool)
+	}
+	s.removed[p] = true
+}
+
+// evict removes the least recently used entries, other than keep, until the
+// cache is within its limits.
+func (s *store) evict(keep string) (int, error) {
+	var size int64
+	over := func() bool {
+		return (s.opts.MaxSize > 0 && size > s.opts.MaxSize) ||
+			(s.opts.MaxEntries > 0 && len(s.index) > s.opts.MaxEntries)
+	}
+	for _, ie := range s.index {
+		size += ie.Size
+	}
+	if !over() {
+		return 0, nil
+	}
+
+	// Other processes may have written or evicted entries since the index was
+	// read, so check it against the files before choosing what to evict.
+	if err := s.sync(); err != nil {
+		return 0, err
+	}
+	size = 0
+	for _, ie := range s.index {
+		size += ie.Size
+	}
+	if !over() {
+		return 0, nil
+	}
+
+	paths := make([]string, 0, len(s.index))
+	for p := range s.index {
+		if p != keep {
+			paths = append(paths, p)
+		}
+	}
+	sort.Slice(paths, func(i, j int) bool {
+		return s.index[paths[i]].Accessed.Before(s.index[paths[j]].Accessed)
+	})
+
+	evicted := 0
+	for _, p := range paths {
+		if !over() {
+			break
+		}
+		if err := s.medium.Delete(p); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return evicted, err
+		}
+		size -= s.index[p].Size
+		s.forget(p)
+		evicted++
+	}
+	return evicted, nil
+}
+
+// load reads the index, rebuilding it from the stored entries if it is
+// missing or damaged.
+func (s *store) load() error {
+	if s.index != nil {
+		return nil
+	}
+	if data, err := s.medium.Read(indexName); err == nil {
+		if json.Unmarshal([]byte(data), &s.index) == nil && s.index != nil {
+			return nil
+		}
+	}
+
+	index, err := s.scan()
+	if err != nil {
+		return err
+	}
+	s.index = index
+	return nil
+}
+
+// sync brings the index in line with the stored entries: entries whose files
+// are gone are dropped, sizes are taken from the files, and files missing
+// from the index are added with their modification time as their last use.
+func (s *store) sync() error {
+	index, err := s.scan()
+	if err != nil {
+		return err
+	}
+	for p, ie := range index {
+		if old, ok := s.index[p]; ok {
+			ie.Accessed = old.Accessed
+		}
+	}
+	for p := range s.index {
+		if _, ok := index[p]; !ok {
+			s.forget(p)
+		}
+	}
+	s.index = index
+	return nil
+}
+
+// scan builds an index from the stored entries.
+func (s *store) scan() (map[string]*indexEntry, error) {
+	index := make(map[string]*indexEntry)
+	if !s.medium.IsDir("") {
+		return index, nil
+	}
+	err := s.medium.WalkDir("", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(p, ".json") {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		index[p] = &indexEntry{Size: info.Size(), Accessed: info.ModTime()}
+		return nil
+	})
+	return index, err
+}
+
+// save merges the index with the one on disk and writes it.
+func (s *store) save() error {
+	s.merge()
+	return s.flush()
+}
+
+// merge adds the entries in the index on disk that other processes sharing
+// the cache have written, and takes the later of the two access times for
+// entries in both. Entries deleted since the index was last saved are left
+// out.
+func (s *store) merge() {
+	data, err := s.medium.Read(indexName)
+	if err != nil {
+		return
+	}
+	var disk map[string]*indexEntry
+	if json.Unmarshal([]byte(data), &disk) != nil {
+		return
+	}
+	for p, ie := range disk {
+		if ie == nil || s.removed[p] {
+			continue
+		}
+		if cur, ok := s.index[p]; !ok {
+			s.index[p] = ie
+		} else if ie.Accessed.After(cur.Accessed) {
+			cur.Accessed = ie.Accessed
+		}
+	}
+}
+
+// flush writes the index.
+func (s *store) flush() error {
+	data, err := json.Marshal(s.index)
+	if err != nil {
+		return err
+	}
+	if err := s.medium.Write(indexName, string(data)); err != nil {
+		return err
+	}
+	s.removed = nil
+	return nil
 }
 
 // GitHub-specific cache keys
 
 // GitHubReposKey returns the cache key for an org's repo list.
 func GitHubReposKey(org string) string {
-	return filepath.Join("github", org, "repos")
+	return path.Join("github", org, "repos")
 }
 
 // GitHubRepoKey returns the cache key for a specific repo's metadata.
 func GitHubRepoKey(org, repo string) string {
-	return filepath.Join("github", org, repo, "meta")
+	return path.Join("github", org, repo, "meta")
 }
@@ -0,0 +1,354 @@
+package cache
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/host-uk/core/pkg/io"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// clock sets a fixed time for c and returns it, to be moved on by the test.
+func clock(c *Cache) *time.Time {
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	c.store.now = func() time.Time { return now }
+	return &now
+}
+
+func TestCache_GetSet(t *testing.T) {
+	c := NewMemory(Options{TTL: time.Minute})
+	now := clock(c)
+
+	var got []string
+	found, err := c.Get("repos", &got)
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	require.NoError(t, c.Set("repos", []string{"core", "docs"}))
+	found, err = c.Get("repos", &got)
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, []string{"core", "docs"}, got)
+
+	*now = now.Add(30 * time.Second)
+	assert.Equal(t, 30*time.Second, c.Age("repos"))
+
+	*now = now.Add(time.Minute)
+	found, err = c.Get("repos", &got)
+	require.NoError(t, err)
+	assert.False(t, found, "expired")
+	assert.Equal(t, time.Duration(-1), c.Age("missing"))
+
+	require.NoError(t, c.Delete("repos"))
+	require.NoError(t, c.Delete("repos"), "deleting a missing key is fine")
+	assert.Equal(t, time.Duration(-1), c.Age("repos"))
+}
+
+func TestCache_Buckets(t *testing.T) {
+	c := NewMemory(Options{})
+	issues := c.Bucket("issues")
+	reviews := c.Bucket("reviews").WithTTL(time.Minute)
+
+	require.NoError(t, issues.Set("core", 1))
+	require.NoError(t, reviews.Set("core", 2))
+	require.NoError(t, c.Set("core", 3))
+	assert.Equal(t, "issues/core", issues.Path("core"))
+	assert.Equal(t, "issues/core", issues.Path("../../core"), "keys cannot leave their bucket")
+
+	var n int
+	found, err := issues.Get("core", &n)
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, 1, n)
+
+	stats, err := c.Stats()
+	require.NoError(t, err)
+	assert.Equal(t, 3, stats.Entries)
+	require.Len(t, stats.Buckets, 3)
+	assert.Equal(t, "", stats.Buckets[0].Name)
+	assert.Equal(t, "issues", stats.Buckets[1].Name)
+	assert.Equal(t, 1, stats.Buckets[1].Entries)
+
+	require.NoError(t, issues.Clear())
+	found, err = issues.Get("core", &n)
+	require.NoError(t, err)
+	assert.False(t, found)
+	found, err = reviews.Get("core", &n)
+	require.NoError(t, err)
+	assert.True(t, found, "other buckets are kept")
+
+	require.NoError(t, c.Clear())
+	stats, err = c.Stats()
+	require.NoError(t, err)
+	assert.Zero(t, stats.Entries)
+}
+
+func TestCache_Eviction(t *testing.T) {
+	c := NewMemory(Options{MaxEntries: 2})
+	now := clock(c)
+
+	for _, key := range []string{"a", "b"} {
+		require.NoError(t, c.Set(key, key))
+		*now = now.Add(time.Second)
+	}
+	var v string
+	found, err := c.Get("a", &v)
+	require.NoError(t, err)
+	require.True(t, found)
+	*now = now.Add(time.Second)
+
+	require.NoError(t, c.Set("c", "c"))
+	for key, want := range map[string]bool{"a": true, "b": false, "c": true} {
+		found, err := c.Get(key, &v)
+		require.NoError(t, err)
+		assert.Equal(t, want, found, key)
+	}
+
+	t.Run("MaxSize", func(t *testing.T) {
+		c := NewMemory(Options{MaxSize: 400})
+		now := clock(c)
+		for _, key := range []string{"a", "b", "c", "d"} {
+			require.NoError(t, c.Set(key, "0123456789012345678901234567890123456789"))
+			*now = now.Add(time.Second)
+		}
+		stats, err := c.Stats()
+		require.NoError(t, err)
+		assert.LessOrEqual(t, stats.Size, int64(400))
+		assert.Less(t, stats.Entries, 4)
+		found, err := c.Get("d", &v)
+		require.NoError(t, err)
+		assert.True(t, found, "the newest entry is kept")
+	})
+}
+
+func TestCache_Prune(t *testing.T) {
+	c := NewMemory(Options{TTL: time.Minute, StaleWhileRevalidate: time.Minute})
+	now := clock(c)
+
+	require.NoError(t, c.Set("old", 1))
+	require.NoError(t, c.Set("stale", 2))
+	*now = now.Add(90 * time.Second)
+	require.NoError(t, c.Set("stale", 2))
+	*now = now.Add(90 * time.Second)
+	require.NoError(t, c.Set("fresh", 3))
+
+	stats, err := c.Stats()
+	require.NoError(t, err)
+	assert.Equal(t, 3, stats.Entries)
+	assert.Equal(t, 2, stats.Expired)
+
+	removed, err := c.Prune()
+	require.NoError(t, err)
+	assert.Equal(t, 1, removed, "stale entries can still be served")
+	assert.Equal(t, time.Duration(-1), c.Age("old"))
+}
+
+func TestCache_SharedIndex(t *testing.T) {
+	t.Run("Hits don't rewrite the index", func(t *testing.T) {
+		m := io.NewMockMedium()
+		c := Open(m, Options{})
+		now := clock(c)
+		require.NoError(t, c.Set("a", 1))
+		before, err := m.Read(indexName)
+		require.NoError(t, err)
+
+		*now = now.Add(time.Minute)
+		var v int
+		found, err := c.Get("a", &v)
+		require.NoError(t, err)
+		require.True(t, found)
+		after, err := m.Read(indexName)
+		require.NoError(t, err)
+		assert.Equal(t, before, after)
+
+		// The access is saved with the next write.
+		require.NoError(t, c.Set("b", 2))
+		var index map[string]*indexEntry
+		data, err := m.Read(indexName)
+		require.NoError(t, err)
+		require.NoError(t, json.Unmarshal([]byte(data), &index))
+		assert.True(t, index["a.json"].Accessed.Equal(*now))
+	})
+
+	t.Run("Processes keep each other's entries", func(t *testing.T) {
+		m := io.NewMockMedium()
+		c1, c2 := Open(m, Options{}), Open(m, Options{})
+		_, err := c1.Stats()
+		require.NoError(t, err)
+		_, err = c2.Stats()
+		require.NoError(t, err)
+
+		require.NoError(t, c1.Set("a", 1))
+		require.NoError(t, c2.Set("b", 2))
+		require.NoError(t, c1.Delete("a"))
+
+		stats, err := Open(m, Options{}).Stats()
+		require.NoError(t, err)
+		assert.Equal(t, 1, stats.Entries)
+		stats, err = c1.Stats()
+		require.NoError(t, err)
+		assert.Equal(t, 1, stats.Entries, "b is picked up from the index on disk")
+	})
+
+	t.Run("Prune evicts entries missing from the index", func(t *testing.T) {
+		m := io.NewMockMedium()
+		c1, c2 := Open(m, Options{MaxEntries: 2}), Open(m, Options{MaxEntries: 2})
+		_, err := c1.Stats()
+		require.NoError(t, err)
+
+		require.NoError(t, c2.Set("a", 1))
+		require.NoError(t, c2.Set("b", 2))
+		// Lose a and b from the index, with files older than c.
+		require.NoError(t, m.Delete(indexName))
+		m.ModTimes["a.json"] = time.Now().Add(-time.Hour)
+		m.ModTimes["b.json"] = time.Now().Add(-time.Hour)
+		require.NoError(t, c1.Set("c", 3))
+		removed, err := c1.Prune()
+		require.NoError(t, err)
+		assert.Equal(t, 1, removed)
+
+		names := 0
+		for _, key := range []string{"a", "b", "c"} {
+			if m.IsFile(key + ".json") {
+				names++
+			}
+		}
+		assert.Equal(t, 2, names)
+		assert.True(t, m.IsFile("c.json"), "the newest entry is kept")
+	})
+}
+
+func TestCache_Fetch(t *testing.T) {
+	c := NewMemory(Options{TTL: time.Minute})
+	now := clock(c)
+
+	var mu sync.Mutex
+	var calls []Validators
+	body := `["core"]`
+	fetch := func(v Validators) (*Response, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls = append(calls, v)
+		if v.ETag == `"v1"` && body == `["core"]` {
+			return &Response{NotModified: true}, nil
+		}
+		return &Response{Data: json.RawMessage(body), ETag: `"v1"`, LastModified: "Wed, 01 May 2024 10:00:00 GMT"}, nil
+	}
+
+	var got []string
+	require.NoError(t, c.Fetch("repos", &got, fetch))
+	assert.Equal(t, []string{"core"}, got)
+	require.NoError(t, c.Fetch("repos", &got, fetch))
+	assert.Len(t, calls, 1, "fresh entries are not fetched")
+	assert.Equal(t, Validators{}, calls[0])
+
+	*now = now.Add(2 * time.Minute)
+	require.NoError(t, c.Fetch("repos", &got, fetch))
+	require.Len(t, calls, 2)
+	assert.Equal(t, Validators{ETag: `"v1"`, LastModified: "Wed, 01 May 2024 10:00:00 GMT"}, calls[1])
+	assert.Equal(t, time.Duration(0), c.Age("repos"), "not modified extends the entry")
+
+	t.Run("Errors", func(t *testing.T) {
+		failed := errors.New("offline")
+		err := c.Fetch("other", &got, func(Validators) (*Response, error) { return nil, failed })
+		assert.ErrorIs(t, err, failed)
+
+		err = c.Fetch("other", &got, func(Validators) (*Response, error) { return &Response{NotModified: true}, nil })
+		assert.Error(t, err)
+	})
+
+	t.Run("StaleWhileRevalidate", func(t *testing.T) {
+		c := NewMemory(Options{TTL: time.Minute, StaleWhileRevalidate: time.Hour})
+		now := clock(c)
+		require.NoError(t, c.Set("repos", []string{"old"}))
+		*now = now.Add(2 * time.Minute)
+
+		release := make(chan struct{})
+		fetch := func(Validators) (*Response, error) {
+			<-release
+			return &Response{Data: []string{"new"}}, nil
+		}
+		var got []string
+		require.NoError(t, c.Fetch("repos", &got, fetch))
+		assert.Equal(t, []string{"old"}, got, "stale data is served straight away")
+
+		close(release)
+		require.NoError(t, c.Wait())
+		found, err := c.Get("repos", &got)
+		require.NoError(t, err)
+		assert.True(t, found)
+		assert.Equal(t, []string{"new"}, got)
+
+		*now = now.Add(2 * time.Minute)
+		require.NoError(t, c.Fetch("repos", &got, func(Validators) (*Response, error) {
+			return nil, errors.New("offline")
+		}))
+		assert.Error(t, c.Wait(), "background errors are reported by Wait")
+	})
+
+	t.Run("OneRevalidationAtATime", func(t *testing.T) {
+		c := NewMemory(Options{TTL: time.Minute, StaleWhileRevalidate: time.Hour})
+		now := clock(c)
+		keys := []string{"a", "b", "c", "d"}
+		for _, key := range keys {
+			require.NoError(t, c.Set(key, []string{key}))
+		}
+		*now = now.Add(2 * time.Minute)
+
+		var mu sync.Mutex
+		var running, most int
+		var order []string
+		for _, key := range keys {
+			var got []string
+			require.NoError(t, c.Fetch(key, &got, func(Validators) (*Response, error) {
+				mu.Lock()
+				running++
+				most = max(most, running)
+				order = append(order, key)
+				mu.Unlock()
+				time.Sleep(10 * time.Millisecond)
+				mu.Lock()
+				running--
+				mu.Unlock()
+				return &Response{NotModified: true}, nil
+			}))
+		}
+		require.NoError(t, c.Wait())
+		assert.Equal(t, 1, most, "revalidations don't overlap")
+		assert.Equal(t, keys, order)
+	})
+}
+
+func TestNew(t *testing.T) {
+	dir := t.TempDir()
+	c, err := New(dir, 0)
+	require.NoError(t, err)
+	require.NoError(t, c.Set(GitHubReposKey("host-uk"), []string{"core"}))
+	assert.FileExists(t, filepath.Join(dir, "github", "host-uk", "repos.json"))
+
+	// A cache opened without its index rebuilds it from the entries.
+	require.NoError(t, os.Remove(filepath.Join(dir, indexName)))
+	c, err = New(dir, 0)
+	require.NoError(t, err)
+	stats, err := c.Stats()
+	require.NoError(t, err)
+	assert.Equal(t, 1, stats.Entries)
+
+	require.NoError(t, c.Clear())
+	assert.DirExists(t, dir)
+	assert.NoFileExists(t, filepath.Join(dir, "github", "host-uk", "repos.json"))
+}
+
+func TestOpen_Medium(t *testing.T) {
+	m := io.NewMockMedium()
+	c := Open(m, Options{})
+	require.NoError(t, c.Bucket("github").Set("repos", 1))
+	assert.True(t, m.IsFile("github/repos.json"))
+	assert.True(t, m.IsFile(indexName))
+}
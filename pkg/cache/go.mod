@@ -1,3 +1,18 @@
 module github.com/host-uk/core/pkg/cache
 
-go 1.24
+go 1.25
+
+require (
+	github.com/host-uk/core v0.0.0
+	github.com/stretchr/testify v1.11.1
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/fsnotify/fsnotify v1.9.0 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	golang.org/x/sys v0.40.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace github.com/host-uk/core => ../../
@@ -14,7 +14,7 @@
 // Example:
 //
 //	// Create a new config service.
-//	cfg, err := config.New()
+//	cfg, err := config.New(config.Options{})
 //	if err != nil {
 //		log.Fatalf("failed to create config service: %v", err)
 //	}
@@ -41,32 +41,64 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
-	"strings"
+	"sync"
 
-	"github.com/host-uk/core/pkg/core"
 	"github.com/adrg/xdg"
+	"github.com/host-uk/core/pkg/config/schema"
+	"github.com/host-uk/core/pkg/core"
+	"github.com/host-uk/core/pkg/io"
 )
 
 // HandleIPCEvents processes IPC messages for the config service.
 func (s *Service) HandleIPCEvents(c *core.Core, msg core.Message) error {
 	switch msg.(type) {
 	case core.ActionServiceStartup:
-		// Config initializes during Register(), no additional startup needed.
+		// Config initializes during Register() and watches its files from
+		// OnStartup, no additional startup needed.
+		return nil
+	case ActionConfigChanged:
+		// Sent by this service.
 		return nil
 	default:
-		if c.App != nil && c.App.Logger != nil {
-			c.App.Logger.Debug("Config: Unhandled message type", "type", fmt.Sprintf("%T", msg))
-		}
+		c.Logger("config").Debug("Unhandled message type", "type", fmt.Sprintf("%T", msg))
 	}
 	return nil
 }
 
+// errKeyNotFound reports that key does not name a configuration value.
+func errKeyNotFound(op, key string) error {
+	return core.E(op, fmt.Sprintf("key '%s' not found in config", key), nil,
+		core.WithKind(core.KindNotFound),
+		core.WithCode("config.key_not_found"),
+		core.WithMeta("key", key))
+}
+
 const appName = "lethean"
 const configFileName = "config.json"
 
-// Options holds configuration for the config service. This struct is provided
-// for future extensibility and currently has no fields.
-type Options struct{}
+// ConfigVersion is the version of the config file format, saved in its
+// "version" field.
+const ConfigVersion = 1
+
+// migrations upgrade config files from older versions. Files from before
+// versions were added have no version field and are taken to be version 0.
+var migrations = schema.NewMigrator(ConfigVersion).Unversioned(0).
+	Register(0, "add version field", func(map[string]any) error { return nil })
+
+// Options holds configuration for the config service. The zero value finds
+// the system and workspace files in their usual places.
+type Options struct {
+	// SystemFile is the machine-wide config file. If empty, the first
+	// lethean/config.json in the XDG config directories is used.
+	SystemFile string
+	// WorkspaceFile overrides values for one workspace. If empty,
+	// .core/config.json in the current directory or its nearest parent that
+	// has one is used.
+	WorkspaceFile string
+	// Flags holds values given on the command line, by key. They override
+	// every other layer.
+	Flags map[string]string
+}
 
 // Service provides access to the application's configuration.
 // It handles loading, saving, and providing access to configuration values,
@@ -74,23 +106,44 @@ type Options struct{}
 // The Service is designed to be a central point for all configuration-related
 // operations within the application.
 //
-// The fields of the Service struct are automatically saved to and loaded from
-// a JSON configuration file. The `json:"-"` tag on ServiceRuntime prevents
-// it from being serialized.
+// The fields of the Service struct hold the resolved configuration. Each is
+// taken from the highest Layer that sets it: built-in defaults, the system
+// file, the user's config.json, the workspace file, CORE_* environment
+// variables and finally command line flags. Only values set in the user
+// layer are saved.
 type Service struct {
 	*core.ServiceRuntime[Options] `json:"-"`
 
-	// Persistent fields, saved to config.json.
-	ConfigPath   string   `json:"configPath,omitempty"`
-	UserHomeDir  string   `json:"userHomeDir,omitempty"`
-	RootDir      string   `json:"rootDir,omitempty"`
-	CacheDir     string   `json:"cacheDir,omitempty"`
-	ConfigDir    string   `json:"configDir,omitempty"`
-	DataDir      string   `json:"dataDir,omitempty"`
-	WorkspaceDir string   `json:"workspaceDir,omitempty"`
-	DefaultRoute string   `json:"default_route"`
+	mu            sync.RWMutex
+	layers        layers
+	systemFile    string
+	userFile      string // the user's config file, fixed when the service is created
+	workspaceFile string
+	notifier      notifier
+	secrets       *Secrets
+
+	watchMu sync.Mutex
+	watches []*io.Watch
+	watchWG sync.WaitGroup
+
+	// Persistent fields, saved to config.json. The validate tags are their
+	// schema, checked on load and by Set.
+	ConfigPath   string   `json:"configPath,omitempty" validate:"required"`
+	UserHomeDir  string   `json:"userHomeDir,omitempty" validate:"required"`
+	RootDir      string   `json:"rootDir,omitempty" validate:"required"`
+	CacheDir     string   `json:"cacheDir,omitempty" validate:"required"`
+	ConfigDir    string   `json:"configDir,omitempty" validate:"required"`
+	DataDir      string   `json:"dataDir,omitempty" validate:"required"`
+	WorkspaceDir string   `json:"workspaceDir,omitempty" validate:"required"`
+	DefaultRoute string   `json:"default_route" validate:"required,startswith=/"`
+	Theme        string   `json:"theme" validate:"required,oneof=system light dark"`
 	Features     []string `json:"features"`
-	Language     string   `json:"language"`
+	Language     string   `json:"language" validate:"required,min=2"`
+
+	// WorkspaceStorage, if set, is a storage URL such as
+	// "s3://bucket/prefix?endpoint=http://minio:9000&pathStyle=true" that
+	// workspaces are stored at instead of WorkspaceDir.
+	WorkspaceStorage string `json:"workspaceStorage,omitempty" validate:"omitempty,url"`
 }
 
 // createServiceInstance handles the setup of the configuration service. It
@@ -98,22 +151,22 @@ type Service struct {
 // file if it exists. If the configuration file is not found, it creates a new
 // one with default values. This function is not exported and is used internally
 // by the New and Register constructors.
-func createServiceInstance() (*Service, error) {
+func createServiceInstance(opts Options) (*Service, error) {
 	// --- Path and Directory Setup ---
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
-		return nil, fmt.Errorf("could not resolve user home directory: %w", err)
+		return nil, core.E("config.New", "could not resolve user home directory", err, core.WithKind(core.KindUnavailable))
 	}
 	userHomeDir := filepath.Join(homeDir, appName)
 
 	rootDir, err := xdg.DataFile(appName)
 	if err != nil {
-		return nil, fmt.Errorf("could not resolve data directory: %w", err)
+		return nil, core.E("config.New", "could not resolve data directory", err, core.WithKind(core.KindUnavailable))
 	}
 
 	cacheDir, err := xdg.CacheFile(appName)
 	if err != nil {
-		return nil, fmt.Errorf("could not resolve cache directory: %w", err)
+		return nil, core.E("config.New", "could not resolve cache directory", err, core.WithKind(core.KindUnavailable))
 	}
 
 	s := &Service{
@@ -124,32 +177,67 @@ func createServiceInstance() (*Service, error) {
 		DataDir:      filepath.Join(userHomeDir, "data"),
 		WorkspaceDir: filepath.Join(userHomeDir, "workspace"),
 		DefaultRoute: "/",
+		Theme:        "system",
 		Features:     []string{},
 		Language:     "en",
 	}
 	s.ConfigPath = filepath.Join(s.ConfigDir, configFileName)
+	s.userFile = s.ConfigPath
+
+	s.loadDefaults()
+
+	// --- Load Layers ---
+	systemFile := opts.SystemFile
+	if systemFile == "" {
+		systemFile = systemConfigFile()
+	}
+	if systemFile != "" {
+		if _, err := s.loadFile(LayerSystem, systemFile); err != nil {
+			return nil, err
+		}
+	}
+	s.systemFile = systemFile
+	userExists, err := s.loadFile(LayerUser, s.userFile)
+	if err != nil {
+		return nil, err
+	}
+	workspaceFile := opts.WorkspaceFile
+	if workspaceFile == "" {
+		workspaceFile = workspaceConfigPath()
+	}
+	if workspaceFile != "" {
+		if _, err := s.loadFile(LayerWorkspace, workspaceFile); err != nil {
+			return nil, err
+		}
+	}
+	s.workspaceFile = workspaceFile
+	if err := s.loadEnv(); err != nil {
+		return nil, err
+	}
+	if err := s.loadFlags(opts.Flags); err != nil {
+		return nil, err
+	}
+	s.resolveAll()
+	if err := schema.Validate(s); err != nil {
+		return nil, core.E("config.New", "invalid config", err,
+			core.WithKind(core.KindInvalid),
+			core.WithCode("config.invalid"))
+	}
+
+	s.secrets = &Secrets{path: filepath.Join(s.ConfigDir, secretsFileName)}
 
-	dirs := []string{s.RootDir, s.ConfigDir, s.DataDir, s.CacheDir, s.WorkspaceDir, s.UserHomeDir}
+	dirs := []string{s.RootDir, s.ConfigDir, filepath.Dir(s.userFile), s.DataDir, s.CacheDir, s.WorkspaceDir, s.UserHomeDir}
 	for _, dir := range dirs {
 		if err := os.MkdirAll(dir, os.ModePerm); err != nil {
-			return nil, fmt.Errorf("could not create directory %s: %w", dir, err)
+			return nil, core.E("config.New", "could not create directory "+dir, err, core.WithMeta("path", dir))
 		}
 	}
 
-	// --- Load or Create Configuration ---
-	if data, err := os.ReadFile(s.ConfigPath); err == nil {
-		// Config file exists, load it.
-		if err := json.Unmarshal(data, s); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal config: %w", err)
-		}
-	} else if os.IsNotExist(err) {
-		// Config file does not exist, create it with default values.
+	if !userExists {
+		// Config file does not exist, create it.
 		if err := s.Save(); err != nil {
-			return nil, fmt.Errorf("failed to create default config file: %w", err)
+			return nil, core.E("config.New", "failed to create default config file", err)
 		}
-	} else {
-		// Another error occurred reading the file.
-		return nil, fmt.Errorf("failed to read config file: %w", err)
 	}
 
 	return s, nil
@@ -158,17 +246,19 @@ func createServiceInstance() (*Service, error) {
 // New creates a new instance of the configuration service. This constructor is
 // intended for static dependency injection, where the service is created and
 // managed manually. It initializes the service with default paths and values,
-// and loads any existing configuration from disk.
+// and layers the configuration files, environment and flags over them.
 //
 // Example:
 //
-//	cfg, err := config.New()
+//	cfg, err := config.New(config.Options{
+//		Flags: map[string]string{"language": "de"},
+//	})
 //	if err != nil {
 //		log.Fatalf("Failed to initialize config: %v", err)
 //	}
 //	// Use cfg to access configuration settings.
-func New() (*Service, error) {
-	return createServiceInstance()
+func New(opts Options) (*Service, error) {
+	return createServiceInstance(opts)
 }
 
 // Register creates a new instance of the configuration service and registers it
@@ -177,20 +267,23 @@ func New() (*Service, error) {
 // It performs the same initialization as New, but also integrates the service
 // with the provided core instance.
 func Register(c *core.Core) (any, error) {
-	s, err := createServiceInstance()
+	s, err := createServiceInstance(Options{})
 	if err != nil {
 		return nil, err
 	}
 	// Defensive check: createServiceInstance should not return nil service with nil error
 	if s == nil {
-		return nil, errors.New("config: createServiceInstance returned a nil service instance with no error")
+		return nil, core.E("config.Register", "createServiceInstance returned a nil service instance with no error", nil)
 	}
 	s.ServiceRuntime = core.NewServiceRuntime(c, Options{})
 	return s, nil
 }
 
-// Save writes the current configuration to a JSON file. The location of the file
-// is determined by the ConfigPath field of the Service struct. This method is
+// Save writes the user layer to a JSON file. The file is the user's
+// config.json found when the service was created, whatever ConfigPath has
+// been set to since. Fields that were changed directly since they were
+// resolved are saved too, while values from the environment, flags and other
+// files are not. Dotted keys are saved as nested objects. This method is
 // typically called automatically by Set, but can be used to explicitly save
 // changes.
 //
@@ -201,21 +294,52 @@ func Register(c *core.Core) (any, error) {
 //		log.Printf("Error saving configuration: %v", err)
 //	}
 func (s *Service) Save() error {
-	data, err := json.MarshalIndent(s, "", "  ")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.save()
+}
+
+// save writes the user layer. The caller must hold s.mu.
+func (s *Service) save() error {
+	if s.layers[LayerUser] == nil {
+		s.layers[LayerUser] = map[string]Source{}
+	}
+	leaves := map[string]any{}
+	for key, src := range s.layers[LayerUser] {
+		if _, _, ok := s.field(key); !ok {
+			leaves[key] = src.Value
+		}
+	}
+	values := expand(leaves)
+	values[schema.VersionKey] = ConfigVersion
+	for _, name := range s.fields() {
+		f, _, _ := s.field(name)
+		if src, ok := s.winner(name); !ok || !reflect.DeepEqual(src.Value, f.Interface()) {
+			s.layers[LayerUser][name] = Source{Layer: LayerUser, Origin: s.userFile, Value: f.Interface()}
+		}
+		if src, ok := s.layers[LayerUser][name]; ok {
+			values[name] = src.Value
+		}
+	}
+
+	data, err := json.MarshalIndent(values, "", "  ")
 	if err != nil {
-		return fmt.Errorf("failed to marshal config: %w", err)
+		return core.E("config.Save", "failed to marshal config", err)
 	}
 
-	if err := os.WriteFile(s.ConfigPath, data, 0644); err != nil {
-		return fmt.Errorf("failed to write config file: %w", err)
+	if err := os.WriteFile(s.userFile, data, 0644); err != nil {
+		return core.E("config.Save", "failed to write config file", err, core.WithMeta("path", s.userFile))
 	}
 	return nil
 }
 
-// Get retrieves a configuration value by its key. The key corresponds to the
-// JSON tag of a field in the Service struct. The retrieved value is stored in
-// the `out` parameter, which must be a non-nil pointer to a variable of the
-// correct type.
+// Get retrieves a configuration value by its key. The key is the JSON tag of
+// a field in the Service struct, or a dotted key such as
+// "modules.mining.pool.url". A dotted prefix reads every value under it, as
+// a map or into a struct. The retrieved value is stored in the `out`
+// parameter, which must be a non-nil pointer to a variable of the correct
+// type. Values of dotted keys are converted where they can be, so a number
+// can be read into an int and "30s" into a time.Duration.
 //
 // Example:
 //
@@ -226,32 +350,38 @@ func (s *Service) Save() error {
 //	}
 //	fmt.Println("Current language is:", currentLanguage)
 func (s *Service) Get(key string, out any) error {
-	val := reflect.ValueOf(s).Elem()
-	typ := val.Type()
-
-	for i := 0; i < val.NumField(); i++ {
-		field := typ.Field(i)
-		jsonTag := field.Tag.Get("json")
-		if jsonTag != "" && jsonTag != "-" {
-			jsonName := strings.Split(jsonTag, ",")[0]
-			if strings.EqualFold(jsonName, key) {
-				outVal := reflect.ValueOf(out)
-				if outVal.Kind() != reflect.Ptr || outVal.IsNil() {
-					return errors.New("output argument must be a non-nil pointer")
-				}
-				targetVal := outVal.Elem()
-				srcVal := val.Field(i)
-
-				if !srcVal.Type().AssignableTo(targetVal.Type()) {
-					return fmt.Errorf("cannot assign config value of type %s to output of type %s", srcVal.Type(), targetVal.Type())
-				}
-				targetVal.Set(srcVal)
-				return nil
-			}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	srcVal, _, isField := s.field(key)
+	value, ok := s.lookup(key)
+	if !ok {
+		return errKeyNotFound("config.Get", key)
+	}
+	outVal := reflect.ValueOf(out)
+	if outVal.Kind() != reflect.Ptr || outVal.IsNil() {
+		return core.E("config.Get", "output argument must be a non-nil pointer", nil, core.WithKind(core.KindInvalid))
+	}
+	targetVal := outVal.Elem()
+	if !isField {
+		v, err := convert(value, targetVal.Type())
+		if err != nil {
+			return core.E("config.Get", fmt.Sprintf("cannot read key '%s' into %s", key, targetVal.Type()), err,
+				core.WithKind(core.KindInvalid),
+				core.WithCode("config.type_mismatch"),
+				core.WithMeta("key", key))
 		}
+		targetVal.Set(v)
+		return nil
 	}
-
-	return fmt.Errorf("key '%s' not found in config", key)
+	if !srcVal.Type().AssignableTo(targetVal.Type()) {
+		return core.E("config.Get", fmt.Sprintf("cannot assign config value of type %s to output of type %s", srcVal.Type(), targetVal.Type()), nil,
+			core.WithKind(core.KindInvalid),
+			core.WithCode("config.type_mismatch"),
+			core.WithMeta("key", key))
+	}
+	targetVal.Set(srcVal)
+	return nil
 }
 
 // SaveStruct saves an arbitrary struct to a JSON file in the config directory.
@@ -271,10 +401,10 @@ func (s *Service) Get(key string, out any) error {
 //		log.Printf("Error saving user preferences: %v", err)
 //	}
 func (s *Service) SaveStruct(key string, data interface{}) error {
-	filePath := filepath.Join(s.ConfigDir, key+".json")
+	filePath := filepath.Join(s.configDir(), key+".json")
 	jsonData, err := json.MarshalIndent(data, "", "  ")
 	if err != nil {
-		return fmt.Errorf("failed to marshal struct for key '%s': %w", key, err)
+		return core.E("config.SaveStruct", fmt.Sprintf("failed to marshal struct for key '%s'", key), err, core.WithMeta("key", key))
 	}
 	return os.WriteFile(filePath, jsonData, 0644)
 }
@@ -293,20 +423,33 @@ func (s *Service) SaveStruct(key string, data interface{}) error {
 //	}
 //	fmt.Printf("User theme is: %s", prefs.Theme)
 func (s *Service) LoadStruct(key string, data interface{}) error {
-	filePath := filepath.Join(s.ConfigDir, key+".json")
+	filePath := filepath.Join(s.configDir(), key+".json")
 	jsonData, err := os.ReadFile(filePath)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return nil // Return nil if the file doesn't exist
 		}
-		return fmt.Errorf("failed to read struct file for key '%s': %w", key, err)
+		return core.E("config.LoadStruct", fmt.Sprintf("failed to read struct file for key '%s'", key), err, core.WithMeta("key", key))
 	}
 	return json.Unmarshal(jsonData, data)
 }
 
+// configDir returns ConfigDir, which a reload may change at any time.
+func (s *Service) configDir() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.ConfigDir
+}
+
 // Set updates a configuration value and saves the change to the configuration
-// file. The key corresponds to the JSON tag of a field in the Service struct.
-// The provided value `v` must be of a type that is assignable to the field.
+// file. The key is the JSON tag of a field in the Service struct, and the
+// provided value `v` must be of a type that is assignable to the field, or a
+// dotted key such as "modules.mining.pool.url". A map given for a dotted key
+// sets every key in it. If the key has a default, `v` must be the same kind
+// of value.
+// The value is stored in the user layer, so while the workspace file, the
+// environment or a flag sets the same key, their value stays in effect.
+// If the value in effect changes, an ActionConfigChanged is sent.
 //
 // Example:
 //
@@ -315,30 +458,94 @@ func (s *Service) LoadStruct(key string, data interface{}) error {
 //		log.Printf("Failed to set default route: %v", err)
 //	}
 func (s *Service) Set(key string, v any) error {
-	val := reflect.ValueOf(s).Elem()
-	typ := val.Type()
-
-	for i := 0; i < val.NumField(); i++ {
-		field := typ.Field(i)
-		jsonTag := field.Tag.Get("json")
-		if jsonTag != "" && jsonTag != "-" {
-			jsonName := strings.Split(jsonTag, ",")[0]
-			if strings.EqualFold(jsonName, key) {
-				fieldVal := val.Field(i)
-				if !fieldVal.CanSet() {
-					return fmt.Errorf("cannot set config field for key '%s'", key)
-				}
-				newVal := reflect.ValueOf(v)
-				if !newVal.Type().AssignableTo(fieldVal.Type()) {
-					return fmt.Errorf("type mismatch for key '%s': expected %s, got %s", key, fieldVal.Type(), newVal.Type())
-				}
-				fieldVal.Set(newVal)
-				return s.Save()
-			}
+	return s.update(func() error { return s.set(key, v) })
+}
+
+// set sets key to v in the user layer and saves it. The caller must hold
+// s.mu.
+func (s *Service) set(key string, v any) error {
+	fieldVal, name, ok := s.field(key)
+	if !ok {
+		return s.setKey(key, v)
+	}
+	newVal := reflect.ValueOf(v)
+	if !newVal.IsValid() || !newVal.Type().AssignableTo(fieldVal.Type()) {
+		return core.E("config.Set", fmt.Sprintf("type mismatch for key '%s': expected %s, got %T", key, fieldVal.Type(), v), nil,
+			core.WithKind(core.KindInvalid),
+			core.WithCode("config.type_mismatch"),
+			core.WithMeta("key", key))
+	}
+	if s.layers[LayerUser] == nil {
+		s.layers[LayerUser] = map[string]Source{}
+	}
+	prev, hadPrev := s.layers[LayerUser][name]
+	s.layers[LayerUser][name] = Source{Layer: LayerUser, Origin: s.userFile, Value: newVal.Convert(fieldVal.Type()).Interface()}
+	s.resolve(name)
+	if err := s.validateField(name); err != nil {
+		if hadPrev {
+			s.layers[LayerUser][name] = prev
+		} else {
+			delete(s.layers[LayerUser], name)
+		}
+		s.resolve(name)
+		return core.E("config.Set", fmt.Sprintf("invalid value for key '%s'", key), err,
+			core.WithKind(core.KindInvalid),
+			core.WithCode("config.invalid"),
+			core.WithMeta("key", key))
+	}
+	return s.save()
+}
+
+// validateField checks the field named name against its schema.
+func (s *Service) validateField(name string) error {
+	err := schema.Validate(s)
+	var verr *schema.Error
+	if !errors.As(err, &verr) {
+		return err
+	}
+	var errs []schema.FieldError
+	for _, fe := range verr.Errors {
+		if fe.Field == name {
+			errs = append(errs, fe)
 		}
 	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return &schema.Error{Errors: errs}
+}
 
-	return fmt.Errorf("key '%s' not found in config", key)
+// setKey sets a dotted key in the user layer and saves it. The caller must
+// hold s.mu.
+func (s *Service) setKey(key string, v any) error {
+	if !namespaced(key) {
+		if validKey(key) {
+			return errKeyNotFound("config.Set", key)
+		}
+		return errInvalidKey("config.Set", key)
+	}
+	if s.shadowsField(key) {
+		return errShadowsField("config.Set", key)
+	}
+	if def, ok := s.layers[LayerDefault][key]; ok && kindClass(def.Value) != kindClass(v) {
+		return core.E("config.Set", fmt.Sprintf("type mismatch for key '%s': expected %T, got %T", key, def.Value, v), nil,
+			core.WithKind(core.KindInvalid),
+			core.WithCode("config.type_mismatch"),
+			core.WithMeta("key", key))
+	}
+	v = normalize(v)
+	if _, ok := v.(map[string]any); !ok && kindClass(v) == "map" {
+		// Save structs as the maps they are read back as.
+		m, err := convert(v, reflect.TypeOf(map[string]any{}))
+		if err != nil {
+			return core.E("config.Set", fmt.Sprintf("cannot set key '%s'", key), err,
+				core.WithKind(core.KindInvalid),
+				core.WithMeta("key", key))
+		}
+		v = m.Interface()
+	}
+	s.setLeaves(LayerUser, key, v, s.userFile)
+	return s.save()
 }
 
 // EnableFeature enables a feature by adding it to the features list.
@@ -351,14 +558,15 @@ func (s *Service) Set(key string, v any) error {
 //		log.Printf("Failed to enable feature: %v", err)
 //	}
 func (s *Service) EnableFeature(feature string) error {
-	// Check if feature is already enabled
-	for _, f := range s.Features {
-		if f == feature {
-			return nil // Already enabled
+	return s.update(func() error {
+		// Check if feature is already enabled
+		for _, f := range s.Features {
+			if f == feature {
+				return nil // Already enabled
+			}
 		}
-	}
-	s.Features = append(s.Features, feature)
-	return s.Save()
+		return s.set("features", append(append([]string{}, s.Features...), feature))
+	})
 }
 
 // DisableFeature disables a feature by removing it from the features list.
@@ -371,13 +579,15 @@ func (s *Service) EnableFeature(feature string) error {
 //		log.Printf("Failed to disable feature: %v", err)
 //	}
 func (s *Service) DisableFeature(feature string) error {
-	for i, f := range s.Features {
-		if f == feature {
-			s.Features = append(s.Features[:i], s.Features[i+1:]...)
-			return s.Save()
+	return s.update(func() error {
+		for i, f := range s.Features {
+			if f == feature {
+				features := append(append([]string{}, s.Features[:i]...), s.Features[i+1:]...)
+				return s.set("features", features)
+			}
 		}
-	}
-	return nil // Feature wasn't enabled, no-op
+		return nil // Feature wasn't enabled, no-op
+	})
 }
 
 // IsFeatureEnabled checks if a feature is enabled.
@@ -388,6 +598,8 @@ func (s *Service) DisableFeature(feature string) error {
 //		// Apply dark mode styles
 //	}
 func (s *Service) IsFeatureEnabled(feature string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 	for _, f := range s.Features {
 		if f == feature {
 			return true
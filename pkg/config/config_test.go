@@ -51,7 +51,7 @@ func TestConfigService(t *testing.T) {
 		_, cleanup := setupTestEnv(t)
 		defer cleanup()
 
-		serviceInstance, err := New()
+		serviceInstance, err := New(Options{})
 		if err != nil {
 			t.Fatalf("New() failed: %v", err)
 		}
@@ -83,7 +83,7 @@ func TestConfigService(t *testing.T) {
 			t.Fatalf("Failed to write custom config file: %v", err)
 		}
 
-		serviceInstance, err := New()
+		serviceInstance, err := New(Options{})
 		if err != nil {
 			t.Fatalf("New() failed while loading existing config: %v", err)
 		}
@@ -109,7 +109,7 @@ func TestConfigService(t *testing.T) {
 		_, cleanup := setupTestEnv(t)
 		defer cleanup()
 
-		s, err := New()
+		s, err := New(Options{})
 		if err != nil {
 			t.Fatalf("New() failed: %v", err)
 		}
@@ -146,7 +146,7 @@ func TestConfigService(t *testing.T) {
 			t.Fatalf("Failed to write invalid config file: %v", err)
 		}
 
-		_, err := New()
+		_, err := New(Options{})
 		if err == nil {
 			t.Error("New() should fail when config file contains invalid JSON")
 		}
@@ -194,7 +194,7 @@ func TestConfigService(t *testing.T) {
 		_, cleanup := setupTestEnv(t)
 		defer cleanup()
 
-		s, err := New()
+		s, err := New(Options{})
 		if err != nil {
 			t.Fatalf("New() failed: %v", err)
 		}
@@ -204,16 +204,19 @@ func TestConfigService(t *testing.T) {
 		if err == nil {
 			t.Error("Get() should fail for nonexistent key")
 		}
-		if err != nil && err.Error() != "key 'nonexistent_key' not found in config" {
+		if err != nil && err.Error() != "config.Get: key 'nonexistent_key' not found in config" {
 			t.Errorf("Expected 'key not found' error, got: %v", err)
 		}
+		if !core.Is(err, core.KindNotFound) || core.CodeOf(err) != "config.key_not_found" {
+			t.Errorf("Expected a not_found error with code config.key_not_found, got: %v", err)
+		}
 	})
 
 	t.Run("Get with non-pointer output", func(t *testing.T) {
 		_, cleanup := setupTestEnv(t)
 		defer cleanup()
 
-		s, err := New()
+		s, err := New(Options{})
 		if err != nil {
 			t.Fatalf("New() failed: %v", err)
 		}
@@ -223,7 +226,7 @@ func TestConfigService(t *testing.T) {
 		if err == nil {
 			t.Error("Get() should fail for non-pointer output")
 		}
-		if err != nil && err.Error() != "output argument must be a non-nil pointer" {
+		if err != nil && err.Error() != "config.Get: output argument must be a non-nil pointer" {
 			t.Errorf("Expected 'non-nil pointer' error, got: %v", err)
 		}
 	})
@@ -232,7 +235,7 @@ func TestConfigService(t *testing.T) {
 		_, cleanup := setupTestEnv(t)
 		defer cleanup()
 
-		s, err := New()
+		s, err := New(Options{})
 		if err != nil {
 			t.Fatalf("New() failed: %v", err)
 		}
@@ -242,7 +245,7 @@ func TestConfigService(t *testing.T) {
 		if err == nil {
 			t.Error("Get() should fail for nil pointer output")
 		}
-		if err != nil && err.Error() != "output argument must be a non-nil pointer" {
+		if err != nil && err.Error() != "config.Get: output argument must be a non-nil pointer" {
 			t.Errorf("Expected 'non-nil pointer' error, got: %v", err)
 		}
 	})
@@ -251,7 +254,7 @@ func TestConfigService(t *testing.T) {
 		_, cleanup := setupTestEnv(t)
 		defer cleanup()
 
-		s, err := New()
+		s, err := New(Options{})
 		if err != nil {
 			t.Fatalf("New() failed: %v", err)
 		}
@@ -270,7 +273,7 @@ func TestConfigService(t *testing.T) {
 		_, cleanup := setupTestEnv(t)
 		defer cleanup()
 
-		s, err := New()
+		s, err := New(Options{})
 		if err != nil {
 			t.Fatalf("New() failed: %v", err)
 		}
@@ -279,16 +282,19 @@ func TestConfigService(t *testing.T) {
 		if err == nil {
 			t.Error("Set() should fail for nonexistent key")
 		}
-		if err != nil && err.Error() != "key 'nonexistent_key' not found in config" {
+		if err != nil && err.Error() != "config.Set: key 'nonexistent_key' not found in config" {
 			t.Errorf("Expected 'key not found' error, got: %v", err)
 		}
+		if !core.Is(err, core.KindNotFound) || core.CodeOf(err) != "config.key_not_found" {
+			t.Errorf("Expected a not_found error with code config.key_not_found, got: %v", err)
+		}
 	})
 
 	t.Run("Set with type mismatch", func(t *testing.T) {
 		_, cleanup := setupTestEnv(t)
 		defer cleanup()
 
-		s, err := New()
+		s, err := New(Options{})
 		if err != nil {
 			t.Fatalf("New() failed: %v", err)
 		}
@@ -314,7 +320,7 @@ func TestSaveStruct(t *testing.T) {
 		_, cleanup := setupTestEnv(t)
 		defer cleanup()
 
-		s, err := New()
+		s, err := New(Options{})
 		if err != nil {
 			t.Fatalf("New() failed: %v", err)
 		}
@@ -345,7 +351,7 @@ func TestSaveStruct(t *testing.T) {
 		_, cleanup := setupTestEnv(t)
 		defer cleanup()
 
-		s, err := New()
+		s, err := New(Options{})
 		if err != nil {
 			t.Fatalf("New() failed: %v", err)
 		}
@@ -370,7 +376,7 @@ func TestLoadStruct(t *testing.T) {
 		_, cleanup := setupTestEnv(t)
 		defer cleanup()
 
-		s, err := New()
+		s, err := New(Options{})
 		if err != nil {
 			t.Fatalf("New() failed: %v", err)
 		}
@@ -398,7 +404,7 @@ func TestLoadStruct(t *testing.T) {
 		_, cleanup := setupTestEnv(t)
 		defer cleanup()
 
-		s, err := New()
+		s, err := New(Options{})
 		if err != nil {
 			t.Fatalf("New() failed: %v", err)
 		}
@@ -414,7 +420,7 @@ func TestLoadStruct(t *testing.T) {
 		_, cleanup := setupTestEnv(t)
 		defer cleanup()
 
-		s, err := New()
+		s, err := New(Options{})
 		if err != nil {
 			t.Fatalf("New() failed: %v", err)
 		}
@@ -436,7 +442,7 @@ func TestLoadStruct(t *testing.T) {
 		_, cleanup := setupTestEnv(t)
 		defer cleanup()
 
-		s, err := New()
+		s, err := New(Options{})
 		if err != nil {
 			t.Fatalf("New() failed: %v", err)
 		}
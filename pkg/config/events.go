@@ -0,0 +1,170 @@
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/host-uk/core/pkg/ws"
+)
+
+// HubChannel is the ws.Hub channel that config changes are published to by
+// default.
+const HubChannel = "config"
+
+// ActionConfigChanged is sent for each key whose value changes, whether by
+// Set, Delete, SetDefaults or an edit to a config file.
+//
+// Example:
+//
+//	core.Subscribe(c, func(msg config.ActionConfigChanged) error {
+//		if msg.Key == "language" {
+//			return i18n.SetLanguage(msg.New.(string))
+//		}
+//		return nil
+//	})
+type ActionConfigChanged struct {
+	// Key is the full key, e.g. "language" or "modules.mining.pool.url".
+	Key string `json:"key"`
+	// Old is the previous value, or nil if the key had none.
+	Old any `json:"old"`
+	// New is the current value, or nil if the key was removed.
+	New any `json:"new"`
+}
+
+// subscription is a function added by Subscribe.
+type subscription struct {
+	prefix string
+	fn     func(ActionConfigChanged)
+}
+
+// notifier delivers changes to subscribers, over IPC and to a ws.Hub.
+type notifier struct {
+	mu      sync.Mutex
+	subs    []*subscription
+	hub     *ws.Hub
+	channel string
+}
+
+// Subscribe calls fn with every change to key and to the keys under it, so
+// "modules.mining" sees "modules.mining.pool.url". An empty key sees every
+// change. fn is called after the change is made, without any lock held, so
+// it may read and set config values.
+//
+// The returned function removes the subscription.
+//
+// Example:
+//
+//	unsubscribe := cfg.Subscribe("default_route", func(msg config.ActionConfigChanged) {
+//		display.Navigate(msg.New.(string))
+//	})
+//	defer unsubscribe()
+func (s *Service) Subscribe(key string, fn func(ActionConfigChanged)) func() {
+	sub := &subscription{prefix: key, fn: fn}
+	s.notifier.mu.Lock()
+	s.notifier.subs = append(s.notifier.subs, sub)
+	s.notifier.mu.Unlock()
+	return func() {
+		s.notifier.mu.Lock()
+		defer s.notifier.mu.Unlock()
+		for i, other := range s.notifier.subs {
+			if other == sub {
+				s.notifier.subs = append(s.notifier.subs[:i:i], s.notifier.subs[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// SetHub sets the ws.Hub that changes are published to, on channel, or on
+// HubChannel if channel is empty. A nil hub stops publishing.
+func (s *Service) SetHub(hub *ws.Hub, channel string) {
+	if channel == "" {
+		channel = HubChannel
+	}
+	s.notifier.mu.Lock()
+	defer s.notifier.mu.Unlock()
+	s.notifier.hub, s.notifier.channel = hub, channel
+}
+
+// update runs fn with s.mu held and reports every value it changes.
+func (s *Service) update(fn func() error) error {
+	s.mu.Lock()
+	before := s.values()
+	err := fn()
+	changes := diff(before, s.values())
+	s.mu.Unlock()
+
+	s.publish(changes)
+	return err
+}
+
+// values returns the current value of every key. The caller must hold s.mu.
+func (s *Service) values() map[string]any {
+	values := map[string]any{}
+	for _, key := range s.keys("") {
+		if v, ok := s.lookup(key); ok {
+			values[key] = v
+		}
+	}
+	return values
+}
+
+// diff returns the changes from before to after, sorted by key.
+func diff(before, after map[string]any) []ActionConfigChanged {
+	var changes []ActionConfigChanged
+	for key, old := range before {
+		if v, ok := after[key]; !ok || !sameValue(old, v) {
+			changes = append(changes, ActionConfigChanged{Key: key, Old: old, New: v})
+		}
+	}
+	for key, v := range after {
+		if _, ok := before[key]; !ok {
+			changes = append(changes, ActionConfigChanged{Key: key, New: v})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Key < changes[j].Key })
+	return changes
+}
+
+// sameValue reports whether a and b are the same value once saved, so the 4
+// that was set and the 4.0 read back from the file are not a change.
+func sameValue(a, b any) bool {
+	if reflect.DeepEqual(a, b) {
+		return true
+	}
+	ja, errA := json.Marshal(a)
+	jb, errB := json.Marshal(b)
+	return errA == nil && errB == nil && string(ja) == string(jb)
+}
+
+// publish delivers changes to the subscribers, over IPC and to the hub.
+func (s *Service) publish(changes []ActionConfigChanged) {
+	if len(changes) == 0 {
+		return
+	}
+	s.notifier.mu.Lock()
+	subs := append([]*subscription(nil), s.notifier.subs...)
+	hub, channel := s.notifier.hub, s.notifier.channel
+	s.notifier.mu.Unlock()
+
+	for _, change := range changes {
+		for _, sub := range subs {
+			if sub.prefix == "" || change.Key == sub.prefix || strings.HasPrefix(change.Key, sub.prefix+".") {
+				sub.fn(change)
+			}
+		}
+		if s.ServiceRuntime != nil {
+			if err := s.Core().ACTION(change); err != nil {
+				s.Logger().Warn("config change handler failed", "key", change.Key, "err", err)
+			}
+		}
+		if hub != nil {
+			if err := hub.SendToChannel(channel, ws.Message{Type: ws.TypeEvent, Data: change}); err != nil {
+				s.Logger().Warn("failed to publish config change", "key", change.Key, "err", err)
+			}
+		}
+	}
+}
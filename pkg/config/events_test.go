@@ -0,0 +1,228 @@
+package config
+
+import (
+	"context"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/host-uk/core/pkg/core"
+	"github.com/host-uk/core/pkg/ws"
+)
+
+func TestChanges(t *testing.T) {
+	_, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	c, err := core.New(core.WithService(Register))
+	if err != nil {
+		t.Fatalf("core.New() failed: %v", err)
+	}
+	s := core.MustServiceFor[*Service](c, "config")
+
+	var actions []ActionConfigChanged
+	core.Subscribe(c, func(msg ActionConfigChanged) error {
+		actions = append(actions, msg)
+		return nil
+	})
+	var mining []string
+	unsubscribe := s.Namespace("modules.mining").Subscribe("", func(msg ActionConfigChanged) {
+		mining = append(mining, msg.Key)
+	})
+
+	if err := s.Set("language", "fr"); err != nil {
+		t.Fatalf("Set() failed: %v", err)
+	}
+	want := []ActionConfigChanged{{Key: "language", Old: "en", New: "fr"}}
+	if !reflect.DeepEqual(actions, want) {
+		t.Errorf("Expected %v, got %v", want, actions)
+	}
+
+	actions = nil
+	if err := s.Set("language", "fr"); err != nil {
+		t.Fatalf("Set() failed: %v", err)
+	}
+	if len(actions) != 0 {
+		t.Errorf("Expected no change for the same value, got %v", actions)
+	}
+
+	if err := s.Set("modules.mining.pool", map[string]any{"url": "stratum+tcp://pool.lthn.io", "port": 3333}); err != nil {
+		t.Fatalf("Set() failed: %v", err)
+	}
+	if err := s.Set("modules.other.enabled", true); err != nil {
+		t.Fatalf("Set() failed: %v", err)
+	}
+	if want := []string{"modules.mining.pool.port", "modules.mining.pool.url"}; !reflect.DeepEqual(mining, want) {
+		t.Errorf("Expected the subscription to see %v, got %v", want, mining)
+	}
+
+	actions = nil
+	if err := s.Delete("modules.mining.pool.url"); err != nil {
+		t.Fatalf("Delete() failed: %v", err)
+	}
+	want = []ActionConfigChanged{{Key: "modules.mining.pool.url", Old: "stratum+tcp://pool.lthn.io"}}
+	if !reflect.DeepEqual(actions, want) {
+		t.Errorf("Expected a change with no new value, got %v", actions)
+	}
+
+	unsubscribe()
+	mining = nil
+	if err := s.Set("modules.mining.threads", 2); err != nil {
+		t.Fatalf("Set() failed: %v", err)
+	}
+	if len(mining) != 0 {
+		t.Errorf("Expected no calls after unsubscribing, got %v", mining)
+	}
+
+	t.Run("Invalid values are not reported", func(t *testing.T) {
+		actions = nil
+		if err := s.Set("default_route", "home"); err == nil {
+			t.Fatal("Expected Set() to fail")
+		}
+		if len(actions) != 0 {
+			t.Errorf("Expected no change, got %v", actions)
+		}
+	})
+}
+
+func TestChanges_Hub(t *testing.T) {
+	_, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	hub := ws.NewHub()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go hub.Run(ctx)
+
+	server := httptest.NewServer(hub.Handler())
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("Dial() failed: %v", err)
+	}
+	defer conn.Close()
+	if err := conn.WriteJSON(ws.Message{Type: ws.TypeSubscribe, Data: HubChannel}); err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+	for deadline := time.Now().Add(time.Second); hub.Stats().Channels != 1; {
+		if time.Now().After(deadline) {
+			t.Fatal("Client did not subscribe")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	s, err := New(Options{})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	s.SetHub(hub, "")
+	if err := s.Set("default_route", "/dashboard"); err != nil {
+		t.Fatalf("Set() failed: %v", err)
+	}
+
+	if err := conn.SetReadDeadline(time.Now().Add(2 * time.Second)); err != nil {
+		t.Fatalf("SetReadDeadline() failed: %v", err)
+	}
+	var msg struct {
+		Type    ws.MessageType      `json:"type"`
+		Channel string              `json:"channel"`
+		Data    ActionConfigChanged `json:"data"`
+	}
+	if err := conn.ReadJSON(&msg); err != nil {
+		t.Fatalf("ReadJSON() failed: %v", err)
+	}
+	want := ActionConfigChanged{Key: "default_route", Old: "/", New: "/dashboard"}
+	if msg.Type != ws.TypeEvent || msg.Channel != HubChannel || !reflect.DeepEqual(msg.Data, want) {
+		t.Errorf("Expected %v on the %s channel, got %+v", want, HubChannel, msg)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	_, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	s, err := New(Options{})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	changes := make(chan ActionConfigChanged, 8)
+	s.Subscribe("", func(msg ActionConfigChanged) { changes <- msg })
+
+	if err := s.OnStartup(context.Background()); err != nil {
+		t.Fatalf("OnStartup() failed: %v", err)
+	}
+	defer s.OnShutdown(context.Background())
+
+	edit := `{"version": 1, "language": "de", "modules": {"mining": {"threads": 8}}}`
+	if err := os.WriteFile(s.ConfigPath, []byte(edit), 0644); err != nil {
+		t.Fatalf("Failed to edit config file: %v", err)
+	}
+
+	got := map[string]any{}
+	timeout := time.After(5 * time.Second)
+	for len(got) < 2 {
+		select {
+		case msg := <-changes:
+			got[msg.Key] = msg.New
+		case <-timeout:
+			t.Fatalf("Expected the edit to be reported, got %v", got)
+		}
+	}
+	if got["language"] != "de" || got["modules.mining.threads"] != float64(8) {
+		t.Errorf("Expected the edited values, got %v", got)
+	}
+	if got := s.GetString("language"); got != "de" {
+		t.Errorf("Expected the language to be reloaded, got '%s'", got)
+	}
+
+	t.Run("Invalid edits are ignored", func(t *testing.T) {
+		if err := os.WriteFile(s.ConfigPath, []byte(`{"version": 1, "language": "x"}`), 0644); err != nil {
+			t.Fatalf("Failed to edit config file: %v", err)
+		}
+		if err := s.Reload(); !core.Is(err, core.KindInvalid) {
+			t.Errorf("Expected an invalid error, got: %v", err)
+		}
+		if s.GetString("language") != "de" {
+			t.Errorf("Expected the language to be kept, got '%s'", s.GetString("language"))
+		}
+	})
+}
+
+func TestReload_Concurrent(t *testing.T) {
+	_, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	s, err := New(Options{})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	// Run with -race: reloads rewrite the fields the helpers below read.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for range 20 {
+			if err := s.Reload(); err != nil {
+				t.Errorf("Reload() failed: %v", err)
+			}
+		}
+	}()
+	for range 20 {
+		if err := s.EnableFeature("beta"); err != nil {
+			t.Errorf("EnableFeature() failed: %v", err)
+		}
+		s.IsFeatureEnabled("beta")
+		if err := s.SaveStruct("prefs", map[string]string{"theme": "dark"}); err != nil {
+			t.Errorf("SaveStruct() failed: %v", err)
+		}
+		if err := s.DisableFeature("beta"); err != nil {
+			t.Errorf("DisableFeature() failed: %v", err)
+		}
+	}
+	<-done
+}
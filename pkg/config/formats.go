@@ -8,6 +8,7 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/host-uk/core/pkg/core"
 	"gopkg.in/ini.v1"
 	"gopkg.in/yaml.v2"
 )
@@ -192,7 +193,9 @@ func GetConfigFormat(path string) (ConfigFormat, error) {
 	case ".xml":
 		return &XMLFormat{}, nil
 	default:
-		return nil, fmt.Errorf("unsupported config format: %s", ext)
+		return nil, core.E("config.GetConfigFormat", "unsupported config format: "+ext, nil,
+			core.WithKind(core.KindInvalid),
+			core.WithCode("config.unsupported_format"))
 	}
 }
 
@@ -213,7 +216,7 @@ func (s *Service) SaveKeyValues(key string, data map[string]interface{}) error {
 	if err != nil {
 		return err
 	}
-	filePath := filepath.Join(s.ConfigDir, key)
+	filePath := filepath.Join(s.configDir(), key)
 	return format.Save(filePath, data)
 }
 
@@ -234,6 +237,6 @@ func (s *Service) LoadKeyValues(key string) (map[string]interface{}, error) {
 	if err != nil {
 		return nil, err
 	}
-	filePath := filepath.Join(s.ConfigDir, key)
+	filePath := filepath.Join(s.configDir(), key)
 	return format.Load(filePath)
 }
@@ -4,9 +4,12 @@ go 1.25
 
 require (
 	github.com/adrg/xdg v0.5.3
+	github.com/host-uk/core/pkg/config/schema v0.0.0
+	github.com/gorilla/websocket v1.5.3
 	github.com/spf13/cobra v1.10.1
 	gopkg.in/ini.v1 v1.67.0
 	gopkg.in/yaml.v2 v2.4.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -18,3 +21,5 @@ require (
 	golang.org/x/sys v0.40.0 // indirect
 	gopkg.in/check.v1 v1.0.0-20201130134442-10cb98267c6c // indirect
 )
+
+replace github.com/host-uk/core/pkg/config/schema => ./schema
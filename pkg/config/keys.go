@@ -0,0 +1,369 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/host-uk/core/pkg/core"
+)
+
+// validKey reports whether key is a key or dotted key with no empty parts.
+func validKey(key string) bool {
+	for _, part := range strings.Split(key, ".") {
+		if part == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// namespaced reports whether key is a dotted key such as
+// "modules.mining.pool.url". Keys other than the struct fields must be
+// namespaced to be set.
+func namespaced(key string) bool {
+	return strings.Contains(key, ".") && validKey(key)
+}
+
+// errInvalidKey reports a key that can't be set.
+func errInvalidKey(op, key string) error {
+	return core.E(op, fmt.Sprintf("invalid key '%s': keys other than the built-in ones must be namespaced, e.g. 'modules.mining.pool.url'", key), nil,
+		core.WithKind(core.KindInvalid),
+		core.WithCode("config.invalid_key"),
+		core.WithMeta("key", key))
+}
+
+// shadowsField reports whether the first part of a dotted key names a struct
+// field, as "language" does in "language.foo". Such a key would be saved as an
+// object in place of the field's value, and the file could not be loaded
+// again.
+func (s *Service) shadowsField(key string) bool {
+	first, _, _ := strings.Cut(key, ".")
+	_, _, ok := s.field(first)
+	return ok
+}
+
+// errShadowsField reports a dotted key under a struct field.
+func errShadowsField(op, key string) error {
+	first, _, _ := strings.Cut(key, ".")
+	return core.E(op, fmt.Sprintf("invalid key '%s': '%s' is a built-in key and can't hold other keys", key, first), nil,
+		core.WithKind(core.KindInvalid),
+		core.WithCode("config.invalid_key"),
+		core.WithMeta("key", key))
+}
+
+// normalize converts the maps YAML decodes to map[string]any, so every
+// format yields the same shapes.
+func normalize(v any) any {
+	switch v := v.(type) {
+	case map[any]any:
+		m := make(map[string]any, len(v))
+		for k, item := range v {
+			m[fmt.Sprint(k)] = normalize(item)
+		}
+		return m
+	case map[string]any:
+		m := make(map[string]any, len(v))
+		for k, item := range v {
+			m[k] = normalize(item)
+		}
+		return m
+	case []any:
+		items := make([]any, len(v))
+		for i, item := range v {
+			items[i] = normalize(item)
+		}
+		return items
+	}
+	return v
+}
+
+// flatten returns the leaves of v under prefix, by dotted key. Values other
+// than non-empty maps are leaves.
+func flatten(prefix string, v any) map[string]any {
+	leaves := map[string]any{}
+	var walk func(key string, v any)
+	walk = func(key string, v any) {
+		m, ok := normalize(v).(map[string]any)
+		if !ok || len(m) == 0 {
+			leaves[key] = v
+			return
+		}
+		for k, item := range m {
+			if key != "" {
+				k = key + "." + k
+			}
+			walk(k, item)
+		}
+	}
+	walk(prefix, v)
+	return leaves
+}
+
+// expand builds nested maps from leaves by dotted key. It is the inverse of
+// flatten.
+func expand(leaves map[string]any) map[string]any {
+	keys := make([]string, 0, len(leaves))
+	for key := range leaves {
+		keys = append(keys, key)
+	}
+	// Shorter keys first, so a group set in a higher layer replaces a leaf.
+	sort.Strings(keys)
+
+	root := map[string]any{}
+	for _, key := range keys {
+		m := root
+		parts := strings.Split(key, ".")
+		for _, part := range parts[:len(parts)-1] {
+			child, ok := m[part].(map[string]any)
+			if !ok {
+				child = map[string]any{}
+				m[part] = child
+			}
+			m = child
+		}
+		m[parts[len(parts)-1]] = leaves[key]
+	}
+	return root
+}
+
+// lookup returns the value of key: a struct field, a single value, or the
+// group of values under a dotted prefix merged across the layers. The caller
+// must hold s.mu.
+func (s *Service) lookup(key string) (any, bool) {
+	if f, _, ok := s.field(key); ok {
+		return f.Interface(), true
+	}
+	if src, ok := s.winner(key); ok {
+		return src.Value, true
+	}
+
+	leaves := map[string]any{}
+	prefix := key + "."
+	for l := LayerDefault; l < layerCount; l++ {
+		for leaf, src := range s.layers[l] {
+			if rest, ok := strings.CutPrefix(leaf, prefix); ok {
+				// A value set in a higher layer replaces the whole of a group
+				// or a single value set in a lower one.
+				for k := range leaves {
+					if strings.HasPrefix(k, rest+".") || strings.HasPrefix(rest, k+".") {
+						delete(leaves, k)
+					}
+				}
+				leaves[rest] = src.Value
+			}
+		}
+	}
+	if len(leaves) == 0 {
+		return nil, false
+	}
+	return expand(leaves), true
+}
+
+// setLeaves replaces the values layer l has for key, including any under it
+// or above it, with the leaves of v.
+func (s *Service) setLeaves(l Layer, key string, v any, origin string) {
+	if s.layers[l] == nil {
+		s.layers[l] = map[string]Source{}
+	}
+	for leaf := range s.layers[l] {
+		if leaf == key || strings.HasPrefix(leaf, key+".") || strings.HasPrefix(key, leaf+".") {
+			delete(s.layers[l], leaf)
+		}
+	}
+	for leaf, value := range flatten(key, v) {
+		s.layers[l][leaf] = Source{Layer: l, Origin: origin, Value: value}
+	}
+}
+
+// kindClass groups kinds that hold the same kind of value, so an int default
+// accepts a float64 decoded from JSON.
+func kindClass(v any) string {
+	switch k := reflect.ValueOf(v).Kind(); k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		return "list"
+	case reflect.Map, reflect.Struct:
+		return "map"
+	case reflect.Invalid:
+		return ""
+	default:
+		return k.String()
+	}
+}
+
+// Keys returns every key that has a value, sorted: the struct fields and the
+// dotted keys from all layers.
+func (s *Service) Keys() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.keys("")
+}
+
+// keys returns the keys under prefix, relative to it. The caller must hold
+// s.mu.
+func (s *Service) keys(prefix string) []string {
+	seen := map[string]bool{}
+	if prefix == "" {
+		for _, name := range s.fields() {
+			seen[name] = true
+		}
+	}
+	for l := LayerDefault; l < layerCount; l++ {
+		for leaf := range s.layers[l] {
+			if _, _, ok := s.field(leaf); ok {
+				continue
+			}
+			if prefix == "" {
+				seen[leaf] = true
+			} else if rest, ok := strings.CutPrefix(leaf, prefix+"."); ok {
+				seen[rest] = true
+			}
+		}
+	}
+	keys := make([]string, 0, len(seen))
+	for key := range seen {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Delete removes the user's value for a dotted key, or for all the keys
+// under it, and saves the change, so that lower layers supply it again.
+//
+// Example:
+//
+//	err := cfg.Delete("modules.mining.pool")
+func (s *Service) Delete(key string) error {
+	return s.update(func() error { return s.delete(key) })
+}
+
+// delete removes the user's values for key and saves the change. The caller
+// must hold s.mu.
+func (s *Service) delete(key string) error {
+	if _, _, ok := s.field(key); ok {
+		return core.E("config.Delete", fmt.Sprintf("cannot delete built-in key '%s'", key), nil,
+			core.WithKind(core.KindInvalid),
+			core.WithCode("config.invalid_key"),
+			core.WithMeta("key", key))
+	}
+	if !namespaced(key) {
+		return errInvalidKey("config.Delete", key)
+	}
+	for leaf := range s.layers[LayerUser] {
+		if leaf == key || strings.HasPrefix(leaf, key+".") {
+			delete(s.layers[LayerUser], leaf)
+		}
+	}
+	return s.save()
+}
+
+// SetDefaults sets the default values of the keys under namespace, replacing
+// any it had. Nested maps become dotted keys, so the defaults in a module's
+// config, {"pool": {"url": "..."}}, set "modules.mining.pool.url" for
+// namespace "modules.mining". CORE_* environment variables for the new keys
+// are read too.
+//
+// Example:
+//
+//	err := cfg.SetDefaults("modules.mining", map[string]any{
+//		"pool": map[string]any{"url": "stratum+tcp://pool.lthn.io:3333"},
+//	})
+func (s *Service) SetDefaults(namespace string, defaults map[string]any) error {
+	if !validKey(namespace) {
+		return errInvalidKey("config.SetDefaults", namespace)
+	}
+	if s.shadowsField(namespace) {
+		return errShadowsField("config.SetDefaults", namespace)
+	}
+	return s.update(func() error { return s.setDefaults(namespace, defaults) })
+}
+
+// setDefaults replaces the default values under namespace. The caller must
+// hold s.mu.
+func (s *Service) setDefaults(namespace string, defaults map[string]any) error {
+	for leaf := range s.layers[LayerDefault] {
+		if strings.HasPrefix(leaf, namespace+".") {
+			delete(s.layers[LayerDefault], leaf)
+		}
+	}
+	for key, value := range defaults {
+		s.setLeaves(LayerDefault, namespace+"."+key, value, "")
+	}
+	for leaf := range s.layers[LayerDefault] {
+		if strings.HasPrefix(leaf, namespace+".") {
+			if err := s.loadEnvKey(leaf); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// getAs stores the value of key in out, reporting whether it was set and
+// could be converted.
+func (s *Service) getAs(key string, out any) bool {
+	return s.Get(key, out) == nil
+}
+
+// GetString returns the value of key as a string, or "" if it isn't set or
+// isn't a string.
+func (s *Service) GetString(key string) string {
+	var v string
+	s.getAs(key, &v)
+	return v
+}
+
+// GetInt returns the value of key as an int, or 0 if it isn't set or isn't a
+// number.
+func (s *Service) GetInt(key string) int {
+	var v int
+	s.getAs(key, &v)
+	return v
+}
+
+// GetFloat returns the value of key as a float64, or 0 if it isn't set or
+// isn't a number.
+func (s *Service) GetFloat(key string) float64 {
+	var v float64
+	s.getAs(key, &v)
+	return v
+}
+
+// GetBool returns the value of key as a bool, or false if it isn't set or
+// isn't a bool.
+func (s *Service) GetBool(key string) bool {
+	var v bool
+	s.getAs(key, &v)
+	return v
+}
+
+// GetDuration returns the value of key as a time.Duration, parsed from a
+// string such as "1m30s", or 0 if it isn't set or isn't a duration.
+func (s *Service) GetDuration(key string) time.Duration {
+	var v time.Duration
+	s.getAs(key, &v)
+	return v
+}
+
+// GetStringSlice returns the value of key as a list of strings, or nil if it
+// isn't set or isn't a list. Strings from the environment and flags are
+// split on commas.
+func (s *Service) GetStringSlice(key string) []string {
+	var v []string
+	s.getAs(key, &v)
+	return v
+}
+
+// GetStringMap returns the values under key, or nil if there are none.
+func (s *Service) GetStringMap(key string) map[string]any {
+	var v map[string]any
+	s.getAs(key, &v)
+	return v
+}
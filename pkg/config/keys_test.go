@@ -0,0 +1,230 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/host-uk/core/pkg/core"
+)
+
+func TestDottedKeys(t *testing.T) {
+	tempHomeDir, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	s, err := New(Options{})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	if err := s.Set("modules.mining.pool.url", "stratum+tcp://pool.lthn.io:3333"); err != nil {
+		t.Fatalf("Set() failed: %v", err)
+	}
+	if err := s.Set("modules.mining.threads", 4); err != nil {
+		t.Fatalf("Set() failed: %v", err)
+	}
+	if got := s.GetString("modules.mining.pool.url"); got != "stratum+tcp://pool.lthn.io:3333" {
+		t.Errorf("Expected the pool url, got '%s'", got)
+	}
+
+	var pool struct {
+		URL string `json:"url"`
+	}
+	if err := s.Get("modules.mining.pool", &pool); err != nil {
+		t.Fatalf("Get() of a group failed: %v", err)
+	}
+	if pool.URL != "stratum+tcp://pool.lthn.io:3333" {
+		t.Errorf("Expected the group to decode into a struct, got %+v", pool)
+	}
+
+	t.Run("Saved as nested objects", func(t *testing.T) {
+		data, err := os.ReadFile(filepath.Join(tempHomeDir, appName, "config", configFileName))
+		if err != nil {
+			t.Fatalf("Failed to read config file: %v", err)
+		}
+		var saved map[string]any
+		if err := json.Unmarshal(data, &saved); err != nil {
+			t.Fatalf("Failed to decode config file: %v", err)
+		}
+		want := map[string]any{"mining": map[string]any{
+			"pool":    map[string]any{"url": "stratum+tcp://pool.lthn.io:3333"},
+			"threads": float64(4),
+		}}
+		if !reflect.DeepEqual(saved["modules"], want) {
+			t.Errorf("Expected modules %v, got %v", want, saved["modules"])
+		}
+
+		s, err := New(Options{})
+		if err != nil {
+			t.Fatalf("New() failed: %v", err)
+		}
+		if got := s.GetInt("modules.mining.threads"); got != 4 {
+			t.Errorf("Expected threads to load as 4, got %d", got)
+		}
+	})
+
+	t.Run("Invalid keys", func(t *testing.T) {
+		err := s.Set("modules..url", "x")
+		if !core.Is(err, core.KindInvalid) || core.CodeOf(err) != "config.invalid_key" {
+			t.Errorf("Expected an invalid_key error, got: %v", err)
+		}
+		var v string
+		err = s.Get("modules.mining.missing", &v)
+		if !core.Is(err, core.KindNotFound) {
+			t.Errorf("Expected a not_found error, got: %v", err)
+		}
+	})
+
+	t.Run("Keys under built-in keys", func(t *testing.T) {
+		for _, key := range []string{"language.foo", "Features.beta"} {
+			err := s.Set(key, "bar")
+			if !core.Is(err, core.KindInvalid) || core.CodeOf(err) != "config.invalid_key" {
+				t.Errorf("Expected an invalid_key error for %s, got: %v", key, err)
+			}
+		}
+		err := s.SetDefaults("default_route", map[string]any{"home": "/"})
+		if !core.Is(err, core.KindInvalid) || core.CodeOf(err) != "config.invalid_key" {
+			t.Errorf("Expected an invalid_key error from SetDefaults, got: %v", err)
+		}
+
+		loaded, err := New(Options{})
+		if err != nil {
+			t.Fatalf("New() failed after rejected keys: %v", err)
+		}
+		if loaded.Language != "en" {
+			t.Errorf("Expected language to be kept, got '%s'", loaded.Language)
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		if err := s.Delete("modules.mining.pool"); err != nil {
+			t.Fatalf("Delete() failed: %v", err)
+		}
+		if got := s.GetString("modules.mining.pool.url"); got != "" {
+			t.Errorf("Expected the pool url to be deleted, got '%s'", got)
+		}
+		if got := s.GetInt("modules.mining.threads"); got != 4 {
+			t.Errorf("Expected other keys to be kept, got %d", got)
+		}
+		if err := s.Delete("language"); !core.Is(err, core.KindInvalid) {
+			t.Errorf("Expected built-in keys not to be deletable, got: %v", err)
+		}
+	})
+}
+
+func TestDefaults(t *testing.T) {
+	tempHomeDir, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	workspaceFile := filepath.Join(tempHomeDir, "project", ".core", "config.yaml")
+	if err := os.MkdirAll(filepath.Dir(workspaceFile), os.ModePerm); err != nil {
+		t.Fatalf("Failed to create workspace dir: %v", err)
+	}
+	yaml := "language: fr\nmodules:\n  mining:\n    pool:\n      port: 4444\n"
+	if err := os.WriteFile(workspaceFile, []byte(yaml), 0644); err != nil {
+		t.Fatalf("Failed to write workspace file: %v", err)
+	}
+	t.Setenv("CORE_MODULES_MINING_TIMEOUT", "90s")
+
+	s, err := New(Options{
+		WorkspaceFile: workspaceFile,
+		Flags:         map[string]string{"modules.mining.enabled": "true"},
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	if s.Language != "fr" {
+		t.Errorf("Expected language from the YAML workspace file, got '%s'", s.Language)
+	}
+
+	mining := s.Namespace("modules.mining")
+	err = mining.SetDefaults(map[string]any{
+		"pool":    map[string]any{"url": "stratum+tcp://pool.lthn.io", "port": 3333},
+		"timeout": "30s",
+		"enabled": false,
+	})
+	if err != nil {
+		t.Fatalf("SetDefaults() failed: %v", err)
+	}
+
+	if got := mining.GetString("pool.url"); got != "stratum+tcp://pool.lthn.io" {
+		t.Errorf("Expected the default pool url, got '%s'", got)
+	}
+	if got := mining.GetInt("pool.port"); got != 4444 {
+		t.Errorf("Expected the workspace to override the port, got %d", got)
+	}
+	if got := mining.GetDuration("timeout"); got != 90*time.Second {
+		t.Errorf("Expected CORE_MODULES_MINING_TIMEOUT to override the timeout, got %s", got)
+	}
+	if !mining.GetBool("enabled") {
+		t.Error("Expected the flag to enable mining")
+	}
+	if got, want := mining.Keys(), []string{"enabled", "pool.port", "pool.url", "timeout"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected keys %v, got %v", want, got)
+	}
+	want := map[string]any{
+		"enabled": "true",
+		"pool":    map[string]any{"url": "stratum+tcp://pool.lthn.io", "port": 4444},
+		"timeout": "90s",
+	}
+	if got := mining.All(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+
+	e, err := mining.Explain("pool.port")
+	if err != nil {
+		t.Fatalf("Explain() failed: %v", err)
+	}
+	if e.Layer != LayerWorkspace || len(e.Overridden) != 1 || e.Overridden[0].Layer != LayerDefault {
+		t.Errorf("Expected the port from the workspace over the default, got %+v", e)
+	}
+
+	err = mining.Set("pool.port", "4444")
+	if !core.Is(err, core.KindInvalid) || core.CodeOf(err) != "config.type_mismatch" {
+		t.Errorf("Expected a type_mismatch error for a string port, got: %v", err)
+	}
+
+	t.Run("Namespaces are isolated", func(t *testing.T) {
+		other := s.Namespace("modules.other")
+		if err := other.Set("pool.url", "x"); err != nil {
+			t.Fatalf("Set() failed: %v", err)
+		}
+		if got := mining.GetString("pool.url"); got != "stratum+tcp://pool.lthn.io" {
+			t.Errorf("Expected mining to keep its pool url, got '%s'", got)
+		}
+		if got := other.Keys(); !reflect.DeepEqual(got, []string{"pool.url"}) {
+			t.Errorf("Expected only the other module's keys, got %v", got)
+		}
+	})
+
+	t.Run("Defaults are replaced", func(t *testing.T) {
+		if err := mining.SetDefaults(map[string]any{"threads": 2}); err != nil {
+			t.Fatalf("SetDefaults() failed: %v", err)
+		}
+		if got := mining.GetString("pool.url"); got != "" {
+			t.Errorf("Expected the old default to be gone, got '%s'", got)
+		}
+		if got := mining.GetInt("threads"); got != 2 {
+			t.Errorf("Expected the new default, got %d", got)
+		}
+	})
+}
+
+func TestFlattenExpand(t *testing.T) {
+	nested := map[string]any{
+		"pool":  map[string]any{"url": "x", "tls": map[string]any{"verify": true}},
+		"empty": map[string]any{},
+		"list":  []any{"a", "b"},
+	}
+	leaves := flatten("", nested)
+	want := map[string]any{"pool.url": "x", "pool.tls.verify": true, "empty": map[string]any{}, "list": []any{"a", "b"}}
+	if !reflect.DeepEqual(leaves, want) {
+		t.Errorf("flatten() = %v, want %v", leaves, want)
+	}
+	if got := expand(leaves); !reflect.DeepEqual(got, nested) {
+		t.Errorf("expand() = %v, want %v", got, nested)
+	}
+}
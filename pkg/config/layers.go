@@ -0,0 +1,524 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/adrg/xdg"
+	"github.com/host-uk/core/pkg/config/schema"
+	"github.com/host-uk/core/pkg/core"
+	"gopkg.in/ini.v1"
+)
+
+// EnvPrefix is the prefix of environment variables that override config
+// values, e.g. CORE_WORKSPACE_DIR for "workspaceDir".
+const EnvPrefix = "CORE_"
+
+// configFileNames are the names the system and workspace config files are
+// looked for under, in order.
+var configFileNames = []string{configFileName, "config.yaml", "config.yml"}
+
+// Layer is a source of configuration values. Values from a later layer
+// override those from an earlier one.
+type Layer int
+
+const (
+	// LayerDefault holds the built-in defaults.
+	LayerDefault Layer = iota
+	// LayerSystem is the machine-wide config file, e.g.
+	// /etc/xdg/lethean/config.yaml.
+	LayerSystem
+	// LayerUser is the user's config file, the one Set and Save write to.
+	LayerUser
+	// LayerWorkspace is .core/config.json or .core/config.yaml in the current
+	// directory or one of its parents.
+	LayerWorkspace
+	// LayerEnv holds CORE_* environment variables.
+	LayerEnv
+	// LayerFlag holds values given on the command line.
+	LayerFlag
+
+	layerCount
+)
+
+var layerNames = [layerCount]string{"default", "system", "user", "workspace", "env", "flag"}
+
+// String returns the name of the layer, e.g. "workspace".
+func (l Layer) String() string {
+	if l < 0 || l >= layerCount {
+		return fmt.Sprintf("Layer(%d)", int(l))
+	}
+	return layerNames[l]
+}
+
+// MarshalText encodes the layer as its name.
+func (l Layer) MarshalText() ([]byte, error) {
+	return []byte(l.String()), nil
+}
+
+// Source is a value supplied for a key by one layer.
+type Source struct {
+	Layer Layer `json:"layer"`
+	// Origin is the file, environment variable or flag the value came from.
+	// It is empty for defaults.
+	Origin string `json:"origin,omitempty"`
+	Value  any    `json:"value"`
+}
+
+// Explanation reports where the value of a key comes from.
+type Explanation struct {
+	Key string `json:"key"`
+	// Source is the layer that supplied the current value.
+	Source
+	// Overridden lists the other layers that set the key, highest first.
+	Overridden []Source `json:"overridden,omitempty"`
+}
+
+// pathFields are the fields that locate the user's files and directories.
+var pathFields = map[string]bool{
+	"configPath":  true,
+	"configDir":   true,
+	"userHomeDir": true,
+	"rootDir":     true,
+	"cacheDir":    true,
+	"dataDir":     true,
+	// Workspaces hold the user's keys and encrypted files.
+	"workspaceDir":     true,
+	"workspaceStorage": true,
+}
+
+// settable reports whether layer l may set the field name. ConfigPath always
+// names the user's file, which is fixed when the service is created, and the
+// system and workspace files can't set the other pathFields, so a config file
+// checked into a repository can't move where the user's config and data are
+// written.
+func settable(l Layer, name string) bool {
+	switch {
+	case name == "configPath":
+		return l == LayerDefault
+	case pathFields[name]:
+		return l != LayerSystem && l != LayerWorkspace
+	}
+	return true
+}
+
+// layers holds the values each layer supplies, by key. Struct fields are
+// held under their JSON name, and other values as leaves under their dotted
+// key, e.g. "modules.mining.pool.url".
+type layers [layerCount]map[string]Source
+
+// field returns the persistent field for key, matched case-insensitively
+// against its JSON name, and that name.
+func (s *Service) field(key string) (reflect.Value, string, bool) {
+	val := reflect.ValueOf(s).Elem()
+	typ := val.Type()
+	for i := 0; i < val.NumField(); i++ {
+		name := jsonName(typ.Field(i))
+		if name != "" && strings.EqualFold(name, key) {
+			return val.Field(i), name, true
+		}
+	}
+	return reflect.Value{}, "", false
+}
+
+// fields returns the JSON names of the persistent fields.
+func (s *Service) fields() []string {
+	typ := reflect.TypeOf(s).Elem()
+	var names []string
+	for i := 0; i < typ.NumField(); i++ {
+		if name := jsonName(typ.Field(i)); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// jsonName returns the JSON name of a persistent field, or "" for fields
+// that are not saved.
+func jsonName(f reflect.StructField) string {
+	tag := f.Tag.Get("json")
+	if tag == "" || tag == "-" || !f.IsExported() {
+		return ""
+	}
+	return strings.Split(tag, ",")[0]
+}
+
+// envName returns the environment variable that overrides key, e.g.
+// CORE_WORKSPACE_DIR for "workspaceDir", CORE_DEFAULT_ROUTE for
+// "default_route" and CORE_MODULES_MINING_POOL_URL for
+// "modules.mining.pool.url".
+func envName(key string) string {
+	var b strings.Builder
+	b.WriteString(EnvPrefix)
+	prev := '_'
+	for _, r := range key {
+		switch {
+		case r == '.' || r == '-':
+			r = '_'
+		case unicode.IsUpper(r) && prev != '_':
+			b.WriteByte('_')
+		}
+		b.WriteRune(unicode.ToUpper(r))
+		prev = r
+	}
+	return b.String()
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// convert converts value to typ. Strings from the environment, flags and
+// flat file formats are parsed, and other values are converted through JSON,
+// so a float64 can be read into an int and a map into a struct.
+func convert(value any, typ reflect.Type) (reflect.Value, error) {
+	v := reflect.ValueOf(value)
+	switch {
+	case !v.IsValid():
+		return reflect.Zero(typ), nil
+	case v.Type().AssignableTo(typ):
+		return v, nil
+	case v.Kind() == reflect.String:
+		return parseValue(v.String(), typ)
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	out := reflect.New(typ)
+	if err := json.Unmarshal(data, out.Interface()); err != nil {
+		return reflect.Value{}, err
+	}
+	return out.Elem(), nil
+}
+
+// parseValue converts a string from the environment or the command line to
+// typ. Lists are comma-separated; other types that are not strings, bools or
+// numbers are parsed as JSON.
+func parseValue(s string, typ reflect.Type) (reflect.Value, error) {
+	v := reflect.New(typ).Elem()
+	if typ == durationType {
+		d, err := time.ParseDuration(s)
+		v.SetInt(int64(d))
+		return v, err
+	}
+	switch typ.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return v, err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, typ.Bits())
+		if err != nil {
+			return v, err
+		}
+		v.SetInt(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(s, typ.Bits())
+		if err != nil {
+			return v, err
+		}
+		v.SetFloat(n)
+	case reflect.Interface:
+		v.Set(reflect.ValueOf(s))
+	case reflect.Slice:
+		if typ.Elem().Kind() != reflect.String {
+			return v, json.Unmarshal([]byte(s), v.Addr().Interface())
+		}
+		items := reflect.MakeSlice(typ, 0, 0)
+		for _, item := range strings.Split(s, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = reflect.Append(items, reflect.ValueOf(item).Convert(typ.Elem()))
+			}
+		}
+		v.Set(items)
+	default:
+		return v, json.Unmarshal([]byte(s), v.Addr().Interface())
+	}
+	return v, nil
+}
+
+// loadDefaults records the current field values as the default layer.
+func (s *Service) loadDefaults() {
+	s.layers[LayerDefault] = map[string]Source{}
+	for _, name := range s.fields() {
+		f, _, _ := s.field(name)
+		s.layers[LayerDefault][name] = Source{Layer: LayerDefault, Value: f.Interface()}
+	}
+}
+
+// loadFile reads the config file at path into layer l, in any format
+// GetConfigFormat supports. A missing file leaves the layer empty and reports
+// false. Files from older versions are migrated: the user's file is upgraded
+// in place, keeping a backup, and other files in memory. Keys that are not
+// struct fields are kept as dotted keys, so module settings survive a Save.
+func (s *Service) loadFile(l Layer, path string) (bool, error) {
+	s.layers[l] = map[string]Source{}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return false, nil
+	} else if err != nil {
+		return false, core.E("config.New", "failed to read config file", err, core.WithMeta("path", path))
+	}
+	if l == LayerUser {
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return false, core.E("config.New", "failed to read config file", err, core.WithMeta("path", path))
+		}
+		if _, err := migrations.Upgrade(path, raw); err != nil {
+			return false, errMigration(path, err)
+		}
+	}
+	format, err := GetConfigFormat(path)
+	if err != nil {
+		return false, err
+	}
+	loaded, err := format.Load(path)
+	if err != nil {
+		return false, errInvalidFile(path, err)
+	}
+
+	data := make(map[string]any, len(loaded))
+	for key, value := range loaded {
+		if _, ok := format.(*INIFormat); ok {
+			// Keys outside any section are top-level keys.
+			key = strings.TrimPrefix(key, ini.DefaultSection+".")
+		}
+		data[key] = normalize(value)
+	}
+	if _, err := migrations.Migrate(data); err != nil {
+		return false, errMigration(path, err)
+	}
+	delete(data, schema.VersionKey)
+
+	for key, value := range data {
+		if f, name, ok := s.field(key); ok {
+			if !settable(l, name) {
+				continue
+			}
+			v, err := convert(value, f.Type())
+			if err != nil {
+				return false, errInvalidFile(path, err)
+			}
+			s.layers[l][name] = Source{Layer: l, Origin: path, Value: v.Interface()}
+			continue
+		}
+		for leaf, v := range flatten(key, value) {
+			s.layers[l][leaf] = Source{Layer: l, Origin: path, Value: v}
+		}
+	}
+	return true, nil
+}
+
+// errMigration reports a config file that can't be upgraded to
+// ConfigVersion.
+func errMigration(path string, err error) error {
+	if !errors.Is(err, schema.ErrUnsupportedVersion) {
+		return errInvalidFile(path, err)
+	}
+	return core.E("config.New", "unsupported config version", err,
+		core.WithKind(core.KindInvalid),
+		core.WithCode("config.unsupported_version"),
+		core.WithMeta("path", path))
+}
+
+// errInvalidFile reports a config file that can't be decoded.
+func errInvalidFile(path string, err error) error {
+	return core.E("config.New", "failed to unmarshal config", err,
+		core.WithKind(core.KindInvalid),
+		core.WithCode("config.invalid_file"),
+		core.WithMeta("path", path))
+}
+
+// loadEnv reads CORE_* environment variables into the env layer, for the
+// struct fields and every dotted key the files set.
+func (s *Service) loadEnv() error {
+	s.layers[LayerEnv] = map[string]Source{}
+	keys := s.fields()
+	for l := LayerDefault; l < LayerEnv; l++ {
+		for key := range s.layers[l] {
+			keys = append(keys, key)
+		}
+	}
+	for _, key := range keys {
+		if err := s.loadEnvKey(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadEnvKey reads the environment variable for key into the env layer.
+// Values for dotted keys are kept as strings and parsed when read.
+func (s *Service) loadEnvKey(key string) error {
+	env := envName(key)
+	raw, ok := os.LookupEnv(env)
+	if !ok {
+		return nil
+	}
+	var value any = raw
+	if f, name, ok := s.field(key); ok {
+		if !settable(LayerEnv, name) {
+			return nil
+		}
+		v, err := parseValue(raw, f.Type())
+		if err != nil {
+			return core.E("config.New", fmt.Sprintf("invalid value for %s", env), err,
+				core.WithKind(core.KindInvalid),
+				core.WithCode("config.invalid_env"),
+				core.WithMeta("key", name),
+				core.WithMeta("env", env))
+		}
+		key, value = name, v.Interface()
+	}
+	s.layers[LayerEnv][key] = Source{Layer: LayerEnv, Origin: env, Value: value}
+	return nil
+}
+
+// loadFlags reads values given on the command line into the flag layer.
+// Values for dotted keys are kept as strings and parsed when read.
+func (s *Service) loadFlags(flags map[string]string) error {
+	s.layers[LayerFlag] = map[string]Source{}
+	for key, raw := range flags {
+		f, name, ok := s.field(key)
+		if !ok {
+			if !namespaced(key) {
+				return errKeyNotFound("config.New", key)
+			}
+			s.layers[LayerFlag][key] = Source{Layer: LayerFlag, Origin: "--" + key, Value: raw}
+			continue
+		}
+		if !settable(LayerFlag, name) {
+			continue
+		}
+		v, err := parseValue(raw, f.Type())
+		if err != nil {
+			return core.E("config.New", fmt.Sprintf("invalid value for flag --%s", key), err,
+				core.WithKind(core.KindInvalid),
+				core.WithCode("config.invalid_flag"),
+				core.WithMeta("key", name))
+		}
+		s.layers[LayerFlag][name] = Source{Layer: LayerFlag, Origin: "--" + key, Value: v.Interface()}
+	}
+	return nil
+}
+
+// resolve sets the field for key from the highest layer that supplies it.
+func (s *Service) resolve(name string) {
+	src, ok := s.winner(name)
+	if !ok {
+		return
+	}
+	f, _, _ := s.field(name)
+	if src.Value == nil {
+		f.SetZero()
+		return
+	}
+	f.Set(reflect.ValueOf(src.Value))
+}
+
+// resolveAll sets every field from the layers.
+func (s *Service) resolveAll() {
+	for _, name := range s.fields() {
+		s.resolve(name)
+	}
+}
+
+// winner returns the source of the current value of key.
+func (s *Service) winner(name string) (Source, bool) {
+	for l := layerCount - 1; l >= 0; l-- {
+		if src, ok := s.layers[l][name]; ok {
+			return src, true
+		}
+	}
+	return Source{}, false
+}
+
+// Explain reports which layer supplied the value of key, and which lower
+// layers it overrides. For dotted keys, key must name a single value rather
+// than a group of them.
+//
+// Example:
+//
+//	e, err := cfg.Explain("workspaceDir")
+//	if err == nil {
+//		fmt.Printf("%s = %v (from %s %s)\n", e.Key, e.Value, e.Layer, e.Origin)
+//	}
+func (s *Service) Explain(key string) (*Explanation, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	name := key
+	if _, field, ok := s.field(key); ok {
+		name = field
+	}
+	e := &Explanation{Key: name}
+	found := false
+	for l := layerCount - 1; l >= 0; l-- {
+		src, ok := s.layers[l][name]
+		if !ok {
+			continue
+		}
+		if !found {
+			e.Source = src
+			found = true
+			continue
+		}
+		e.Overridden = append(e.Overridden, src)
+	}
+	if !found {
+		return nil, errKeyNotFound("config.Explain", key)
+	}
+	return e, nil
+}
+
+// findConfigFile returns the first of configFileNames that exists in dir,
+// or "".
+func findConfigFile(dir string) string {
+	for _, name := range configFileNames {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+// systemConfigFile returns the first machine-wide config file that exists
+// in the XDG config directories, or "".
+func systemConfigFile() string {
+	for _, dir := range xdg.ConfigDirs {
+		if path := findConfigFile(filepath.Join(dir, appName)); path != "" {
+			return path
+		}
+	}
+	return ""
+}
+
+// workspaceConfigPath returns the config file in .core in the current
+// directory or the nearest parent that has one, or "".
+func workspaceConfigPath() string {
+	dir, err := os.Getwd()
+	if err != nil {
+		return ""
+	}
+	for {
+		if path := findConfigFile(filepath.Join(dir, ".core")); path != "" {
+			return path
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
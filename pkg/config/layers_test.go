@@ -0,0 +1,250 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/host-uk/core/pkg/core"
+)
+
+// writeConfigFile writes a JSON config file with the given values.
+func writeConfigFile(t *testing.T, path string, values map[string]any) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		t.Fatalf("Failed to create config dir: %v", err)
+	}
+	data, err := json.Marshal(values)
+	if err != nil {
+		t.Fatalf("Failed to marshal config: %v", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+}
+
+func TestLayers(t *testing.T) {
+	tempHomeDir, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	systemFile := filepath.Join(tempHomeDir, "etc", configFileName)
+	workspaceFile := filepath.Join(tempHomeDir, "project", ".core", configFileName)
+	userFile := filepath.Join(tempHomeDir, appName, "config", configFileName)
+	writeConfigFile(t, systemFile, map[string]any{"language": "fr", "default_route": "/system", "features": []string{"a"}})
+	writeConfigFile(t, userFile, map[string]any{"language": "de", "default_route": "/user"})
+	writeConfigFile(t, workspaceFile, map[string]any{"default_route": "/workspace"})
+	t.Setenv("CORE_WORKSPACE_DIR", filepath.Join(tempHomeDir, "ci-workspace"))
+	t.Setenv("CORE_FEATURES", "beta, dark_mode")
+
+	s, err := New(Options{
+		SystemFile:    systemFile,
+		WorkspaceFile: workspaceFile,
+		Flags:         map[string]string{"language": "es"},
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	if s.Language != "es" {
+		t.Errorf("Expected the flag to win for language, got '%s'", s.Language)
+	}
+	if s.DefaultRoute != "/workspace" {
+		t.Errorf("Expected the workspace file to win for default_route, got '%s'", s.DefaultRoute)
+	}
+	if s.WorkspaceDir != filepath.Join(tempHomeDir, "ci-workspace") {
+		t.Errorf("Expected CORE_WORKSPACE_DIR to set workspaceDir, got '%s'", s.WorkspaceDir)
+	}
+	if _, err := os.Stat(s.WorkspaceDir); err != nil {
+		t.Errorf("Expected the overridden workspace dir to be created: %v", err)
+	}
+	if !reflect.DeepEqual(s.Features, []string{"beta", "dark_mode"}) {
+		t.Errorf("Expected features from CORE_FEATURES, got %v", s.Features)
+	}
+
+	t.Run("Explain", func(t *testing.T) {
+		e, err := s.Explain("default_route")
+		if err != nil {
+			t.Fatalf("Explain() failed: %v", err)
+		}
+		if e.Layer != LayerWorkspace || e.Origin != workspaceFile || e.Value != "/workspace" {
+			t.Errorf("Unexpected source: %+v", e.Source)
+		}
+		var overridden []Layer
+		for _, src := range e.Overridden {
+			overridden = append(overridden, src.Layer)
+		}
+		if !reflect.DeepEqual(overridden, []Layer{LayerUser, LayerSystem, LayerDefault}) {
+			t.Errorf("Expected user, system and default to be overridden, got %v", overridden)
+		}
+
+		e, err = s.Explain("workspaceDir")
+		if err != nil {
+			t.Fatalf("Explain() failed: %v", err)
+		}
+		if e.Layer != LayerEnv || e.Origin != "CORE_WORKSPACE_DIR" {
+			t.Errorf("Expected workspaceDir from CORE_WORKSPACE_DIR, got %s %s", e.Layer, e.Origin)
+		}
+
+		e, err = s.Explain("LANGUAGE")
+		if err != nil {
+			t.Fatalf("Explain() failed: %v", err)
+		}
+		if e.Key != "language" || e.Layer != LayerFlag || e.Origin != "--language" {
+			t.Errorf("Expected language from --language, got %+v", e)
+		}
+
+		_, err = s.Explain("nonexistent_key")
+		if !core.Is(err, core.KindNotFound) {
+			t.Errorf("Expected a not_found error, got: %v", err)
+		}
+	})
+
+	t.Run("Set saves only the user layer", func(t *testing.T) {
+		if err := s.Set("language", "it"); err != nil {
+			t.Fatalf("Set() failed: %v", err)
+		}
+		if s.Language != "es" {
+			t.Errorf("Expected the flag to stay in effect, got '%s'", s.Language)
+		}
+
+		data, err := os.ReadFile(userFile)
+		if err != nil {
+			t.Fatalf("Failed to read user file: %v", err)
+		}
+		var saved map[string]any
+		if err := json.Unmarshal(data, &saved); err != nil {
+			t.Fatalf("Failed to decode user file: %v", err)
+		}
+		want := map[string]any{"version": float64(ConfigVersion), "language": "it", "default_route": "/user"}
+		if !reflect.DeepEqual(saved, want) {
+			t.Errorf("Expected user file %v, got %v", want, saved)
+		}
+	})
+}
+
+func TestLayers_DirectChangesAreSaved(t *testing.T) {
+	_, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	s, err := New(Options{})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	s.DefaultRoute = "/dashboard"
+	if err := s.Save(); err != nil {
+		t.Fatalf("Save() failed: %v", err)
+	}
+
+	s, err = New(Options{})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	if s.DefaultRoute != "/dashboard" {
+		t.Errorf("Expected default_route to be saved, got '%s'", s.DefaultRoute)
+	}
+	e, err := s.Explain("default_route")
+	if err != nil {
+		t.Fatalf("Explain() failed: %v", err)
+	}
+	if e.Layer != LayerUser {
+		t.Errorf("Expected default_route from the user layer, got %s", e.Layer)
+	}
+}
+
+func TestLayers_PathsFromFiles(t *testing.T) {
+	tempHomeDir, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	userFile := filepath.Join(tempHomeDir, appName, "config", configFileName)
+	elsewhere := filepath.Join(tempHomeDir, "attacker")
+	hostile := map[string]any{
+		"configPath":       filepath.Join(elsewhere, configFileName),
+		"configDir":        elsewhere,
+		"userHomeDir":      elsewhere,
+		"rootDir":          elsewhere,
+		"cacheDir":         elsewhere,
+		"dataDir":          elsewhere,
+		"workspaceDir":     elsewhere,
+		"workspaceStorage": "s3://attacker/workspaces",
+		"default_route":    "/workspace",
+	}
+	systemFile := filepath.Join(tempHomeDir, "etc", configFileName)
+	workspaceFile := filepath.Join(tempHomeDir, "project", ".core", configFileName)
+	writeConfigFile(t, systemFile, hostile)
+	writeConfigFile(t, workspaceFile, hostile)
+	t.Setenv("CORE_CONFIG_PATH", filepath.Join(elsewhere, "env.json"))
+
+	s, err := New(Options{SystemFile: systemFile, WorkspaceFile: workspaceFile})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	if s.DefaultRoute != "/workspace" {
+		t.Errorf("Expected other keys to be taken from the workspace file, got '%s'", s.DefaultRoute)
+	}
+	for name := range hostile {
+		if name == "default_route" {
+			continue
+		}
+		e, err := s.Explain(name)
+		if err == nil && e.Layer != LayerDefault {
+			t.Errorf("Expected %s not to be taken from %s (%s)", name, e.Layer, e.Origin)
+		}
+	}
+	if s.ConfigPath != userFile {
+		t.Errorf("Expected ConfigPath %s, got %s", userFile, s.ConfigPath)
+	}
+
+	// Save writes the user's file, whatever ConfigPath says.
+	s.ConfigPath = filepath.Join(elsewhere, "moved.json")
+	if err := s.Set("language", "fr"); err != nil {
+		t.Fatalf("Set() failed: %v", err)
+	}
+	data, err := os.ReadFile(userFile)
+	if err != nil {
+		t.Fatalf("Failed to read user file: %v", err)
+	}
+	var saved map[string]any
+	if err := json.Unmarshal(data, &saved); err != nil {
+		t.Fatalf("Failed to decode user file: %v", err)
+	}
+	if saved["language"] != "fr" {
+		t.Errorf("Expected the user file to be saved, got %v", saved)
+	}
+	if _, err := os.Stat(elsewhere); !os.IsNotExist(err) {
+		t.Errorf("Expected nothing to be written to %s, got %v", elsewhere, err)
+	}
+}
+
+func TestLayers_InvalidValues(t *testing.T) {
+	_, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	_, err := New(Options{Flags: map[string]string{"nonexistent_key": "x"}})
+	if !core.Is(err, core.KindNotFound) {
+		t.Errorf("Expected a not_found error for an unknown flag, got: %v", err)
+	}
+
+	t.Setenv("CORE_FEATURES", "")
+	s, err := New(Options{})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	if len(s.Features) != 0 {
+		t.Errorf("Expected an empty CORE_FEATURES to clear features, got %v", s.Features)
+	}
+}
+
+func TestEnvName(t *testing.T) {
+	for key, want := range map[string]string{
+		"workspaceDir":     "CORE_WORKSPACE_DIR",
+		"default_route":    "CORE_DEFAULT_ROUTE",
+		"language":         "CORE_LANGUAGE",
+		"workspaceStorage": "CORE_WORKSPACE_STORAGE",
+	} {
+		if got := envName(key); got != want {
+			t.Errorf("envName(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package config
+
+import "time"
+
+// Namespace is a view of the config keys under a dotted prefix, such as
+// "modules.mining". Keys given to its methods are relative to the prefix, so
+// a module holding its Namespace can only read and write its own settings.
+//
+// Example:
+//
+//	mining := cfg.Namespace("modules.mining")
+//	url := mining.GetString("pool.url")
+//	err := mining.Set("pool.url", "stratum+tcp://eu.pool.lthn.io:3333")
+type Namespace struct {
+	s      *Service
+	prefix string
+}
+
+// Namespace returns the keys under name. Names are dotted keys, e.g.
+// "modules.mining".
+func (s *Service) Namespace(name string) *Namespace {
+	return &Namespace{s: s, prefix: name}
+}
+
+// Name returns the prefix of the namespace.
+func (n *Namespace) Name() string {
+	return n.prefix
+}
+
+// Key returns the full key for a key in the namespace. An empty key names
+// the namespace itself.
+func (n *Namespace) Key(key string) string {
+	if key == "" {
+		return n.prefix
+	}
+	return n.prefix + "." + key
+}
+
+// Namespace returns the keys under name within n.
+func (n *Namespace) Namespace(name string) *Namespace {
+	return &Namespace{s: n.s, prefix: n.Key(name)}
+}
+
+// Get stores the value of key in out. An empty key reads every value in the
+// namespace as a map, or into a struct.
+func (n *Namespace) Get(key string, out any) error {
+	return n.s.Get(n.Key(key), out)
+}
+
+// Set sets key to v and saves the change. A map sets every key in it.
+func (n *Namespace) Set(key string, v any) error {
+	return n.s.Set(n.Key(key), v)
+}
+
+// Delete removes the user's value for key, or for every key in the
+// namespace if key is empty, and saves the change.
+func (n *Namespace) Delete(key string) error {
+	return n.s.Delete(n.Key(key))
+}
+
+// Explain reports which layer supplied the value of key.
+func (n *Namespace) Explain(key string) (*Explanation, error) {
+	return n.s.Explain(n.Key(key))
+}
+
+// Subscribe calls fn with every change to key, or to any key in the
+// namespace if key is empty. The changes carry full keys.
+func (n *Namespace) Subscribe(key string, fn func(ActionConfigChanged)) func() {
+	return n.s.Subscribe(n.Key(key), fn)
+}
+
+// SetDefaults sets the default values of the keys in the namespace.
+func (n *Namespace) SetDefaults(defaults map[string]any) error {
+	return n.s.SetDefaults(n.prefix, defaults)
+}
+
+// Keys returns the keys in the namespace that have a value, sorted and
+// relative to it.
+func (n *Namespace) Keys() []string {
+	n.s.mu.RLock()
+	defer n.s.mu.RUnlock()
+	return n.s.keys(n.prefix)
+}
+
+// All returns every value in the namespace as nested maps.
+func (n *Namespace) All() map[string]any {
+	return n.s.GetStringMap(n.prefix)
+}
+
+// GetString returns the value of key as a string, or "".
+func (n *Namespace) GetString(key string) string { return n.s.GetString(n.Key(key)) }
+
+// GetInt returns the value of key as an int, or 0.
+func (n *Namespace) GetInt(key string) int { return n.s.GetInt(n.Key(key)) }
+
+// GetFloat returns the value of key as a float64, or 0.
+func (n *Namespace) GetFloat(key string) float64 { return n.s.GetFloat(n.Key(key)) }
+
+// GetBool returns the value of key as a bool, or false.
+func (n *Namespace) GetBool(key string) bool { return n.s.GetBool(n.Key(key)) }
+
+// GetDuration returns the value of key as a time.Duration, or 0.
+func (n *Namespace) GetDuration(key string) time.Duration { return n.s.GetDuration(n.Key(key)) }
+
+// GetStringSlice returns the value of key as a list of strings, or nil.
+func (n *Namespace) GetStringSlice(key string) []string { return n.s.GetStringSlice(n.Key(key)) }
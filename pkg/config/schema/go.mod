@@ -0,0 +1,5 @@
+module github.com/host-uk/core/pkg/config/schema
+
+go 1.25
+
+require gopkg.in/yaml.v3 v3.0.1
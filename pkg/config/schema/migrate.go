@@ -0,0 +1,261 @@
+package schema
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// VersionKey is the top-level field that holds the version of a file.
+const VersionKey = "version"
+
+// ErrUnsupportedVersion is matched by the errors for files that are newer
+// than the program reading them, or too old to be upgraded.
+var ErrUnsupportedVersion = errors.New("unsupported config version")
+
+// MigrateFunc upgrades a decoded file by one version, changing doc in place.
+// Nested objects in doc are map[string]any.
+type MigrateFunc func(doc map[string]any) error
+
+// Migration is a registered upgrade from one version to the next.
+type Migration struct {
+	From        int
+	Description string
+	Up          MigrateFunc
+}
+
+// Migrator holds the migrations for one kind of file.
+//
+// Example:
+//
+//	var migrations = schema.NewMigrator(2).
+//		Register(1, "rename binary to output", func(doc map[string]any) error {
+//			schema.Rename(doc, "project.binary", "project.output")
+//			return nil
+//		})
+type Migrator struct {
+	current     int
+	unversioned int
+	migrations  map[int]Migration
+}
+
+// NewMigrator returns a Migrator for files whose current version is
+// current. Files without a version field are taken to be version 1.
+func NewMigrator(current int) *Migrator {
+	return &Migrator{current: current, unversioned: 1, migrations: map[int]Migration{}}
+}
+
+// Unversioned sets the version files without a version field are taken to
+// be, such as 0 for files from before versions were added.
+func (m *Migrator) Unversioned(version int) *Migrator {
+	m.unversioned = version
+	return m
+}
+
+// Register adds the migration from version from to from+1. It panics if
+// that migration is already registered or would go past the current
+// version, as migrations are registered when a package is initialised.
+func (m *Migrator) Register(from int, description string, up MigrateFunc) *Migrator {
+	if from >= m.current {
+		panic(fmt.Sprintf("schema: migration from version %d goes past current version %d", from, m.current))
+	}
+	if _, ok := m.migrations[from]; ok {
+		panic(fmt.Sprintf("schema: migration from version %d registered twice", from))
+	}
+	m.migrations[from] = Migration{From: from, Description: description, Up: up}
+	return m
+}
+
+// Current returns the version files are upgraded to.
+func (m *Migrator) Current() int {
+	return m.current
+}
+
+// Migrations returns the registered migrations, oldest first.
+func (m *Migrator) Migrations() []Migration {
+	list := make([]Migration, 0, len(m.migrations))
+	for _, mig := range m.migrations {
+		list = append(list, mig)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].From < list[j].From })
+	return list
+}
+
+// Version returns the version of doc.
+func (m *Migrator) Version(doc map[string]any) (int, error) {
+	raw, ok := doc[VersionKey]
+	if !ok || raw == nil {
+		return m.unversioned, nil
+	}
+	switch v := raw.(type) {
+	case int:
+		return v, nil
+	case float64:
+		if v == float64(int(v)) {
+			return int(v), nil
+		}
+	case string:
+		if n, err := strconv.Atoi(v); err == nil {
+			return n, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: version must be a whole number, not %v", ErrUnsupportedVersion, raw)
+}
+
+// Migrate upgrades doc in place to the current version and sets its version
+// field. It returns the version doc was at.
+func (m *Migrator) Migrate(doc map[string]any) (int, error) {
+	from, err := m.Version(doc)
+	if err != nil {
+		return 0, err
+	}
+	if from > m.current {
+		return from, fmt.Errorf("%w: version %d is newer than the supported version %d", ErrUnsupportedVersion, from, m.current)
+	}
+	for v := from; v < m.current; v++ {
+		mig, ok := m.migrations[v]
+		if !ok {
+			return from, fmt.Errorf("%w: no migration from version %d", ErrUnsupportedVersion, v)
+		}
+		if err := mig.Up(doc); err != nil {
+			return from, fmt.Errorf("migration from version %d (%s) failed: %w", v, mig.Description, err)
+		}
+	}
+	doc[VersionKey] = m.current
+	return from, nil
+}
+
+// Upgrade upgrades data, the content of the JSON or YAML file at path, to
+// the current version. If the file is older, the original is kept next to
+// it as <path>.v<version>.bak and the file is rewritten with the upgraded
+// content, which is returned. Comments and key order in YAML files are not
+// kept in the rewritten file. Files in other formats are returned as they
+// are.
+func (m *Migrator) Upgrade(path string, data []byte) ([]byte, error) {
+	var doc map[string]any
+	switch format(path) {
+	case formatJSON:
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+		}
+	case formatYAML:
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+		}
+	default:
+		return data, nil
+	}
+	if doc == nil {
+		doc = map[string]any{}
+	}
+
+	from, err := m.Version(doc)
+	if err != nil {
+		return nil, fmt.Errorf("config file %s: %w", path, err)
+	}
+	if from == m.current {
+		return data, nil
+	}
+	if _, err := m.Migrate(doc); err != nil {
+		return nil, fmt.Errorf("config file %s: %w", path, err)
+	}
+
+	var upgraded []byte
+	if format(path) == formatJSON {
+		upgraded, err = json.MarshalIndent(doc, "", "  ")
+	} else {
+		upgraded, err = yaml.Marshal(doc)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode upgraded config file %s: %w", path, err)
+	}
+
+	backup := fmt.Sprintf("%s.v%d.bak", path, from)
+	if err := os.WriteFile(backup, data, 0644); err != nil {
+		return nil, fmt.Errorf("failed to back up config file %s: %w", path, err)
+	}
+	if err := writeFile(path, upgraded); err != nil {
+		return nil, fmt.Errorf("failed to write upgraded config file %s: %w", path, err)
+	}
+	return upgraded, nil
+}
+
+// writeFile replaces the file at path through a temporary file, so it is
+// never left half written.
+func writeFile(path string, data []byte) error {
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Rename moves the value at the dotted path from in doc to the dotted path
+// to, creating objects on the way. It reports whether there was a value to
+// move. It is a helper for migrations.
+func Rename(doc map[string]any, from, to string) bool {
+	v, ok := Delete(doc, from)
+	if ok {
+		Set(doc, to, v)
+	}
+	return ok
+}
+
+// Delete removes the value at a dotted path in doc and returns it.
+func Delete(doc map[string]any, path string) (any, bool) {
+	parent, key := walk(doc, path, false)
+	if parent == nil {
+		return nil, false
+	}
+	v, ok := parent[key]
+	delete(parent, key)
+	return v, ok
+}
+
+// Set sets the value at a dotted path in doc, creating objects on the way.
+func Set(doc map[string]any, path string, v any) {
+	parent, key := walk(doc, path, true)
+	parent[key] = v
+}
+
+// walk returns the object holding the last part of a dotted path, and that
+// part. If create is false and an object on the way is missing, it returns
+// nil.
+func walk(doc map[string]any, path string, create bool) (map[string]any, string) {
+	parts := strings.Split(path, ".")
+	m := doc
+	for _, part := range parts[:len(parts)-1] {
+		child, ok := m[part].(map[string]any)
+		if !ok {
+			if !create {
+				return nil, ""
+			}
+			child = map[string]any{}
+			m[part] = child
+		}
+		m = child
+	}
+	return m, parts[len(parts)-1]
+}
@@ -0,0 +1,163 @@
+package schema
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func newTestMigrator() *Migrator {
+	return NewMigrator(3).
+		Register(1, "rename binary to output", func(doc map[string]any) error {
+			Rename(doc, "project.binary", "project.output")
+			return nil
+		}).
+		Register(2, "drop legacy flag", func(doc map[string]any) error {
+			Delete(doc, "legacy")
+			return nil
+		})
+}
+
+func TestMigrate(t *testing.T) {
+	m := newTestMigrator()
+
+	doc := map[string]any{
+		"project": map[string]any{"binary": "core"},
+		"legacy":  true,
+	}
+	from, err := m.Migrate(doc)
+	if err != nil {
+		t.Fatalf("Migrate() failed: %v", err)
+	}
+	if from != 1 {
+		t.Errorf("Expected an unversioned file to be version 1, got %d", from)
+	}
+	want := map[string]any{
+		"version": 3,
+		"project": map[string]any{"output": "core"},
+	}
+	if !reflect.DeepEqual(doc, want) {
+		t.Errorf("Expected %v, got %v", want, doc)
+	}
+
+	t.Run("Newer versions are rejected", func(t *testing.T) {
+		_, err := m.Migrate(map[string]any{"version": float64(4)})
+		if !errors.Is(err, ErrUnsupportedVersion) {
+			t.Errorf("Expected ErrUnsupportedVersion, got: %v", err)
+		}
+	})
+
+	t.Run("Missing migrations are rejected", func(t *testing.T) {
+		_, err := NewMigrator(2).Migrate(map[string]any{})
+		if !errors.Is(err, ErrUnsupportedVersion) {
+			t.Errorf("Expected ErrUnsupportedVersion, got: %v", err)
+		}
+	})
+
+	t.Run("Version must be a number", func(t *testing.T) {
+		_, err := m.Migrate(map[string]any{"version": "two"})
+		if !errors.Is(err, ErrUnsupportedVersion) {
+			t.Errorf("Expected ErrUnsupportedVersion, got: %v", err)
+		}
+	})
+
+	t.Run("Migrations are listed in order", func(t *testing.T) {
+		var got []int
+		for _, mig := range m.Migrations() {
+			got = append(got, mig.From)
+		}
+		if !reflect.DeepEqual(got, []int{1, 2}) {
+			t.Errorf("Expected migrations from 1 and 2, got %v", got)
+		}
+	})
+
+	t.Run("Registering twice panics", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Error("Expected Register() to panic")
+			}
+		}()
+		m.Register(1, "again", func(map[string]any) error { return nil })
+	})
+}
+
+func TestUpgrade(t *testing.T) {
+	m := newTestMigrator()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "build.yaml")
+	original := []byte("project:\n  binary: core\nlegacy: true\n")
+	if err := os.WriteFile(path, original, 0600); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	upgraded, err := m.Upgrade(path, original)
+	if err != nil {
+		t.Fatalf("Upgrade() failed: %v", err)
+	}
+	want := "project:\n    output: core\nversion: 3\n"
+	if string(upgraded) != want {
+		t.Errorf("Expected upgraded content %q, got %q", want, upgraded)
+	}
+
+	onDisk, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read file: %v", err)
+	}
+	if string(onDisk) != want {
+		t.Errorf("Expected the file to be rewritten, got %q", onDisk)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("Expected the file mode to be kept, got %v (%v)", info.Mode(), err)
+	}
+	backup, err := os.ReadFile(path + ".v1.bak")
+	if err != nil {
+		t.Fatalf("Expected a backup of the original: %v", err)
+	}
+	if string(backup) != string(original) {
+		t.Errorf("Expected the backup to hold the original, got %q", backup)
+	}
+
+	t.Run("Current files are left alone", func(t *testing.T) {
+		again, err := m.Upgrade(path, onDisk)
+		if err != nil {
+			t.Fatalf("Upgrade() failed: %v", err)
+		}
+		if string(again) != string(onDisk) {
+			t.Errorf("Expected the content unchanged, got %q", again)
+		}
+		if _, err := os.Stat(path + ".v3.bak"); !os.IsNotExist(err) {
+			t.Error("Expected no backup for a current file")
+		}
+	})
+
+	t.Run("Newer files are rejected", func(t *testing.T) {
+		newer := filepath.Join(dir, "newer.json")
+		data := []byte(`{"version": 9}`)
+		if _, err := m.Upgrade(newer, data); !errors.Is(err, ErrUnsupportedVersion) {
+			t.Errorf("Expected ErrUnsupportedVersion, got: %v", err)
+		}
+	})
+
+	t.Run("Invalid files are reported", func(t *testing.T) {
+		if _, err := m.Upgrade(filepath.Join(dir, "bad.json"), []byte("{")); err == nil {
+			t.Error("Expected a parse error")
+		}
+	})
+}
+
+func TestPaths(t *testing.T) {
+	doc := map[string]any{"a": map[string]any{"b": 1}}
+	Set(doc, "x.y.z", 2)
+	if v, ok := Delete(doc, "a.b"); !ok || v != 1 {
+		t.Errorf("Expected Delete() to return 1, got %v, %v", v, ok)
+	}
+	if Rename(doc, "missing.key", "other") {
+		t.Error("Expected Rename() of a missing key to report false")
+	}
+	want := map[string]any{"a": map[string]any{}, "x": map[string]any{"y": map[string]any{"z": 2}}}
+	if !reflect.DeepEqual(doc, want) {
+		t.Errorf("Expected %v, got %v", want, doc)
+	}
+}
@@ -0,0 +1,298 @@
+// Package schema validates config files and upgrades them between versions.
+//
+// A config struct declares its schema with `validate` struct tags, which
+// Validate checks:
+//
+//	type Target struct {
+//		OS   string `yaml:"os" validate:"required,oneof=linux darwin windows"`
+//		Arch string `yaml:"arch" validate:"required"`
+//	}
+//
+// The rules are required, omitempty, oneof=a b c, min=n, max=n (the value of
+// a number, or the length of a string, list or map), startswith=prefix and
+// url. Rules are separated by commas, and omitempty skips the other rules for
+// empty values.
+//
+// Files carry a top-level "version" field. A Migrator holds the functions
+// that upgrade a file from one version to the next, and Upgrade rewrites
+// old files in place, keeping a backup of the original.
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ErrInvalid is matched by the errors Validate returns.
+var ErrInvalid = errors.New("invalid config")
+
+// FieldError is a value that breaks a rule.
+type FieldError struct {
+	// Field is the path of the value, e.g. "targets[1].os".
+	Field string
+	// Rule is the rule that failed, e.g. "oneof".
+	Rule    string
+	Message string
+}
+
+// Error is returned by Validate and lists every value that breaks a rule.
+type Error struct {
+	Errors []FieldError
+}
+
+func (e *Error) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, fe := range e.Errors {
+		msgs[i] = fe.Field + ": " + fe.Message
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Is reports whether target is ErrInvalid.
+func (e *Error) Is(target error) bool {
+	return target == ErrInvalid
+}
+
+// Validate checks v, a struct or a pointer to one, against the `validate`
+// tags of its fields and of the structs, lists and maps of structs it holds.
+// It returns an *Error listing every value that breaks a rule, or nil.
+func Validate(v any) error {
+	var errs []FieldError
+	validateValue(reflect.ValueOf(v), "", &errs)
+	if len(errs) > 0 {
+		return &Error{Errors: errs}
+	}
+	return nil
+}
+
+// validateValue checks the fields of the struct in v, and of the structs
+// held in lists and maps, appending failures to errs.
+func validateValue(v reflect.Value, path string, errs *[]FieldError) {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		typ := v.Type()
+		for i := 0; i < typ.NumField(); i++ {
+			f := typ.Field(i)
+			if !f.IsExported() || f.Anonymous {
+				continue
+			}
+			fieldPath := join(path, fieldName(f))
+			fv := v.Field(i)
+			if tag := f.Tag.Get("validate"); tag != "" {
+				if !checkRules(fv, fieldPath, tag, errs) {
+					continue
+				}
+			}
+			validateValue(fv, fieldPath, errs)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			validateValue(v.Index(i), fmt.Sprintf("%s[%d]", path, i), errs)
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			validateValue(iter.Value(), join(path, fmt.Sprint(iter.Key().Interface())), errs)
+		}
+	}
+}
+
+// checkRules checks v against the rules in tag. It reports false if the
+// value is empty and may be, so nothing inside it needs checking.
+func checkRules(v reflect.Value, path, tag string, errs *[]FieldError) bool {
+	fail := func(rule, format string, args ...any) {
+		*errs = append(*errs, FieldError{Field: path, Rule: rule, Message: fmt.Sprintf(format, args...)})
+	}
+	for _, rule := range strings.Split(tag, ",") {
+		name, arg, _ := strings.Cut(strings.TrimSpace(rule), "=")
+		switch name {
+		case "omitempty":
+			if v.IsZero() {
+				return false
+			}
+		case "required":
+			if isEmpty(v) {
+				fail(name, "is required")
+				return false
+			}
+		case "oneof":
+			allowed := strings.Fields(arg)
+			s := fmt.Sprint(indirect(v).Interface())
+			found := false
+			for _, a := range allowed {
+				if s == a {
+					found = true
+					break
+				}
+			}
+			if !found {
+				fail(name, "must be one of %s, not %q", strings.Join(allowed, ", "), s)
+			}
+		case "min", "max":
+			limit, err := strconv.ParseFloat(arg, 64)
+			if err != nil {
+				fail(name, "has an invalid %s rule %q", name, arg)
+				continue
+			}
+			n, isLength := size(v)
+			if (name == "min" && n < limit) || (name == "max" && n > limit) {
+				what := "be"
+				if isLength {
+					what = "have a length of"
+				}
+				if name == "min" {
+					fail(name, "must %s at least %s", what, arg)
+				} else {
+					fail(name, "must %s at most %s", what, arg)
+				}
+			}
+		case "startswith":
+			if s := fmt.Sprint(indirect(v).Interface()); !strings.HasPrefix(s, arg) {
+				fail(name, "must start with %q, not %q", arg, s)
+			}
+		case "url":
+			s := fmt.Sprint(indirect(v).Interface())
+			if u, err := url.Parse(s); err != nil || u.Scheme == "" {
+				fail(name, "must be a URL, not %q", s)
+			}
+		case "":
+		default:
+			fail(name, "has an unknown rule %q", name)
+		}
+	}
+	return true
+}
+
+// isEmpty reports whether v is its zero value, or an empty list or map.
+func isEmpty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	}
+	return v.IsZero()
+}
+
+// indirect follows pointers in v.
+func indirect(v reflect.Value) reflect.Value {
+	for (v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface) && !v.IsNil() {
+		v = v.Elem()
+	}
+	return v
+}
+
+// size returns the number in v, or the length of a string, list or map and
+// true.
+func size(v reflect.Value) (float64, bool) {
+	v = indirect(v)
+	switch v.Kind() {
+	case reflect.String, reflect.Slice, reflect.Array, reflect.Map:
+		return float64(v.Len()), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), false
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), false
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), false
+	}
+	return 0, false
+}
+
+// fieldName returns the name of a field in the file: its json or yaml tag
+// name, or its Go name.
+func fieldName(f reflect.StructField) string {
+	for _, key := range []string{"json", "yaml"} {
+		if name, _, _ := strings.Cut(f.Tag.Get(key), ","); name != "" && name != "-" {
+			return name
+		}
+	}
+	return f.Name
+}
+
+func join(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// Unmarshal decodes data, the content of the file at path, into out, in the
+// format of the file's extension: JSON, or YAML for .yaml and .yml. Unlike
+// json.Unmarshal and yaml.Unmarshal, fields that out doesn't have are
+// errors, so misspelled and renamed fields are caught.
+func Unmarshal(path string, data []byte, out any) error {
+	return decode(path, data, out, true)
+}
+
+// UnmarshalLenient decodes data like Unmarshal, but fields that out doesn't
+// have are skipped, for files that other versions may add fields to. If there
+// were any, warning is the error Unmarshal would have returned.
+func UnmarshalLenient(path string, data []byte, out any) (warning, err error) {
+	strict := decode(path, data, out, true)
+	if strict == nil {
+		return nil, nil
+	}
+	reflect.ValueOf(out).Elem().SetZero()
+	if err := decode(path, data, out, false); err != nil {
+		return nil, err
+	}
+	return strict, nil
+}
+
+// decode decodes data into out in the format of path. If strict, fields that
+// out doesn't have are errors.
+func decode(path string, data []byte, out any, strict bool) error {
+	switch format(path) {
+	case formatJSON:
+		dec := json.NewDecoder(bytes.NewReader(data))
+		if strict {
+			dec.DisallowUnknownFields()
+		}
+		if err := dec.Decode(out); err != nil && err != io.EOF {
+			return err
+		}
+		return nil
+	case formatYAML:
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(strict)
+		if err := dec.Decode(out); err != nil && err != io.EOF {
+			return err
+		}
+		return nil
+	}
+	return fmt.Errorf("unsupported config format: %s", filepath.Ext(path))
+}
+
+type fileFormat int
+
+const (
+	formatUnknown fileFormat = iota
+	formatJSON
+	formatYAML
+)
+
+// format returns the format of the file at path, by its extension.
+func format(path string) fileFormat {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return formatJSON
+	case ".yaml", ".yml":
+		return formatYAML
+	}
+	return formatUnknown
+}
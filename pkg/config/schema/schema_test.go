@@ -0,0 +1,119 @@
+package schema
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type target struct {
+	OS   string `yaml:"os" validate:"required,oneof=linux darwin windows"`
+	Arch string `yaml:"arch" validate:"required"`
+}
+
+type project struct {
+	Name    string            `yaml:"name" validate:"required,min=2,max=8"`
+	Route   string            `yaml:"route" validate:"omitempty,startswith=/"`
+	Storage string            `yaml:"storage" validate:"omitempty,url"`
+	Workers int               `yaml:"workers" validate:"min=1"`
+	Targets []target          `yaml:"targets" validate:"required"`
+	Extra   map[string]target `yaml:"extra"`
+	hidden  string            `validate:"required"`
+}
+
+func TestValidate(t *testing.T) {
+	valid := project{
+		Name:    "core",
+		Workers: 2,
+		Targets: []target{{OS: "linux", Arch: "amd64"}},
+	}
+	if err := Validate(&valid); err != nil {
+		t.Fatalf("Expected a valid config, got: %v", err)
+	}
+	_ = valid.hidden
+
+	invalid := project{
+		Name:    "a",
+		Route:   "home",
+		Storage: "not a url",
+		Targets: []target{{OS: "linux", Arch: "amd64"}, {OS: "plan9"}},
+		Extra:   map[string]target{"arm": {OS: "linux"}},
+	}
+	err := Validate(invalid)
+	if !errors.Is(err, ErrInvalid) {
+		t.Fatalf("Expected ErrInvalid, got: %v", err)
+	}
+	var verr *Error
+	if !errors.As(err, &verr) {
+		t.Fatalf("Expected an *Error, got %T", err)
+	}
+	var got []string
+	for _, fe := range verr.Errors {
+		got = append(got, fe.Field+" "+fe.Rule)
+	}
+	want := []string{
+		"name min",
+		"route startswith",
+		"storage url",
+		"workers min",
+		"targets[1].os oneof",
+		"targets[1].arch required",
+		"extra.arm.arch required",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected errors %v, got %v", want, got)
+	}
+	if !strings.Contains(err.Error(), `targets[1].os: must be one of linux, darwin, windows, not "plan9"`) {
+		t.Errorf("Expected the message to name the field and the allowed values, got: %v", err)
+	}
+
+	if err := Validate(project{Name: "core", Workers: 1}); err == nil || !strings.Contains(err.Error(), "targets: is required") {
+		t.Errorf("Expected an empty list to fail required, got: %v", err)
+	}
+}
+
+func TestUnmarshal(t *testing.T) {
+	var p project
+	if err := Unmarshal("build.yaml", []byte("name: core\nworkers: 2\n"), &p); err != nil {
+		t.Fatalf("Unmarshal() failed: %v", err)
+	}
+	if p.Name != "core" || p.Workers != 2 {
+		t.Errorf("Expected the YAML to decode, got %+v", p)
+	}
+
+	if err := Unmarshal("build.yaml", []byte("name: core\nwrokers: 2\n"), &p); err == nil {
+		t.Error("Expected an unknown YAML field to be an error")
+	}
+	if err := Unmarshal("config.json", []byte(`{"Name": "core", "Wrokers": 2}`), &p); err == nil {
+		t.Error("Expected an unknown JSON field to be an error")
+	}
+	if err := Unmarshal("build.yaml", nil, &p); err != nil {
+		t.Errorf("Expected an empty file to decode, got: %v", err)
+	}
+	if err := Unmarshal("config.toml", nil, &p); err == nil {
+		t.Error("Expected an unsupported format to be an error")
+	}
+}
+
+func TestUnmarshalLenient(t *testing.T) {
+	p := project{Workers: 9}
+	warning, err := UnmarshalLenient("repos.yaml", []byte("name: core\nmirror: true\n"), &p)
+	if err != nil {
+		t.Fatalf("UnmarshalLenient() failed: %v", err)
+	}
+	if warning == nil {
+		t.Error("Expected a warning for the unknown field")
+	}
+	if p.Name != "core" || p.Workers != 0 {
+		t.Errorf("Expected only the known fields to decode, got %+v", p)
+	}
+
+	warning, err = UnmarshalLenient("repos.yaml", []byte("name: core\n"), &p)
+	if err != nil || warning != nil {
+		t.Errorf("Expected no warning or error, got %v, %v", warning, err)
+	}
+	if _, err := UnmarshalLenient("repos.yaml", []byte("name: [core\n"), &p); err == nil {
+		t.Error("Expected invalid YAML to be an error")
+	}
+}
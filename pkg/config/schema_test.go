@@ -0,0 +1,113 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/host-uk/core/pkg/core"
+)
+
+func TestVersioning(t *testing.T) {
+	tempHomeDir, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	userFile := filepath.Join(tempHomeDir, appName, "config", configFileName)
+	if err := os.MkdirAll(filepath.Dir(userFile), os.ModePerm); err != nil {
+		t.Fatalf("Failed to create config dir: %v", err)
+	}
+	original := []byte(`{"language": "de"}`)
+	if err := os.WriteFile(userFile, original, 0644); err != nil {
+		t.Fatalf("Failed to write user file: %v", err)
+	}
+
+	s, err := New(Options{})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	if s.Language != "de" {
+		t.Errorf("Expected the language from the old file, got '%s'", s.Language)
+	}
+
+	t.Run("Old files are upgraded with a backup", func(t *testing.T) {
+		backup, err := os.ReadFile(userFile + ".v0.bak")
+		if err != nil {
+			t.Fatalf("Expected a backup of the old file: %v", err)
+		}
+		if string(backup) != string(original) {
+			t.Errorf("Expected the backup to hold the old file, got %s", backup)
+		}
+		data, err := os.ReadFile(userFile)
+		if err != nil {
+			t.Fatalf("Failed to read user file: %v", err)
+		}
+		var saved map[string]any
+		if err := json.Unmarshal(data, &saved); err != nil {
+			t.Fatalf("Failed to decode user file: %v", err)
+		}
+		if saved["version"] != float64(ConfigVersion) || saved["language"] != "de" {
+			t.Errorf("Expected the upgraded file to keep the language and have a version, got %v", saved)
+		}
+	})
+
+	t.Run("Version is not a key", func(t *testing.T) {
+		var v any
+		if err := s.Get("version", &v); !core.Is(err, core.KindNotFound) {
+			t.Errorf("Expected version to be hidden, got %v (%v)", v, err)
+		}
+	})
+
+	t.Run("Newer files are rejected", func(t *testing.T) {
+		if err := os.WriteFile(userFile, []byte(`{"version": 99, "language": "de"}`), 0644); err != nil {
+			t.Fatalf("Failed to write user file: %v", err)
+		}
+		_, err := New(Options{})
+		if !core.Is(err, core.KindInvalid) || core.CodeOf(err) != "config.unsupported_version" {
+			t.Errorf("Expected an unsupported_version error, got: %v", err)
+		}
+	})
+}
+
+func TestValidation(t *testing.T) {
+	_, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	t.Setenv("CORE_DEFAULT_ROUTE", "home")
+	_, err := New(Options{})
+	if !core.Is(err, core.KindInvalid) || core.CodeOf(err) != "config.invalid" {
+		t.Fatalf("Expected an invalid error for a route without a slash, got: %v", err)
+	}
+	os.Unsetenv("CORE_DEFAULT_ROUTE")
+
+	s, err := New(Options{})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	err = s.Set("workspaceStorage", "not a url")
+	if !core.Is(err, core.KindInvalid) || core.CodeOf(err) != "config.invalid" {
+		t.Errorf("Expected an invalid error for a bad storage URL, got: %v", err)
+	}
+	if s.WorkspaceStorage != "" {
+		t.Errorf("Expected the invalid value to be discarded, got '%s'", s.WorkspaceStorage)
+	}
+
+	if err := s.Set("language", "x"); err == nil {
+		t.Error("Expected a one-letter language to be rejected")
+	}
+	if s.Language != "en" {
+		t.Errorf("Expected the language to be kept, got '%s'", s.Language)
+	}
+
+	if err := s.Set("theme", "blue"); err == nil {
+		t.Error("Expected an unknown theme to be rejected")
+	}
+	if err := s.Set("theme", "dark"); err != nil {
+		t.Errorf("Expected the dark theme to be accepted, got: %v", err)
+	}
+
+	if err := s.Set("workspaceStorage", "s3://bucket/prefix"); err != nil {
+		t.Errorf("Expected a storage URL to be accepted, got: %v", err)
+	}
+}
@@ -0,0 +1,373 @@
+package config
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/host-uk/core/pkg/core"
+	"github.com/host-uk/core/pkg/io/encrypted"
+)
+
+// secretsFileName is the file secrets are kept in, next to config.json.
+const secretsFileName = "secrets.json"
+
+// secretsVersion is the version of the secrets file format.
+const secretsVersion = 1
+
+// secretsCheck is encrypted into the secrets file, so a wrong passphrase or
+// key is caught when unlocking rather than on the first GetSecret.
+const secretsCheck = "core-secrets"
+
+// Redacted is printed, logged and saved in place of a secret.
+const Redacted = "[REDACTED]"
+
+// Secret is a secret value, such as an API token. It prints, logs and
+// marshals as Redacted, so passing it to a logger or saving it in a config
+// file doesn't leak it. Call Reveal for the value itself.
+type Secret string
+
+// Reveal returns the secret value.
+func (s Secret) Reveal() string { return string(s) }
+
+// String returns Redacted.
+func (s Secret) String() string { return Redacted }
+
+// GoString returns Redacted, for the %#v verb.
+func (s Secret) GoString() string { return Redacted }
+
+// LogValue returns Redacted, for slog.
+func (s Secret) LogValue() slog.Value { return slog.StringValue(Redacted) }
+
+// MarshalText returns Redacted, for JSON, YAML and other encodings.
+func (s Secret) MarshalText() ([]byte, error) { return []byte(Redacted), nil }
+
+// secretsFile is the content of secrets.json. Only the names of secrets are
+// readable; their values are encrypted.
+type secretsFile struct {
+	Version int `json:"version"`
+	// Salt is the salt passphrase keys are derived with.
+	Salt []byte `json:"salt,omitempty"`
+	// Check is secretsCheck, encrypted with the key the secrets are.
+	Check   []byte            `json:"check,omitempty"`
+	Secrets map[string][]byte `json:"secrets"`
+}
+
+// Secrets stores secrets such as API tokens and RPC credentials, encrypted
+// at rest in secrets.json in the config directory. Values are encrypted with
+// an AES-256-GCM key derived from a master passphrase, or with any
+// encrypted.Cipher, such as the active workspace's key, and bound to their
+// name, so they can't be swapped in the file. Secrets are never part of
+// config.json and are only decrypted once unlocked.
+//
+// Example:
+//
+//	secrets := cfg.Secrets()
+//	if err := secrets.UnlockWithPassphrase(passphrase); err != nil {
+//		return err
+//	}
+//	err := secrets.SetSecret("github.token", token)
+//	token, err := secrets.GetSecret("github.token")
+//	req.Header.Set("Authorization", "Bearer "+token.Reveal())
+type Secrets struct {
+	path string
+
+	mu     sync.Mutex
+	cipher encrypted.Cipher
+}
+
+// Secrets returns the service's secret store.
+func (s *Service) Secrets() *Secrets {
+	return s.secrets
+}
+
+// errSecretsLocked reports an operation that needs the secrets unlocked.
+func errSecretsLocked(op string) error {
+	return core.E(op, "secrets are locked", nil,
+		core.WithKind(core.KindPermission),
+		core.WithCode("config.secrets_locked"))
+}
+
+// Unlock decrypts secrets with c from now on. If secrets were stored with a
+// different key, it fails and the secrets stay locked.
+//
+// Example:
+//
+//	c, err := workspaceSvc.Cipher()
+//	if err == nil {
+//		err = cfg.Secrets().Unlock(c)
+//	}
+func (sec *Secrets) Unlock(c encrypted.Cipher) error {
+	sec.mu.Lock()
+	defer sec.mu.Unlock()
+	f, err := sec.read()
+	if err != nil {
+		return err
+	}
+	if err := verify(f, c); err != nil {
+		return err
+	}
+	sec.cipher = c
+	return nil
+}
+
+// UnlockWithPassphrase derives the key secrets are encrypted with from
+// passphrase. The first passphrase used sets the key.
+func (sec *Secrets) UnlockWithPassphrase(passphrase string) error {
+	sec.mu.Lock()
+	defer sec.mu.Unlock()
+	f, err := sec.read()
+	if err != nil {
+		return err
+	}
+	if f.Salt == nil {
+		f.Salt = make([]byte, encrypted.SaltSize)
+		if _, err := rand.Read(f.Salt); err != nil {
+			return core.E("config.Secrets.Unlock", "failed to generate salt", err)
+		}
+	}
+	key, err := encrypted.PasswordKey(passphrase, f.Salt)
+	if err != nil {
+		return core.E("config.Secrets.Unlock", "failed to derive key", err)
+	}
+	c, err := encrypted.Symmetric(key)
+	if err != nil {
+		return core.E("config.Secrets.Unlock", "failed to derive key", err)
+	}
+	if err := verify(f, c); err != nil {
+		return err
+	}
+	if f.Check == nil {
+		// Keep the salt, so the same passphrase gives the same key.
+		if err := sec.seal(f, c); err != nil {
+			return err
+		}
+	}
+	sec.cipher = c
+	return nil
+}
+
+// verify checks that c decrypts the secrets in f.
+func verify(f *secretsFile, c encrypted.Cipher) error {
+	if f.Check == nil {
+		return nil
+	}
+	check, err := c.Decrypt(f.Check)
+	if err != nil || string(check) != secretsCheck {
+		return core.E("config.Secrets.Unlock", "wrong passphrase or key for secrets", err,
+			core.WithKind(core.KindPermission),
+			core.WithCode("config.wrong_secrets_key"))
+	}
+	return nil
+}
+
+// seal writes f with its check value encrypted with c. The caller must hold
+// sec.mu.
+func (sec *Secrets) seal(f *secretsFile, c encrypted.Cipher) error {
+	if f.Check == nil {
+		check, err := c.Encrypt([]byte(secretsCheck))
+		if err != nil {
+			return core.E("config.Secrets", "failed to encrypt secrets", err)
+		}
+		f.Check = check
+	}
+	return sec.write(f)
+}
+
+// Lock forgets the key, so secrets can't be read or set until unlocked
+// again.
+func (sec *Secrets) Lock() {
+	sec.mu.Lock()
+	defer sec.mu.Unlock()
+	sec.cipher = nil
+}
+
+// IsLocked reports whether the secrets need unlocking before use.
+func (sec *Secrets) IsLocked() bool {
+	sec.mu.Lock()
+	defer sec.mu.Unlock()
+	return sec.cipher == nil
+}
+
+// SetSecret encrypts value and stores it under name, a key such as
+// "github.token" or "modules.mining.pool.password".
+func (sec *Secrets) SetSecret(name, value string) error {
+	if !validKey(name) {
+		return core.E("config.SetSecret", fmt.Sprintf("invalid secret name '%s'", name), nil,
+			core.WithKind(core.KindInvalid),
+			core.WithCode("config.invalid_key"),
+			core.WithMeta("name", name))
+	}
+	sec.mu.Lock()
+	defer sec.mu.Unlock()
+	if sec.cipher == nil {
+		return errSecretsLocked("config.SetSecret")
+	}
+	f, err := sec.read()
+	if err != nil {
+		return err
+	}
+	ciphertext, err := encrypted.EncryptFor(sec.cipher, name, []byte(value))
+	if err != nil {
+		return core.E("config.SetSecret", "failed to encrypt secret", err, core.WithMeta("name", name))
+	}
+	f.Secrets[name] = ciphertext
+	return sec.seal(f, sec.cipher)
+}
+
+// GetSecret returns the secret stored under name.
+func (sec *Secrets) GetSecret(name string) (Secret, error) {
+	sec.mu.Lock()
+	defer sec.mu.Unlock()
+	if sec.cipher == nil {
+		return "", errSecretsLocked("config.GetSecret")
+	}
+	f, err := sec.read()
+	if err != nil {
+		return "", err
+	}
+	ciphertext, ok := f.Secrets[name]
+	if !ok {
+		return "", errSecretNotFound("config.GetSecret", name)
+	}
+	plaintext, err := encrypted.DecryptFor(sec.cipher, name, ciphertext)
+	if err != nil {
+		return "", core.E("config.GetSecret", "failed to decrypt secret", err,
+			core.WithKind(core.KindPermission),
+			core.WithCode("config.wrong_secrets_key"),
+			core.WithMeta("name", name))
+	}
+	return Secret(plaintext), nil
+}
+
+// ListSecrets returns the names of the stored secrets, sorted. It doesn't
+// need the secrets unlocked.
+func (sec *Secrets) ListSecrets() ([]string, error) {
+	sec.mu.Lock()
+	defer sec.mu.Unlock()
+	f, err := sec.read()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(f.Secrets))
+	for name := range f.Secrets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// DeleteSecret removes the secret stored under name. Like setting a secret,
+// it needs the secrets unlocked.
+func (sec *Secrets) DeleteSecret(name string) error {
+	sec.mu.Lock()
+	defer sec.mu.Unlock()
+	if sec.cipher == nil {
+		return errSecretsLocked("config.DeleteSecret")
+	}
+	f, err := sec.read()
+	if err != nil {
+		return err
+	}
+	if _, ok := f.Secrets[name]; !ok {
+		return errSecretNotFound("config.DeleteSecret", name)
+	}
+	delete(f.Secrets, name)
+	return sec.write(f)
+}
+
+// ExportSecrets returns every secret, decrypted, by name. It is the only way
+// to read all the secrets at once, for backups and moving them to another
+// machine, and the values it returns are not redacted.
+func (sec *Secrets) ExportSecrets() (map[string]string, error) {
+	sec.mu.Lock()
+	defer sec.mu.Unlock()
+	if sec.cipher == nil {
+		return nil, errSecretsLocked("config.ExportSecrets")
+	}
+	f, err := sec.read()
+	if err != nil {
+		return nil, err
+	}
+	exported := make(map[string]string, len(f.Secrets))
+	for name, ciphertext := range f.Secrets {
+		plaintext, err := encrypted.DecryptFor(sec.cipher, name, ciphertext)
+		if err != nil {
+			return nil, core.E("config.ExportSecrets", "failed to decrypt secret", err,
+				core.WithKind(core.KindPermission),
+				core.WithCode("config.wrong_secrets_key"),
+				core.WithMeta("name", name))
+		}
+		exported[name] = string(plaintext)
+	}
+	return exported, nil
+}
+
+// errSecretNotFound reports that no secret is stored under name.
+func errSecretNotFound(op, name string) error {
+	return core.E(op, fmt.Sprintf("secret '%s' not found", name), nil,
+		core.WithKind(core.KindNotFound),
+		core.WithCode("config.secret_not_found"),
+		core.WithMeta("name", name))
+}
+
+// read loads the secrets file. A missing file holds no secrets. The caller
+// must hold sec.mu.
+func (sec *Secrets) read() (*secretsFile, error) {
+	f := &secretsFile{Version: secretsVersion, Secrets: map[string][]byte{}}
+	data, err := os.ReadFile(sec.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return f, nil
+	} else if err != nil {
+		return nil, core.E("config.Secrets", "failed to read secrets file", err, core.WithMeta("path", sec.path))
+	}
+	if err := json.Unmarshal(data, f); err != nil {
+		return nil, errInvalidFile(sec.path, err)
+	}
+	if f.Version > secretsVersion {
+		return nil, core.E("config.Secrets", fmt.Sprintf("secrets file version %d is newer than the supported version %d", f.Version, secretsVersion), nil,
+			core.WithKind(core.KindInvalid),
+			core.WithCode("config.unsupported_version"),
+			core.WithMeta("path", sec.path))
+	}
+	if f.Secrets == nil {
+		f.Secrets = map[string][]byte{}
+	}
+	return f, nil
+}
+
+// write saves the secrets file, readable only by the user, through a
+// temporary file so it is never left half written. The caller must hold
+// sec.mu.
+func (sec *Secrets) write(f *secretsFile) error {
+	f.Version = secretsVersion
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return core.E("config.Secrets", "failed to marshal secrets", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(sec.path), 0700); err != nil {
+		return core.E("config.Secrets", "failed to create secrets directory", err, core.WithMeta("path", sec.path))
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(sec.path), "."+secretsFileName+".*")
+	if err != nil {
+		return core.E("config.Secrets", "failed to write secrets file", err, core.WithMeta("path", sec.path))
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), sec.path)
+	}
+	if err != nil {
+		return core.E("config.Secrets", "failed to write secrets file", err, core.WithMeta("path", sec.path))
+	}
+	return nil
+}
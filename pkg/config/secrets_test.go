@@ -0,0 +1,236 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/host-uk/core/pkg/core"
+	"github.com/host-uk/core/pkg/io/encrypted"
+)
+
+func TestSecrets(t *testing.T) {
+	tempHomeDir, cleanup := setupTestEnv(t)
+	defer cleanup()
+	secretsFile := filepath.Join(tempHomeDir, appName, "config", secretsFileName)
+
+	s, err := New(Options{})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	secrets := s.Secrets()
+
+	if err := secrets.SetSecret("github.token", "ghp_abc123"); core.CodeOf(err) != "config.secrets_locked" {
+		t.Errorf("Expected a secrets_locked error, got: %v", err)
+	}
+	if err := secrets.UnlockWithPassphrase("correct horse"); err != nil {
+		t.Fatalf("UnlockWithPassphrase() failed: %v", err)
+	}
+	if err := secrets.SetSecret("github.token", "ghp_abc123"); err != nil {
+		t.Fatalf("SetSecret() failed: %v", err)
+	}
+	if err := secrets.SetSecret("modules.mining.pool.password", "x"); err != nil {
+		t.Fatalf("SetSecret() failed: %v", err)
+	}
+
+	token, err := secrets.GetSecret("github.token")
+	if err != nil {
+		t.Fatalf("GetSecret() failed: %v", err)
+	}
+	if token.Reveal() != "ghp_abc123" {
+		t.Errorf("Expected the token, got '%s'", token.Reveal())
+	}
+	names, err := secrets.ListSecrets()
+	if err != nil {
+		t.Fatalf("ListSecrets() failed: %v", err)
+	}
+	if want := []string{"github.token", "modules.mining.pool.password"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("Expected %v, got %v", want, names)
+	}
+	if _, err := secrets.GetSecret("missing"); !core.Is(err, core.KindNotFound) {
+		t.Errorf("Expected a not_found error, got: %v", err)
+	}
+
+	t.Run("Encrypted at rest", func(t *testing.T) {
+		data, err := os.ReadFile(secretsFile)
+		if err != nil {
+			t.Fatalf("Failed to read secrets file: %v", err)
+		}
+		if bytes.Contains(data, []byte("ghp_abc123")) {
+			t.Error("Expected the secrets file not to contain the token")
+		}
+		if info, err := os.Stat(secretsFile); err != nil || info.Mode().Perm() != 0600 {
+			t.Errorf("Expected the secrets file to be private, got %v (%v)", info.Mode(), err)
+		}
+		config, err := os.ReadFile(s.ConfigPath)
+		if err != nil {
+			t.Fatalf("Failed to read config file: %v", err)
+		}
+		if bytes.Contains(config, []byte("ghp_abc123")) {
+			t.Error("Expected config.json not to contain the token")
+		}
+	})
+
+	t.Run("Swapped in the file", func(t *testing.T) {
+		data, err := os.ReadFile(secretsFile)
+		if err != nil {
+			t.Fatalf("Failed to read secrets file: %v", err)
+		}
+		defer os.WriteFile(secretsFile, data, 0600)
+		var f map[string]any
+		if err := json.Unmarshal(data, &f); err != nil {
+			t.Fatalf("Failed to decode secrets file: %v", err)
+		}
+		stored := f["secrets"].(map[string]any)
+		stored["github.token"], stored["modules.mining.pool.password"] = stored["modules.mining.pool.password"], stored["github.token"]
+		swapped, _ := json.Marshal(f)
+		if err := os.WriteFile(secretsFile, swapped, 0600); err != nil {
+			t.Fatalf("Failed to write secrets file: %v", err)
+		}
+		if _, err := secrets.GetSecret("github.token"); core.CodeOf(err) != "config.wrong_secrets_key" {
+			t.Errorf("Expected a swapped secret not to decrypt, got: %v", err)
+		}
+	})
+
+	t.Run("Redacted", func(t *testing.T) {
+		var logs bytes.Buffer
+		slog.New(slog.NewJSONHandler(&logs, nil)).Info("connecting", "token", token)
+		for _, out := range []string{
+			fmt.Sprint(token),
+			fmt.Sprintf("%v %s %q %#v", token, token, token, token),
+			logs.String(),
+		} {
+			if strings.Contains(out, "ghp_abc123") || !strings.Contains(out, Redacted) {
+				t.Errorf("Expected the token to be redacted, got %s", out)
+			}
+		}
+
+		data, err := json.Marshal(map[string]any{"token": token})
+		if err != nil || string(data) != `{"token":"[REDACTED]"}` {
+			t.Errorf("Expected the token to be redacted in JSON, got %s (%v)", data, err)
+		}
+		if err := s.Set("modules.github.token", token); err != nil {
+			t.Fatalf("Set() failed: %v", err)
+		}
+		config, err := os.ReadFile(s.ConfigPath)
+		if err != nil {
+			t.Fatalf("Failed to read config file: %v", err)
+		}
+		if bytes.Contains(config, []byte("ghp_abc123")) {
+			t.Error("Expected Save to redact the token")
+		}
+	})
+
+	t.Run("Export", func(t *testing.T) {
+		exported, err := secrets.ExportSecrets()
+		if err != nil {
+			t.Fatalf("ExportSecrets() failed: %v", err)
+		}
+		want := map[string]string{"github.token": "ghp_abc123", "modules.mining.pool.password": "x"}
+		if !reflect.DeepEqual(exported, want) {
+			t.Errorf("Expected %v, got %v", want, exported)
+		}
+	})
+
+	t.Run("Lock and unlock", func(t *testing.T) {
+		secrets.Lock()
+		if !secrets.IsLocked() {
+			t.Error("Expected the secrets to be locked")
+		}
+		if _, err := secrets.GetSecret("github.token"); core.CodeOf(err) != "config.secrets_locked" {
+			t.Errorf("Expected a secrets_locked error, got: %v", err)
+		}
+		if _, err := secrets.ExportSecrets(); core.CodeOf(err) != "config.secrets_locked" {
+			t.Errorf("Expected a secrets_locked error, got: %v", err)
+		}
+		if err := secrets.DeleteSecret("github.token"); core.CodeOf(err) != "config.secrets_locked" {
+			t.Errorf("Expected a secrets_locked error, got: %v", err)
+		}
+
+		err := secrets.UnlockWithPassphrase("wrong")
+		if !core.Is(err, core.KindPermission) || core.CodeOf(err) != "config.wrong_secrets_key" {
+			t.Errorf("Expected a wrong_secrets_key error, got: %v", err)
+		}
+		if !secrets.IsLocked() {
+			t.Error("Expected a wrong passphrase to leave the secrets locked")
+		}
+
+		// A new service reads the same file with the same passphrase.
+		other, err := New(Options{})
+		if err != nil {
+			t.Fatalf("New() failed: %v", err)
+		}
+		if err := other.Secrets().UnlockWithPassphrase("correct horse"); err != nil {
+			t.Fatalf("UnlockWithPassphrase() failed: %v", err)
+		}
+		if token, err := other.Secrets().GetSecret("github.token"); err != nil || token.Reveal() != "ghp_abc123" {
+			t.Errorf("Expected the token after unlocking again, got %v", err)
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		if err := secrets.UnlockWithPassphrase("correct horse"); err != nil {
+			t.Fatalf("UnlockWithPassphrase() failed: %v", err)
+		}
+		if err := secrets.DeleteSecret("modules.mining.pool.password"); err != nil {
+			t.Fatalf("DeleteSecret() failed: %v", err)
+		}
+		if err := secrets.DeleteSecret("modules.mining.pool.password"); !core.Is(err, core.KindNotFound) {
+			t.Errorf("Expected a not_found error, got: %v", err)
+		}
+		if names, _ := secrets.ListSecrets(); !reflect.DeepEqual(names, []string{"github.token"}) {
+			t.Errorf("Expected only the token to be left, got %v", names)
+		}
+	})
+}
+
+func TestSecrets_Cipher(t *testing.T) {
+	_, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	s, err := New(Options{})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	key, err := encrypted.GenerateKey()
+	if err != nil {
+		t.Fatalf("GenerateKey() failed: %v", err)
+	}
+	c, err := encrypted.Symmetric(key)
+	if err != nil {
+		t.Fatalf("Symmetric() failed: %v", err)
+	}
+
+	secrets := s.Secrets()
+	if err := secrets.Unlock(c); err != nil {
+		t.Fatalf("Unlock() failed: %v", err)
+	}
+	if err := secrets.SetSecret("rpc.password", "hunter2"); err != nil {
+		t.Fatalf("SetSecret() failed: %v", err)
+	}
+	if err := secrets.SetSecret("rpc..password", "x"); !core.Is(err, core.KindInvalid) {
+		t.Errorf("Expected an invalid name to be rejected, got: %v", err)
+	}
+
+	secrets.Lock()
+	otherKey, _ := encrypted.GenerateKey()
+	other, _ := encrypted.Symmetric(otherKey)
+	if err := secrets.Unlock(other); core.CodeOf(err) != "config.wrong_secrets_key" {
+		t.Errorf("Expected another key to be rejected, got: %v", err)
+	}
+	if err := secrets.UnlockWithPassphrase("passphrase"); core.CodeOf(err) != "config.wrong_secrets_key" {
+		t.Errorf("Expected a passphrase to be rejected for secrets stored with a key, got: %v", err)
+	}
+	if err := secrets.Unlock(c); err != nil {
+		t.Fatalf("Unlock() failed: %v", err)
+	}
+	if password, err := secrets.GetSecret("rpc.password"); err != nil || password.Reveal() != "hunter2" {
+		t.Errorf("Expected the password, got %v", err)
+	}
+}
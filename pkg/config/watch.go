@@ -0,0 +1,144 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+
+	"github.com/host-uk/core/pkg/config/schema"
+	"github.com/host-uk/core/pkg/core"
+	"github.com/host-uk/core/pkg/io"
+	"github.com/host-uk/core/pkg/io/local"
+)
+
+// files returns the config file of each file layer, if it has one.
+func (s *Service) files() map[Layer]string {
+	return map[Layer]string{
+		LayerSystem:    s.systemFile,
+		LayerUser:      s.userFile,
+		LayerWorkspace: s.workspaceFile,
+	}
+}
+
+// Reload reads the config files and the environment again and reports every
+// value that changed. If a file can't be read or the result is invalid, the
+// values are left as they were.
+//
+// Example:
+//
+//	if err := cfg.Reload(); err != nil {
+//		log.Printf("keeping the old config: %v", err)
+//	}
+func (s *Service) Reload() error {
+	return s.update(s.reload)
+}
+
+// reload reads the file layers and the environment. The caller must hold
+// s.mu.
+func (s *Service) reload() error {
+	saved := s.layers
+	restore := func() {
+		s.layers = saved
+		s.resolveAll()
+	}
+	for l, path := range s.files() {
+		if path == "" {
+			continue
+		}
+		if _, err := s.loadFile(l, path); err != nil {
+			restore()
+			return err
+		}
+	}
+	if err := s.loadEnv(); err != nil {
+		restore()
+		return err
+	}
+	s.resolveAll()
+	if err := schema.Validate(s); err != nil {
+		restore()
+		return core.E("config.Reload", "invalid config", err,
+			core.WithKind(core.KindInvalid),
+			core.WithCode("config.invalid"))
+	}
+	return nil
+}
+
+// Watch reloads the config whenever one of its files changes, until Unwatch
+// is called, so edits made outside the application take effect and are
+// reported like calls to Set. Files that don't exist yet are not watched.
+// The Core calls Watch on startup and Unwatch on shutdown.
+func (s *Service) Watch() error {
+	s.watchMu.Lock()
+	defer s.watchMu.Unlock()
+	if s.watches != nil {
+		return nil
+	}
+
+	s.mu.RLock()
+	files := s.files()
+	s.mu.RUnlock()
+
+	var watches []*io.Watch
+	for _, path := range files {
+		if path == "" {
+			continue
+		}
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			continue
+		}
+		m, err := local.New(filepath.Dir(path))
+		if err == nil {
+			var w *io.Watch
+			w, err = io.StartWatch(m, filepath.Base(path), false)
+			if err == nil {
+				watches = append(watches, w)
+				continue
+			}
+		}
+		for _, w := range watches {
+			w.Close()
+		}
+		return core.E("config.Watch", "failed to watch config file", err, core.WithMeta("path", path))
+	}
+
+	for _, w := range watches {
+		s.watchWG.Add(1)
+		go func() {
+			defer s.watchWG.Done()
+			for range w.Events() {
+				if err := s.Reload(); err != nil {
+					s.Logger().Warn("failed to reload config", "err", err)
+				}
+			}
+		}()
+	}
+	s.watches = watches
+	return nil
+}
+
+// Unwatch stops watching the config files.
+func (s *Service) Unwatch() error {
+	s.watchMu.Lock()
+	watches := s.watches
+	s.watches = nil
+	s.watchMu.Unlock()
+
+	var errs []error
+	for _, w := range watches {
+		errs = append(errs, w.Close())
+	}
+	s.watchWG.Wait()
+	return errors.Join(errs...)
+}
+
+// OnStartup starts watching the config files.
+func (s *Service) OnStartup(context.Context) error {
+	return s.Watch()
+}
+
+// OnShutdown stops watching the config files.
+func (s *Service) OnShutdown(context.Context) error {
+	return s.Unwatch()
+}
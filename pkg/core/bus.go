@@ -0,0 +1,322 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// Default sizes for the asynchronous event bus.
+const (
+	defaultBusWorkers   = 4
+	defaultBusQueueSize = 64
+)
+
+// EventBusOptions configures the asynchronous delivery used by ACTIONAsync.
+type EventBusOptions struct {
+	// Workers is the number of goroutines delivering messages. Defaults to 4.
+	Workers int
+	// QueueSize is the number of undelivered messages each handler may have
+	// queued before ACTIONAsync blocks. Defaults to 64.
+	QueueSize int
+	// OnError, if set, is called with any error returned by a handler during
+	// asynchronous delivery. It is called from a worker goroutine.
+	OnError func(error)
+}
+
+// WithEventBus creates an Option that configures the worker pool behind
+// ACTIONAsync. Without it, the pool is created with default sizes the first
+// time ACTIONAsync is called.
+func WithEventBus(opts EventBusOptions) Option {
+	return func(c *Core) error {
+		if opts.Workers < 0 || opts.QueueSize < 0 {
+			return E("core.WithEventBus", "workers and queue size must not be negative", nil)
+		}
+		c.busOptions = opts
+		return nil
+	}
+}
+
+// Subscribe registers fn to receive every message of type T sent through
+// ACTION, ACTIONContext or ACTIONAsync. Unlike handlers added with
+// RegisterAction, fn is only invoked for matching messages, so it does not
+// need to type-switch. T may also be an interface type, in which case fn
+// receives every message that implements it.
+//
+// The returned function removes the subscription.
+//
+// Example:
+//
+//	unsubscribe := core.Subscribe(c, func(msg display.ActionOpenWindow) error {
+//		return openWindow(msg)
+//	})
+//	defer unsubscribe()
+func Subscribe[T Message](c *Core, fn func(T) error) func() {
+	msgType := reflect.TypeOf((*T)(nil)).Elem()
+	if msgType.Kind() == reflect.Interface {
+		// Interface subscriptions cannot be indexed by concrete type, so
+		// they are checked against every message.
+		msgType = nil
+	}
+	h := c.addActionHandler(msgType, "", func(_ *Core, msg Message) error {
+		typed, ok := msg.(T)
+		if !ok {
+			return nil
+		}
+		return fn(typed)
+	})
+	return func() { c.removeActionHandler(h) }
+}
+
+// ACTIONAsync queues a message for delivery to all registered IPC handlers
+// and returns without waiting for them to run. Delivery happens on a bounded
+// pool of worker goroutines; each handler receives its messages one at a
+// time and in the order they were sent. If a handler's queue is full,
+// ACTIONAsync blocks until there is room or ctx is done.
+//
+// Errors returned by handlers are passed to EventBusOptions.OnError.
+// Interceptors see the dispatch when the message is queued and each handler
+// invocation when it runs on a worker.
+func (c *Core) ACTIONAsync(ctx context.Context, msg Message) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	bus := c.eventBus()
+	return c.intercept(Invocation{Ctx: ctx, Stage: StageDispatch, Message: msg, Async: true}, func() error {
+		for _, h := range c.handlersFor(msg) {
+			if err := bus.enqueue(ctx, h, msg); err != nil {
+				return E("core.ACTIONAsync", "failed to queue message", err)
+			}
+		}
+		return nil
+	})
+}
+
+// addActionHandler registers fn for messages of msgType, or for every message
+// if msgType is nil, and returns the new handler.
+func (c *Core) addActionHandler(msgType reflect.Type, service string, fn func(*Core, Message) error) *actionHandler {
+	c.ipcMu.Lock()
+	defer c.ipcMu.Unlock()
+
+	c.handlerSeq++
+	h := &actionHandler{id: c.handlerSeq, service: service, msgType: msgType, fn: fn}
+	if msgType == nil {
+		c.ipcHandlers = append(c.ipcHandlers, h)
+		return h
+	}
+	if c.typedHandlers == nil {
+		c.typedHandlers = make(map[reflect.Type][]*actionHandler)
+	}
+	c.typedHandlers[msgType] = append(c.typedHandlers[msgType], h)
+	return h
+}
+
+// removeActionHandler unregisters a handler added by addActionHandler.
+func (c *Core) removeActionHandler(h *actionHandler) {
+	c.ipcMu.Lock()
+	defer c.ipcMu.Unlock()
+
+	remove := func(list []*actionHandler) []*actionHandler {
+		for i, existing := range list {
+			if existing == h {
+				return append(list[:i:i], list[i+1:]...)
+			}
+		}
+		return list
+	}
+	if h.msgType == nil {
+		c.ipcHandlers = remove(c.ipcHandlers)
+		return
+	}
+	c.typedHandlers[h.msgType] = remove(c.typedHandlers[h.msgType])
+	if len(c.typedHandlers[h.msgType]) == 0 {
+		delete(c.typedHandlers, h.msgType)
+	}
+}
+
+// handlersFor returns the handlers that should receive msg, in registration
+// order: the catch-all handlers plus those subscribed to msg's exact type.
+func (c *Core) handlersFor(msg Message) []*actionHandler {
+	c.ipcMu.RLock()
+	typed := c.typedHandlers[reflect.TypeOf(msg)]
+	handlers := make([]*actionHandler, 0, len(c.ipcHandlers)+len(typed))
+	handlers = append(handlers, c.ipcHandlers...)
+	handlers = append(handlers, typed...)
+	c.ipcMu.RUnlock()
+
+	if len(typed) > 0 && len(handlers) > len(typed) {
+		sort.Slice(handlers, func(i, j int) bool { return handlers[i].id < handlers[j].id })
+	}
+	return handlers
+}
+
+// eventBus returns the Core's asynchronous event bus, starting it on first use.
+func (c *Core) eventBus() *eventBus {
+	c.busMu.Lock()
+	defer c.busMu.Unlock()
+	if c.bus == nil {
+		c.bus = newEventBus(c, c.busOptions)
+	}
+	return c.bus
+}
+
+// stopEventBus waits for queued messages to be delivered, or for ctx to be
+// done, and then stops the bus workers. It is a no-op if the bus was never
+// started.
+func (c *Core) stopEventBus(ctx context.Context) error {
+	c.busMu.Lock()
+	bus := c.bus
+	c.busMu.Unlock()
+	if bus == nil {
+		return nil
+	}
+	return bus.stop(ctx)
+}
+
+// errBusStopped is returned when a message is sent after the bus has stopped.
+var errBusStopped = errors.New("event bus stopped")
+
+// eventBus delivers messages to handlers on a fixed pool of workers. Each
+// handler has its own bounded inbox; a handler with pending messages is
+// scheduled onto the ready queue at most once, so its messages are handled
+// sequentially by a single worker at a time.
+type eventBus struct {
+	core      *Core
+	queueSize int
+	onError   func(error)
+	ready     chan *actionHandler
+	quit      chan struct{}
+	pending   sync.WaitGroup
+	workers   sync.WaitGroup
+	mu        sync.RWMutex
+	stopped   bool
+}
+
+func newEventBus(c *Core, opts EventBusOptions) *eventBus {
+	workers := opts.Workers
+	if workers == 0 {
+		workers = defaultBusWorkers
+	}
+	queueSize := opts.QueueSize
+	if queueSize == 0 {
+		queueSize = defaultBusQueueSize
+	}
+	b := &eventBus{
+		core:      c,
+		queueSize: queueSize,
+		onError:   opts.OnError,
+		ready:     make(chan *actionHandler, queueSize),
+		quit:      make(chan struct{}),
+	}
+	b.workers.Add(workers)
+	for i := 0; i < workers; i++ {
+		go b.work()
+	}
+	return b
+}
+
+// enqueue adds msg to the handler's inbox and schedules the handler if it is
+// not already waiting for a worker.
+func (b *eventBus) enqueue(ctx context.Context, h *actionHandler, msg Message) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if b.stopped {
+		return errBusStopped
+	}
+
+	b.core.ipcMu.Lock()
+	if h.inbox == nil {
+		h.inbox = make(chan Message, b.queueSize)
+	}
+	inbox := h.inbox
+	b.core.ipcMu.Unlock()
+
+	b.pending.Add(1)
+	select {
+	case inbox <- msg:
+	case <-ctx.Done():
+		b.pending.Done()
+		return ctx.Err()
+	}
+
+	if h.scheduled.CompareAndSwap(false, true) {
+		select {
+		case b.ready <- h:
+		case <-ctx.Done():
+			// The message is already queued, so the handler must still be
+			// scheduled or it would wait for the next send. Finish in the
+			// background rather than keep the caller waiting.
+			go func() {
+				select {
+				case b.ready <- h:
+				case <-b.quit:
+				}
+			}()
+		}
+	}
+	return nil
+}
+
+// work takes scheduled handlers off the ready queue and drains their inboxes.
+func (b *eventBus) work() {
+	defer b.workers.Done()
+	for {
+		select {
+		case h := <-b.ready:
+			b.drain(h)
+		case <-b.quit:
+			return
+		}
+	}
+}
+
+// drain delivers every message currently queued for h, in order.
+func (b *eventBus) drain(h *actionHandler) {
+	for {
+		select {
+		case msg := <-h.inbox:
+			if err := b.core.runAction(context.Background(), h, msg, true); err != nil && b.onError != nil {
+				b.onError(err)
+			}
+			b.pending.Done()
+		default:
+			h.scheduled.Store(false)
+			// A message may have arrived after the inbox was seen empty but
+			// before the handler was unscheduled; keep going if so.
+			if len(h.inbox) == 0 || !h.scheduled.CompareAndSwap(false, true) {
+				return
+			}
+		}
+	}
+}
+
+// stop refuses new messages, waits for queued ones to be delivered, and then
+// shuts down the workers. If ctx is done first, the workers are told to stop
+// without waiting for them and ctx's error is returned.
+func (b *eventBus) stop(ctx context.Context) error {
+	b.mu.Lock()
+	if b.stopped {
+		b.mu.Unlock()
+		return nil
+	}
+	b.stopped = true
+	b.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		b.pending.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		close(b.quit)
+		b.workers.Wait()
+		return nil
+	case <-ctx.Done():
+		close(b.quit)
+		return ctx.Err()
+	}
+}
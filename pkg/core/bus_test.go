@@ -0,0 +1,230 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type busPing struct {
+	N int
+}
+
+type busPong struct{}
+
+type busNamed interface {
+	BusName() string
+}
+
+type busNamedMessage struct{}
+
+func (busNamedMessage) BusName() string { return "named" }
+
+func TestSubscribe_Good(t *testing.T) {
+	c, err := New()
+	assert.NoError(t, err)
+
+	var pings []int
+	Subscribe(c, func(msg busPing) error {
+		pings = append(pings, msg.N)
+		return nil
+	})
+
+	assert.NoError(t, c.ACTION(busPing{N: 1}))
+	assert.NoError(t, c.ACTION(busPong{}))
+	assert.NoError(t, c.ACTION(busPing{N: 2}))
+	assert.Equal(t, []int{1, 2}, pings)
+}
+
+func TestSubscribe_Interface(t *testing.T) {
+	c, err := New()
+	assert.NoError(t, err)
+
+	var names []string
+	Subscribe(c, func(msg busNamed) error {
+		names = append(names, msg.BusName())
+		return nil
+	})
+
+	assert.NoError(t, c.ACTION(busNamedMessage{}))
+	assert.NoError(t, c.ACTION(busPing{}))
+	assert.Equal(t, []string{"named"}, names)
+}
+
+func TestSubscribe_Order(t *testing.T) {
+	c, err := New()
+	assert.NoError(t, err)
+
+	var order []string
+	c.RegisterAction(func(c *Core, msg Message) error {
+		order = append(order, "action-1")
+		return nil
+	})
+	Subscribe(c, func(msg busPing) error {
+		order = append(order, "subscriber")
+		return nil
+	})
+	c.RegisterAction(func(c *Core, msg Message) error {
+		order = append(order, "action-2")
+		return nil
+	})
+
+	assert.NoError(t, c.ACTION(busPing{}))
+	assert.Equal(t, []string{"action-1", "subscriber", "action-2"}, order)
+}
+
+func TestSubscribe_Unsubscribe(t *testing.T) {
+	c, err := New()
+	assert.NoError(t, err)
+
+	calls := 0
+	unsubscribe := Subscribe(c, func(msg busPing) error {
+		calls++
+		return nil
+	})
+	assert.NoError(t, c.ACTION(busPing{}))
+	unsubscribe()
+	assert.NoError(t, c.ACTION(busPing{}))
+	assert.Equal(t, 1, calls)
+}
+
+func TestSubscribe_Bad(t *testing.T) {
+	c, err := New()
+	assert.NoError(t, err)
+
+	Subscribe(c, func(msg busPing) error { return assert.AnError })
+	err = c.ACTION(busPing{})
+	assert.ErrorIs(t, err, assert.AnError)
+}
+
+func TestCore_ACTIONAsync_Good(t *testing.T) {
+	c, err := New(WithEventBus(EventBusOptions{Workers: 4, QueueSize: 8}))
+	assert.NoError(t, err)
+
+	const n = 100
+	var mu sync.Mutex
+	var first, second []int
+	var wg sync.WaitGroup
+	wg.Add(2 * n)
+	Subscribe(c, func(msg busPing) error {
+		mu.Lock()
+		first = append(first, msg.N)
+		mu.Unlock()
+		wg.Done()
+		return nil
+	})
+	Subscribe(c, func(msg busPing) error {
+		mu.Lock()
+		second = append(second, msg.N)
+		mu.Unlock()
+		wg.Done()
+		return nil
+	})
+
+	for i := 0; i < n; i++ {
+		assert.NoError(t, c.ACTIONAsync(context.Background(), busPing{N: i}))
+	}
+	wg.Wait()
+
+	expected := make([]int, n)
+	for i := range expected {
+		expected[i] = i
+	}
+	assert.Equal(t, expected, first, "each subscriber receives messages in order")
+	assert.Equal(t, expected, second, "each subscriber receives messages in order")
+	assert.NoError(t, c.ServiceShutdown(context.Background()))
+}
+
+func TestCore_ACTIONAsync_Backpressure(t *testing.T) {
+	c, err := New(WithEventBus(EventBusOptions{Workers: 1, QueueSize: 1}))
+	assert.NoError(t, err)
+
+	release := make(chan struct{})
+	Subscribe(c, func(msg busPing) error {
+		<-release
+		return nil
+	})
+
+	// The first message is picked up by the worker, the second fills the
+	// queue, and the third cannot be queued until the handler makes progress.
+	assert.NoError(t, c.ACTIONAsync(context.Background(), busPing{N: 1}))
+	assert.NoError(t, c.ACTIONAsync(context.Background(), busPing{N: 2}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err = c.ACTIONAsync(ctx, busPing{N: 3})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	close(release)
+	assert.NoError(t, c.ServiceShutdown(context.Background()))
+}
+
+func TestCore_ACTIONAsync_ScheduledAfterTimeout(t *testing.T) {
+	c, err := New(WithEventBus(EventBusOptions{Workers: 1, QueueSize: 2}))
+	assert.NoError(t, err)
+
+	release := make(chan struct{})
+	Subscribe(c, func(msg busPing) error {
+		<-release
+		return nil
+	})
+	Subscribe(c, func(msg busPing) error { return nil })
+	Subscribe(c, func(msg busPing) error { return nil })
+
+	// The worker is stuck in the first handler and the other two fill the
+	// ready queue.
+	assert.NoError(t, c.ACTIONAsync(context.Background(), busPing{N: 1}))
+
+	received := make(chan int, 1)
+	Subscribe(c, func(msg busPing) error {
+		received <- msg.N
+		return nil
+	})
+
+	// The message fits in the new handler's inbox, but ctx is done before
+	// the handler can be scheduled. It must still be delivered.
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.NoError(t, c.ACTIONAsync(ctx, busPing{N: 2}))
+
+	close(release)
+	select {
+	case n := <-received:
+		assert.Equal(t, 2, n)
+	case <-time.After(time.Second):
+		t.Fatal("expected the queued message to be delivered")
+	}
+	assert.NoError(t, c.ServiceShutdown(context.Background()))
+}
+
+func TestCore_ACTIONAsync_Bad(t *testing.T) {
+	errs := make(chan error, 1)
+	c, err := New(WithEventBus(EventBusOptions{OnError: func(err error) { errs <- err }}))
+	assert.NoError(t, err)
+
+	Subscribe(c, func(msg busPong) error {
+		return errors.New("async failure")
+	})
+	assert.NoError(t, c.ACTIONAsync(context.Background(), busPong{}))
+
+	select {
+	case err := <-errs:
+		assert.EqualError(t, err, "async failure")
+	case <-time.After(time.Second):
+		t.Fatal("expected OnError to be called")
+	}
+
+	assert.NoError(t, c.ServiceShutdown(context.Background()))
+	err = c.ACTIONAsync(context.Background(), busPong{})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "event bus stopped")
+}
+
+func TestCore_WithEventBus_Bad(t *testing.T) {
+	_, err := New(WithEventBus(EventBusOptions{Workers: -1}))
+	assert.Error(t, err)
+}
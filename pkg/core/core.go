@@ -5,10 +5,9 @@ import (
 	"embed"
 	"errors"
 	"fmt"
+	"log/slog"
 	"reflect"
 	"strings"
-
-	"github.com/wailsapp/wails/v3/pkg/application"
 )
 
 // New initialises a Core instance using the provided options and performs the necessary setup.
@@ -31,6 +30,10 @@ func New(opts ...Option) (*Core, error) {
 		}
 	}
 
+	if err := c.orderServices("core.New"); err != nil {
+		return nil, err
+	}
+
 	if c.serviceLock {
 		c.servicesLocked = true
 	}
@@ -38,8 +41,8 @@ func New(opts ...Option) (*Core, error) {
 }
 
 // WithService creates an Option that registers a service. It automatically discovers
-// the service name from its package path and registers its IPC handler if it
-// implements a method named `HandleIPCEvents`.
+// the service name from its package path and registers its IPC handlers if it
+// implements methods named `HandleIPCEvents`, `HandleQuery` or `HandleTask`.
 //
 // Example:
 //
@@ -72,18 +75,36 @@ func WithService(factory func(*Core) (any, error)) Option {
 		name := strings.ToLower(parts[len(parts)-1])
 
 		// --- IPC Handler Discovery ---
-		instanceValue := reflect.ValueOf(serviceInstance)
-		handlerMethod := instanceValue.MethodByName("HandleIPCEvents")
-		if handlerMethod.IsValid() {
-			if handler, ok := handlerMethod.Interface().(func(*Core, Message) error); ok {
-				c.RegisterAction(handler)
-			}
-		}
+		c.discoverHandlers(name, serviceInstance)
 
 		return c.RegisterService(name, serviceInstance)
 	}
 }
 
+// discoverHandlers registers the service's HandleIPCEvents, HandleQuery and
+// HandleTask methods, if it has them, as handlers owned by the named service.
+func (c *Core) discoverHandlers(name string, serviceInstance any) {
+	instanceValue := reflect.ValueOf(serviceInstance)
+	handlerMethod := instanceValue.MethodByName("HandleIPCEvents")
+	if handlerMethod.IsValid() {
+		if handler, ok := handlerMethod.Interface().(func(*Core, Message) error); ok {
+			c.registerAction(name, handler)
+		}
+	}
+	queryMethod := instanceValue.MethodByName("HandleQuery")
+	if queryMethod.IsValid() {
+		if handler, ok := queryMethod.Interface().(func(*Core, Query) (any, bool, error)); ok {
+			c.registerQuery(name, handler)
+		}
+	}
+	taskMethod := instanceValue.MethodByName("HandleTask")
+	if taskMethod.IsValid() {
+		if handler, ok := taskMethod.Interface().(func(*Core, Task, func(TaskProgress)) (any, bool, error)); ok {
+			c.registerTask(name, handler)
+		}
+	}
+}
+
 // WithName creates an option that registers a service with a specific name.
 // This is useful when the service name cannot be inferred from the package path,
 // such as when using anonymous functions as factories.
@@ -100,15 +121,6 @@ func WithName(name string, factory func(*Core) (any, error)) Option {
 	}
 }
 
-// WithWails creates an Option that injects the Wails application instance into the Core.
-// This is essential for services that need to interact with the Wails runtime.
-func WithWails(app *application.App) Option {
-	return func(c *Core) error {
-		c.App = app
-		return nil
-	}
-}
-
 // WithAssets creates an Option that registers the application's embedded assets.
 // This is necessary for the application to be able to serve its frontend.
 func WithAssets(fs embed.FS) Option {
@@ -128,23 +140,56 @@ func WithServiceLock() Option {
 	}
 }
 
+// WithContract creates an Option that sets the operational guarantees the Core
+// enforces. With DontPanic set, panics in IPC handlers and in OnStartup or
+// OnShutdown are recovered and returned as *Error values naming the service.
+// Panics in health checks are always recovered. A non-zero HandlerTimeout
+// bounds how long a single IPC handler may run.
+func WithContract(contract Contract) Option {
+	return func(c *Core) error {
+		c.contract = contract
+		return nil
+	}
+}
+
 // --- Core Methods ---
 
-// ServiceStartup is the entry point for the Core service's startup lifecycle.
-// It is called by Wails when the application starts.
-func (c *Core) ServiceStartup(ctx context.Context, options application.ServiceOptions) error {
+// Contract returns the operational guarantees the Core was configured with.
+func (c *Core) Contract() Contract {
+	return c.contract
+}
+
+// Start runs the Core's startup lifecycle: every Startable service's OnStartup
+// is called, then ActionServiceStartup is sent. Services are started in
+// dependency order, so a service's OnStartup runs after those it depends on.
+// Start does not need a Wails application and is the entry point for headless
+// programs such as CLIs, servers and tests.
+func (c *Core) Start(ctx context.Context) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if err := c.orderServices("core.Start"); err != nil {
+		return err
+	}
+
 	c.serviceMu.RLock()
-	startables := append([]Startable(nil), c.startables...)
+	order := append([]string(nil), c.serviceOrder...)
+	services := make([]any, len(order))
+	for i, name := range order {
+		services[i] = c.services[name]
+	}
 	c.serviceMu.RUnlock()
 
 	var agg error
-	for _, s := range startables {
-		if err := s.OnStartup(ctx); err != nil {
-			agg = errors.Join(agg, err)
+	for i, name := range order {
+		if s, ok := services[i].(Startable); ok {
+			if err := c.guard("core.Start", name, func() error { return s.OnStartup(ctx) }); err != nil {
+				agg = errors.Join(agg, err)
+			}
 		}
 	}
 
-	if err := c.ACTION(ActionServiceStartup{}); err != nil {
+	if err := c.ACTIONContext(ctx, ActionServiceStartup{}); err != nil {
 		agg = errors.Join(agg, err)
 	}
 
@@ -152,20 +197,41 @@ func (c *Core) ServiceStartup(ctx context.Context, options application.ServiceOp
 }
 
 // ServiceShutdown is the entry point for the Core service's shutdown lifecycle.
-// It is called by Wails when the application shuts down.
+// It is called by Wails when the application shuts down, and is equivalent
+// to Stop.
 func (c *Core) ServiceShutdown(ctx context.Context) error {
+	return c.Stop(ctx)
+}
+
+// Stop runs the Core's shutdown lifecycle: ActionServiceShutdown is sent,
+// queued asynchronous messages are delivered, and every Stoppable service's
+// OnShutdown is called in the reverse of the startup order.
+func (c *Core) Stop(ctx context.Context) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
 	var agg error
-	if err := c.ACTION(ActionServiceShutdown{}); err != nil {
+	if err := c.ACTIONContext(ctx, ActionServiceShutdown{}); err != nil {
 		agg = errors.Join(agg, err)
 	}
+	if err := c.stopEventBus(ctx); err != nil {
+		agg = errors.Join(agg, E("core.Stop", "undelivered asynchronous messages", err))
+	}
 
 	c.serviceMu.RLock()
-	stoppables := append([]Stoppable(nil), c.stoppables...)
+	order := append([]string(nil), c.serviceOrder...)
+	services := make([]any, len(order))
+	for i, name := range order {
+		services[i] = c.services[name]
+	}
 	c.serviceMu.RUnlock()
 
-	for i := len(stoppables) - 1; i >= 0; i-- {
-		if err := stoppables[i].OnShutdown(ctx); err != nil {
-			agg = errors.Join(agg, err)
+	for i := len(order) - 1; i >= 0; i-- {
+		if s, ok := services[i].(Stoppable); ok {
+			if err := c.guard("core.Stop", order[i], func() error { return s.OnShutdown(ctx) }); err != nil {
+				agg = errors.Join(agg, err)
+			}
 		}
 	}
 
@@ -175,33 +241,244 @@ func (c *Core) ServiceShutdown(ctx context.Context) error {
 // ACTION dispatches a message to all registered IPC handlers.
 // This is the primary mechanism for services to communicate with each other.
 func (c *Core) ACTION(msg Message) error {
+	return c.ACTIONContext(context.Background(), msg)
+}
+
+// ACTIONContext dispatches a message to all registered IPC handlers, giving
+// each handler its own deadline. The deadline is the earlier of ctx's deadline
+// and the Contract's HandlerTimeout. A handler that misses its deadline is
+// reported as an error and the dispatch moves on to the next handler; the
+// slow handler is left to finish in the background.
+func (c *Core) ACTIONContext(ctx context.Context, msg Message) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return c.intercept(Invocation{Ctx: ctx, Stage: StageDispatch, Message: msg}, func() error {
+		var agg error
+		for _, h := range c.handlersFor(msg) {
+			if err := c.runAction(ctx, h, msg, false); err != nil {
+				agg = errors.Join(agg, err)
+			}
+		}
+		return agg
+	})
+}
+
+// runAction invokes a single IPC handler through the registered
+// interceptors, applying the Contract's panic recovery and, when a deadline
+// applies, the per-handler timeout. async reports whether the message was
+// sent with ACTIONAsync.
+func (c *Core) runAction(ctx context.Context, h *actionHandler, msg Message, async bool) error {
+	inv := Invocation{Ctx: ctx, Stage: StageHandler, Message: msg, Service: h.service, Async: async}
+	return c.intercept(inv, func() error { return c.invokeAction(ctx, h, msg) })
+}
+
+// invokeAction calls the handler with panic recovery and the per-handler
+// timeout.
+func (c *Core) invokeAction(ctx context.Context, h *actionHandler, msg Message) error {
+	call := func() error {
+		return c.guard("core.ACTION", h.service, func() error { return h.fn(c, msg) })
+	}
+
+	if c.contract.HandlerTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.contract.HandlerTimeout)
+		defer cancel()
+	}
+	if ctx.Done() == nil {
+		return call()
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- call() }()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return E("core.ACTION", fmt.Sprintf("%s did not finish handling %T", describeService(h.service), msg), ctx.Err())
+	}
+}
+
+// RegisterAction adds a new IPC handler to the Core.
+func (c *Core) RegisterAction(handler func(*Core, Message) error) {
+	c.registerAction("", handler)
+}
+
+// RegisterActions adds multiple IPC handlers to the Core.
+func (c *Core) RegisterActions(handlers ...func(*Core, Message) error) {
+	for _, h := range handlers {
+		c.registerAction("", h)
+	}
+}
+
+// registerAction adds an IPC handler owned by the named service. The name is
+// used to identify the handler in errors; it may be empty.
+func (c *Core) registerAction(service string, handler func(*Core, Message) error) {
+	c.addActionHandler(nil, service, handler)
+}
+
+// QUERY asks the registered query handlers for data. Handlers are tried in
+// registration order and the result of the first one that handles the query
+// is returned. handled is false if no handler recognised the query.
+//
+// Example:
+//
+//	result, handled, err := c.QUERY(users.QueryByID{ID: 42})
+func (c *Core) QUERY(q Query) (any, bool, error) {
 	c.ipcMu.RLock()
-	handlers := append([]func(*Core, Message) error(nil), c.ipcHandlers...)
+	handlers := append([]queryHandler(nil), c.queryHandlers...)
 	c.ipcMu.RUnlock()
 
+	for _, h := range handlers {
+		result, handled, err := c.runQuery(h, q)
+		if handled {
+			return result, true, err
+		}
+	}
+	return nil, false, nil
+}
+
+// QUERYALL asks every registered query handler for data and collects the
+// results of all handlers that handled the query. Errors from individual
+// handlers are aggregated and do not stop the remaining handlers.
+func (c *Core) QUERYALL(q Query) ([]any, error) {
+	c.ipcMu.RLock()
+	handlers := append([]queryHandler(nil), c.queryHandlers...)
+	c.ipcMu.RUnlock()
+
+	var results []any
 	var agg error
 	for _, h := range handlers {
-		if err := h(c, msg); err != nil {
-			agg = fmt.Errorf("%w; %v", agg, err)
+		result, handled, err := c.runQuery(h, q)
+		if err != nil {
+			agg = errors.Join(agg, err)
+		}
+		if handled && err == nil {
+			results = append(results, result)
 		}
 	}
-	return agg
+	return results, agg
 }
 
-// RegisterAction adds a new IPC handler to the Core.
-func (c *Core) RegisterAction(handler func(*Core, Message) error) {
+// PERFORM asks the registered task handlers to perform a task. The first
+// handler that handles the task runs it and its result is returned. Progress
+// updates reported by the handler are sent to the progress channel, which
+// may be nil if the caller is not interested in them.
+//
+// Example:
+//
+//	progress := make(chan core.TaskProgress, 8)
+//	go func() {
+//		for p := range progress {
+//			fmt.Printf("%.0f%% %s\n", p.Percent, p.Message)
+//		}
+//	}()
+//	result, handled, err := c.PERFORM(backup.TaskExport{Path: dst}, progress)
+//	close(progress)
+func (c *Core) PERFORM(t Task, progress chan<- TaskProgress) (any, bool, error) {
+	c.ipcMu.RLock()
+	handlers := append([]taskHandler(nil), c.taskHandlers...)
+	c.ipcMu.RUnlock()
+
+	report := func(p TaskProgress) {
+		if progress == nil {
+			return
+		}
+		if p.Task == nil {
+			p.Task = t
+		}
+		progress <- p
+	}
+
+	for _, h := range handlers {
+		result, handled, err := c.runTask(h, t, report)
+		if handled {
+			return result, true, err
+		}
+	}
+	return nil, false, nil
+}
+
+// runQuery invokes a single query handler, applying the Contract's panic
+// recovery. A handler that panics is treated as having handled the query.
+func (c *Core) runQuery(h queryHandler, q Query) (result any, handled bool, err error) {
+	err = c.guard("core.QUERY", h.service, func() error {
+		var err error
+		result, handled, err = h.fn(c, q)
+		return err
+	})
+	return result, handled || isPanic(err), err
+}
+
+// runTask invokes a single task handler, applying the Contract's panic
+// recovery. A handler that panics is treated as having handled the task.
+func (c *Core) runTask(h taskHandler, t Task, progress func(TaskProgress)) (result any, handled bool, err error) {
+	err = c.guard("core.PERFORM", h.service, func() error {
+		var err error
+		result, handled, err = h.fn(c, t, progress)
+		return err
+	})
+	return result, handled || isPanic(err), err
+}
+
+// RegisterQuery adds a new query handler to the Core.
+func (c *Core) RegisterQuery(handler QueryHandler) {
+	c.registerQuery("", handler)
+}
+
+// RegisterTask adds a new task handler to the Core.
+func (c *Core) RegisterTask(handler TaskHandler) {
+	c.registerTask("", handler)
+}
+
+// registerQuery adds a query handler owned by the named service.
+func (c *Core) registerQuery(service string, handler QueryHandler) {
 	c.ipcMu.Lock()
-	c.ipcHandlers = append(c.ipcHandlers, handler)
+	c.queryHandlers = append(c.queryHandlers, queryHandler{service: service, fn: handler})
 	c.ipcMu.Unlock()
 }
 
-// RegisterActions adds multiple IPC handlers to the Core.
-func (c *Core) RegisterActions(handlers ...func(*Core, Message) error) {
+// registerTask adds a task handler owned by the named service.
+func (c *Core) registerTask(service string, handler TaskHandler) {
 	c.ipcMu.Lock()
-	c.ipcHandlers = append(c.ipcHandlers, handlers...)
+	c.taskHandlers = append(c.taskHandlers, taskHandler{service: service, fn: handler})
 	c.ipcMu.Unlock()
 }
 
+// guard runs fn on behalf of the named service. If the Contract sets
+// DontPanic, a panic in fn is recovered and returned as an *Error that names
+// the service and carries ErrPanic.
+func (c *Core) guard(op, service string, fn func() error) error {
+	if c.contract.DontPanic {
+		return recovered(op, service, fn)
+	}
+	return fn()
+}
+
+// recovered runs fn on behalf of the named service, returning a panic in fn
+// as an *Error that names the service and carries ErrPanic.
+func recovered(op, service string, fn func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = E(op, fmt.Sprintf("%s panicked: %v", describeService(service), r), ErrPanic)
+		}
+	}()
+	return fn()
+}
+
+// isPanic reports whether err was produced by guard recovering a panic.
+func isPanic(err error) bool {
+	return errors.Is(err, ErrPanic)
+}
+
+// describeService returns a short description of a service for error messages.
+func describeService(name string) string {
+	if name == "" {
+		return "unnamed handler"
+	}
+	return fmt.Sprintf("service %q", name)
+}
+
 // RegisterService adds a new service to the Core.
 func (c *Core) RegisterService(name string, api any) error {
 	if c.servicesLocked {
@@ -216,14 +493,85 @@ func (c *Core) RegisterService(name string, api any) error {
 		return fmt.Errorf("core: service %q already registered", name)
 	}
 	c.services[name] = api
+	c.serviceOrder = append(c.serviceOrder, name)
+	if named, ok := api.(interface{ setServiceName(string) }); ok {
+		named.setServiceName(name)
+	}
+
+	return nil
+}
+
+// orderServices sorts the registered services topologically by their declared
+// dependencies, including optional dependencies that are registered. Startup
+// follows the sorted order and shutdown reverses it.
+// Services without dependencies keep their registration order. It returns an
+// error, reported under op, if a service depends on a service that is not
+// registered, or if the dependencies form a cycle.
+func (c *Core) orderServices(op string) error {
+	c.serviceMu.Lock()
+	defer c.serviceMu.Unlock()
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[string]int, len(c.serviceOrder))
+	sorted := make([]string, 0, len(c.serviceOrder))
+	var path []string
+
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case visited:
+			return nil
+		case visiting:
+			cycle := append([]string(nil), path...)
+			for i, n := range cycle {
+				if n == name {
+					cycle = cycle[i:]
+					break
+				}
+			}
+			cycle = append(cycle, name)
+			return E(op, fmt.Sprintf("dependency cycle detected: %s", strings.Join(cycle, " -> ")), nil)
+		}
 
-	if s, ok := api.(Startable); ok {
-		c.startables = append(c.startables, s)
+		state[name] = visiting
+		path = append(path, name)
+		if d, ok := c.services[name].(Dependent); ok {
+			for _, dep := range d.Dependencies() {
+				if _, exists := c.services[dep]; !exists {
+					return E(op, fmt.Sprintf("service %q depends on %q, which is not registered", name, dep), nil)
+				}
+				if err := visit(dep); err != nil {
+					return err
+				}
+			}
+		}
+		if d, ok := c.services[name].(OptionalDependent); ok {
+			for _, dep := range d.OptionalDependencies() {
+				if _, exists := c.services[dep]; !exists {
+					continue
+				}
+				if err := visit(dep); err != nil {
+					return err
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		state[name] = visited
+		sorted = append(sorted, name)
+		return nil
 	}
-	if s, ok := api.(Stoppable); ok {
-		c.stoppables = append(c.stoppables, s)
+
+	for _, name := range c.serviceOrder {
+		if err := visit(name); err != nil {
+			return err
+		}
 	}
 
+	c.serviceOrder = sorted
 	return nil
 }
 
@@ -265,7 +613,7 @@ func MustServiceFor[T any](c *Core, name string) T {
 
 // App returns the global application instance.
 // It panics if the Core has not been initialized.
-func App() *application.App {
+func App() *WailsApp {
 	if instance == nil {
 		panic("core.App() called before core.Setup() was successfully initialized")
 	}
@@ -284,6 +632,27 @@ func (c *Core) Display() Display {
 	return d
 }
 
+// Logger returns a structured logger for the named service. Records go to
+// the registered log service if there is one, and otherwise to the Wails
+// application's logger or slog.Default(). If Contract.DisableLogging is set,
+// the returned logger discards everything.
+func (c *Core) Logger(service string) *slog.Logger {
+	if c.contract.DisableLogging {
+		return slog.New(slog.DiscardHandler)
+	}
+	if l, ok := c.Service("log").(Log); ok {
+		return l.Logger(service)
+	}
+	logger := slog.Default()
+	if c.App != nil && c.App.Logger != nil {
+		logger = c.App.Logger
+	}
+	if service != "" {
+		logger = logger.With("service", service)
+	}
+	return logger
+}
+
 func (c *Core) Core() *Core { return c }
 
 // Assets returns the embedded filesystem containing the application's assets.
@@ -0,0 +1,131 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type MockPanickingService struct {
+	MockService
+}
+
+func (m *MockPanickingService) HandleIPCEvents(c *Core, msg Message) error {
+	panic("handler exploded")
+}
+
+func (m *MockPanickingService) OnStartup(ctx context.Context) error {
+	panic("startup exploded")
+}
+
+func (m *MockPanickingService) OnShutdown(ctx context.Context) error {
+	panic("shutdown exploded")
+}
+
+func TestCore_WithContract_Good(t *testing.T) {
+	contract := Contract{DontPanic: true, HandlerTimeout: time.Second}
+	c, err := New(WithContract(contract))
+	assert.NoError(t, err)
+	assert.Equal(t, contract, c.Contract())
+}
+
+func TestCore_DontPanic_Good(t *testing.T) {
+	c, err := New(
+		WithContract(Contract{DontPanic: true}),
+		WithService(func(c *Core) (any, error) { return &MockPanickingService{}, nil }),
+	)
+	assert.NoError(t, err)
+
+	action := &MockAction{}
+	c.RegisterAction(action.Handle)
+
+	err = c.ACTION(nil)
+	assert.Error(t, err)
+	assert.ErrorIs(t, err, ErrPanic)
+	assert.Contains(t, err.Error(), `service "core" panicked: handler exploded`)
+	assert.True(t, action.handled, "handlers after the panicking one should still run")
+
+	var coreErr *Error
+	assert.True(t, errors.As(err, &coreErr))
+	assert.Equal(t, "core.ACTION", coreErr.Op)
+}
+
+func TestCore_DontPanic_Lifecycle(t *testing.T) {
+	c, err := New(WithContract(Contract{DontPanic: true}))
+	assert.NoError(t, err)
+	assert.NoError(t, c.RegisterService("exploding", &MockPanickingService{}))
+
+	err = c.Start(context.Background())
+	assert.ErrorIs(t, err, ErrPanic)
+	assert.Contains(t, err.Error(), `core.Start: service "exploding" panicked: startup exploded`)
+
+	err = c.ServiceShutdown(context.Background())
+	assert.ErrorIs(t, err, ErrPanic)
+	assert.Contains(t, err.Error(), `core.Stop: service "exploding" panicked: shutdown exploded`)
+}
+
+func TestCore_DontPanic_QueryAndTask(t *testing.T) {
+	c, err := New(WithContract(Contract{DontPanic: true}))
+	assert.NoError(t, err)
+
+	c.RegisterQuery(func(c *Core, q Query) (any, bool, error) { panic("query exploded") })
+	c.RegisterTask(func(c *Core, t Task, progress func(TaskProgress)) (any, bool, error) { panic("task exploded") })
+
+	_, handled, err := c.QUERY(queryName{})
+	assert.True(t, handled)
+	assert.ErrorIs(t, err, ErrPanic)
+
+	_, handled, err = c.PERFORM(taskDouble{}, nil)
+	assert.True(t, handled)
+	assert.ErrorIs(t, err, ErrPanic)
+	assert.Contains(t, err.Error(), "unnamed handler panicked: task exploded")
+}
+
+func TestCore_DontPanic_Ugly(t *testing.T) {
+	// Without the contract, panics propagate as before.
+	c, err := New()
+	assert.NoError(t, err)
+	c.RegisterAction(func(c *Core, msg Message) error { panic("boom") })
+	assert.Panics(t, func() { _ = c.ACTION(nil) })
+}
+
+func TestCore_ACTIONContext_Timeout(t *testing.T) {
+	c, err := New(WithContract(Contract{HandlerTimeout: 20 * time.Millisecond}))
+	assert.NoError(t, err)
+
+	release := make(chan struct{})
+	defer close(release)
+	c.RegisterAction(func(c *Core, msg Message) error {
+		<-release
+		return nil
+	})
+	action := &MockAction{}
+	c.RegisterAction(action.Handle)
+
+	start := time.Now()
+	err = c.ACTIONContext(context.Background(), nil)
+	assert.Less(t, time.Since(start), time.Second)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Contains(t, err.Error(), "unnamed handler did not finish handling <nil>")
+	assert.True(t, action.handled)
+}
+
+func TestCore_ACTIONContext_Cancelled(t *testing.T) {
+	c, err := New()
+	assert.NoError(t, err)
+
+	release := make(chan struct{})
+	defer close(release)
+	c.RegisterAction(func(c *Core, msg Message) error {
+		<-release
+		return nil
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err = c.ACTIONContext(ctx, startupMessage{})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
@@ -0,0 +1,170 @@
+package core
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type queryName struct{}
+type queryOther struct{}
+type taskDouble struct {
+	N int
+}
+
+type MockServiceWithQuery struct {
+	MockService
+}
+
+func (m *MockServiceWithQuery) HandleQuery(c *Core, q Query) (any, bool, error) {
+	if _, ok := q.(queryName); ok {
+		return m.Name, true, nil
+	}
+	return nil, false, nil
+}
+
+func (m *MockServiceWithQuery) HandleTask(c *Core, t Task, progress func(TaskProgress)) (any, bool, error) {
+	if task, ok := t.(taskDouble); ok {
+		progress(TaskProgress{Percent: 50, Message: "halfway"})
+		progress(TaskProgress{Percent: 100})
+		return task.N * 2, true, nil
+	}
+	return nil, false, nil
+}
+
+func TestCore_QUERY_Good(t *testing.T) {
+	c, err := New()
+	assert.NoError(t, err)
+
+	c.RegisterQuery(func(c *Core, q Query) (any, bool, error) {
+		return nil, false, nil
+	})
+	c.RegisterQuery(func(c *Core, q Query) (any, bool, error) {
+		if _, ok := q.(queryName); ok {
+			return "first", true, nil
+		}
+		return nil, false, nil
+	})
+	c.RegisterQuery(func(c *Core, q Query) (any, bool, error) {
+		return "second", true, nil
+	})
+
+	result, handled, err := c.QUERY(queryName{})
+	assert.NoError(t, err)
+	assert.True(t, handled)
+	assert.Equal(t, "first", result)
+}
+
+func TestCore_QUERY_Bad(t *testing.T) {
+	c, err := New()
+	assert.NoError(t, err)
+
+	result, handled, err := c.QUERY(queryName{})
+	assert.NoError(t, err)
+	assert.False(t, handled)
+	assert.Nil(t, result)
+
+	c.RegisterQuery(func(c *Core, q Query) (any, bool, error) {
+		return nil, true, assert.AnError
+	})
+	_, handled, err = c.QUERY(queryName{})
+	assert.True(t, handled)
+	assert.ErrorIs(t, err, assert.AnError)
+}
+
+func TestCore_QUERYALL_Good(t *testing.T) {
+	c, err := New()
+	assert.NoError(t, err)
+
+	c.RegisterQuery(func(c *Core, q Query) (any, bool, error) {
+		return "a", true, nil
+	})
+	c.RegisterQuery(func(c *Core, q Query) (any, bool, error) {
+		return nil, false, nil
+	})
+	c.RegisterQuery(func(c *Core, q Query) (any, bool, error) {
+		return "b", true, nil
+	})
+
+	results, err := c.QUERYALL(queryOther{})
+	assert.NoError(t, err)
+	assert.Equal(t, []any{"a", "b"}, results)
+}
+
+func TestCore_QUERYALL_Bad(t *testing.T) {
+	c, err := New()
+	assert.NoError(t, err)
+
+	c.RegisterQuery(func(c *Core, q Query) (any, bool, error) {
+		return nil, true, errors.New("query failed")
+	})
+	c.RegisterQuery(func(c *Core, q Query) (any, bool, error) {
+		return "ok", true, nil
+	})
+
+	results, err := c.QUERYALL(queryOther{})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "query failed")
+	assert.Equal(t, []any{"ok"}, results)
+}
+
+func TestCore_PERFORM_Good(t *testing.T) {
+	c, err := New()
+	assert.NoError(t, err)
+
+	c.RegisterTask(func(c *Core, t Task, progress func(TaskProgress)) (any, bool, error) {
+		return nil, false, nil
+	})
+	c.RegisterTask(func(c *Core, t Task, progress func(TaskProgress)) (any, bool, error) {
+		task := t.(taskDouble)
+		progress(TaskProgress{Percent: 100, Message: "done"})
+		return task.N * 2, true, nil
+	})
+
+	progress := make(chan TaskProgress, 1)
+	result, handled, err := c.PERFORM(taskDouble{N: 21}, progress)
+	assert.NoError(t, err)
+	assert.True(t, handled)
+	assert.Equal(t, 42, result)
+
+	update := <-progress
+	assert.Equal(t, 100.0, update.Percent)
+	assert.Equal(t, "done", update.Message)
+	assert.Equal(t, taskDouble{N: 21}, update.Task)
+}
+
+func TestCore_PERFORM_Bad(t *testing.T) {
+	c, err := New()
+	assert.NoError(t, err)
+
+	_, handled, err := c.PERFORM(taskDouble{N: 1}, nil)
+	assert.NoError(t, err)
+	assert.False(t, handled)
+
+	c.RegisterTask(func(c *Core, t Task, progress func(TaskProgress)) (any, bool, error) {
+		progress(TaskProgress{Percent: 10})
+		return nil, true, assert.AnError
+	})
+	_, handled, err = c.PERFORM(taskDouble{N: 1}, nil)
+	assert.True(t, handled)
+	assert.ErrorIs(t, err, assert.AnError)
+}
+
+func TestCore_WithService_QueryAndTask(t *testing.T) {
+	svc := &MockServiceWithQuery{MockService: MockService{Name: "query-service"}}
+	c, err := New(WithService(func(c *Core) (any, error) { return svc, nil }))
+	assert.NoError(t, err)
+
+	result, handled, err := c.QUERY(queryName{})
+	assert.NoError(t, err)
+	assert.True(t, handled)
+	assert.Equal(t, "query-service", result)
+
+	progress := make(chan TaskProgress, 2)
+	result, handled, err = c.PERFORM(taskDouble{N: 4}, progress)
+	assert.NoError(t, err)
+	assert.True(t, handled)
+	assert.Equal(t, 8, result)
+	assert.Len(t, progress, 2)
+}
@@ -6,7 +6,6 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
-	"github.com/wailsapp/wails/v3/pkg/application"
 )
 
 type MockStartable struct {
@@ -51,7 +50,7 @@ func TestCore_LifecycleInterfaces(t *testing.T) {
 	assert.NoError(t, err)
 
 	// Startup
-	err = c.ServiceStartup(context.Background(), application.ServiceOptions{})
+	err = c.Start(context.Background())
 	assert.NoError(t, err)
 	assert.True(t, startable.started)
 	assert.True(t, lifecycle.started)
@@ -94,7 +93,7 @@ func TestCore_LifecycleOrder(t *testing.T) {
 	assert.NoError(t, err)
 
 	// Startup
-	err = c.ServiceStartup(context.Background(), application.ServiceOptions{})
+	err = c.Start(context.Background())
 	assert.NoError(t, err)
 	assert.Equal(t, []string{"start-1", "start-2"}, callOrder)
 
@@ -117,7 +116,7 @@ func TestCore_LifecycleErrors(t *testing.T) {
 	c.RegisterService("s1", s1)
 	c.RegisterService("s2", s2)
 
-	err = c.ServiceStartup(context.Background(), application.ServiceOptions{})
+	err = c.Start(context.Background())
 	assert.Error(t, err)
 	assert.ErrorIs(t, err, assert.AnError)
 
@@ -151,7 +150,7 @@ func TestCore_LifecycleErrors_Aggregated(t *testing.T) {
 	assert.NoError(t, err)
 
 	// Startup
-	err = c.ServiceStartup(context.Background(), application.ServiceOptions{})
+	err = c.Start(context.Background())
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "startup action error")
 	assert.Contains(t, err.Error(), "startup service error")
@@ -162,3 +161,121 @@ func TestCore_LifecycleErrors_Aggregated(t *testing.T) {
 	assert.Contains(t, err.Error(), "shutdown action error")
 	assert.Contains(t, err.Error(), "shutdown service error")
 }
+
+type MockDependentLifecycle struct {
+	MockLifecycleWithLog
+	deps []string
+}
+
+func (m *MockDependentLifecycle) Dependencies() []string {
+	return m.deps
+}
+
+func newDependentService(id string, log *[]string, deps ...string) *MockDependentLifecycle {
+	return &MockDependentLifecycle{
+		MockLifecycleWithLog: MockLifecycleWithLog{id: id, log: log},
+		deps:                 deps,
+	}
+}
+
+func TestCore_LifecycleDependencies_Good(t *testing.T) {
+	var callOrder []string
+
+	// Registered in the "wrong" order: workspace before config.
+	workspace := newDependentService("workspace", &callOrder, "config")
+	display := newDependentService("display", &callOrder, "workspace", "config")
+	config := newDependentService("config", &callOrder)
+
+	c, err := New(
+		WithName("display", func(c *Core) (any, error) { return display, nil }),
+		WithName("workspace", func(c *Core) (any, error) { return workspace, nil }),
+		WithName("config", func(c *Core) (any, error) { return config, nil }),
+	)
+	assert.NoError(t, err)
+
+	err = c.Start(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"start-config", "start-workspace", "start-display"}, callOrder)
+
+	callOrder = nil
+	err = c.ServiceShutdown(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"stop-display", "stop-workspace", "stop-config"}, callOrder)
+}
+
+func TestCore_LifecycleDependencies_Bad(t *testing.T) {
+	var callOrder []string
+	workspace := newDependentService("workspace", &callOrder, "config")
+
+	_, err := New(WithName("workspace", func(c *Core) (any, error) { return workspace, nil }))
+	assert.Error(t, err)
+
+	var coreErr *Error
+	assert.ErrorAs(t, err, &coreErr)
+	assert.Equal(t, "core.New", coreErr.Op)
+	assert.Contains(t, err.Error(), `service "workspace" depends on "config", which is not registered`)
+}
+
+func TestCore_LifecycleDependencies_Ugly(t *testing.T) {
+	var callOrder []string
+	a := newDependentService("a", &callOrder, "b")
+	b := newDependentService("b", &callOrder, "c")
+	cc := newDependentService("c", &callOrder, "a")
+
+	_, err := New(
+		WithName("a", func(c *Core) (any, error) { return a, nil }),
+		WithName("b", func(c *Core) (any, error) { return b, nil }),
+		WithName("c", func(c *Core) (any, error) { return cc, nil }),
+	)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "dependency cycle detected: a -> b -> c -> a")
+
+	// Services registered after New are checked when startup begins.
+	c, err := New()
+	assert.NoError(t, err)
+	assert.NoError(t, c.RegisterService("self", newDependentService("self", &callOrder, "self")))
+	err = c.Start(context.Background())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "dependency cycle detected: self -> self")
+	assert.Empty(t, callOrder)
+}
+
+type MockOptionalDependentLifecycle struct {
+	MockLifecycleWithLog
+	optional []string
+}
+
+func (m *MockOptionalDependentLifecycle) OptionalDependencies() []string {
+	return m.optional
+}
+
+func TestCore_LifecycleOptionalDependencies_Good(t *testing.T) {
+	var callOrder []string
+	ui := &MockOptionalDependentLifecycle{MockLifecycleWithLog: MockLifecycleWithLog{id: "ui", log: &callOrder}, optional: []string{"display"}}
+	display := &MockLifecycleWithLog{id: "display", log: &callOrder}
+
+	c, err := New(
+		WithName("ui", func(c *Core) (any, error) { return ui, nil }),
+		WithName("display", func(c *Core) (any, error) { return display, nil }),
+	)
+	assert.NoError(t, err)
+	assert.NoError(t, c.Start(context.Background()))
+	assert.Equal(t, []string{"start-display", "start-ui"}, callOrder)
+}
+
+func TestCore_LifecycleOptionalDependencies_Headless(t *testing.T) {
+	var callOrder []string
+	ui := &MockOptionalDependentLifecycle{MockLifecycleWithLog: MockLifecycleWithLog{id: "ui", log: &callOrder}, optional: []string{"display"}}
+
+	// Without a display registered, the optional dependency is ignored.
+	c, err := New(WithName("ui", func(c *Core) (any, error) { return ui, nil }))
+	assert.NoError(t, err)
+	assert.Nil(t, c.App)
+
+	assert.NoError(t, c.Start(context.Background()))
+	assert.NoError(t, c.Stop(context.Background()))
+	assert.Equal(t, []string{"start-ui", "stop-ui"}, callOrder)
+
+	_, err = ServiceFor[Display](c, "display")
+	assert.Error(t, err)
+}
@@ -0,0 +1,80 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// recordingLog is a Log implementation that writes JSON records to a buffer.
+type recordingLog struct {
+	buf bytes.Buffer
+}
+
+func (l *recordingLog) Logger(service string) *slog.Logger {
+	return slog.New(slog.NewJSONHandler(&l.buf, nil)).With("service", service)
+}
+
+type loggingService struct {
+	*ServiceRuntime[struct{}]
+}
+
+func TestCore_Logger_Good(t *testing.T) {
+	c, err := New()
+	assert.NoError(t, err)
+
+	rec := &recordingLog{}
+	assert.NoError(t, c.RegisterService("log", rec))
+
+	c.Logger("workspace").Info("hello")
+	assert.Contains(t, rec.buf.String(), `"service":"workspace"`)
+	assert.Contains(t, rec.buf.String(), `"msg":"hello"`)
+}
+
+func TestCore_Logger_DisableLogging(t *testing.T) {
+	c, err := New(WithContract(Contract{DisableLogging: true}))
+	assert.NoError(t, err)
+
+	rec := &recordingLog{}
+	assert.NoError(t, c.RegisterService("log", rec))
+
+	logger := c.Logger("workspace")
+	assert.False(t, logger.Enabled(context.Background(), slog.LevelError))
+	logger.Error("dropped")
+	assert.Empty(t, rec.buf.String())
+}
+
+func TestCore_Logger_Fallback(t *testing.T) {
+	c, err := New()
+	assert.NoError(t, err)
+
+	// Without a log service, a usable logger is still returned.
+	assert.NotNil(t, c.Logger("workspace"))
+	assert.NotNil(t, c.Logger(""))
+}
+
+func TestServiceRuntime_Logger_Good(t *testing.T) {
+	rec := &recordingLog{}
+	c, err := New(
+		WithName("log", func(c *Core) (any, error) { return rec, nil }),
+		WithName("ledger", func(c *Core) (any, error) {
+			return &loggingService{ServiceRuntime: NewServiceRuntime(c, struct{}{})}, nil
+		}),
+	)
+	assert.NoError(t, err)
+
+	svc := MustServiceFor[*loggingService](c, "ledger")
+	svc.Logger().Info("synced")
+	assert.Contains(t, rec.buf.String(), `"service":"ledger"`)
+}
+
+func TestServiceRuntime_Logger_Detached(t *testing.T) {
+	var r *ServiceRuntime[struct{}]
+	assert.Equal(t, slog.Default(), r.Logger())
+
+	svc := &loggingService{}
+	assert.NotPanics(t, func() { svc.Logger().Info("no runtime") })
+}
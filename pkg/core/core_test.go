@@ -6,7 +6,6 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
-	"github.com/wailsapp/wails/v3/pkg/application"
 )
 
 func TestCore_New_Good(t *testing.T) {
@@ -81,19 +80,6 @@ func TestCore_Services_Ugly(t *testing.T) {
 	})
 }
 
-func TestCore_App_Good(t *testing.T) {
-	app := &application.App{}
-	c, err := New(WithWails(app))
-	assert.NoError(t, err)
-
-	// To test the global App() function, we need to set the global instance.
-	originalInstance := instance
-	instance = c
-	defer func() { instance = originalInstance }()
-
-	assert.Equal(t, app, App())
-}
-
 func TestCore_App_Ugly(t *testing.T) {
 	// This test ensures that calling App() before the core is initialized panics.
 	originalInstance := instance
@@ -124,35 +110,6 @@ func TestFeatures_IsEnabled_Good(t *testing.T) {
 type startupMessage struct{}
 type shutdownMessage struct{}
 
-func TestCore_ServiceLifecycle_Good(t *testing.T) {
-	c, err := New()
-	assert.NoError(t, err)
-
-	var messageReceived Message
-	handler := func(c *Core, msg Message) error {
-		messageReceived = msg
-		return nil
-	}
-	c.RegisterAction(handler)
-
-	// Test Startup
-	_ = c.ServiceStartup(nil, application.ServiceOptions{})
-	_, ok := messageReceived.(ActionServiceStartup)
-	assert.True(t, ok, "expected ActionServiceStartup message")
-
-	// Test Shutdown
-	_ = c.ServiceShutdown(nil)
-	_, ok = messageReceived.(ActionServiceShutdown)
-	assert.True(t, ok, "expected ActionServiceShutdown message")
-}
-
-func TestCore_WithWails_Good(t *testing.T) {
-	app := &application.App{}
-	c, err := New(WithWails(app))
-	assert.NoError(t, err)
-	assert.Equal(t, app, c.App)
-}
-
 //go:embed testdata
 var testFS embed.FS
 
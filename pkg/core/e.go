@@ -13,10 +13,41 @@
 //     that is more informative than a raw stack trace.
 //   - Consistent error handling: Encourages a uniform approach to error
 //     handling across the entire codebase.
+//   - Machine-readable classification: A Kind and an optional Code let callers,
+//     including the frontend, branch on what went wrong without matching on
+//     message strings.
 package core
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io/fs"
+	"runtime"
+)
+
+// ErrPanic is wrapped by errors that Core produces when it recovers a panic
+// from a service, as requested by Contract.DontPanic, or from a health check.
+var ErrPanic = errors.New("panic recovered")
+
+// Kind classifies an error by what went wrong, independently of where it
+// happened. The zero value means the kind is unknown.
+type Kind string
+
+const (
+	// KindNotFound means the requested item does not exist.
+	KindNotFound Kind = "not_found"
+	// KindPermission means the caller is not allowed to perform the operation.
+	KindPermission Kind = "permission"
+	// KindConflict means the operation clashes with existing state, such as
+	// creating an item that already exists.
+	KindConflict Kind = "conflict"
+	// KindInvalid means the input was malformed or out of range.
+	KindInvalid Kind = "invalid"
+	// KindUnavailable means a dependency could not be reached or did not
+	// respond in time; retrying later may succeed.
+	KindUnavailable Kind = "unavailable"
 )
 
 // Error represents a standardized error with operational context.
@@ -27,6 +58,51 @@ type Error struct {
 	Msg string
 	// Err is the underlying error that was wrapped.
 	Err error
+	// Kind classifies the error. If empty, KindOf falls back to the kind of
+	// the wrapped error.
+	Kind Kind
+	// Code is a stable, machine-readable identifier such as
+	// "config.key_not_found".
+	Code string
+	// Meta holds additional structured context about the error.
+	Meta map[string]any
+	// Stack is the call stack at the point the error was created, if it was
+	// captured with WithStack.
+	Stack []string
+}
+
+// ErrorOption configures an Error created by E.
+type ErrorOption func(*Error)
+
+// WithKind sets the Kind of the error.
+func WithKind(kind Kind) ErrorOption {
+	return func(e *Error) {
+		e.Kind = kind
+	}
+}
+
+// WithCode sets the machine-readable Code of the error.
+func WithCode(code string) ErrorOption {
+	return func(e *Error) {
+		e.Code = code
+	}
+}
+
+// WithMeta attaches a key/value pair to the error's metadata.
+func WithMeta(key string, value any) ErrorOption {
+	return func(e *Error) {
+		if e.Meta == nil {
+			e.Meta = make(map[string]any)
+		}
+		e.Meta[key] = value
+	}
+}
+
+// WithStack captures the call stack of the caller of E.
+func WithStack() ErrorOption {
+	return func(e *Error) {
+		e.Stack = callers(4)
+	}
 }
 
 // E is a helper function to create a new Error.
@@ -38,11 +114,19 @@ type Error struct {
 // The 'op' parameter should be in the format of 'package.function' or 'service.method'.
 // The 'msg' parameter should be a human-readable message that can be displayed to the user.
 // The 'err' parameter is the underlying error that is being wrapped.
-func E(op, msg string, err error) error {
-	if err == nil {
-		return &Error{Op: op, Msg: msg}
+//
+// Options classify the error and attach context:
+//
+//	return core.E("config.Get", "key not found", nil,
+//		core.WithKind(core.KindNotFound),
+//		core.WithCode("config.key_not_found"),
+//		core.WithMeta("key", key))
+func E(op, msg string, err error, opts ...ErrorOption) error {
+	e := &Error{Op: op, Msg: msg, Err: err}
+	for _, opt := range opts {
+		opt(e)
 	}
-	return &Error{Op: op, Msg: msg, Err: err}
+	return e
 }
 
 // Error returns the string representation of the error.
@@ -56,4 +140,161 @@ func (e *Error) Error() string {
 // Unwrap provides compatibility for Go's errors.Is and errors.As functions.
 func (e *Error) Unwrap() error {
 	return e.Err
-}
\ No newline at end of file
+}
+
+// Is reports whether err, or any error it wraps, is of the given kind.
+//
+// Example:
+//
+//	if core.Is(err, core.KindNotFound) {
+//		// fall back to defaults
+//	}
+func Is(err error, kind Kind) bool {
+	return kind != "" && KindOf(err) == kind
+}
+
+// KindOf returns the kind of err. It uses the first Kind set on an *Error in
+// the chain and otherwise recognises the standard library's fs and context
+// errors. It returns the empty Kind if the error is unclassified.
+func KindOf(err error) Kind {
+	if err == nil {
+		return ""
+	}
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		if ce, ok := e.(*Error); ok && ce.Kind != "" {
+			return ce.Kind
+		}
+		if ke, ok := e.(*kindError); ok {
+			return ke.kind
+		}
+	}
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return KindNotFound
+	case errors.Is(err, fs.ErrPermission):
+		return KindPermission
+	case errors.Is(err, fs.ErrExist):
+		return KindConflict
+	case errors.Is(err, fs.ErrInvalid):
+		return KindInvalid
+	case errors.Is(err, context.DeadlineExceeded):
+		return KindUnavailable
+	}
+	return ""
+}
+
+// CodeOf returns the first Code set on an *Error in err's chain, or an empty
+// string if there is none.
+func CodeOf(err error) string {
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		if ce, ok := e.(*Error); ok && ce.Code != "" {
+			return ce.Code
+		}
+	}
+	return ""
+}
+
+// errorJSON is the wire format of an Error. Field names are part of the
+// frontend contract and must not change.
+type errorJSON struct {
+	Op      string          `json:"op,omitempty"`
+	Message string          `json:"message"`
+	Error   string          `json:"error"`
+	Kind    Kind            `json:"kind,omitempty"`
+	Code    string          `json:"code,omitempty"`
+	Meta    map[string]any  `json:"meta,omitempty"`
+	Stack   []string        `json:"stack,omitempty"`
+	Cause   json.RawMessage `json:"cause,omitempty"`
+}
+
+// MarshalJSON encodes the error, its classification and its chain of causes.
+// Wails uses it when a bound method returns an *Error, so the frontend
+// receives the kind and code alongside the message. The kind is resolved
+// with KindOf, so wrapped fs and context errors are classified too.
+func (e *Error) MarshalJSON() ([]byte, error) {
+	out := errorJSON{
+		Op:      e.Op,
+		Message: e.Msg,
+		Error:   e.Error(),
+		Kind:    KindOf(e),
+		Code:    e.Code,
+		Meta:    e.Meta,
+		Stack:   e.Stack,
+	}
+	if e.Err != nil {
+		var cause []byte
+		var err error
+		if ce, ok := e.Err.(*Error); ok {
+			cause, err = ce.MarshalJSON()
+		} else {
+			cause, err = json.Marshal(errorJSON{Message: e.Err.Error(), Error: e.Err.Error(), Kind: KindOf(e.Err)})
+		}
+		if err != nil {
+			return nil, err
+		}
+		out.Cause = cause
+	}
+	return json.Marshal(out)
+}
+
+// kindError is a cause restored by UnmarshalJSON that was not an *Error but
+// had a kind, such as a wrapped fs.ErrNotExist. It keeps the original message
+// and the kind, so KindOf gives the same answer after a round trip.
+type kindError struct {
+	msg  string
+	kind Kind
+}
+
+func (e *kindError) Error() string { return e.msg }
+
+// UnmarshalJSON decodes an error produced by MarshalJSON. Causes that were
+// *Error values are restored as such; other causes become plain errors with
+// the original message and kind.
+func (e *Error) UnmarshalJSON(data []byte) error {
+	var in errorJSON
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+	*e = Error{Op: in.Op, Msg: in.Message, Kind: in.Kind, Code: in.Code, Meta: in.Meta, Stack: in.Stack}
+	if len(in.Cause) == 0 {
+		return nil
+	}
+	var cause errorJSON
+	if err := json.Unmarshal(in.Cause, &cause); err != nil {
+		return err
+	}
+	// MarshalJSON writes other causes with their message as the error text,
+	// which an *Error never has.
+	plain := cause.Op == "" && cause.Code == "" && len(cause.Meta) == 0 && len(cause.Stack) == 0 &&
+		len(cause.Cause) == 0 && (cause.Error == "" || cause.Error == cause.Message)
+	if plain && cause.Kind == "" {
+		e.Err = errors.New(cause.Message)
+		return nil
+	}
+	if plain {
+		e.Err = &kindError{msg: cause.Message, kind: cause.Kind}
+		return nil
+	}
+	inner := &Error{}
+	if err := inner.UnmarshalJSON(in.Cause); err != nil {
+		return err
+	}
+	e.Err = inner
+	return nil
+}
+
+// callers formats the call stack, skipping the given number of frames.
+func callers(skip int) []string {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(skip, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	var stack []string
+	for {
+		frame, more := frames.Next()
+		stack = append(stack, fmt.Sprintf("%s\n\t%s:%d", frame.Function, frame.File, frame.Line))
+		if !more {
+			break
+		}
+	}
+	return stack
+}
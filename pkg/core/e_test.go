@@ -1,7 +1,11 @@
 package core
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"io/fs"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -27,3 +31,120 @@ func TestE_Unwrap(t *testing.T) {
 	assert.True(t, errors.As(err, &eErr))
 	assert.Equal(t, "test.op", eErr.Op)
 }
+
+func TestE_Options(t *testing.T) {
+	err := E("config.Get", "key not found", nil,
+		WithKind(KindNotFound),
+		WithCode("config.key_not_found"),
+		WithMeta("key", "language"))
+
+	var eErr *Error
+	assert.True(t, errors.As(err, &eErr))
+	assert.Equal(t, KindNotFound, eErr.Kind)
+	assert.Equal(t, "config.key_not_found", eErr.Code)
+	assert.Equal(t, map[string]any{"key": "language"}, eErr.Meta)
+	assert.Nil(t, eErr.Stack)
+	assert.Equal(t, "config.Get: key not found", err.Error())
+}
+
+func TestE_WithStack(t *testing.T) {
+	err := E("test.op", "test message", nil, WithStack())
+
+	var eErr *Error
+	assert.True(t, errors.As(err, &eErr))
+	assert.NotEmpty(t, eErr.Stack)
+	assert.Contains(t, eErr.Stack[0], "TestE_WithStack")
+}
+
+func TestIs_Good(t *testing.T) {
+	inner := E("io.Read", "missing file", nil, WithKind(KindNotFound))
+	err := E("workspace.Load", "failed to load workspace", inner)
+	assert.True(t, Is(err, KindNotFound))
+	assert.False(t, Is(err, KindPermission))
+
+	// The outermost kind wins.
+	err = E("workspace.Load", "access denied", inner, WithKind(KindPermission))
+	assert.Equal(t, KindPermission, KindOf(err))
+
+	// Standard library errors are classified.
+	assert.True(t, Is(E("test.op", "open", fs.ErrNotExist), KindNotFound))
+	assert.True(t, Is(fmt.Errorf("wrapped: %w", fs.ErrPermission), KindPermission))
+	assert.True(t, Is(fs.ErrExist, KindConflict))
+	assert.True(t, Is(context.DeadlineExceeded, KindUnavailable))
+}
+
+func TestIs_Bad(t *testing.T) {
+	assert.False(t, Is(nil, KindNotFound))
+	assert.False(t, Is(errors.New("plain"), KindNotFound))
+	assert.False(t, Is(E("test.op", "no kind", nil), ""))
+	assert.Equal(t, Kind(""), KindOf(errors.New("plain")))
+}
+
+func TestCodeOf_Good(t *testing.T) {
+	inner := E("config.Get", "key not found", nil, WithCode("config.key_not_found"))
+	err := E("workspace.Register", "failed to read config", inner)
+	assert.Equal(t, "config.key_not_found", CodeOf(err))
+	assert.Equal(t, "", CodeOf(errors.New("plain")))
+}
+
+func TestError_MarshalJSON_Good(t *testing.T) {
+	inner := E("io.Read", "missing file", fs.ErrNotExist)
+	err := E("workspace.Load", "failed to load workspace", inner,
+		WithCode("workspace.load_failed"),
+		WithMeta("name", "default"))
+
+	data, jsonErr := json.Marshal(err)
+	assert.NoError(t, jsonErr)
+
+	var out map[string]any
+	assert.NoError(t, json.Unmarshal(data, &out))
+	assert.Equal(t, "workspace.Load", out["op"])
+	assert.Equal(t, "failed to load workspace", out["message"])
+	assert.Equal(t, err.Error(), out["error"])
+	assert.Equal(t, "not_found", out["kind"])
+	assert.Equal(t, "workspace.load_failed", out["code"])
+	assert.Equal(t, map[string]any{"name": "default"}, out["meta"])
+
+	cause := out["cause"].(map[string]any)
+	assert.Equal(t, "io.Read", cause["op"])
+	assert.Equal(t, "not_found", cause["kind"])
+	root := cause["cause"].(map[string]any)
+	assert.Equal(t, fs.ErrNotExist.Error(), root["message"])
+}
+
+func TestError_UnmarshalJSON_Good(t *testing.T) {
+	original := E("workspace.Load", "failed to load workspace",
+		E("io.Read", "missing file", errors.New("disk gone"), WithCode("io.read_failed")),
+		WithKind(KindUnavailable))
+
+	data, err := json.Marshal(original)
+	assert.NoError(t, err)
+
+	var decoded Error
+	assert.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, original.Error(), decoded.Error())
+	assert.True(t, Is(&decoded, KindUnavailable))
+	assert.Equal(t, "io.read_failed", CodeOf(&decoded))
+}
+
+func TestError_UnmarshalJSON_Kinds(t *testing.T) {
+	original := E("workspace.Load", "failed to load workspace",
+		E("io.Read", "missing file", fs.ErrNotExist))
+	bare := E("workspace.Save", "failed to save workspace",
+		&Error{Msg: "quota exceeded", Kind: KindUnavailable, Stack: []string{"io.Write"}})
+
+	for _, err := range []error{original, bare} {
+		data, jsonErr := json.Marshal(err)
+		assert.NoError(t, jsonErr)
+
+		var decoded Error
+		assert.NoError(t, json.Unmarshal(data, &decoded))
+		assert.Equal(t, err.Error(), decoded.Error())
+		assert.Equal(t, KindOf(err), KindOf(&decoded))
+	}
+}
+
+func TestError_UnmarshalJSON_Bad(t *testing.T) {
+	var decoded Error
+	assert.Error(t, json.Unmarshal([]byte(`{"message": 1}`), &decoded))
+}
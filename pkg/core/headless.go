@@ -0,0 +1,14 @@
+//go:build headless
+
+package core
+
+import "log/slog"
+
+// This file replaces wails.go in builds with the "headless" tag, so that
+// pkg/core can be used without importing Wails.
+
+// WailsApp stands in for the Wails application in headless builds. It has the
+// fields services read from Core.App; Core.App is always nil in these builds.
+type WailsApp struct {
+	Logger *slog.Logger
+}
@@ -0,0 +1,158 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// defaultHealthTimeout bounds each health check when WithHealthTimeout is not
+// used and the context passed to Health has no earlier deadline.
+const defaultHealthTimeout = 5 * time.Second
+
+// HealthStatus summarises the health of a service or of the whole Core.
+type HealthStatus string
+
+const (
+	// HealthHealthy means the service is working normally.
+	HealthHealthy HealthStatus = "healthy"
+	// HealthDegraded means the service is working with reduced function,
+	// for example serving cached data while its source is unreachable.
+	HealthDegraded HealthStatus = "degraded"
+	// HealthUnhealthy means the service is not working.
+	HealthUnhealthy HealthStatus = "unhealthy"
+)
+
+// ErrDegraded is wrapped by a HealthChecker's error to report the service as
+// degraded rather than unhealthy:
+//
+//	return core.E("updater.CheckHealth", "update source unreachable", core.ErrDegraded)
+var ErrDegraded = errors.New("degraded")
+
+// HealthChecker is implemented by services that can report whether they are
+// working after startup. CheckHealth returns nil when the service is healthy,
+// an error wrapping ErrDegraded when it is degraded, and any other error when
+// it is unhealthy. It should return promptly once ctx is done.
+type HealthChecker interface {
+	CheckHealth(ctx context.Context) error
+}
+
+// ServiceHealth is the result of one service's health check.
+type ServiceHealth struct {
+	Name     string        `json:"name"`
+	Status   HealthStatus  `json:"status"`
+	Error    string        `json:"error,omitempty"`
+	Duration time.Duration `json:"duration"`
+}
+
+// HealthReport is the aggregate result of Core.Health.
+type HealthReport struct {
+	// Status is the worst status of any service, or HealthHealthy if no
+	// service implements HealthChecker.
+	Status HealthStatus `json:"status"`
+	// Checked is when the checks were started.
+	Checked time.Time `json:"checked"`
+	// Services holds a result for every service that implements
+	// HealthChecker, in startup order.
+	Services []ServiceHealth `json:"services"`
+}
+
+// WithHealthTimeout creates an Option that sets how long a single service's
+// health check may run before it is reported as unhealthy. Defaults to 5s.
+func WithHealthTimeout(d time.Duration) Option {
+	return func(c *Core) error {
+		if d <= 0 {
+			return E("core.WithHealthTimeout", "timeout must be positive", nil, WithKind(KindInvalid))
+		}
+		c.healthTimeout = d
+		return nil
+	}
+}
+
+// Health runs the health checks of every service that implements
+// HealthChecker concurrently and returns the combined report. Each check is
+// bounded by the health timeout; a check that runs over, returns an error or
+// panics marks its service unhealthy.
+func (c *Core) Health(ctx context.Context) HealthReport {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	timeout := c.healthTimeout
+	if timeout <= 0 {
+		timeout = defaultHealthTimeout
+	}
+
+	c.serviceMu.RLock()
+	var names []string
+	var checkers []HealthChecker
+	for _, name := range c.serviceOrder {
+		if hc, ok := c.services[name].(HealthChecker); ok {
+			names = append(names, name)
+			checkers = append(checkers, hc)
+		}
+	}
+	c.serviceMu.RUnlock()
+
+	report := HealthReport{
+		Status:   HealthHealthy,
+		Checked:  time.Now(),
+		Services: make([]ServiceHealth, len(checkers)),
+	}
+	var wg sync.WaitGroup
+	for i := range checkers {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			report.Services[i] = c.checkHealth(ctx, names[i], checkers[i], timeout)
+		}(i)
+	}
+	wg.Wait()
+
+	for _, s := range report.Services {
+		report.Status = worseHealth(report.Status, s.Status)
+	}
+	return report
+}
+
+// checkHealth runs a single health check with a timeout.
+func (c *Core) checkHealth(ctx context.Context, name string, hc HealthChecker, timeout time.Duration) ServiceHealth {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		// A panic here would end the process whatever the Contract says, as
+		// nothing above this goroutine can recover it.
+		done <- recovered("core.Health", name, func() error { return hc.CheckHealth(ctx) })
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = E("core.Health", fmt.Sprintf("%s did not finish its health check", describeService(name)), ctx.Err(),
+			WithKind(KindUnavailable))
+	}
+
+	result := ServiceHealth{Name: name, Status: HealthHealthy, Duration: time.Since(start)}
+	if err != nil {
+		result.Status = HealthUnhealthy
+		if errors.Is(err, ErrDegraded) {
+			result.Status = HealthDegraded
+		}
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// worseHealth returns the more severe of two statuses.
+func worseHealth(a, b HealthStatus) HealthStatus {
+	rank := map[HealthStatus]int{HealthHealthy: 0, HealthDegraded: 1, HealthUnhealthy: 2}
+	if rank[b] > rank[a] {
+		return b
+	}
+	return a
+}
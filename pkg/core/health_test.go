@@ -0,0 +1,124 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// healthService reports the configured error after an optional delay.
+type healthService struct {
+	err   error
+	delay time.Duration
+	panic bool
+}
+
+func (h *healthService) CheckHealth(ctx context.Context) error {
+	if h.panic {
+		panic("health check exploded")
+	}
+	if h.delay > 0 {
+		select {
+		case <-time.After(h.delay):
+		case <-ctx.Done():
+			<-time.After(h.delay)
+		}
+	}
+	return h.err
+}
+
+func newHealthCore(t *testing.T, opts []Option, services map[string]*healthService, order ...string) *Core {
+	t.Helper()
+	for _, name := range order {
+		svc := services[name]
+		opts = append(opts, WithName(name, func(c *Core) (any, error) { return svc, nil }))
+	}
+	c, err := New(opts...)
+	assert.NoError(t, err)
+	return c
+}
+
+func TestCore_Health_Good(t *testing.T) {
+	c := newHealthCore(t, nil, map[string]*healthService{
+		"a": {},
+		"b": {delay: 10 * time.Millisecond},
+	}, "a", "b")
+	assert.NoError(t, c.RegisterService("plain", &MockService{}))
+
+	report := c.Health(context.Background())
+	assert.Equal(t, HealthHealthy, report.Status)
+	assert.False(t, report.Checked.IsZero())
+	assert.Len(t, report.Services, 2, "services without CheckHealth are not reported")
+	assert.Equal(t, "a", report.Services[0].Name)
+	assert.Equal(t, "b", report.Services[1].Name)
+	assert.Equal(t, HealthHealthy, report.Services[1].Status)
+	assert.GreaterOrEqual(t, report.Services[1].Duration, 10*time.Millisecond)
+}
+
+func TestCore_Health_Concurrent(t *testing.T) {
+	services := map[string]*healthService{}
+	var names []string
+	for _, name := range []string{"a", "b", "c", "d"} {
+		services[name] = &healthService{delay: 50 * time.Millisecond}
+		names = append(names, name)
+	}
+	c := newHealthCore(t, nil, services, names...)
+
+	start := time.Now()
+	report := c.Health(context.Background())
+	assert.Equal(t, HealthHealthy, report.Status)
+	assert.Less(t, time.Since(start), 150*time.Millisecond, "checks run concurrently")
+}
+
+func TestCore_Health_Bad(t *testing.T) {
+	c := newHealthCore(t, nil, map[string]*healthService{
+		"ok":       {},
+		"degraded": {err: E("updater.CheckHealth", "update source unreachable", ErrDegraded)},
+	}, "ok", "degraded")
+
+	report := c.Health(context.Background())
+	assert.Equal(t, HealthDegraded, report.Status)
+	assert.Equal(t, HealthDegraded, report.Services[1].Status)
+	assert.Contains(t, report.Services[1].Error, "update source unreachable")
+
+	assert.NoError(t, c.RegisterService("down", &healthService{err: errors.New("hub not running")}))
+	report = c.Health(context.Background())
+	assert.Equal(t, HealthUnhealthy, report.Status)
+	assert.Equal(t, "hub not running", report.Services[2].Error)
+}
+
+func TestCore_Health_Ugly(t *testing.T) {
+	c := newHealthCore(t,
+		[]Option{WithHealthTimeout(20 * time.Millisecond), WithContract(Contract{DontPanic: true})},
+		map[string]*healthService{
+			"slow":   {delay: time.Second},
+			"panics": {panic: true},
+		}, "slow", "panics")
+
+	start := time.Now()
+	report := c.Health(context.Background())
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+	assert.Equal(t, HealthUnhealthy, report.Status)
+	assert.Equal(t, HealthUnhealthy, report.Services[0].Status)
+	assert.Contains(t, report.Services[0].Error, `service "slow" did not finish its health check`)
+	assert.Equal(t, HealthUnhealthy, report.Services[1].Status)
+	assert.Contains(t, report.Services[1].Error, "health check exploded")
+}
+
+func TestCore_Health_PanicWithoutDontPanic(t *testing.T) {
+	c := newHealthCore(t, nil, map[string]*healthService{
+		"panics": {panic: true},
+	}, "panics")
+
+	report := c.Health(context.Background())
+	assert.Equal(t, HealthUnhealthy, report.Status)
+	assert.Contains(t, report.Services[0].Error, `service "panics" panicked: health check exploded`)
+}
+
+func TestCore_WithHealthTimeout_Bad(t *testing.T) {
+	_, err := New(WithHealthTimeout(0))
+	assert.True(t, Is(err, KindInvalid))
+}
@@ -0,0 +1,255 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Stage identifies which part of message delivery an Interceptor is wrapping.
+type Stage string
+
+const (
+	// StageDispatch wraps the delivery of a message to all of its handlers,
+	// from ACTION, ACTIONContext or ACTIONAsync. Returning an error without
+	// calling next vetoes the message: no handler receives it.
+	StageDispatch Stage = "dispatch"
+	// StageHandler wraps the invocation of a single handler. Returning an
+	// error without calling next skips that handler.
+	StageHandler Stage = "handler"
+)
+
+// Invocation describes the message delivery an Interceptor is wrapping.
+type Invocation struct {
+	// Ctx is the context the message was sent with.
+	Ctx context.Context
+	// Stage is the part of delivery being wrapped.
+	Stage Stage
+	// Message is the message being delivered.
+	Message Message
+	// Service is the name of the service that owns the handler. It is empty
+	// for StageDispatch and for handlers added with RegisterAction or
+	// Subscribe.
+	Service string
+	// Async is true when the message was sent with ACTIONAsync.
+	Async bool
+	// Start is when the dispatch or handler invocation began.
+	Start time.Time
+}
+
+// Interceptor wraps message delivery. It must call next to continue delivery
+// and should return next's error. An interceptor can observe the message and
+// its timing around next, or veto it by returning an error without calling
+// next.
+//
+// Example:
+//
+//	c.Use(func(inv core.Invocation, next func() error) error {
+//		if _, ok := inv.Message.(display.ActionOpenWindow); ok && locked() {
+//			return core.E("workspace.lock", "workspace is locked", nil, core.WithKind(core.KindPermission))
+//		}
+//		return next()
+//	})
+type Interceptor func(inv Invocation, next func() error) error
+
+// Use adds an interceptor that wraps every subsequent message dispatch and
+// handler invocation. Interceptors run in the order they were added, so the
+// first one added is the outermost.
+func (c *Core) Use(interceptor Interceptor) {
+	c.ipcMu.Lock()
+	defer c.ipcMu.Unlock()
+	c.interceptors = append(c.interceptors, interceptor)
+}
+
+// intercept runs fn wrapped by the registered interceptors.
+func (c *Core) intercept(inv Invocation, fn func() error) error {
+	c.ipcMu.RLock()
+	interceptors := c.interceptors
+	c.ipcMu.RUnlock()
+	if len(interceptors) == 0 {
+		return fn()
+	}
+
+	inv.Start = time.Now()
+	next := fn
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		interceptor, inner := interceptors[i], next
+		next = func() error { return interceptor(inv, inner) }
+	}
+	return next()
+}
+
+// HandlerStats holds counters for one service's handlers.
+type HandlerStats struct {
+	Calls  uint64        `json:"calls"`
+	Errors uint64        `json:"errors"`
+	Total  time.Duration `json:"total"`
+	Max    time.Duration `json:"max"`
+}
+
+// MetricsSnapshot is a point-in-time copy of the counters kept by Metrics.
+type MetricsSnapshot struct {
+	// Dispatches counts the messages sent, keyed by message type.
+	Dispatches map[string]uint64 `json:"dispatches"`
+	// Handlers holds handler counters keyed by service name. Handlers that do
+	// not belong to a service are counted under "".
+	Handlers map[string]HandlerStats `json:"handlers"`
+}
+
+// Metrics is an interceptor that counts messages and times handlers.
+// Add it to a Core with Use:
+//
+//	metrics := core.NewMetrics()
+//	c.Use(metrics.Intercept)
+type Metrics struct {
+	mu       sync.Mutex
+	snapshot MetricsSnapshot
+}
+
+// NewMetrics creates an empty Metrics interceptor.
+func NewMetrics() *Metrics {
+	m := &Metrics{}
+	m.Reset()
+	return m
+}
+
+// Intercept implements Interceptor.
+func (m *Metrics) Intercept(inv Invocation, next func() error) error {
+	if inv.Stage == StageDispatch {
+		m.mu.Lock()
+		m.snapshot.Dispatches[fmt.Sprintf("%T", inv.Message)]++
+		m.mu.Unlock()
+		return next()
+	}
+
+	err := next()
+	elapsed := time.Since(inv.Start)
+	m.mu.Lock()
+	stats := m.snapshot.Handlers[inv.Service]
+	stats.Calls++
+	if err != nil {
+		stats.Errors++
+	}
+	stats.Total += elapsed
+	if elapsed > stats.Max {
+		stats.Max = elapsed
+	}
+	m.snapshot.Handlers[inv.Service] = stats
+	m.mu.Unlock()
+	return err
+}
+
+// Snapshot returns a copy of the current counters.
+func (m *Metrics) Snapshot() MetricsSnapshot {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := MetricsSnapshot{
+		Dispatches: make(map[string]uint64, len(m.snapshot.Dispatches)),
+		Handlers:   make(map[string]HandlerStats, len(m.snapshot.Handlers)),
+	}
+	for k, v := range m.snapshot.Dispatches {
+		out.Dispatches[k] = v
+	}
+	for k, v := range m.snapshot.Handlers {
+		out.Handlers[k] = v
+	}
+	return out
+}
+
+// Reset clears all counters.
+func (m *Metrics) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.snapshot = MetricsSnapshot{
+		Dispatches: make(map[string]uint64),
+		Handlers:   make(map[string]HandlerStats),
+	}
+}
+
+// Record is one entry in a Recorder's history.
+type Record struct {
+	Time        time.Time     `json:"time"`
+	Stage       Stage         `json:"stage"`
+	Service     string        `json:"service,omitempty"`
+	MessageType string        `json:"messageType"`
+	Message     Message       `json:"-"`
+	Async       bool          `json:"async,omitempty"`
+	Duration    time.Duration `json:"duration"`
+	Error       string        `json:"error,omitempty"`
+}
+
+// defaultRecorderSize is the number of records kept when NewRecorder is given
+// a non-positive size.
+const defaultRecorderSize = 256
+
+// Recorder is an interceptor that keeps the most recent dispatches and
+// handler invocations in memory, for debugging:
+//
+//	recorder := core.NewRecorder(500)
+//	c.Use(recorder.Intercept)
+//	...
+//	for _, r := range recorder.Records() {
+//		fmt.Println(r.Stage, r.Service, r.MessageType, r.Duration, r.Error)
+//	}
+type Recorder struct {
+	mu      sync.Mutex
+	records []Record
+	next    int
+	full    bool
+}
+
+// NewRecorder creates a Recorder that keeps the last size records.
+func NewRecorder(size int) *Recorder {
+	if size <= 0 {
+		size = defaultRecorderSize
+	}
+	return &Recorder{records: make([]Record, size)}
+}
+
+// Intercept implements Interceptor.
+func (r *Recorder) Intercept(inv Invocation, next func() error) error {
+	err := next()
+	rec := Record{
+		Time:        inv.Start,
+		Stage:       inv.Stage,
+		Service:     inv.Service,
+		MessageType: fmt.Sprintf("%T", inv.Message),
+		Message:     inv.Message,
+		Async:       inv.Async,
+		Duration:    time.Since(inv.Start),
+	}
+	if err != nil {
+		rec.Error = err.Error()
+	}
+
+	r.mu.Lock()
+	r.records[r.next] = rec
+	r.next = (r.next + 1) % len(r.records)
+	if r.next == 0 {
+		r.full = true
+	}
+	r.mu.Unlock()
+	return err
+}
+
+// Records returns the recorded entries, oldest first.
+func (r *Recorder) Records() []Record {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.full {
+		return append([]Record(nil), r.records[:r.next]...)
+	}
+	out := make([]Record, 0, len(r.records))
+	out = append(out, r.records[r.next:]...)
+	return append(out, r.records[:r.next]...)
+}
+
+// Reset discards all records.
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	clear(r.records)
+	r.next = 0
+	r.full = false
+}
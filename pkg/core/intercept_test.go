@@ -0,0 +1,186 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCore_Use_Good(t *testing.T) {
+	c, err := New(WithService(func(c *Core) (any, error) {
+		return &MockServiceWithIPC{MockService: MockService{Name: "mock"}}, nil
+	}))
+	assert.NoError(t, err)
+
+	var calls []string
+	c.Use(func(inv Invocation, next func() error) error {
+		calls = append(calls, fmt.Sprintf("outer %s %s", inv.Stage, inv.Service))
+		return next()
+	})
+	c.Use(func(inv Invocation, next func() error) error {
+		calls = append(calls, fmt.Sprintf("inner %s %s", inv.Stage, inv.Service))
+		assert.Equal(t, busPing{N: 1}, inv.Message)
+		assert.False(t, inv.Start.IsZero())
+		return next()
+	})
+	c.RegisterAction(func(c *Core, msg Message) error {
+		calls = append(calls, "handler")
+		return nil
+	})
+
+	assert.NoError(t, c.ACTION(busPing{N: 1}))
+	assert.Equal(t, []string{
+		"outer dispatch ",
+		"inner dispatch ",
+		"outer handler core",
+		"inner handler core",
+		"outer handler ",
+		"inner handler ",
+		"handler",
+	}, calls)
+}
+
+func TestCore_Use_Veto(t *testing.T) {
+	c, err := New()
+	assert.NoError(t, err)
+
+	locked := errors.New("workspace is locked")
+	c.Use(func(inv Invocation, next func() error) error {
+		if _, ok := inv.Message.(busPing); ok {
+			return locked
+		}
+		return next()
+	})
+
+	delivered := 0
+	c.RegisterAction(func(c *Core, msg Message) error {
+		delivered++
+		return nil
+	})
+
+	assert.ErrorIs(t, c.ACTION(busPing{}), locked)
+	assert.ErrorIs(t, c.ACTIONAsync(context.Background(), busPing{}), locked)
+	assert.Equal(t, 0, delivered)
+
+	assert.NoError(t, c.ACTION(busPong{}))
+	assert.Equal(t, 1, delivered)
+}
+
+func TestCore_Use_SkipHandler(t *testing.T) {
+	c, err := New(WithService(func(c *Core) (any, error) {
+		return &MockServiceWithIPC{MockService: MockService{Name: "mock"}}, nil
+	}))
+	assert.NoError(t, err)
+
+	c.Use(func(inv Invocation, next func() error) error {
+		if inv.Stage == StageHandler && inv.Service == "" {
+			return nil
+		}
+		return next()
+	})
+	called := false
+	c.RegisterAction(func(c *Core, msg Message) error {
+		called = true
+		return nil
+	})
+
+	assert.NoError(t, c.ACTION(busPing{}))
+	assert.False(t, called)
+}
+
+func TestCore_Use_Async(t *testing.T) {
+	c, err := New()
+	assert.NoError(t, err)
+
+	var mu sync.Mutex
+	var stages []Stage
+	var wg sync.WaitGroup
+	wg.Add(1)
+	c.Use(func(inv Invocation, next func() error) error {
+		assert.True(t, inv.Async)
+		mu.Lock()
+		stages = append(stages, inv.Stage)
+		mu.Unlock()
+		return next()
+	})
+	Subscribe(c, func(msg busPing) error {
+		wg.Done()
+		return nil
+	})
+
+	assert.NoError(t, c.ACTIONAsync(context.Background(), busPing{}))
+	wg.Wait()
+	assert.NoError(t, c.stopEventBus(context.Background()))
+	assert.Equal(t, []Stage{StageDispatch, StageHandler}, stages)
+}
+
+func TestMetrics_Good(t *testing.T) {
+	c, err := New(WithService(func(c *Core) (any, error) {
+		return &MockServiceWithIPC{MockService: MockService{Name: "mock"}}, nil
+	}))
+	assert.NoError(t, err)
+
+	metrics := NewMetrics()
+	c.Use(metrics.Intercept)
+	c.RegisterAction(func(c *Core, msg Message) error {
+		if _, ok := msg.(busPong); ok {
+			time.Sleep(time.Millisecond)
+			return assert.AnError
+		}
+		return nil
+	})
+
+	assert.NoError(t, c.ACTION(busPing{}))
+	assert.NoError(t, c.ACTION(busPing{}))
+	assert.Error(t, c.ACTION(busPong{}))
+
+	snap := metrics.Snapshot()
+	assert.Equal(t, map[string]uint64{"core.busPing": 2, "core.busPong": 1}, snap.Dispatches)
+	assert.Equal(t, uint64(3), snap.Handlers["core"].Calls)
+	assert.Equal(t, uint64(0), snap.Handlers["core"].Errors)
+	assert.Equal(t, uint64(3), snap.Handlers[""].Calls)
+	assert.Equal(t, uint64(1), snap.Handlers[""].Errors)
+	assert.GreaterOrEqual(t, snap.Handlers[""].Max, time.Millisecond)
+
+	metrics.Reset()
+	assert.Empty(t, metrics.Snapshot().Dispatches)
+}
+
+func TestRecorder_Good(t *testing.T) {
+	c, err := New()
+	assert.NoError(t, err)
+
+	recorder := NewRecorder(3)
+	c.Use(recorder.Intercept)
+	c.RegisterAction(func(c *Core, msg Message) error {
+		if ping, ok := msg.(busPing); ok && ping.N == 2 {
+			return errors.New("bad ping")
+		}
+		return nil
+	})
+
+	assert.NoError(t, c.ACTION(busPing{N: 1}))
+	assert.Len(t, recorder.Records(), 2)
+
+	assert.Error(t, c.ACTION(busPing{N: 2}))
+	records := recorder.Records()
+	assert.Len(t, records, 3, "only the most recent records are kept")
+
+	last := records[len(records)-1]
+	assert.Equal(t, StageDispatch, last.Stage)
+	assert.Equal(t, "core.busPing", last.MessageType)
+	assert.Equal(t, busPing{N: 2}, last.Message)
+	assert.Contains(t, last.Error, "bad ping")
+
+	handler := records[len(records)-2]
+	assert.Equal(t, StageHandler, handler.Stage)
+	assert.Equal(t, "bad ping", handler.Error)
+
+	recorder.Reset()
+	assert.Empty(t, recorder.Records())
+}
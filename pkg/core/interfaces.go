@@ -3,9 +3,11 @@ package core
 import (
 	"context"
 	"embed"
+	"log/slog"
+	"reflect"
 	"sync"
-
-	"github.com/wailsapp/wails/v3/pkg/application"
+	"sync/atomic"
+	"time"
 )
 
 // This file defines the public API contracts (interfaces) for the services
@@ -19,6 +21,9 @@ type Contract struct {
 	DontPanic bool
 	// DisableLogging, if true, disables all logging from the Core and its services.
 	DisableLogging bool
+	// HandlerTimeout, if non-zero, is the longest a single IPC handler may run
+	// before ACTION moves on to the next handler and reports a timeout.
+	HandlerTimeout time.Duration
 }
 
 // Features provides a way to check if a feature is enabled.
@@ -46,6 +51,33 @@ type Option func(*Core) error
 // Any struct can be a message, allowing for structured data to be passed between services.
 type Message interface{}
 
+// Query is the interface for messages that ask a service for data.
+// Unlike a Message, a Query expects a result from the service that handles it.
+type Query interface{}
+
+// Task is the interface for messages that ask a service to perform work and
+// report back a result.
+type Task interface{}
+
+// QueryHandler answers a Query. It returns handled=false when the query is not
+// one it understands, so that Core can move on to the next handler.
+type QueryHandler func(*Core, Query) (result any, handled bool, err error)
+
+// TaskHandler performs a Task. It returns handled=false when the task is not
+// one it understands. Progress updates can be reported through the progress
+// function, which is always non-nil.
+type TaskHandler func(c *Core, t Task, progress func(TaskProgress)) (result any, handled bool, err error)
+
+// TaskProgress is a progress update emitted by a TaskHandler while it runs.
+type TaskProgress struct {
+	// Task is the task the update belongs to.
+	Task Task
+	// Percent is the completion percentage, from 0 to 100.
+	Percent float64
+	// Message is an optional human-readable description of the current step.
+	Message string
+}
+
 // Startable is an interface for services that need to perform initialization.
 type Startable interface {
 	OnStartup(ctx context.Context) error
@@ -56,21 +88,71 @@ type Stoppable interface {
 	OnShutdown(ctx context.Context) error
 }
 
+// Dependent is an interface for services that require other services to be
+// started before them. Dependencies returns the names of those services, as
+// registered with the Core.
+type Dependent interface {
+	Dependencies() []string
+}
+
+// OptionalDependent is an interface for services that work with or without
+// certain other services, such as a display in headless builds. If a service
+// named by OptionalDependencies is registered, it is started first; if not,
+// it is ignored.
+type OptionalDependent interface {
+	OptionalDependencies() []string
+}
+
 // Core is the central application object that manages services, assets, and communication.
 type Core struct {
 	once           sync.Once
 	initErr        error
-	App            *application.App
+	App            *WailsApp
 	assets         embed.FS
 	Features       *Features
 	serviceLock    bool
 	ipcMu          sync.RWMutex
-	ipcHandlers    []func(*Core, Message) error
+	contract       Contract
+	ipcHandlers    []*actionHandler
+	typedHandlers  map[reflect.Type][]*actionHandler
+	handlerSeq     uint64
+	busOptions     EventBusOptions
+	busMu          sync.Mutex
+	bus            *eventBus
+	queryHandlers  []queryHandler
+	taskHandlers   []taskHandler
+	interceptors   []Interceptor
+	healthTimeout  time.Duration
 	serviceMu      sync.RWMutex
 	services       map[string]any
+	serviceOrder   []string
 	servicesLocked bool
-	startables     []Startable
-	stoppables     []Stoppable
+	replaceMu      sync.Mutex
+}
+
+// actionHandler is an IPC handler together with the name of the service that
+// registered it. The name is empty for handlers added with RegisterAction or
+// Subscribe. The inbox and scheduled fields are used by the asynchronous event
+// bus to deliver messages to the handler one at a time, in order.
+type actionHandler struct {
+	id        uint64
+	service   string
+	msgType   reflect.Type
+	fn        func(*Core, Message) error
+	inbox     chan Message
+	scheduled atomic.Bool
+}
+
+// queryHandler is a QueryHandler together with the name of its service.
+type queryHandler struct {
+	service string
+	fn      QueryHandler
+}
+
+// taskHandler is a TaskHandler together with the name of its service.
+type taskHandler struct {
+	service string
+	fn      TaskHandler
 }
 
 var instance *Core
@@ -94,6 +176,13 @@ type Display interface {
 	OpenWindow(opts ...WindowOption) error
 }
 
+// Log provides structured loggers for services. It is implemented by the
+// service registered as "log".
+type Log interface {
+	// Logger returns a logger whose records are attributed to the named service.
+	Logger(service string) *slog.Logger
+}
+
 // ActionServiceStartup is a message sent when the application's services are starting up.
 // This provides a hook for services to perform initialization tasks.
 type ActionServiceStartup struct{}
@@ -101,3 +190,15 @@ type ActionServiceStartup struct{}
 // ActionServiceShutdown is a message sent when the application is shutting down.
 // This allows services to perform cleanup tasks, such as saving state or closing resources.
 type ActionServiceShutdown struct{}
+
+// ActionServiceReplaced is a message sent after Core.ReplaceService has
+// swapped a service for a new instance. Services that cached a reference to
+// the old instance should drop it and look the service up again.
+type ActionServiceReplaced struct {
+	// Name is the name the service is registered under.
+	Name string
+	// Old is the instance that was stopped and removed.
+	Old any
+	// New is the instance that replaced it.
+	New any
+}
@@ -0,0 +1,187 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ReplaceService swaps the service registered under name for a new instance
+// built by factory, without restarting the application. It is intended for
+// reloading a module during development, or for rebuilding a service around
+// a different dependency, such as the io.Medium behind the workspace.
+//
+// The old instance receives ActionServiceShutdown and its OnShutdown is
+// called; its IPC, query and task handlers are then removed. The new
+// instance takes the old one's place in the startup order, its
+// HandleIPCEvents, HandleQuery and HandleTask methods are registered, its
+// OnStartup is called and it receives ActionServiceStartup. Finally an
+// ActionServiceReplaced is sent to every handler.
+//
+// If factory fails, or the old instance fails to stop, the old instance stays
+// registered. If the new instance fails to start, the new instance is
+// stopped if its OnStartup succeeded, and the old instance is put back with
+// its handlers, started again and sent ActionServiceStartup; no
+// ActionServiceReplaced is sent. Calls for the same Core run one at a time.
+// ReplaceService always fails after WithServiceLock has locked the Core.
+//
+// Example:
+//
+//	err := c.ReplaceService("workspace", func(c *core.Core) (any, error) {
+//		return workspace.New(s3Medium)
+//	})
+func (c *Core) ReplaceService(name string, factory func(*Core) (any, error)) error {
+	const op = "core.ReplaceService"
+	if c.servicesLocked {
+		return E(op, fmt.Sprintf("service %q is not permitted by the serviceLock setting", name), nil,
+			WithKind(KindPermission))
+	}
+
+	c.replaceMu.Lock()
+	defer c.replaceMu.Unlock()
+
+	old := c.Service(name)
+	if old == nil {
+		return E(op, fmt.Sprintf("service %q is not registered", name), nil, WithKind(KindNotFound))
+	}
+	replacement, err := factory(c)
+	if err != nil {
+		return E(op, fmt.Sprintf("failed to create service %q", name), err)
+	}
+	if replacement == nil {
+		return E(op, fmt.Sprintf("factory for service %q returned nil", name), nil, WithKind(KindInvalid))
+	}
+
+	ctx := context.Background()
+
+	// Stop the old instance while its handlers are still wired.
+	if err := c.deliverTo(ctx, name, ActionServiceShutdown{}); err != nil {
+		return E(op, fmt.Sprintf("failed to stop service %q", name), err)
+	}
+	if s, ok := old.(Stoppable); ok {
+		if err := c.guard(op, name, func() error { return s.OnShutdown(ctx) }); err != nil {
+			return E(op, fmt.Sprintf("failed to stop service %q", name), err)
+		}
+	}
+
+	oldHandlers := c.removeServiceHandlers(name)
+	c.swapService(name, replacement)
+	c.discoverHandlers(name, replacement)
+
+	// Start the new instance, or put the old one back.
+	started, err := c.startService(ctx, op, name, replacement)
+	if err != nil {
+		if started {
+			if s, ok := replacement.(Stoppable); ok {
+				_ = c.guard(op, name, func() error { return s.OnShutdown(ctx) })
+			}
+		}
+		c.removeServiceHandlers(name)
+		c.swapService(name, old)
+		c.restoreServiceHandlers(oldHandlers)
+		if _, restoreErr := c.startService(ctx, op, name, old); restoreErr != nil {
+			return E(op, fmt.Sprintf("failed to start service %q, and the old instance failed to restart", name),
+				errors.Join(err, restoreErr))
+		}
+		return E(op, fmt.Sprintf("failed to start service %q; the old instance was restored", name), err)
+	}
+
+	return c.ACTIONContext(ctx, ActionServiceReplaced{Name: name, Old: old, New: replacement})
+}
+
+// swapService registers instance under name in place of the current one.
+func (c *Core) swapService(name string, instance any) {
+	c.serviceMu.Lock()
+	c.services[name] = instance
+	c.serviceMu.Unlock()
+	if named, ok := instance.(interface{ setServiceName(string) }); ok {
+		named.setServiceName(name)
+	}
+}
+
+// startService calls the OnStartup of the instance registered under name and
+// sends it ActionServiceStartup. started reports whether OnStartup succeeded.
+func (c *Core) startService(ctx context.Context, op, name string, instance any) (started bool, err error) {
+	if s, ok := instance.(Startable); ok {
+		if err := c.guard(op, name, func() error { return s.OnStartup(ctx) }); err != nil {
+			return false, err
+		}
+	}
+	return true, c.deliverTo(ctx, name, ActionServiceStartup{})
+}
+
+// deliverTo sends msg only to the action handlers owned by the named service.
+func (c *Core) deliverTo(ctx context.Context, name string, msg Message) error {
+	c.ipcMu.RLock()
+	var owned []*actionHandler
+	for _, h := range c.ipcHandlers {
+		if h.service == name {
+			owned = append(owned, h)
+		}
+	}
+	c.ipcMu.RUnlock()
+
+	for _, h := range owned {
+		if err := c.runAction(ctx, h, msg, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// serviceHandlers are the action, query and task handlers owned by one
+// service.
+type serviceHandlers struct {
+	actions []*actionHandler
+	queries []queryHandler
+	tasks   []taskHandler
+}
+
+// removeServiceHandlers removes every action, query and task handler owned
+// by the named service and returns them.
+func (c *Core) removeServiceHandlers(name string) serviceHandlers {
+	c.ipcMu.Lock()
+	defer c.ipcMu.Unlock()
+
+	var removed serviceHandlers
+	actions := c.ipcHandlers[:0:0]
+	for _, h := range c.ipcHandlers {
+		if h.service != name {
+			actions = append(actions, h)
+		} else {
+			removed.actions = append(removed.actions, h)
+		}
+	}
+	c.ipcHandlers = actions
+
+	queries := c.queryHandlers[:0:0]
+	for _, h := range c.queryHandlers {
+		if h.service != name {
+			queries = append(queries, h)
+		} else {
+			removed.queries = append(removed.queries, h)
+		}
+	}
+	c.queryHandlers = queries
+
+	tasks := c.taskHandlers[:0:0]
+	for _, h := range c.taskHandlers {
+		if h.service != name {
+			tasks = append(tasks, h)
+		} else {
+			removed.tasks = append(removed.tasks, h)
+		}
+	}
+	c.taskHandlers = tasks
+	return removed
+}
+
+// restoreServiceHandlers registers handlers returned by removeServiceHandlers
+// again, after the handlers already registered.
+func (c *Core) restoreServiceHandlers(h serviceHandlers) {
+	c.ipcMu.Lock()
+	defer c.ipcMu.Unlock()
+	c.ipcHandlers = append(c.ipcHandlers, h.actions...)
+	c.queryHandlers = append(c.queryHandlers, h.queries...)
+	c.taskHandlers = append(c.taskHandlers, h.tasks...)
+}
@@ -0,0 +1,187 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// reloadableService records its lifecycle and the messages it handles.
+type reloadableService struct {
+	version  int
+	events   *[]string
+	startErr error
+	stopErr  error
+}
+
+func (s *reloadableService) OnStartup(ctx context.Context) error {
+	*s.events = append(*s.events, "start", s.label())
+	return s.startErr
+}
+
+func (s *reloadableService) OnShutdown(ctx context.Context) error {
+	*s.events = append(*s.events, "stop", s.label())
+	return s.stopErr
+}
+
+func (s *reloadableService) HandleIPCEvents(c *Core, msg Message) error {
+	switch msg.(type) {
+	case ActionServiceStartup:
+		*s.events = append(*s.events, "startup-action", s.label())
+	case ActionServiceShutdown:
+		*s.events = append(*s.events, "shutdown-action", s.label())
+	case busPing:
+		*s.events = append(*s.events, "ping", s.label())
+	}
+	return nil
+}
+
+func (s *reloadableService) HandleQuery(c *Core, q Query) (any, bool, error) {
+	return s.version, true, nil
+}
+
+func (s *reloadableService) label() string {
+	return map[int]string{1: "v1", 2: "v2"}[s.version]
+}
+
+func TestCore_ReplaceService_Good(t *testing.T) {
+	var events []string
+	v1 := &reloadableService{version: 1, events: &events}
+	c, err := New(WithService(func(c *Core) (any, error) { return v1, nil }))
+	assert.NoError(t, err)
+	assert.NoError(t, c.Start(context.Background()))
+
+	var replaced ActionServiceReplaced
+	Subscribe(c, func(msg ActionServiceReplaced) error {
+		replaced = msg
+		return nil
+	})
+
+	events = nil
+	v2 := &reloadableService{version: 2, events: &events}
+	err = c.ReplaceService("core", func(c *Core) (any, error) { return v2, nil })
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"shutdown-action", "v1",
+		"stop", "v1",
+		"start", "v2",
+		"startup-action", "v2",
+	}, events)
+
+	assert.Same(t, v2, c.Service("core"))
+	assert.Equal(t, "core", replaced.Name)
+	assert.Same(t, v1, replaced.Old)
+	assert.Same(t, v2, replaced.New)
+
+	// Only the new instance's handlers remain wired.
+	events = nil
+	assert.NoError(t, c.ACTION(busPing{}))
+	assert.Equal(t, []string{"ping", "v2"}, events)
+	result, handled, err := c.QUERY(queryName{})
+	assert.NoError(t, err)
+	assert.True(t, handled)
+	assert.Equal(t, 2, result)
+}
+
+func TestCore_ReplaceService_Bad(t *testing.T) {
+	var events []string
+	v1 := &reloadableService{version: 1, events: &events}
+	c, err := New(WithService(func(c *Core) (any, error) { return v1, nil }))
+	assert.NoError(t, err)
+
+	err = c.ReplaceService("missing", func(c *Core) (any, error) { return v1, nil })
+	assert.True(t, Is(err, KindNotFound))
+
+	err = c.ReplaceService("core", func(c *Core) (any, error) { return nil, assert.AnError })
+	assert.ErrorIs(t, err, assert.AnError)
+	assert.Same(t, v1, c.Service("core"), "the old instance stays registered")
+
+	v1.stopErr = errors.New("still busy")
+	err = c.ReplaceService("core", func(c *Core) (any, error) {
+		return &reloadableService{version: 2, events: &events}, nil
+	})
+	assert.ErrorContains(t, err, "still busy")
+	assert.Same(t, v1, c.Service("core"), "the old instance stays registered")
+}
+
+func TestCore_ReplaceService_StartFails(t *testing.T) {
+	var events []string
+	v1 := &reloadableService{version: 1, events: &events}
+	c, err := New(WithService(func(c *Core) (any, error) { return v1, nil }))
+	assert.NoError(t, err)
+
+	events = nil
+	err = c.ReplaceService("core", func(c *Core) (any, error) {
+		return &reloadableService{version: 2, events: &events, startErr: errors.New("port in use")}, nil
+	})
+	assert.ErrorContains(t, err, "port in use")
+	assert.Same(t, v1, c.Service("core"), "the old instance is restored")
+	assert.Equal(t, []string{
+		"shutdown-action", "v1", "stop", "v1",
+		"start", "v2",
+		"start", "v1", "startup-action", "v1",
+	}, events)
+
+	events = nil
+	assert.NoError(t, c.ACTION(busPing{}))
+	assert.Equal(t, []string{"ping", "v1"}, events, "only the old instance's handlers are registered")
+	result, handled, err := c.QUERY(queryName{})
+	assert.NoError(t, err)
+	assert.True(t, handled)
+	assert.Equal(t, 1, result)
+}
+
+func TestCore_ReplaceService_Concurrent(t *testing.T) {
+	var events []string
+	c, err := New(WithService(func(c *Core) (any, error) {
+		return &reloadableService{version: 1, events: &events}, nil
+	}))
+	assert.NoError(t, err)
+
+	// The services append to events without a lock, so the race detector
+	// catches replacements that overlap.
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, c.ReplaceService("core", func(c *Core) (any, error) {
+				return &reloadableService{version: 2, events: &events}, nil
+			}))
+		}()
+	}
+	wg.Wait()
+
+	events = nil
+	assert.NoError(t, c.ACTION(busPing{}))
+	assert.Equal(t, []string{"ping", "v2"}, events, "one instance's handlers are registered")
+}
+
+func TestCore_ReplaceService_ServiceLock(t *testing.T) {
+	var events []string
+	c, err := New(
+		WithService(func(c *Core) (any, error) { return &reloadableService{version: 1, events: &events}, nil }),
+		WithServiceLock(),
+	)
+	assert.NoError(t, err)
+
+	err = c.ReplaceService("core", func(c *Core) (any, error) {
+		t.Fatal("factory must not be called when services are locked")
+		return nil, nil
+	})
+	assert.True(t, Is(err, KindPermission))
+	assert.Empty(t, events)
+}
+
+func TestCore_ReplaceService_Runtime(t *testing.T) {
+	c, err := New()
+	assert.NoError(t, err)
+	assert.NoError(t, c.RegisterService("ledger", &loggingService{}))
+
+	replacement := &loggingService{ServiceRuntime: NewServiceRuntime(c, struct{}{})}
+	assert.NoError(t, c.ReplaceService("ledger", func(c *Core) (any, error) { return replacement, nil }))
+	assert.Equal(t, "ledger", replacement.name)
+}
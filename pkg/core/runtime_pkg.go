@@ -3,9 +3,8 @@ package core
 import (
 	"context"
 	"fmt"
+	"log/slog"
 	"sort"
-
-	"github.com/wailsapp/wails/v3/pkg/application"
 )
 
 // ServiceRuntime is a helper struct embedded in services to provide access to the core application.
@@ -13,6 +12,7 @@ import (
 type ServiceRuntime[T any] struct {
 	core *Core
 	opts T
+	name string
 }
 
 // NewServiceRuntime creates a new ServiceRuntime instance for a service.
@@ -35,11 +35,29 @@ func (r *ServiceRuntime[T]) Config() Config {
 	return r.core.Config()
 }
 
+// Logger returns a structured logger attributed to the service, using the
+// name it was registered under. It falls back to slog.Default() if the
+// runtime is not attached to a Core.
+func (r *ServiceRuntime[T]) Logger() *slog.Logger {
+	if r == nil || r.core == nil {
+		return slog.Default()
+	}
+	return r.core.Logger(r.name)
+}
+
+// setServiceName records the name the service was registered under. It is
+// called by Core.RegisterService.
+func (r *ServiceRuntime[T]) setServiceName(name string) {
+	if r != nil {
+		r.name = name
+	}
+}
+
 // Runtime is the container that holds all instantiated services.
 // Its fields are the concrete types, allowing Wails to bind them directly.
 // This struct is the primary entry point for the Wails application.
 type Runtime struct {
-	app  *application.App
+	app  *WailsApp
 	Core *Core
 }
 
@@ -50,10 +68,13 @@ type ServiceFactory func() (any, error)
 // NewWithFactories creates a new Runtime instance using the provided service factories.
 // This is the most flexible way to create a new Runtime, as it allows for
 // the registration of any number of services.
-func NewWithFactories(app *application.App, factories map[string]ServiceFactory) (*Runtime, error) {
+func NewWithFactories(app *WailsApp, factories map[string]ServiceFactory) (*Runtime, error) {
 	services := make(map[string]any)
 	coreOpts := []Option{
-		WithWails(app),
+		func(c *Core) error {
+			c.App = app
+			return nil
+		},
 	}
 
 	names := make([]string, 0, len(factories))
@@ -91,7 +112,9 @@ func NewWithFactories(app *application.App, factories map[string]ServiceFactory)
 // NewRuntime creates and wires together all application services.
 // This is the simplest way to create a new Runtime, but it does not allow for
 // the registration of any custom services.
-func NewRuntime(app *application.App) (*Runtime, error) {
+//
+// Pass a nil app to create a headless Runtime and drive it with Start and Stop.
+func NewRuntime(app *WailsApp) (*Runtime, error) {
 	return NewWithFactories(app, map[string]ServiceFactory{})
 }
 
@@ -100,10 +123,17 @@ func (r *Runtime) ServiceName() string {
 	return "Core"
 }
 
-// ServiceStartup is called by Wails at application startup.
-// This is where the Core's startup lifecycle is initiated.
-func (r *Runtime) ServiceStartup(ctx context.Context, options application.ServiceOptions) {
-	r.Core.ServiceStartup(ctx, options)
+// Start runs the Core's startup lifecycle without Wails.
+func (r *Runtime) Start(ctx context.Context) error {
+	return r.Core.Start(ctx)
+}
+
+// Stop runs the Core's shutdown lifecycle without Wails.
+func (r *Runtime) Stop(ctx context.Context) error {
+	if r.Core == nil {
+		return nil
+	}
+	return r.Core.Stop(ctx)
 }
 
 // ServiceShutdown is called by Wails at application shutdown.
@@ -4,13 +4,12 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
-	"github.com/wailsapp/wails/v3/pkg/application"
 )
 
 func TestNewRuntime(t *testing.T) {
 	testCases := []struct {
 		name         string
-		app          *application.App
+		app          *WailsApp
 		factories    map[string]ServiceFactory
 		expectErr    bool
 		expectErrStr string
@@ -28,7 +27,7 @@ func TestNewRuntime(t *testing.T) {
 		},
 		{
 			name:      "With non-nil app",
-			app:       &application.App{},
+			app:       &WailsApp{},
 			factories: map[string]ServiceFactory{},
 			expectErr: false,
 			checkRuntime: func(t *testing.T, rt *Runtime) {
@@ -101,15 +100,17 @@ func TestRuntime_Lifecycle_Good(t *testing.T) {
 	// ServiceName
 	assert.Equal(t, "Core", rt.ServiceName())
 
-	// ServiceStartup & ServiceShutdown
+	// Start, Stop & ServiceShutdown
 	// These are simple wrappers around the core methods, which are tested in core_test.go.
 	// We call them here to ensure coverage.
-	rt.ServiceStartup(nil, application.ServiceOptions{})
+	assert.NoError(t, rt.Start(nil))
+	assert.NoError(t, rt.Stop(nil))
 	rt.ServiceShutdown(nil)
 
 	// Test shutdown with nil core
 	rt.Core = nil
 	rt.ServiceShutdown(nil)
+	assert.NoError(t, rt.Stop(nil))
 }
 
 func TestNewServiceRuntime_Good(t *testing.T) {
@@ -0,0 +1,38 @@
+//go:build !headless
+
+package core
+
+import (
+	"context"
+
+	"github.com/wailsapp/wails/v3/pkg/application"
+)
+
+// This file holds the parts of the Core that are bound to Wails. Build with
+// the "headless" tag to leave them, and the Wails dependency, out of server
+// and CLI binaries.
+
+// WailsApp is the Wails application a Core can be bound to.
+type WailsApp = application.App
+
+// WithWails creates an Option that injects the Wails application instance into the Core.
+// This is essential for services that need to interact with the Wails runtime.
+func WithWails(app *application.App) Option {
+	return func(c *Core) error {
+		c.App = app
+		return nil
+	}
+}
+
+// ServiceStartup is the entry point for the Core service's startup lifecycle.
+// It is called by Wails when the application starts, and is equivalent to
+// Start.
+func (c *Core) ServiceStartup(ctx context.Context, options application.ServiceOptions) error {
+	return c.Start(ctx)
+}
+
+// ServiceStartup is called by Wails at application startup.
+// This is where the Core's startup lifecycle is initiated.
+func (r *Runtime) ServiceStartup(ctx context.Context, options application.ServiceOptions) {
+	r.Core.ServiceStartup(ctx, options)
+}
@@ -0,0 +1,72 @@
+//go:build !headless
+
+package core
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wailsapp/wails/v3/pkg/application"
+)
+
+func TestCore_App_Good(t *testing.T) {
+	app := &application.App{}
+	c, err := New(WithWails(app))
+	assert.NoError(t, err)
+
+	// To test the global App() function, we need to set the global instance.
+	originalInstance := instance
+	instance = c
+	defer func() { instance = originalInstance }()
+
+	assert.Equal(t, app, App())
+}
+
+func TestCore_ServiceLifecycle_Good(t *testing.T) {
+	c, err := New()
+	assert.NoError(t, err)
+
+	var messageReceived Message
+	handler := func(c *Core, msg Message) error {
+		messageReceived = msg
+		return nil
+	}
+	c.RegisterAction(handler)
+
+	// Test Startup
+	_ = c.ServiceStartup(nil, application.ServiceOptions{})
+	_, ok := messageReceived.(ActionServiceStartup)
+	assert.True(t, ok, "expected ActionServiceStartup message")
+
+	// Test Shutdown
+	_ = c.ServiceShutdown(nil)
+	_, ok = messageReceived.(ActionServiceShutdown)
+	assert.True(t, ok, "expected ActionServiceShutdown message")
+}
+
+func TestCore_WithWails_Good(t *testing.T) {
+	app := &application.App{}
+	c, err := New(WithWails(app))
+	assert.NoError(t, err)
+	assert.Equal(t, app, c.App)
+}
+
+func TestCore_ServiceStartup_Good(t *testing.T) {
+	var callOrder []string
+	c, err := New()
+	assert.NoError(t, err)
+	assert.NoError(t, c.RegisterService("s1", &MockLifecycleWithLog{id: "1", log: &callOrder}))
+
+	// ServiceStartup is the Wails entry point and runs the same lifecycle as Start.
+	err = c.ServiceStartup(context.Background(), application.ServiceOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"start-1"}, callOrder)
+}
+
+func TestRuntime_ServiceStartup_Good(t *testing.T) {
+	rt, err := NewRuntime(&application.App{})
+	assert.NoError(t, err)
+	rt.ServiceStartup(nil, application.ServiceOptions{})
+	rt.ServiceShutdown(nil)
+}
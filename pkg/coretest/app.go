@@ -0,0 +1,319 @@
+package coretest
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/host-uk/core/pkg/display"
+	"github.com/wailsapp/wails/v3/pkg/application"
+	"github.com/wailsapp/wails/v3/pkg/events"
+)
+
+// FakeApp is an implementation of display.App that needs no Wails runtime. It
+// records the windows, menus, dialogs and events the display service creates
+// so tests can assert on them:
+//
+//	app := coretest.NewFakeApp()
+//	svc, _ := display.New()
+//	svc.SetApp(app)
+//	_ = svc.OpenWindow(display.WindowName("editor"))
+//	assert.Equal(t, "editor", app.Windows.Created()[0].Name)
+//
+// Windows and system trays cannot exist without the Wails runtime, so
+// NewWithOptions and the tray manager's New return nil. Menus and dialogs are
+// real Wails values that are never shown.
+type FakeApp struct {
+	Windows     *FakeWindows
+	Menus       *FakeMenus
+	Dialogs     *FakeDialogs
+	Tray        *FakeTray
+	Environment *FakeEnv
+	Events      *FakeEvents
+	Log         *FakeLogger
+	quit        atomic.Bool
+}
+
+// NewFakeApp creates a FakeApp reporting a light-mode "test" environment.
+func NewFakeApp() *FakeApp {
+	return &FakeApp{
+		Windows: &FakeWindows{},
+		Menus:   &FakeMenus{},
+		Dialogs: &FakeDialogs{},
+		Tray:    &FakeTray{},
+		Environment: &FakeEnv{Details: application.EnvironmentInfo{
+			OS:           "test",
+			Arch:         "test",
+			PlatformInfo: map[string]any{},
+		}},
+		Events: &FakeEvents{handlers: make(map[events.ApplicationEventType][]*eventHandler)},
+		Log:    &FakeLogger{},
+	}
+}
+
+// Window implements display.App.
+func (a *FakeApp) Window() display.WindowManager { return a.Windows }
+
+// Menu implements display.App.
+func (a *FakeApp) Menu() display.MenuManager { return a.Menus }
+
+// Dialog implements display.App.
+func (a *FakeApp) Dialog() display.DialogManager { return a.Dialogs }
+
+// SystemTray implements display.App.
+func (a *FakeApp) SystemTray() display.SystemTrayManager { return a.Tray }
+
+// Env implements display.App.
+func (a *FakeApp) Env() display.EnvManager { return a.Environment }
+
+// Event implements display.App.
+func (a *FakeApp) Event() display.EventManager { return a.Events }
+
+// Logger implements display.App.
+func (a *FakeApp) Logger() display.Logger { return a.Log }
+
+// Quit implements display.App.
+func (a *FakeApp) Quit() { a.quit.Store(true) }
+
+// QuitCalled reports whether Quit has been called.
+func (a *FakeApp) QuitCalled() bool { return a.quit.Load() }
+
+// FakeWindows records window creation.
+type FakeWindows struct {
+	mu      sync.Mutex
+	created []application.WebviewWindowOptions
+	open    []application.Window
+}
+
+// NewWithOptions records opts and returns nil.
+func (w *FakeWindows) NewWithOptions(opts application.WebviewWindowOptions) *application.WebviewWindow {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.created = append(w.created, opts)
+	return nil
+}
+
+// GetAll returns the windows added with Add.
+func (w *FakeWindows) GetAll() []application.Window {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]application.Window(nil), w.open...)
+}
+
+// Add makes windows visible to GetAll.
+func (w *FakeWindows) Add(windows ...application.Window) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.open = append(w.open, windows...)
+}
+
+// Created returns the options of every NewWithOptions call, in order.
+func (w *FakeWindows) Created() []application.WebviewWindowOptions {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]application.WebviewWindowOptions(nil), w.created...)
+}
+
+// FakeMenus records menu creation.
+type FakeMenus struct {
+	mu      sync.Mutex
+	created []*application.Menu
+	current *application.Menu
+}
+
+// New creates and records a menu.
+func (m *FakeMenus) New() *application.Menu {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	menu := application.NewMenu()
+	m.created = append(m.created, menu)
+	return menu
+}
+
+// Set records menu as the application menu.
+func (m *FakeMenus) Set(menu *application.Menu) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.current = menu
+}
+
+// Created returns every menu created with New, in order.
+func (m *FakeMenus) Created() []*application.Menu {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]*application.Menu(nil), m.created...)
+}
+
+// Current returns the menu passed to the last Set call.
+func (m *FakeMenus) Current() *application.Menu {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.current
+}
+
+// FakeDialogs records dialog creation. The dialogs it returns can be
+// configured but must not be shown.
+type FakeDialogs struct {
+	mu        sync.Mutex
+	infos     []*application.MessageDialog
+	warnings  []*application.MessageDialog
+	openFiles []*application.OpenFileDialogStruct
+}
+
+// Info creates and records an info dialog.
+func (d *FakeDialogs) Info() *application.MessageDialog {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	dialog := application.InfoDialog()
+	d.infos = append(d.infos, dialog)
+	return dialog
+}
+
+// Warning creates and records a warning dialog.
+func (d *FakeDialogs) Warning() *application.MessageDialog {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	dialog := application.WarningDialog()
+	d.warnings = append(d.warnings, dialog)
+	return dialog
+}
+
+// OpenFile creates and records an open-file dialog.
+func (d *FakeDialogs) OpenFile() *application.OpenFileDialogStruct {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	dialog := application.OpenFileDialog()
+	d.openFiles = append(d.openFiles, dialog)
+	return dialog
+}
+
+// Infos returns every info dialog created, in order.
+func (d *FakeDialogs) Infos() []*application.MessageDialog {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]*application.MessageDialog(nil), d.infos...)
+}
+
+// Warnings returns every warning dialog created, in order.
+func (d *FakeDialogs) Warnings() []*application.MessageDialog {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]*application.MessageDialog(nil), d.warnings...)
+}
+
+// OpenFiles returns every open-file dialog created, in order.
+func (d *FakeDialogs) OpenFiles() []*application.OpenFileDialogStruct {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]*application.OpenFileDialogStruct(nil), d.openFiles...)
+}
+
+// FakeTray counts system tray creation.
+type FakeTray struct {
+	created atomic.Int32
+}
+
+// New counts the call and returns nil.
+func (t *FakeTray) New() *application.SystemTray {
+	t.created.Add(1)
+	return nil
+}
+
+// Created returns the number of New calls.
+func (t *FakeTray) Created() int { return int(t.created.Load()) }
+
+// FakeEnv reports a fixed environment. Set its fields before use.
+type FakeEnv struct {
+	Details  application.EnvironmentInfo
+	DarkMode bool
+}
+
+// Info returns e.Details.
+func (e *FakeEnv) Info() application.EnvironmentInfo { return e.Details }
+
+// IsDarkMode returns e.DarkMode.
+func (e *FakeEnv) IsDarkMode() bool { return e.DarkMode }
+
+// EmittedEvent is a custom event sent with FakeEvents.Emit.
+type EmittedEvent struct {
+	Name string
+	Data []any
+}
+
+// eventHandler wraps a handler so it can be found again to unsubscribe.
+type eventHandler struct {
+	fn func(*application.ApplicationEvent)
+}
+
+// FakeEvents keeps application event handlers so tests can fire them, and
+// records emitted custom events.
+type FakeEvents struct {
+	mu       sync.Mutex
+	handlers map[events.ApplicationEventType][]*eventHandler
+	emitted  []EmittedEvent
+}
+
+// OnApplicationEvent registers handler for eventType and returns a function
+// that removes it.
+func (e *FakeEvents) OnApplicationEvent(eventType events.ApplicationEventType, handler func(*application.ApplicationEvent)) func() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	h := &eventHandler{fn: handler}
+	e.handlers[eventType] = append(e.handlers[eventType], h)
+	return func() {
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		list := e.handlers[eventType]
+		for i, existing := range list {
+			if existing == h {
+				e.handlers[eventType] = append(list[:i:i], list[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// Emit records a custom event and reports it as delivered.
+func (e *FakeEvents) Emit(name string, data ...any) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.emitted = append(e.emitted, EmittedEvent{Name: name, Data: data})
+	return true
+}
+
+// Fire calls the handlers registered for eventType and returns how many ran.
+func (e *FakeEvents) Fire(eventType events.ApplicationEventType) int {
+	e.mu.Lock()
+	handlers := append([]*eventHandler(nil), e.handlers[eventType]...)
+	e.mu.Unlock()
+	for _, h := range handlers {
+		h.fn(&application.ApplicationEvent{Id: uint(eventType)})
+	}
+	return len(handlers)
+}
+
+// Emitted returns the custom events sent with Emit, in order.
+func (e *FakeEvents) Emitted() []EmittedEvent {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]EmittedEvent(nil), e.emitted...)
+}
+
+// FakeLogger records log messages.
+type FakeLogger struct {
+	mu       sync.Mutex
+	messages []string
+}
+
+// Info records message.
+func (l *FakeLogger) Info(message string, args ...any) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.messages = append(l.messages, message)
+}
+
+// Messages returns the recorded messages, in order.
+func (l *FakeLogger) Messages() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]string(nil), l.messages...)
+}
@@ -0,0 +1,83 @@
+package coretest
+
+import (
+	"testing"
+
+	"github.com/host-uk/core/pkg/display"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wailsapp/wails/v3/pkg/application"
+	"github.com/wailsapp/wails/v3/pkg/events"
+)
+
+var _ display.App = (*FakeApp)(nil)
+
+func newDisplay(t *testing.T) (*display.Service, *FakeApp) {
+	t.Helper()
+	svc, err := display.New()
+	require.NoError(t, err)
+	app := NewFakeApp()
+	svc.SetApp(app)
+	return svc, app
+}
+
+func TestFakeApp_Windows(t *testing.T) {
+	svc, app := newDisplay(t)
+
+	assert.NoError(t, svc.OpenWindow(display.WindowName("editor"), display.WindowWidth(640)))
+	created := app.Windows.Created()
+	require.Len(t, created, 1)
+	assert.Equal(t, "editor", created[0].Name)
+	assert.Equal(t, 640, created[0].Width)
+	assert.Empty(t, app.Windows.GetAll())
+}
+
+func TestFakeApp_Dialogs(t *testing.T) {
+	app := NewFakeApp()
+	app.Environment.DarkMode = true
+
+	dialog := app.Dialog().Info().SetTitle("Environment Information")
+	assert.Equal(t, "Environment Information", dialog.Title)
+	assert.Equal(t, []*application.MessageDialog{dialog}, app.Dialogs.Infos())
+	assert.Len(t, app.Dialogs.Warnings(), 0)
+	assert.NotNil(t, app.Dialog().OpenFile())
+	assert.Len(t, app.Dialogs.OpenFiles(), 1)
+	assert.True(t, app.Env().IsDarkMode())
+	assert.Equal(t, "test", app.Env().Info().OS)
+}
+
+func TestFakeApp_Menus(t *testing.T) {
+	app := NewFakeApp()
+	menu := app.Menu().New()
+	menu.Add("Quit")
+	app.Menu().Set(menu)
+
+	assert.Same(t, menu, app.Menus.Current())
+	assert.Len(t, app.Menus.Created(), 1)
+	assert.Nil(t, app.SystemTray().New())
+	assert.Equal(t, 1, app.Tray.Created())
+
+	app.Quit()
+	assert.True(t, app.QuitCalled())
+}
+
+func TestFakeApp_Events(t *testing.T) {
+	app := NewFakeApp()
+	calls := 0
+	off := app.Event().OnApplicationEvent(events.Common.ThemeChanged, func(e *application.ApplicationEvent) {
+		calls++
+		assert.Equal(t, uint(events.Common.ThemeChanged), e.Id)
+	})
+
+	assert.Equal(t, 1, app.Events.Fire(events.Common.ThemeChanged))
+	assert.Equal(t, 0, app.Events.Fire(events.Common.ApplicationStarted))
+	off()
+	assert.Equal(t, 0, app.Events.Fire(events.Common.ThemeChanged))
+	assert.Equal(t, 1, calls)
+
+	assert.True(t, app.Event().Emit("window:focus", "main"))
+	assert.Equal(t, []EmittedEvent{{Name: "window:focus", Data: []any{"main"}}}, app.Events.Emitted())
+
+	app.Logger().Info("started", "k", "v")
+	assert.Equal(t, []string{"started"}, app.Log.Messages())
+}
@@ -0,0 +1,91 @@
+package coretest
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Clock is a deterministic clock. Time only moves when Advance or Set is
+// called, so code that takes a clock, such as a `now func() time.Time` field,
+// can be tested without sleeping:
+//
+//	clock := coretest.NewClock(coretest.Epoch)
+//	svc.now = clock.Now
+//	clock.Advance(time.Hour)
+type Clock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*clockTimer
+}
+
+// clockTimer is a pending channel returned by After.
+type clockTimer struct {
+	deadline time.Time
+	ch       chan time.Time
+}
+
+// NewClock creates a Clock set to start.
+func NewClock(start time.Time) *Clock {
+	return &Clock{now: start}
+}
+
+// Now returns the clock's current time.
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Since returns the time elapsed on the clock since t.
+func (c *Clock) Since(t time.Time) time.Duration {
+	return c.Now().Sub(t)
+}
+
+// After returns a channel that receives the clock's time once it has been
+// advanced by at least d. A non-positive d fires immediately.
+func (c *Clock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	timer := &clockTimer{deadline: c.now.Add(d), ch: make(chan time.Time, 1)}
+	if d <= 0 {
+		timer.ch <- c.now
+		return timer.ch
+	}
+	c.timers = append(c.timers, timer)
+	return timer.ch
+}
+
+// Advance moves the clock forward by d and fires any timers that are due, in
+// deadline order.
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.setLocked(c.now.Add(d))
+}
+
+// Set moves the clock to t and fires any timers that are due. Moving the
+// clock backwards does not fire timers.
+func (c *Clock) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.setLocked(t)
+}
+
+// setLocked sets the time and fires due timers. c.mu must be held.
+func (c *Clock) setLocked(t time.Time) {
+	c.now = t
+	sort.SliceStable(c.timers, func(i, j int) bool {
+		return c.timers[i].deadline.Before(c.timers[j].deadline)
+	})
+	pending := c.timers[:0]
+	for _, timer := range c.timers {
+		if timer.deadline.After(t) {
+			pending = append(pending, timer)
+			continue
+		}
+		timer.ch <- t
+	}
+	clear(c.timers[len(pending):])
+	c.timers = pending
+}
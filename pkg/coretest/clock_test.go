@@ -0,0 +1,45 @@
+package coretest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClock_Good(t *testing.T) {
+	clock := NewClock(Epoch)
+	start := clock.Now()
+
+	clock.Advance(90 * time.Minute)
+	assert.Equal(t, Epoch.Add(90*time.Minute), clock.Now())
+	assert.Equal(t, 90*time.Minute, clock.Since(start))
+
+	clock.Set(Epoch)
+	assert.Equal(t, Epoch, clock.Now())
+}
+
+func TestClock_After(t *testing.T) {
+	clock := NewClock(Epoch)
+	late := clock.After(time.Hour)
+	soon := clock.After(time.Minute)
+
+	select {
+	case <-clock.After(0):
+	default:
+		t.Fatal("a zero duration fires immediately")
+	}
+
+	clock.Advance(59 * time.Second)
+	assert.Empty(t, soon)
+
+	clock.Advance(time.Second)
+	assert.Equal(t, Epoch.Add(time.Minute), <-soon)
+	assert.Empty(t, late)
+
+	clock.Set(Epoch)
+	assert.Empty(t, late, "moving backwards does not fire timers")
+
+	clock.Advance(2 * time.Hour)
+	assert.Equal(t, Epoch.Add(2*time.Hour), <-late)
+}
@@ -0,0 +1,239 @@
+// Package coretest provides helpers for testing services built on the Core
+// framework. It assembles a *core.Core with stub config, display and medium
+// services, records the IPC messages the Core dispatches, supplies a
+// deterministic clock and a fake Wails application for the display service.
+//
+// Example:
+//
+//	h := coretest.NewBuilder().
+//		WithConfig("workspaceDir", t.TempDir()).
+//		WithService("workspace", workspace.Register).
+//		Build(t)
+//	h.Start()
+//
+//	h.IPC.AssertEmitted(t, core.ActionServiceStartup{})
+package coretest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/host-uk/core/pkg/core"
+	"github.com/host-uk/core/pkg/io"
+)
+
+// Epoch is the time a Harness clock starts at unless WithClock is used.
+var Epoch = time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// Harness is a Core assembled for a test, together with the stubs it was
+// built with.
+type Harness struct {
+	// Core is the Core under test.
+	Core *core.Core
+	// Config is the stub registered as the "config" service.
+	Config *Config
+	// Display is the stub registered as the "display" service.
+	Display *Display
+	// Medium is the in-memory medium registered as the "medium" service.
+	Medium *io.MockMedium
+	// IPC records every message dispatched through Core.
+	IPC *Recorder
+	// Clock is a deterministic clock for services that accept one.
+	Clock *Clock
+
+	t       testing.TB
+	started bool
+}
+
+// Start starts the Core's services, failing the test on error. The services
+// are stopped when the test finishes.
+func (h *Harness) Start() {
+	h.t.Helper()
+	if err := h.Core.Start(context.Background()); err != nil {
+		h.t.Fatalf("coretest: failed to start core: %v", err)
+	}
+	if !h.started {
+		h.started = true
+		h.t.Cleanup(func() {
+			if err := h.Core.Stop(context.Background()); err != nil {
+				h.t.Errorf("coretest: failed to stop core: %v", err)
+			}
+		})
+	}
+}
+
+// namedService is a service factory and the name to register it under.
+type namedService struct {
+	name    string
+	factory func(*core.Core) (any, error)
+}
+
+// Builder assembles a Harness. The zero value is not usable; create one with
+// NewBuilder.
+type Builder struct {
+	config   map[string]any
+	services []namedService
+	options  []core.Option
+	start    time.Time
+}
+
+// NewBuilder creates a Builder with an empty config and a clock set to Epoch.
+func NewBuilder() *Builder {
+	return &Builder{config: make(map[string]any), start: Epoch}
+}
+
+// WithConfig sets a value in the stub config service.
+func (b *Builder) WithConfig(key string, value any) *Builder {
+	b.config[key] = value
+	return b
+}
+
+// WithService registers a service under the given name, after the stubs.
+func (b *Builder) WithService(name string, factory func(*core.Core) (any, error)) *Builder {
+	b.services = append(b.services, namedService{name: name, factory: factory})
+	return b
+}
+
+// WithOption passes options through to core.New, for example a Contract or
+// core.WithServiceLock.
+func (b *Builder) WithOption(opts ...core.Option) *Builder {
+	b.options = append(b.options, opts...)
+	return b
+}
+
+// WithClock sets the time the harness clock starts at.
+func (b *Builder) WithClock(start time.Time) *Builder {
+	b.start = start
+	return b
+}
+
+// Build creates the Core, failing the test if any service cannot be created.
+func (b *Builder) Build(t testing.TB) *Harness {
+	t.Helper()
+	h := &Harness{
+		Config:  NewConfig(b.config),
+		Display: &Display{},
+		Medium:  io.NewMockMedium(),
+		IPC:     NewRecorder(),
+		Clock:   NewClock(b.start),
+		t:       t,
+	}
+
+	opts := []core.Option{
+		core.WithName("config", func(*core.Core) (any, error) { return h.Config, nil }),
+		core.WithName("display", func(*core.Core) (any, error) { return h.Display, nil }),
+		core.WithName("medium", func(*core.Core) (any, error) { return h.Medium, nil }),
+	}
+	for _, svc := range b.services {
+		opts = append(opts, core.WithName(svc.name, svc.factory))
+	}
+	opts = append(opts, b.options...)
+
+	c, err := core.New(opts...)
+	if err != nil {
+		t.Fatalf("coretest: failed to build core: %v", err)
+	}
+	c.Use(h.IPC.Intercept)
+	h.Core = c
+	return h
+}
+
+// Config is an in-memory implementation of core.Config.
+type Config struct {
+	mu     sync.RWMutex
+	values map[string]any
+}
+
+// NewConfig creates a Config holding a copy of values.
+func NewConfig(values map[string]any) *Config {
+	c := &Config{values: make(map[string]any, len(values))}
+	for k, v := range values {
+		c.values[k] = v
+	}
+	return c
+}
+
+// Get stores the value for key in out, which must be a non-nil pointer.
+// Values of a different type are converted through JSON, so a float64 can be
+// read into an int.
+func (c *Config) Get(key string, out any) error {
+	c.mu.RLock()
+	value, ok := c.values[key]
+	c.mu.RUnlock()
+	if !ok {
+		return core.E("coretest.Config.Get", fmt.Sprintf("key '%s' not found in config", key), nil,
+			core.WithKind(core.KindNotFound), core.WithCode("config.key_not_found"), core.WithMeta("key", key))
+	}
+
+	target := reflect.ValueOf(out)
+	if target.Kind() != reflect.Pointer || target.IsNil() {
+		return core.E("coretest.Config.Get", "output argument must be a non-nil pointer", nil, core.WithKind(core.KindInvalid))
+	}
+	v := reflect.ValueOf(value)
+	if !v.IsValid() {
+		target.Elem().SetZero()
+		return nil
+	}
+	if v.Type().AssignableTo(target.Elem().Type()) {
+		target.Elem().Set(v)
+		return nil
+	}
+
+	data, err := json.Marshal(value)
+	if err == nil {
+		err = json.Unmarshal(data, out)
+	}
+	if err != nil {
+		return core.E("coretest.Config.Get", fmt.Sprintf("cannot read key '%s' into %s", key, target.Elem().Type()), err,
+			core.WithKind(core.KindInvalid), core.WithCode("config.type_mismatch"))
+	}
+	return nil
+}
+
+// Set stores a value for key.
+func (c *Config) Set(key string, v any) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.values[key] = v
+	return nil
+}
+
+// Values returns a copy of all stored values.
+func (c *Config) Values() map[string]any {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	out := make(map[string]any, len(c.values))
+	for k, v := range c.values {
+		out[k] = v
+	}
+	return out
+}
+
+// Display is an implementation of core.Display that records the windows it
+// is asked to open.
+type Display struct {
+	mu      sync.Mutex
+	windows [][]core.WindowOption
+	// Err, if set, is returned by OpenWindow.
+	Err error
+}
+
+// OpenWindow records the options and returns d.Err.
+func (d *Display) OpenWindow(opts ...core.WindowOption) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.windows = append(d.windows, opts)
+	return d.Err
+}
+
+// Windows returns the options of every OpenWindow call, in order.
+func (d *Display) Windows() [][]core.WindowOption {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([][]core.WindowOption(nil), d.windows...)
+}
@@ -0,0 +1,133 @@
+package coretest
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/host-uk/core/pkg/core"
+	"github.com/stretchr/testify/assert"
+)
+
+type greeting struct{ Name string }
+
+// greeter announces itself on startup and greets on request.
+type greeter struct {
+	*core.ServiceRuntime[struct{}]
+	lang string
+}
+
+func (g *greeter) OnStartup(ctx context.Context) error {
+	if err := g.Config().Get("lang", &g.lang); err != nil {
+		return err
+	}
+	return g.Core().ACTION(greeting{Name: "startup"})
+}
+
+func (g *greeter) Greet(name string) error {
+	return g.Core().ACTIONAsync(context.Background(), greeting{Name: name})
+}
+
+func newGreeter(c *core.Core) (any, error) {
+	return &greeter{ServiceRuntime: core.NewServiceRuntime(c, struct{}{})}, nil
+}
+
+func TestBuilder_Good(t *testing.T) {
+	h := NewBuilder().
+		WithConfig("lang", "en").
+		WithService("greeter", newGreeter).
+		Build(t)
+	h.Start()
+
+	g := h.Core.Service("greeter").(*greeter)
+	assert.Equal(t, "en", g.lang)
+	assert.Same(t, h.Config, h.Core.Config())
+	assert.Same(t, h.Display, h.Core.Display())
+	assert.Same(t, h.Medium, h.Core.Service("medium"))
+	assert.Equal(t, Epoch, h.Clock.Now())
+
+	assert.NoError(t, g.Greet("ada"))
+	h.IPC.AssertEmitted(t, core.ActionServiceStartup{})
+	h.IPC.AssertEmitted(t, greeting{Name: "startup"})
+	h.IPC.AssertEmitted(t, greeting{Name: "ada"})
+	h.IPC.AssertNotEmitted(t, greeting{Name: "grace"})
+	assert.Equal(t, []greeting{{Name: "startup"}, {Name: "ada"}}, Emitted[greeting](h.IPC))
+
+	assert.NoError(t, h.Core.Display().OpenWindow())
+	assert.Len(t, h.Display.Windows(), 1)
+}
+
+func TestBuilder_Bad(t *testing.T) {
+	h := NewBuilder().WithService("greeter", newGreeter).Build(t)
+	err := h.Core.Start(context.Background())
+	assert.ErrorContains(t, err, "key 'lang' not found in config")
+}
+
+func TestBuilder_WithOption(t *testing.T) {
+	h := NewBuilder().WithOption(core.WithServiceLock()).Build(t)
+	assert.Error(t, h.Core.RegisterService("late", &greeter{}))
+}
+
+func TestConfig_Get(t *testing.T) {
+	cfg := NewConfig(map[string]any{"name": "core", "width": float64(800), "tags": []any{"a", "b"}})
+
+	var name string
+	assert.NoError(t, cfg.Get("name", &name))
+	assert.Equal(t, "core", name)
+
+	var width int
+	assert.NoError(t, cfg.Get("width", &width))
+	assert.Equal(t, 800, width)
+
+	var tags []string
+	assert.NoError(t, cfg.Get("tags", &tags))
+	assert.Equal(t, []string{"a", "b"}, tags)
+
+	err := cfg.Get("missing", &name)
+	assert.True(t, core.Is(err, core.KindNotFound))
+	assert.Equal(t, "config.key_not_found", core.CodeOf(err))
+
+	err = cfg.Get("name", &width)
+	assert.Equal(t, "config.type_mismatch", core.CodeOf(err))
+
+	assert.True(t, core.Is(cfg.Get("name", name), core.KindInvalid))
+
+	assert.NoError(t, cfg.Set("name", "other"))
+	assert.Equal(t, "other", cfg.Values()["name"])
+}
+
+// failures captures test failures so assertion helpers can be tested.
+type failures struct {
+	testing.TB
+	messages []string
+}
+
+func (f *failures) Helper() {}
+
+func (f *failures) Errorf(format string, args ...any) {
+	f.messages = append(f.messages, fmt.Sprintf(format, args...))
+}
+
+func TestRecorder_Bad(t *testing.T) {
+	c, err := core.New()
+	assert.NoError(t, err)
+	rec := NewRecorder()
+	c.Use(rec.Intercept)
+	c.Use(func(inv core.Invocation, next func() error) error {
+		return assert.AnError
+	})
+
+	assert.Error(t, c.ACTION(greeting{Name: "vetoed"}))
+	assert.Equal(t, []core.Message{greeting{Name: "vetoed"}}, rec.Messages(), "vetoed messages are recorded")
+
+	f := &failures{}
+	assert.False(t, rec.AssertEmitted(f, greeting{Name: "ada"}))
+	assert.False(t, rec.AssertNotEmitted(f, greeting{Name: "vetoed"}))
+	assert.Len(t, f.messages, 2)
+	assert.Contains(t, f.messages[0], "coretest.greeting")
+
+	rec.Reset()
+	assert.Empty(t, rec.Messages())
+	assert.False(t, rec.AssertEmitted(f, greeting{Name: "vetoed"}))
+	assert.Contains(t, f.messages[2], "emitted: none")
+}
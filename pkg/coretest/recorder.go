@@ -0,0 +1,101 @@
+package coretest
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/host-uk/core/pkg/core"
+)
+
+// Recorder is an interceptor that keeps every message dispatched through a
+// Core with ACTION, ACTIONContext or ACTIONAsync, including messages vetoed by
+// other interceptors. A Harness installs one as h.IPC; to use it with a Core
+// built by hand, add it with Use:
+//
+//	rec := coretest.NewRecorder()
+//	c.Use(rec.Intercept)
+type Recorder struct {
+	mu       sync.Mutex
+	messages []core.Message
+}
+
+// NewRecorder creates an empty Recorder.
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+// Intercept implements core.Interceptor.
+func (r *Recorder) Intercept(inv core.Invocation, next func() error) error {
+	if inv.Stage == core.StageDispatch {
+		r.mu.Lock()
+		r.messages = append(r.messages, inv.Message)
+		r.mu.Unlock()
+	}
+	return next()
+}
+
+// Messages returns the recorded messages, oldest first.
+func (r *Recorder) Messages() []core.Message {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]core.Message(nil), r.messages...)
+}
+
+// Reset discards all recorded messages.
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.messages = nil
+}
+
+// AssertEmitted fails the test unless a message equal to msg was recorded.
+func (r *Recorder) AssertEmitted(t testing.TB, msg core.Message) bool {
+	t.Helper()
+	for _, m := range r.Messages() {
+		if reflect.DeepEqual(m, msg) {
+			return true
+		}
+	}
+	t.Errorf("coretest: message %#v was not emitted; emitted: %s", msg, r.describe())
+	return false
+}
+
+// AssertNotEmitted fails the test if a message equal to msg was recorded.
+func (r *Recorder) AssertNotEmitted(t testing.TB, msg core.Message) bool {
+	t.Helper()
+	for _, m := range r.Messages() {
+		if reflect.DeepEqual(m, msg) {
+			t.Errorf("coretest: message %#v was emitted", msg)
+			return false
+		}
+	}
+	return true
+}
+
+// describe lists the types of the recorded messages for failure output.
+func (r *Recorder) describe() string {
+	messages := r.Messages()
+	if len(messages) == 0 {
+		return "none"
+	}
+	types := make([]string, len(messages))
+	for i, m := range messages {
+		types[i] = fmt.Sprintf("%T", m)
+	}
+	return fmt.Sprint(types)
+}
+
+// Emitted returns the recorded messages of type T, oldest first.
+//
+//	opened := coretest.Emitted[display.ActionOpenWindow](h.IPC)
+func Emitted[T any](r *Recorder) []T {
+	var out []T
+	for _, m := range r.Messages() {
+		if v, ok := m.(T); ok {
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -18,9 +18,7 @@ func (s *Service) HandleIPCEvents(c *core.Core, msg core.Message) error {
 		// Crypt is stateless, no startup needed.
 		return nil
 	default:
-		if c.App != nil && c.App.Logger != nil {
-			c.App.Logger.Debug("Crypt: Unhandled message type", "type", fmt.Sprintf("%T", msg))
-		}
+		c.Logger("crypt").Debug("Unhandled message type", "type", fmt.Sprintf("%T", msg))
 	}
 	return nil
 }
@@ -75,6 +75,22 @@ func TestEncryptToStringAndDecrypt(t *testing.T) {
 	assert.Equal(t, originalMessage, decrypted)
 }
 
+// TestDecryptPGPFromString decrypts with a private key held in memory.
+func TestDecryptPGPFromString(t *testing.T) {
+	keyPair, err := CreateKeyPair("test-user", "")
+	assert.NoError(t, err)
+
+	encrypted, err := EncryptPGPToString(keyPair.PublicKey, "in-memory secret")
+	assert.NoError(t, err)
+
+	decrypted, err := DecryptPGPFromString(keyPair.PrivateKey, encrypted)
+	assert.NoError(t, err)
+	assert.Equal(t, "in-memory secret", decrypted)
+
+	_, err = DecryptPGPFromString(keyPair.PrivateKey, "not a PGP message")
+	assert.ErrorContains(t, err, "failed to read PGP message")
+}
+
 // TestCreateKeyPair tests key pair generation.
 func TestCreateKeyPair(t *testing.T) {
 	t.Run("creates valid key pair", func(t *testing.T) {
@@ -121,3 +121,13 @@ func EncryptPGPToString(recipientKey, data string) (string, error) {
 	}
 	return string(ciphertext), nil
 }
+
+// DecryptPGPFromString is a convenience function that decrypts a message with
+// an armored private key held in memory rather than in a file.
+func DecryptPGPFromString(privateKey, message string) (string, error) {
+	plaintext, err := service.DecryptPGP([]byte(privateKey), []byte(message))
+	if err != nil {
+		return "", fmt.Errorf("failed to read PGP message: %w", err)
+	}
+	return string(plaintext), nil
+}
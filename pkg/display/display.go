@@ -3,6 +3,7 @@ package display
 import (
 	"context"
 	"fmt"
+	"sync"
 
 	"github.com/host-uk/core/pkg/core"
 	"github.com/wailsapp/wails/v3/pkg/application"
@@ -24,6 +25,10 @@ type Service struct {
 	layouts      *LayoutManager
 	notifier     *notifications.NotificationService
 	events       *WSEventManager
+
+	mu           sync.RWMutex // guards defaultRoute and theme
+	defaultRoute string
+	theme        string
 }
 
 // newDisplayService contains the common logic for initializing a Service struct.
@@ -79,17 +84,29 @@ func (s *Service) ServiceStartup(ctx context.Context, options application.Servic
 //		log.Fatal(err)
 //	}
 func (s *Service) Startup(ctx context.Context) error {
-	s.app = newWailsApp(application.Get())
+	if s.app == nil {
+		s.app = newWailsApp(application.Get())
+	}
 	s.windowStates = NewWindowStateManager()
 	s.layouts = NewLayoutManager()
-	s.events = NewWSEventManager(s)
-	s.events.SetupWindowEventListeners()
+	events := NewWSEventManager(s)
+	s.mu.Lock()
+	s.events = events
+	s.mu.Unlock()
+	events.SetupWindowEventListeners()
 	s.app.Logger().Info("Display service started")
 	s.buildMenu()
 	s.systemTray()
 	return s.OpenWindow()
 }
 
+// SetApp replaces the Wails application the service drives. It is intended
+// for tests, which can pass a coretest.FakeApp; Startup keeps an App set here
+// instead of using the running Wails application.
+func (s *Service) SetApp(app App) {
+	s.app = app
+}
+
 // handleOpenWindowAction processes a message to configure and create a new window
 // using the specified name and options.
 func (s *Service) handleOpenWindowAction(msg map[string]any) error {
@@ -169,6 +186,12 @@ func (s *Service) ShowEnvironmentDialog() {
 //		log.Fatal(err)
 //	}
 func (s *Service) OpenWindow(opts ...WindowOption) error {
+	s.mu.RLock()
+	route := s.defaultRoute
+	s.mu.RUnlock()
+	if route != "" {
+		opts = append([]WindowOption{WindowURL(route)}, opts...)
+	}
 	wailsOpts := buildWailsWindowOptions(opts...)
 
 	// Apply saved window state (position, size)
@@ -186,6 +209,21 @@ func (s *Service) OpenWindow(opts ...WindowOption) error {
 	return nil
 }
 
+// SetDefaultRoute sets the route that windows opened by OpenWindow without a
+// URL start at. Clients of the event manager are sent a route.change event,
+// so open windows can follow.
+//
+//	displayService.SetDefaultRoute("/dashboard")
+func (s *Service) SetDefaultRoute(route string) {
+	s.mu.Lock()
+	s.defaultRoute = route
+	events := s.events
+	s.mu.Unlock()
+	if events != nil {
+		events.Emit(Event{Type: EventRouteChange, Data: map[string]any{"route": route}})
+	}
+}
+
 // trackWindowState sets up event listeners to track window position/size changes.
 func (s *Service) trackWindowState(name string, window *application.WebviewWindow) {
 	// Register for window events
@@ -290,6 +290,32 @@ func TestOpenWindow(t *testing.T) {
 	})
 }
 
+func TestSetDefaultRoute(t *testing.T) {
+	service, mock := newServiceWithMockApp(t)
+	service.SetDefaultRoute("/dashboard")
+
+	require.NoError(t, service.OpenWindow())
+	require.NoError(t, service.OpenWindow(WindowName("docs"), WindowURL("/docs")))
+
+	require.Len(t, mock.windowManager.createdWindows, 2)
+	assert.Equal(t, "/dashboard", mock.windowManager.createdWindows[0].URL)
+	assert.Equal(t, "/docs", mock.windowManager.createdWindows[1].URL, "an explicit URL wins")
+}
+
+func TestSetTheme(t *testing.T) {
+	service, err := New()
+	require.NoError(t, err)
+
+	service.SetTheme("dark")
+	assert.Equal(t, ThemeInfo{IsDark: true, Theme: "dark"}, service.GetTheme())
+
+	service.SetTheme("light")
+	assert.Equal(t, ThemeInfo{Theme: "light"}, service.GetTheme())
+
+	service.SetTheme("system")
+	assert.Equal(t, service.GetSystemTheme(), service.GetTheme())
+}
+
 func TestNewWithStruct(t *testing.T) {
 	t.Run("creates window from struct", func(t *testing.T) {
 		service, mock := newServiceWithMockApp(t)
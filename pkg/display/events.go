@@ -23,6 +23,7 @@ const (
 	EventWindowClose  EventType = "window.close"
 	EventWindowCreate EventType = "window.create"
 	EventThemeChange  EventType = "theme.change"
+	EventRouteChange  EventType = "route.change"
 	EventScreenChange EventType = "screen.change"
 )
 
@@ -311,14 +312,21 @@ func (em *WSEventManager) SetupWindowEventListeners() {
 
 	// Listen for theme changes
 	app.Event.OnApplicationEvent(events.Common.ThemeChanged, func(event *application.ApplicationEvent) {
-		isDark := app.Env.IsDarkMode()
-		em.Emit(Event{
-			Type: EventThemeChange,
-			Data: map[string]any{
-				"isDark": isDark,
-				"theme":  map[bool]string{true: "dark", false: "light"}[isDark],
-			},
-		})
+		if theme := em.display.GetTheme(); theme.System {
+			em.emitTheme(theme)
+		}
+	})
+}
+
+// emitTheme sends a theme.change event for theme.
+func (em *WSEventManager) emitTheme(theme ThemeInfo) {
+	em.Emit(Event{
+		Type: EventThemeChange,
+		Data: map[string]any{
+			"isDark": theme.IsDark,
+			"theme":  theme.Theme,
+			"system": theme.System,
+		},
 	})
 }
 
@@ -11,8 +11,35 @@ type ThemeInfo struct {
 	System bool   `json:"system"` // Whether following system theme
 }
 
-// GetTheme returns the current application theme.
+// SetTheme sets the theme the user chose: "dark", "light", or "system" (or
+// "") to follow the system theme. Clients of the event manager are sent a
+// theme.change event.
+//
+//	displayService.SetTheme("dark")
+func (s *Service) SetTheme(theme string) {
+	s.mu.Lock()
+	s.theme = theme
+	events := s.events
+	s.mu.Unlock()
+	if events != nil {
+		events.emitTheme(s.GetTheme())
+	}
+}
+
+// GetTheme returns the current application theme: the one set by SetTheme,
+// or the system's.
 func (s *Service) GetTheme() ThemeInfo {
+	s.mu.RLock()
+	theme := s.theme
+	s.mu.RUnlock()
+	if theme == "dark" || theme == "light" {
+		return ThemeInfo{IsDark: theme == "dark", Theme: theme}
+	}
+	return s.GetSystemTheme()
+}
+
+// GetSystemTheme returns the system's theme preference.
+func (s *Service) GetSystemTheme() ThemeInfo {
 	app := application.Get()
 	if app == nil {
 		return ThemeInfo{Theme: "unknown"}
@@ -30,9 +57,3 @@ func (s *Service) GetTheme() ThemeInfo {
 		System: true, // Wails follows system theme by default
 	}
 }
-
-// GetSystemTheme returns the system's theme preference.
-// This is the same as GetTheme since Wails follows the system theme.
-func (s *Service) GetSystemTheme() ThemeInfo {
-	return s.GetTheme()
-}
@@ -1,11 +1,11 @@
 package ide
 
 import (
-	"os"
 	"path/filepath"
 	"strings"
 
 	"github.com/host-uk/core/pkg/core"
+	"github.com/host-uk/core/pkg/io"
 )
 
 // Options holds configuration for the IDE service.
@@ -18,6 +18,7 @@ type Options struct {
 type Service struct {
 	*core.ServiceRuntime[Options]
 	config Options
+	medium io.Medium
 }
 
 // FileInfo represents information about a file for the editor.
@@ -53,6 +54,14 @@ func (s *Service) ServiceName() string {
 	return "github.com/host-uk/core/ide"
 }
 
+// SetMedium sets the storage backend used for file operations, for example a
+// sandboxed local.Medium rooted at a project. Paths are then interpreted by
+// the medium. By default files are read from the local filesystem, with
+// relative paths resolved against the working directory.
+func (s *Service) SetMedium(m io.Medium) {
+	s.medium = m
+}
+
 // NewFile creates a new untitled file with the specified language.
 func (s *Service) NewFile(language string) FileInfo {
 	if language == "" {
@@ -67,9 +76,9 @@ func (s *Service) NewFile(language string) FileInfo {
 	}
 }
 
-// OpenFile reads a file from disk and returns its content with language detection.
+// OpenFile reads a file from the medium and returns its content with language detection.
 func (s *Service) OpenFile(path string) (FileInfo, error) {
-	content, err := os.ReadFile(path)
+	content, err := s.ReadFile(path)
 	if err != nil {
 		return FileInfo{}, err
 	}
@@ -77,7 +86,7 @@ func (s *Service) OpenFile(path string) (FileInfo, error) {
 	return FileInfo{
 		Path:     path,
 		Name:     filepath.Base(path),
-		Content:  string(content),
+		Content:  content,
 		Language: detectLanguage(path),
 		IsNew:    false,
 	}, nil
@@ -85,25 +94,20 @@ func (s *Service) OpenFile(path string) (FileInfo, error) {
 
 // SaveFile saves content to the specified path.
 func (s *Service) SaveFile(path string, content string) error {
-	dir := filepath.Dir(path)
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return err
-	}
-	return os.WriteFile(path, []byte(content), 0644)
+	m, p := io.Resolve(s.medium, path)
+	return m.Write(p, content)
 }
 
 // ReadFile reads content from a file without additional metadata.
 func (s *Service) ReadFile(path string) (string, error) {
-	content, err := os.ReadFile(path)
-	if err != nil {
-		return "", err
-	}
-	return string(content), nil
+	m, p := io.Resolve(s.medium, path)
+	return m.Read(p)
 }
 
 // ListDirectory returns a list of files and directories in the given path.
 func (s *Service) ListDirectory(path string) ([]DirectoryEntry, error) {
-	entries, err := os.ReadDir(path)
+	m, p := io.Resolve(s.medium, path)
+	entries, err := m.List(p)
 	if err != nil {
 		return nil, err
 	}
@@ -251,21 +255,25 @@ type LanguageInfo struct {
 
 // FileExists checks if a file exists at the given path.
 func (s *Service) FileExists(path string) bool {
-	_, err := os.Stat(path)
-	return err == nil
+	m, p := io.Resolve(s.medium, path)
+	return m.Exists(p)
 }
 
 // CreateDirectory creates a new directory at the given path.
 func (s *Service) CreateDirectory(path string) error {
-	return os.MkdirAll(path, 0755)
+	m, p := io.Resolve(s.medium, path)
+	return m.EnsureDir(p)
 }
 
 // DeleteFile removes a file at the given path.
 func (s *Service) DeleteFile(path string) error {
-	return os.Remove(path)
+	m, p := io.Resolve(s.medium, path)
+	return m.Delete(p)
 }
 
 // RenameFile renames/moves a file from oldPath to newPath.
 func (s *Service) RenameFile(oldPath, newPath string) error {
-	return os.Rename(oldPath, newPath)
+	m, from := io.Resolve(s.medium, oldPath)
+	_, to := io.Resolve(s.medium, newPath)
+	return m.Rename(from, to)
 }
@@ -1,6 +1,11 @@
 package io
 
 import (
+	"errors"
+	goio "io"
+	"io/fs"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -75,6 +80,121 @@ func TestMockMedium_FileSet(t *testing.T) {
 	assert.Equal(t, "content", m.Files["test.txt"])
 }
 
+func TestMockMedium_OpenCreate(t *testing.T) {
+	m := NewMockMedium()
+
+	w, err := m.Create("dir/stream.txt")
+	assert.NoError(t, err)
+	_, err = goio.WriteString(w, "streamed")
+	assert.NoError(t, err)
+	assert.False(t, m.IsFile("dir/stream.txt"), "content is stored on close")
+	assert.NoError(t, w.Close())
+	assert.Equal(t, "streamed", m.Files["dir/stream.txt"])
+
+	f, err := m.Open("dir/stream.txt")
+	assert.NoError(t, err)
+	defer f.Close()
+	data, err := goio.ReadAll(f)
+	assert.NoError(t, err)
+	assert.Equal(t, "streamed", string(data))
+	info, err := f.Stat()
+	assert.NoError(t, err)
+	assert.Equal(t, "stream.txt", info.Name())
+	assert.Equal(t, int64(8), info.Size())
+
+	_, err = m.Open("missing.txt")
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+	_, err = m.Create("dir")
+	assert.ErrorIs(t, err, fs.ErrExist)
+}
+
+func TestMockMedium_ListStat(t *testing.T) {
+	m := NewMockMedium()
+	m.Files["/ws/b.txt"] = "bb"
+	m.Files["/ws/sub/c.txt"] = "c"
+	m.Dirs["/ws/empty"] = true
+
+	entries, err := m.List("/ws")
+	assert.NoError(t, err)
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	assert.Equal(t, []string{"b.txt", "empty", "sub"}, names)
+	assert.False(t, entries[0].IsDir())
+	assert.True(t, entries[1].IsDir())
+
+	info, err := m.Stat("/ws/b.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), info.Size())
+	assert.False(t, info.IsDir())
+
+	assert.True(t, m.IsDir("/ws/sub"), "parents of files are directories")
+	assert.True(t, m.Exists("/ws/sub/c.txt"))
+	assert.False(t, m.Exists("/ws/nope"))
+
+	_, err = m.Stat("/ws/nope")
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+	_, err = m.List("/ws/b.txt")
+	assert.ErrorIs(t, err, fs.ErrInvalid)
+}
+
+func TestMockMedium_DeleteRename(t *testing.T) {
+	m := NewMockMedium()
+	m.Files["a/one.txt"] = "1"
+	m.Files["a/deep/two.txt"] = "2"
+	m.Dirs["a/deep/empty"] = true
+
+	assert.ErrorIs(t, m.Delete("a/deep"), fs.ErrExist, "non-empty directories are kept")
+	assert.NoError(t, m.Delete("a/deep/empty"))
+	assert.ErrorIs(t, m.Delete("a/deep/empty"), fs.ErrNotExist)
+
+	assert.NoError(t, m.Rename("a/one.txt", "b/one.txt"))
+	assert.Equal(t, "1", m.Files["b/one.txt"])
+	assert.NoError(t, m.Rename("a", "c"))
+	assert.Equal(t, "2", m.Files["c/deep/two.txt"])
+	assert.False(t, m.Exists("a"))
+	assert.ErrorIs(t, m.Rename("a", "d"), fs.ErrNotExist)
+
+	assert.NoError(t, m.DeleteAll("c"))
+	assert.NoError(t, m.DeleteAll("c"))
+	assert.Equal(t, map[string]string{"b/one.txt": "1"}, m.Files)
+}
+
+func TestWalkDir(t *testing.T) {
+	m := NewMockMedium()
+	m.Files["root/a.txt"] = "a"
+	m.Files["root/skip/x.txt"] = "x"
+	m.Files["root/sub/b.txt"] = "b"
+
+	var visited []string
+	err := m.WalkDir("root", func(path string, d fs.DirEntry, err error) error {
+		assert.NoError(t, err)
+		visited = append(visited, path)
+		if d.IsDir() && d.Name() == "skip" {
+			return fs.SkipDir
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"root", "root/a.txt", "root/skip", "root/sub", "root/sub/b.txt"}, visited)
+
+	stop := errors.New("stop")
+	err = WalkDir(m, "root", func(path string, d fs.DirEntry, err error) error {
+		if path == "root/sub" {
+			return stop
+		}
+		return nil
+	})
+	assert.ErrorIs(t, err, stop)
+
+	err = WalkDir(m, "missing", func(path string, d fs.DirEntry, err error) error {
+		assert.Nil(t, d)
+		return err
+	})
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+}
+
 // --- Wrapper Function Tests ---
 
 func TestRead(t *testing.T) {
@@ -144,3 +264,16 @@ func TestLocalGlobal(t *testing.T) {
 	var m Medium = Local
 	assert.NotNil(t, m)
 }
+
+func TestResolve(t *testing.T) {
+	mock := NewMockMedium()
+	m, path := Resolve(mock, "notes.txt")
+	assert.Same(t, mock, m)
+	assert.Equal(t, "notes.txt", path)
+
+	wd, err := os.Getwd()
+	assert.NoError(t, err)
+	m, path = Resolve(nil, "notes.txt")
+	assert.Equal(t, Local, m)
+	assert.Equal(t, filepath.Join(wd, "notes.txt"), path)
+}
@@ -0,0 +1,241 @@
+package encrypted
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/host-uk/core/pkg/crypt/openpgp"
+)
+
+// Cipher encrypts and decrypts file contents.
+type Cipher interface {
+	Encrypt(plaintext []byte) ([]byte, error)
+	Decrypt(ciphertext []byte) ([]byte, error)
+}
+
+// ErrDecrypt is wrapped by errors for content that cannot be decrypted,
+// because the key or password is wrong or the content was not written by the
+// same kind of Cipher.
+var ErrDecrypt = errors.New("content cannot be decrypted")
+
+// KeySize is the length of a key for Symmetric, in bytes.
+const KeySize = 32
+
+// SaltSize is the length of the random salt Password stores with each file,
+// and the salt length to use with PasswordKey.
+const SaltSize = 16
+
+const (
+	// symmetricMagic prefixes content written by Symmetric.
+	symmetricMagic = "CENC1"
+	// passwordMagic prefixes content written by Password.
+	passwordMagic = "CENCP1"
+	// passwordIterations is the PBKDF2 iteration count used by Password.
+	passwordIterations = 600_000
+)
+
+// --- PGP ---
+
+// pgpCipher encrypts to a public key and decrypts with its private key.
+type pgpCipher struct {
+	publicKey  string
+	privateKey string
+}
+
+// PGP returns a Cipher that encrypts for an armored public key and decrypts
+// with the matching armored private key. Either key may be empty, giving a
+// cipher that can only decrypt or only encrypt.
+func PGP(publicKey, privateKey string) Cipher {
+	return &pgpCipher{publicKey: publicKey, privateKey: privateKey}
+}
+
+func (c *pgpCipher) Encrypt(plaintext []byte) ([]byte, error) {
+	if c.publicKey == "" {
+		return nil, errors.New("encrypted: no public key to encrypt with")
+	}
+	ciphertext, err := openpgp.EncryptPGPToString(c.publicKey, string(plaintext))
+	if err != nil {
+		return nil, err
+	}
+	return []byte(ciphertext), nil
+}
+
+func (c *pgpCipher) Decrypt(ciphertext []byte) ([]byte, error) {
+	if c.privateKey == "" {
+		return nil, errors.New("encrypted: no private key to decrypt with")
+	}
+	plaintext, err := openpgp.DecryptPGPFromString(c.privateKey, string(ciphertext))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDecrypt, err)
+	}
+	return []byte(plaintext), nil
+}
+
+// --- Symmetric ---
+
+// symmetricCipher is AES-256-GCM with a random nonce per message.
+type symmetricCipher struct {
+	aead cipher.AEAD
+}
+
+// Symmetric returns a Cipher using AES-256-GCM with the given KeySize-byte
+// key. It is much faster than PGP and suits large or frequently written
+// files. Use GenerateKey to create a key.
+func Symmetric(key []byte) (Cipher, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	return &symmetricCipher{aead: aead}, nil
+}
+
+// GenerateKey returns a random key for Symmetric.
+func GenerateKey() ([]byte, error) {
+	key := make([]byte, KeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+func (c *symmetricCipher) Encrypt(plaintext []byte) ([]byte, error) {
+	return seal(c.aead, []byte(symmetricMagic), plaintext)
+}
+
+func (c *symmetricCipher) Decrypt(ciphertext []byte) ([]byte, error) {
+	body, ok := bytes.CutPrefix(ciphertext, []byte(symmetricMagic))
+	if !ok {
+		return nil, fmt.Errorf("%w: not written by a symmetric cipher", ErrDecrypt)
+	}
+	return open(c.aead, []byte(symmetricMagic), body)
+}
+
+// --- Password ---
+
+// passwordCipher derives an AES-256-GCM key from a password and a random salt
+// stored with each message.
+type passwordCipher struct {
+	password string
+}
+
+// Password returns a Cipher that derives its key from password with PBKDF2.
+// Key derivation is deliberately slow, so it suits small secrets such as a
+// private key rather than general file content.
+func Password(password string) Cipher {
+	return &passwordCipher{password: password}
+}
+
+func (c *passwordCipher) Encrypt(plaintext []byte) ([]byte, error) {
+	salt := make([]byte, SaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	aead, err := c.aead(salt)
+	if err != nil {
+		return nil, err
+	}
+	header := append([]byte(passwordMagic), salt...)
+	return seal(aead, header, plaintext)
+}
+
+func (c *passwordCipher) Decrypt(ciphertext []byte) ([]byte, error) {
+	body, ok := bytes.CutPrefix(ciphertext, []byte(passwordMagic))
+	if !ok || len(body) < SaltSize {
+		return nil, fmt.Errorf("%w: not written by a password cipher", ErrDecrypt)
+	}
+	aead, err := c.aead(body[:SaltSize])
+	if err != nil {
+		return nil, err
+	}
+	return open(aead, ciphertext[:len(passwordMagic)+SaltSize], body[SaltSize:])
+}
+
+// aead derives the key for salt.
+func (c *passwordCipher) aead(salt []byte) (cipher.AEAD, error) {
+	key, err := PasswordKey(c.password, salt)
+	if err != nil {
+		return nil, err
+	}
+	return newAEAD(key)
+}
+
+// PasswordKey derives a key for Symmetric from password and salt with PBKDF2,
+// the way Password does. The same password and salt always give the same key.
+func PasswordKey(password string, salt []byte) ([]byte, error) {
+	return pbkdf2.Key(sha256.New, password, salt, passwordIterations, KeySize)
+}
+
+// IsPasswordEncrypted reports whether content was written by a Password
+// cipher.
+func IsPasswordEncrypted(content []byte) bool {
+	return bytes.HasPrefix(content, []byte(passwordMagic))
+}
+
+// --- Names ---
+
+// EncryptFor encrypts plaintext with c, bound to name, such as the path of a
+// file or the name of a secret. The result only decrypts with DecryptFor and
+// the same name, so someone who can write the stored ciphertexts but doesn't
+// have the key can't swap them between names.
+func EncryptFor(c Cipher, name string, plaintext []byte) ([]byte, error) {
+	bound := binary.AppendUvarint(nil, uint64(len(name)))
+	bound = append(append(bound, name...), plaintext...)
+	return c.Encrypt(bound)
+}
+
+// DecryptFor decrypts a ciphertext written by EncryptFor for name.
+func DecryptFor(c Cipher, name string, ciphertext []byte) ([]byte, error) {
+	bound, err := c.Decrypt(ciphertext)
+	if err != nil {
+		return nil, err
+	}
+	n, size := binary.Uvarint(bound)
+	if size <= 0 || n != uint64(len(name)) || !bytes.HasPrefix(bound[size:], []byte(name)) {
+		return nil, fmt.Errorf("%w: not written for %q", ErrDecrypt, name)
+	}
+	return bound[size+len(name):], nil
+}
+
+// --- AES-GCM helpers ---
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("encrypted: key must be %d bytes, got %d", KeySize, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal returns header, a random nonce and the sealed plaintext. The header is
+// authenticated as additional data.
+func seal(aead cipher.AEAD, header, plaintext []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	out := append(append([]byte(nil), header...), nonce...)
+	return aead.Seal(out, nonce, plaintext, header), nil
+}
+
+// open reverses seal. body is everything after the header.
+func open(aead cipher.AEAD, header, body []byte) ([]byte, error) {
+	if len(body) < aead.NonceSize() {
+		return nil, fmt.Errorf("%w: content is truncated", ErrDecrypt)
+	}
+	nonce, sealed := body[:aead.NonceSize()], body[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, sealed, header)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDecrypt, err)
+	}
+	return plaintext, nil
+}
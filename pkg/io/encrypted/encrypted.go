@@ -0,0 +1,285 @@
+// Package encrypted provides an io.Medium that encrypts file contents before
+// passing them to another medium and decrypts them when they are read back.
+//
+// Only file contents are encrypted. Paths, directory structure and
+// modification times are visible to anyone with access to the inner medium.
+// Contents are bound to their path, so a file moved or copied in the inner
+// medium, rather than with Rename, no longer decrypts.
+package encrypted
+
+import (
+	"bytes"
+	goio "io"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/host-uk/core/pkg/io"
+)
+
+// Option configures a Medium created by New.
+type Option func(*Medium)
+
+// WithBase binds the contents of files below dir to their path relative to
+// dir, rather than to their full path, so dir can be moved as a whole.
+func WithBase(dir string) Option {
+	return func(m *Medium) {
+		m.base = cleanName(dir)
+	}
+}
+
+// Medium wraps another io.Medium, encrypting on write and decrypting on read.
+type Medium struct {
+	inner  io.Medium
+	cipher Cipher
+	base   string
+}
+
+// Ensure Medium implements io.Medium.
+var _ io.Medium = (*Medium)(nil)
+
+// New creates a Medium that stores files in inner, encrypted with c.
+func New(inner io.Medium, c Cipher, opts ...Option) *Medium {
+	m := &Medium{inner: inner, cipher: c}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// Inner returns the medium the encrypted files are stored in.
+func (m *Medium) Inner() io.Medium {
+	return m.inner
+}
+
+// Read decrypts and returns the content of a file.
+func (m *Medium) Read(path string) (string, error) {
+	plaintext, err := m.read(path)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// Write encrypts content and saves it to a file, overwriting it if it exists.
+func (m *Medium) Write(path, content string) error {
+	ciphertext, err := EncryptFor(m.cipher, m.boundName(path), []byte(content))
+	if err != nil {
+		return &fs.PathError{Op: "encrypt", Path: path, Err: err}
+	}
+	return m.inner.Write(path, string(ciphertext))
+}
+
+// EnsureDir makes sure a directory exists in the inner medium.
+func (m *Medium) EnsureDir(path string) error {
+	return m.inner.EnsureDir(path)
+}
+
+// IsFile checks if a path exists and is a regular file.
+func (m *Medium) IsFile(path string) bool {
+	return m.inner.IsFile(path)
+}
+
+// FileGet is a convenience function that decrypts and reads a file.
+func (m *Medium) FileGet(path string) (string, error) {
+	return m.Read(path)
+}
+
+// FileSet is a convenience function that encrypts and writes a file.
+func (m *Medium) FileSet(path, content string) error {
+	return m.Write(path, content)
+}
+
+// Open decrypts a file and returns it for reading. The whole file is
+// decrypted before Open returns.
+func (m *Medium) Open(name string) (fs.File, error) {
+	if m.inner.IsDir(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	info, err := m.inner.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := m.read(name)
+	if err != nil {
+		return nil, err
+	}
+	return &file{
+		Reader: bytes.NewReader(plaintext),
+		info:   io.NewFileInfo(info.Name(), int64(len(plaintext)), info.Mode(), info.ModTime()),
+	}, nil
+}
+
+// Create returns a writer whose content is encrypted and stored when it is
+// closed. Nothing is written to the inner medium before then.
+func (m *Medium) Create(name string) (goio.WriteCloser, error) {
+	if m.inner.IsDir(name) {
+		return nil, &fs.PathError{Op: "create", Path: name, Err: fs.ErrExist}
+	}
+	return &writer{medium: m, path: name}, nil
+}
+
+// List returns the entries of a directory. The Info of each file entry
+// reports its decrypted size.
+func (m *Medium) List(name string) ([]fs.DirEntry, error) {
+	entries, err := m.inner.List(name)
+	if err != nil {
+		return nil, err
+	}
+	for i, e := range entries {
+		entries[i] = &dirEntry{DirEntry: e, medium: m, path: path.Join(name, e.Name())}
+	}
+	return entries, nil
+}
+
+// Stat returns information about a file or directory. For files, the size is
+// the decrypted size, so Stat has to decrypt the file.
+func (m *Medium) Stat(name string) (fs.FileInfo, error) {
+	info, err := m.inner.Stat(name)
+	if err != nil || info.IsDir() {
+		return info, err
+	}
+	plaintext, err := m.read(name)
+	if err != nil {
+		return nil, err
+	}
+	return io.NewFileInfo(info.Name(), int64(len(plaintext)), info.Mode(), info.ModTime()), nil
+}
+
+// Exists checks if a path exists, as either a file or a directory.
+func (m *Medium) Exists(path string) bool {
+	return m.inner.Exists(path)
+}
+
+// IsDir checks if a path exists and is a directory.
+func (m *Medium) IsDir(path string) bool {
+	return m.inner.IsDir(path)
+}
+
+// Delete removes a file or an empty directory.
+func (m *Medium) Delete(path string) error {
+	return m.inner.Delete(path)
+}
+
+// DeleteAll removes a path and everything below it.
+func (m *Medium) DeleteAll(path string) error {
+	return m.inner.DeleteAll(path)
+}
+
+// Rename moves a file or directory. As contents are bound to their path,
+// each file moved is decrypted and encrypted again for its new path.
+func (m *Medium) Rename(oldPath, newPath string) error {
+	if err := m.inner.Rename(oldPath, newPath); err != nil {
+		return err
+	}
+	if !m.inner.IsDir(newPath) {
+		return m.rebind(m.boundName(oldPath), newPath)
+	}
+	return m.inner.WalkDir(newPath, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel := strings.TrimPrefix(m.boundName(p), m.boundName(newPath))
+		return m.rebind(m.boundName(oldPath)+rel, p)
+	})
+}
+
+// rebind encrypts the file at p, which was encrypted for oldName, for p.
+func (m *Medium) rebind(oldName, p string) error {
+	ciphertext, err := m.inner.Read(p)
+	if err != nil {
+		return err
+	}
+	plaintext, err := DecryptFor(m.cipher, oldName, []byte(ciphertext))
+	if err != nil {
+		return &fs.PathError{Op: "decrypt", Path: p, Err: err}
+	}
+	return m.Write(p, string(plaintext))
+}
+
+// WalkDir walks the inner medium. As with List, the Info of file entries
+// reports the decrypted size.
+func (m *Medium) WalkDir(root string, fn fs.WalkDirFunc) error {
+	return m.inner.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if d != nil {
+			d = &dirEntry{DirEntry: d, medium: m, path: p}
+		}
+		return fn(p, d, err)
+	})
+}
+
+// read returns the decrypted content of a file.
+func (m *Medium) read(p string) ([]byte, error) {
+	ciphertext, err := m.inner.Read(p)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := DecryptFor(m.cipher, m.boundName(p), []byte(ciphertext))
+	if err != nil {
+		return nil, &fs.PathError{Op: "decrypt", Path: p, Err: err}
+	}
+	return plaintext, nil
+}
+
+// boundName returns the name the contents of the file at p are bound to.
+func (m *Medium) boundName(p string) string {
+	name := cleanName(p)
+	if rel, ok := strings.CutPrefix(name, m.base+"/"); ok && m.base != "" {
+		return rel
+	}
+	return name
+}
+
+// cleanName returns p the same way for every spelling of the path, such as
+// "a/b", "/a/b" and "a/./b".
+func cleanName(p string) string {
+	return strings.TrimPrefix(path.Clean("/"+filepath.ToSlash(p)), "/")
+}
+
+// file is a decrypted file opened from a Medium.
+type file struct {
+	*bytes.Reader
+	info fs.FileInfo
+}
+
+func (f *file) Stat() (fs.FileInfo, error) { return f.info, nil }
+func (f *file) Close() error               { return nil }
+
+// writer buffers a file created in a Medium until it is closed.
+type writer struct {
+	medium *Medium
+	path   string
+	buf    bytes.Buffer
+	closed bool
+}
+
+func (w *writer) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, fs.ErrClosed
+	}
+	return w.buf.Write(p)
+}
+
+func (w *writer) Close() error {
+	if w.closed {
+		return fs.ErrClosed
+	}
+	w.closed = true
+	return w.medium.Write(w.path, w.buf.String())
+}
+
+// dirEntry reports decrypted file information for an entry of the inner
+// medium. The file is only decrypted if Info is called.
+type dirEntry struct {
+	fs.DirEntry
+	medium *Medium
+	path   string
+}
+
+func (e *dirEntry) Info() (fs.FileInfo, error) {
+	if e.IsDir() {
+		return e.DirEntry.Info()
+	}
+	return e.medium.Stat(e.path)
+}
@@ -0,0 +1,218 @@
+package encrypted
+
+import (
+	"errors"
+	goio "io"
+	"io/fs"
+	"testing"
+
+	"github.com/host-uk/core/pkg/crypt/openpgp"
+	"github.com/host-uk/core/pkg/io"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newSymmetric(t *testing.T) Cipher {
+	t.Helper()
+	key, err := GenerateKey()
+	require.NoError(t, err)
+	c, err := Symmetric(key)
+	require.NoError(t, err)
+	return c
+}
+
+func TestMedium_Good(t *testing.T) {
+	inner := io.NewMockMedium()
+	m := New(inner, newSymmetric(t))
+
+	require.NoError(t, m.Write("notes/a.txt", "secret"))
+	assert.NotContains(t, inner.Files["notes/a.txt"], "secret")
+
+	content, err := m.Read("notes/a.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "secret", content)
+	assert.True(t, m.IsFile("notes/a.txt"))
+	assert.True(t, m.IsDir("notes"))
+
+	info, err := m.Stat("notes/a.txt")
+	require.NoError(t, err)
+	assert.Equal(t, int64(len("secret")), info.Size())
+
+	w, err := m.Create("notes/b.txt")
+	require.NoError(t, err)
+	_, err = w.Write([]byte("streamed"))
+	require.NoError(t, err)
+	assert.False(t, inner.Exists("notes/b.txt"), "nothing is stored before Close")
+	require.NoError(t, w.Close())
+
+	f, err := m.Open("notes/b.txt")
+	require.NoError(t, err)
+	data, err := goio.ReadAll(f)
+	require.NoError(t, err)
+	assert.Equal(t, "streamed", string(data))
+	require.NoError(t, f.Close())
+
+	entries, err := m.List("notes")
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	entryInfo, err := entries[1].Info()
+	require.NoError(t, err)
+	assert.Equal(t, int64(len("streamed")), entryInfo.Size())
+
+	var walked []string
+	err = m.WalkDir("notes", func(p string, d fs.DirEntry, err error) error {
+		require.NoError(t, err)
+		walked = append(walked, p)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"notes", "notes/a.txt", "notes/b.txt"}, walked)
+
+	require.NoError(t, m.Rename("notes/a.txt", "notes/c.txt"))
+	content, err = m.Read("notes/c.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "secret", content)
+
+	require.NoError(t, m.Delete("notes/c.txt"))
+	assert.False(t, m.Exists("notes/c.txt"))
+	assert.Same(t, inner, m.Inner())
+}
+
+func TestMedium_Bad(t *testing.T) {
+	inner := io.NewMockMedium()
+	m := New(inner, newSymmetric(t))
+
+	_, err := m.Read("missing.txt")
+	assert.True(t, errors.Is(err, fs.ErrNotExist))
+
+	// Written with another key.
+	require.NoError(t, New(inner, newSymmetric(t)).Write("other.txt", "secret"))
+	_, err = m.Read("other.txt")
+	var pathErr *fs.PathError
+	require.ErrorAs(t, err, &pathErr)
+	assert.Equal(t, "decrypt", pathErr.Op)
+	assert.True(t, errors.Is(err, ErrDecrypt))
+
+	// Written without encryption.
+	inner.Files["plain.txt"] = "plain"
+	_, err = m.Open("plain.txt")
+	assert.True(t, errors.Is(err, ErrDecrypt))
+
+	_, err = Symmetric([]byte("short"))
+	assert.Error(t, err)
+}
+
+func TestMedium_BoundToPath(t *testing.T) {
+	inner := io.NewMockMedium()
+	m := New(inner, newSymmetric(t))
+
+	require.NoError(t, m.Write("tokens/github", "ghp_abc123"))
+	require.NoError(t, m.Write("tokens/pool", "hunter2"))
+	assert.Equal(t, "tokens/github", m.boundName("/tokens/./github"), "every spelling of a path is bound the same")
+
+	// Swapped in the inner medium, without the key.
+	inner.Files["tokens/github"], inner.Files["tokens/pool"] = inner.Files["tokens/pool"], inner.Files["tokens/github"]
+	_, err := m.Read("tokens/github")
+	assert.True(t, errors.Is(err, ErrDecrypt))
+
+	t.Run("Rename a directory", func(t *testing.T) {
+		require.NoError(t, m.Write("notes/a.txt", "first"))
+		require.NoError(t, m.Write("notes/sub/b.txt", "second"))
+		require.NoError(t, m.Rename("notes", "archive"))
+
+		content, err := m.Read("archive/a.txt")
+		require.NoError(t, err)
+		assert.Equal(t, "first", content)
+		content, err = m.Read("archive/sub/b.txt")
+		require.NoError(t, err)
+		assert.Equal(t, "second", content)
+		assert.False(t, m.Exists("notes/a.txt"))
+	})
+
+	t.Run("WithBase", func(t *testing.T) {
+		c := newSymmetric(t)
+		require.NoError(t, New(inner, c, WithBase("/old/ws")).Write("/old/ws/data/a.txt", "moved"))
+		inner.Files["new/ws/data/a.txt"] = inner.Files["/old/ws/data/a.txt"]
+
+		content, err := New(inner, c, WithBase("new/ws")).Read("new/ws/data/a.txt")
+		require.NoError(t, err)
+		assert.Equal(t, "moved", content, "the base can be moved as a whole")
+		_, err = New(inner, c).Read("new/ws/data/a.txt")
+		assert.True(t, errors.Is(err, ErrDecrypt))
+	})
+}
+
+func TestEncryptFor(t *testing.T) {
+	c := newSymmetric(t)
+	ciphertext, err := EncryptFor(c, "github.token", []byte("ghp_abc123"))
+	require.NoError(t, err)
+
+	plaintext, err := DecryptFor(c, "github.token", ciphertext)
+	require.NoError(t, err)
+	assert.Equal(t, "ghp_abc123", string(plaintext))
+
+	for _, name := range []string{"github", "github.token2", ""} {
+		_, err = DecryptFor(c, name, ciphertext)
+		assert.True(t, errors.Is(err, ErrDecrypt), "decrypting for %q", name)
+	}
+	plain, err := c.Encrypt([]byte("ghp_abc123"))
+	require.NoError(t, err)
+	_, err = DecryptFor(c, "github.token", plain)
+	assert.True(t, errors.Is(err, ErrDecrypt), "content not written by EncryptFor")
+}
+
+func TestMedium_PGP(t *testing.T) {
+	keys, err := openpgp.CreateKeyPair("test", "")
+	require.NoError(t, err)
+
+	inner := io.NewMockMedium()
+	m := New(inner, PGP(keys.PublicKey, keys.PrivateKey))
+	require.NoError(t, m.Write("data/a.json", `{"ok":true}`))
+	assert.NotContains(t, inner.Files["data/a.json"], "ok")
+
+	content, err := m.Read("data/a.json")
+	require.NoError(t, err)
+	assert.Equal(t, `{"ok":true}`, content)
+
+	// A public key alone can write but not read.
+	writeOnly := New(inner, PGP(keys.PublicKey, ""))
+	require.NoError(t, writeOnly.Write("data/b.json", "{}"))
+	_, err = writeOnly.Read("data/b.json")
+	assert.Error(t, err)
+}
+
+func TestPassword(t *testing.T) {
+	c := Password("hunter2")
+	ciphertext, err := c.Encrypt([]byte("private key"))
+	require.NoError(t, err)
+	assert.True(t, IsPasswordEncrypted(ciphertext))
+
+	plaintext, err := c.Decrypt(ciphertext)
+	require.NoError(t, err)
+	assert.Equal(t, "private key", string(plaintext))
+
+	_, err = Password("wrong").Decrypt(ciphertext)
+	assert.True(t, errors.Is(err, ErrDecrypt))
+
+	_, err = c.Decrypt([]byte("not encrypted"))
+	assert.True(t, errors.Is(err, ErrDecrypt))
+	assert.False(t, IsPasswordEncrypted([]byte("not encrypted")))
+}
+
+func TestPasswordKey(t *testing.T) {
+	salt := make([]byte, SaltSize)
+	key, err := PasswordKey("hunter2", salt)
+	require.NoError(t, err)
+	assert.Len(t, key, KeySize)
+
+	again, err := PasswordKey("hunter2", salt)
+	require.NoError(t, err)
+	assert.Equal(t, key, again)
+
+	other, err := PasswordKey("wrong", salt)
+	require.NoError(t, err)
+	assert.NotEqual(t, key, other)
+
+	_, err = Symmetric(key)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,212 @@
+package io
+
+import (
+	goio "io"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// --- fs.FS adapters ---
+
+// FromFS returns a read-only Medium backed by fsys, such as an embed.FS or
+// os.DirFS. Paths are cleaned and made relative to the root of fsys, so ".."
+// cannot escape it. Every method that would change fsys returns an error
+// wrapping fs.ErrPermission.
+func FromFS(fsys fs.FS) Medium {
+	return &fsMedium{fsys: fsys}
+}
+
+// fsMedium is the Medium returned by FromFS.
+type fsMedium struct {
+	fsys fs.FS
+}
+
+// name converts a medium path to an fs.FS path.
+func (m *fsMedium) name(p string) string {
+	p = strings.TrimPrefix(path.Clean("/"+p), "/")
+	if p == "" {
+		return "."
+	}
+	return p
+}
+
+func (m *fsMedium) Read(p string) (string, error) {
+	data, err := fs.ReadFile(m.fsys, m.name(p))
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+func (m *fsMedium) Write(p, content string) error {
+	return &fs.PathError{Op: "write", Path: p, Err: fs.ErrPermission}
+}
+
+func (m *fsMedium) EnsureDir(p string) error {
+	if m.IsDir(p) {
+		return nil
+	}
+	return &fs.PathError{Op: "mkdir", Path: p, Err: fs.ErrPermission}
+}
+
+func (m *fsMedium) IsFile(p string) bool {
+	info, err := m.Stat(p)
+	return err == nil && info.Mode().IsRegular()
+}
+
+func (m *fsMedium) FileGet(p string) (string, error) {
+	return m.Read(p)
+}
+
+func (m *fsMedium) FileSet(p, content string) error {
+	return m.Write(p, content)
+}
+
+func (m *fsMedium) Open(p string) (fs.File, error) {
+	return m.fsys.Open(m.name(p))
+}
+
+func (m *fsMedium) Create(p string) (goio.WriteCloser, error) {
+	return nil, &fs.PathError{Op: "create", Path: p, Err: fs.ErrPermission}
+}
+
+func (m *fsMedium) List(p string) ([]fs.DirEntry, error) {
+	return fs.ReadDir(m.fsys, m.name(p))
+}
+
+func (m *fsMedium) Stat(p string) (fs.FileInfo, error) {
+	return fs.Stat(m.fsys, m.name(p))
+}
+
+func (m *fsMedium) Exists(p string) bool {
+	_, err := m.Stat(p)
+	return err == nil
+}
+
+func (m *fsMedium) IsDir(p string) bool {
+	info, err := m.Stat(p)
+	return err == nil && info.IsDir()
+}
+
+func (m *fsMedium) Delete(p string) error {
+	return &fs.PathError{Op: "remove", Path: p, Err: fs.ErrPermission}
+}
+
+func (m *fsMedium) DeleteAll(p string) error {
+	return &fs.PathError{Op: "remove", Path: p, Err: fs.ErrPermission}
+}
+
+func (m *fsMedium) Rename(oldPath, newPath string) error {
+	return &fs.PathError{Op: "rename", Path: oldPath, Err: fs.ErrPermission}
+}
+
+func (m *fsMedium) WalkDir(root string, fn fs.WalkDirFunc) error {
+	return WalkDir(m, root, fn)
+}
+
+// ToFS returns an fs.FS view of m, for APIs that take an fs.FS such as
+// http.FS or help.Options.Assets. Names are passed to m unchanged, after
+// being checked with fs.ValidPath.
+func ToFS(m Medium) fs.FS {
+	return mediumFS{m}
+}
+
+// mediumFS is the fs.FS returned by ToFS.
+type mediumFS struct {
+	m Medium
+}
+
+// Ensure mediumFS implements the optional fs interfaces.
+var (
+	_ fs.ReadDirFS  = mediumFS{}
+	_ fs.ReadFileFS = mediumFS{}
+	_ fs.StatFS     = mediumFS{}
+)
+
+// medium converts an fs.FS name to a medium path.
+func (f mediumFS) medium(op, name string) (string, error) {
+	if !fs.ValidPath(name) {
+		return "", &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	if name == "." {
+		return "", nil
+	}
+	return name, nil
+}
+
+func (f mediumFS) Open(name string) (fs.File, error) {
+	p, err := f.medium("open", name)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.m.Stat(p)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return f.m.Open(p)
+	}
+	entries, err := f.m.List(p)
+	if err != nil {
+		return nil, err
+	}
+	return &dirFile{info: info, entries: entries}, nil
+}
+
+func (f mediumFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	p, err := f.medium("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	return f.m.List(p)
+}
+
+func (f mediumFS) ReadFile(name string) ([]byte, error) {
+	p, err := f.medium("read", name)
+	if err != nil {
+		return nil, err
+	}
+	content, err := f.m.Read(p)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(content), nil
+}
+
+func (f mediumFS) Stat(name string) (fs.FileInfo, error) {
+	p, err := f.medium("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	return f.m.Stat(p)
+}
+
+// dirFile is an open directory from a mediumFS.
+type dirFile struct {
+	info    fs.FileInfo
+	entries []fs.DirEntry
+}
+
+func (d *dirFile) Stat() (fs.FileInfo, error) { return d.info, nil }
+func (d *dirFile) Close() error               { return nil }
+
+func (d *dirFile) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.Name(), Err: fs.ErrInvalid}
+}
+
+// ReadDir implements fs.ReadDirFile.
+func (d *dirFile) ReadDir(n int) ([]fs.DirEntry, error) {
+	if n <= 0 {
+		entries := d.entries
+		d.entries = nil
+		return entries, nil
+	}
+	if len(d.entries) == 0 {
+		return nil, goio.EOF
+	}
+	n = min(n, len(d.entries))
+	entries := d.entries[:n]
+	d.entries = d.entries[n:]
+	return entries, nil
+}
@@ -0,0 +1,22 @@
+package io
+
+import (
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFromFS(t *testing.T) {
+	m := FromFS(fstest.MapFS{"a/b.txt": {Data: []byte("b")}})
+
+	content, err := m.Read("/a/../a/b.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "b", content)
+	assert.True(t, m.IsDir(""))
+	assert.ErrorIs(t, m.Write("a/c.txt", "c"), fs.ErrPermission)
+	assert.ErrorIs(t, m.Delete("a/b.txt"), fs.ErrPermission)
+	assert.NoError(t, m.EnsureDir("a"))
+}
@@ -1,7 +1,13 @@
 package io
 
 import (
-	"errors"
+	goio "io"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/host-uk/core/pkg/io/local"
 )
@@ -9,6 +15,9 @@ import (
 // Medium defines the standard interface for a storage backend.
 // This allows for different implementations (e.g., local disk, S3, SFTP)
 // to be used interchangeably.
+//
+// Errors for missing paths wrap fs.ErrNotExist, so they can be checked with
+// errors.Is(err, fs.ErrNotExist).
 type Medium interface {
 	// Read retrieves the content of a file as a string.
 	Read(path string) (string, error)
@@ -27,6 +36,43 @@ type Medium interface {
 
 	// FileSet is a convenience function that writes a file to the medium.
 	FileSet(path, content string) error
+
+	// Open opens a file for streaming reads. The caller must close it.
+	Open(path string) (fs.File, error)
+
+	// Create creates or truncates a file for streaming writes, creating parent
+	// directories as needed. The content is only guaranteed to be stored once
+	// the writer is closed.
+	Create(path string) (goio.WriteCloser, error)
+
+	// List returns the entries of a directory, sorted by name.
+	List(path string) ([]fs.DirEntry, error)
+
+	// Stat returns information about a file or directory.
+	Stat(path string) (fs.FileInfo, error)
+
+	// Exists checks if a path exists, as either a file or a directory.
+	Exists(path string) bool
+
+	// IsDir checks if a path exists and is a directory.
+	IsDir(path string) bool
+
+	// Delete removes a file or an empty directory.
+	Delete(path string) error
+
+	// DeleteAll removes a path and everything below it. It returns nil if the
+	// path does not exist.
+	DeleteAll(path string) error
+
+	// Rename moves a file or directory, creating the destination's parent
+	// directories as needed.
+	Rename(oldPath, newPath string) error
+
+	// WalkDir walks the tree rooted at root in lexical order, calling fn for
+	// each file and directory, including root. It follows the rules of
+	// fs.WalkDir: fn may return fs.SkipDir or fs.SkipAll. The paths passed to
+	// fn are root joined with the entry's path below it.
+	WalkDir(root string, fn fs.WalkDirFunc) error
 }
 
 // Local is a pre-initialized medium for the local filesystem.
@@ -44,6 +90,20 @@ func init() {
 
 // --- Helper Functions ---
 
+// Resolve returns the medium to use for path and the path to pass to it. If
+// m is nil, files are on the local filesystem: Local is returned, with a
+// relative path resolved against the working directory. Otherwise m and path
+// are returned as they are, for m to interpret.
+func Resolve(m Medium, path string) (Medium, string) {
+	if m != nil {
+		return m, path
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	return Local, path
+}
+
 // Read retrieves the content of a file from the given medium.
 func Read(m Medium, path string) (string, error) {
 	return m.Read(path)
@@ -64,28 +124,118 @@ func IsFile(m Medium, path string) bool {
 	return m.IsFile(path)
 }
 
-// Copy copies a file from one medium to another.
+// Copy copies a file from one medium to another, streaming its content.
 func Copy(src Medium, srcPath string, dst Medium, dstPath string) error {
-	content, err := src.Read(srcPath)
+	in, err := src.Open(srcPath)
 	if err != nil {
 		return err
 	}
-	return dst.Write(dstPath, content)
+	defer in.Close()
+
+	out, err := dst.Create(dstPath)
+	if err != nil {
+		return err
+	}
+	if _, err := goio.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// WalkDir walks a medium using only its Stat and List methods. Backends that
+// have no native walk can implement Medium.WalkDir by calling it:
+//
+//	func (m *Medium) WalkDir(root string, fn fs.WalkDirFunc) error {
+//		return io.WalkDir(m, root, fn)
+//	}
+func WalkDir(m Medium, root string, fn fs.WalkDirFunc) error {
+	info, err := m.Stat(root)
+	if err != nil {
+		err = fn(root, nil, err)
+	} else {
+		err = walkDir(m, root, fs.FileInfoToDirEntry(info), fn)
+	}
+	if err == fs.SkipDir || err == fs.SkipAll {
+		return nil
+	}
+	return err
+}
+
+// walkDir recursively walks the directory at name, following fs.WalkDir.
+func walkDir(m Medium, name string, d fs.DirEntry, fn fs.WalkDirFunc) error {
+	if err := fn(name, d, nil); err != nil || !d.IsDir() {
+		if err == fs.SkipDir && d.IsDir() {
+			err = nil
+		}
+		return err
+	}
+
+	entries, err := m.List(name)
+	if err != nil {
+		// Second call, to report the List error.
+		if err = fn(name, d, err); err != nil {
+			if err == fs.SkipDir && d.IsDir() {
+				err = nil
+			}
+			return err
+		}
+	}
+
+	for _, entry := range entries {
+		if err := walkDir(m, path.Join(name, entry.Name()), entry, fn); err != nil {
+			if err == fs.SkipDir {
+				break
+			}
+			return err
+		}
+	}
+	return nil
 }
 
+// --- FileInfo ---
+
+// fileInfo is a simple fs.FileInfo for backends without native file info.
+type fileInfo struct {
+	name    string
+	size    int64
+	mode    fs.FileMode
+	modTime time.Time
+}
+
+// NewFileInfo returns an fs.FileInfo with the given values. Setting
+// fs.ModeDir in mode makes it a directory. It is intended for Medium
+// implementations whose storage has no native file info.
+func NewFileInfo(name string, size int64, mode fs.FileMode, modTime time.Time) fs.FileInfo {
+	return &fileInfo{name: name, size: size, mode: mode, modTime: modTime}
+}
+
+func (fi *fileInfo) Name() string       { return fi.name }
+func (fi *fileInfo) Size() int64        { return fi.size }
+func (fi *fileInfo) Mode() fs.FileMode  { return fi.mode }
+func (fi *fileInfo) ModTime() time.Time { return fi.modTime }
+func (fi *fileInfo) IsDir() bool        { return fi.mode.IsDir() }
+func (fi *fileInfo) Sys() any           { return nil }
+
 // --- MockMedium ---
 
 // MockMedium is an in-memory implementation of Medium for testing.
+// Directories are those added to Dirs, plus every parent of a file or
+// directory.
 type MockMedium struct {
-	Files map[string]string
-	Dirs  map[string]bool
+	Files    map[string]string
+	Dirs     map[string]bool
+	ModTimes map[string]time.Time
+
+	watches []*mockWatch
 }
 
 // NewMockMedium creates a new MockMedium instance.
 func NewMockMedium() *MockMedium {
 	return &MockMedium{
-		Files: make(map[string]string),
-		Dirs:  make(map[string]bool),
+		Files:    make(map[string]string),
+		Dirs:     make(map[string]bool),
+		ModTimes: make(map[string]time.Time),
 	}
 }
 
@@ -93,19 +243,31 @@ func NewMockMedium() *MockMedium {
 func (m *MockMedium) Read(path string) (string, error) {
 	content, ok := m.Files[path]
 	if !ok {
-		return "", errors.New("file not found: " + path)
+		return "", &fs.PathError{Op: "read", Path: path, Err: fs.ErrNotExist}
 	}
 	return content, nil
 }
 
 // Write saves the given content to a file in the mock filesystem.
 func (m *MockMedium) Write(path, content string) error {
+	if m.ModTimes == nil {
+		m.ModTimes = make(map[string]time.Time)
+	}
+	op := OpWrite
+	if !m.IsFile(path) {
+		op = OpCreate
+	}
 	m.Files[path] = content
+	m.ModTimes[path] = time.Now()
+	m.notify(path, op)
 	return nil
 }
 
 // EnsureDir records that a directory exists in the mock filesystem.
 func (m *MockMedium) EnsureDir(path string) error {
+	if !m.isDir(path) {
+		defer m.notify(path, OpCreate)
+	}
 	m.Dirs[path] = true
 	return nil
 }
@@ -125,3 +287,247 @@ func (m *MockMedium) FileGet(path string) (string, error) {
 func (m *MockMedium) FileSet(path, content string) error {
 	return m.Write(path, content)
 }
+
+// Open opens a file in the mock filesystem for reading.
+func (m *MockMedium) Open(name string) (fs.File, error) {
+	content, ok := m.Files[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return &mockFile{
+		Reader: strings.NewReader(content),
+		info:   NewFileInfo(path.Base(name), int64(len(content)), 0644, m.ModTimes[name]),
+	}, nil
+}
+
+// Create returns a writer whose content is stored in the mock filesystem
+// when it is closed.
+func (m *MockMedium) Create(name string) (goio.WriteCloser, error) {
+	if m.isDir(name) {
+		return nil, &fs.PathError{Op: "create", Path: name, Err: fs.ErrExist}
+	}
+	return &mockWriter{medium: m, path: name}, nil
+}
+
+// List returns the files and directories directly inside a directory.
+func (m *MockMedium) List(name string) ([]fs.DirEntry, error) {
+	if !m.isDir(name) {
+		if m.IsFile(name) {
+			return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+		}
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+
+	prefix := dirPrefix(name)
+	children := make(map[string]fs.FileInfo)
+	for _, p := range m.paths() {
+		rest, ok := strings.CutPrefix(p, prefix)
+		if !ok || rest == "" {
+			continue
+		}
+		child, _, nested := strings.Cut(rest, "/")
+		if child == "" {
+			continue
+		}
+		if _, seen := children[child]; seen {
+			continue
+		}
+		if _, isFile := m.Files[prefix+child]; isFile && !nested {
+			children[child], _ = m.Stat(prefix + child)
+		} else {
+			children[child] = NewFileInfo(child, 0, fs.ModeDir|0755, time.Time{})
+		}
+	}
+
+	entries := make([]fs.DirEntry, 0, len(children))
+	for _, info := range children {
+		entries = append(entries, fs.FileInfoToDirEntry(info))
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
+
+// Stat returns information about a file or directory in the mock filesystem.
+func (m *MockMedium) Stat(name string) (fs.FileInfo, error) {
+	if content, ok := m.Files[name]; ok {
+		return NewFileInfo(path.Base(name), int64(len(content)), 0644, m.ModTimes[name]), nil
+	}
+	if m.isDir(name) {
+		return NewFileInfo(path.Base(name), 0, fs.ModeDir|0755, time.Time{}), nil
+	}
+	return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+}
+
+// Exists checks if a path exists as a file or directory in the mock filesystem.
+func (m *MockMedium) Exists(path string) bool {
+	return m.IsFile(path) || m.isDir(path)
+}
+
+// IsDir checks if a path exists as a directory in the mock filesystem.
+func (m *MockMedium) IsDir(path string) bool {
+	return m.isDir(path)
+}
+
+// Delete removes a file or an empty directory from the mock filesystem.
+func (m *MockMedium) Delete(path string) error {
+	if m.IsFile(path) {
+		delete(m.Files, path)
+		delete(m.ModTimes, path)
+		m.notify(path, OpRemove)
+		return nil
+	}
+	if !m.isDir(path) {
+		return &fs.PathError{Op: "remove", Path: path, Err: fs.ErrNotExist}
+	}
+	prefix := dirPrefix(path)
+	for _, p := range m.paths() {
+		if strings.HasPrefix(p, prefix) {
+			return &fs.PathError{Op: "remove", Path: path, Err: fs.ErrExist}
+		}
+	}
+	delete(m.Dirs, path)
+	m.notify(path, OpRemove)
+	return nil
+}
+
+// DeleteAll removes a path and everything below it from the mock filesystem.
+func (m *MockMedium) DeleteAll(path string) error {
+	if m.Exists(path) {
+		defer m.notify(path, OpRemove)
+	}
+	prefix := dirPrefix(path)
+	for p := range m.Files {
+		if p == path || strings.HasPrefix(p, prefix) {
+			delete(m.Files, p)
+			delete(m.ModTimes, p)
+			if p != path {
+				m.notify(p, OpRemove)
+			}
+		}
+	}
+	for p := range m.Dirs {
+		if p == path || strings.HasPrefix(p, prefix) {
+			delete(m.Dirs, p)
+			if p != path {
+				m.notify(p, OpRemove)
+			}
+		}
+	}
+	return nil
+}
+
+// Rename moves a file or directory in the mock filesystem.
+func (m *MockMedium) Rename(oldPath, newPath string) error {
+	if m.ModTimes == nil {
+		m.ModTimes = make(map[string]time.Time)
+	}
+	if content, ok := m.Files[oldPath]; ok {
+		m.Files[newPath] = content
+		m.ModTimes[newPath] = m.ModTimes[oldPath]
+		delete(m.Files, oldPath)
+		delete(m.ModTimes, oldPath)
+		m.notify(oldPath, OpRemove)
+		m.notify(newPath, OpCreate)
+		return nil
+	}
+	if !m.isDir(oldPath) {
+		return &fs.PathError{Op: "rename", Path: oldPath, Err: fs.ErrNotExist}
+	}
+
+	oldPrefix, newPrefix := dirPrefix(oldPath), dirPrefix(newPath)
+	for p, content := range m.Files {
+		if rest, ok := strings.CutPrefix(p, oldPrefix); ok {
+			m.Files[newPrefix+rest] = content
+			m.ModTimes[newPrefix+rest] = m.ModTimes[p]
+			delete(m.Files, p)
+			delete(m.ModTimes, p)
+		}
+	}
+	for p := range m.Dirs {
+		if p == oldPath {
+			delete(m.Dirs, p)
+			m.Dirs[newPath] = true
+		} else if rest, ok := strings.CutPrefix(p, oldPrefix); ok {
+			delete(m.Dirs, p)
+			m.Dirs[newPrefix+rest] = true
+		}
+	}
+	m.notify(oldPath, OpRemove)
+	m.notify(newPath, OpCreate)
+	return nil
+}
+
+// WalkDir walks the mock filesystem below root.
+func (m *MockMedium) WalkDir(root string, fn fs.WalkDirFunc) error {
+	return WalkDir(m, root, fn)
+}
+
+// isDir reports whether path was created with EnsureDir or is the parent of a
+// file or directory. The root paths "", "." and "/" are always directories.
+func (m *MockMedium) isDir(path string) bool {
+	if path == "" || path == "." || path == "/" || m.Dirs[path] {
+		return true
+	}
+	prefix := dirPrefix(path)
+	for _, p := range m.paths() {
+		if strings.HasPrefix(p, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// paths returns every file and directory path in the mock filesystem.
+func (m *MockMedium) paths() []string {
+	paths := make([]string, 0, len(m.Files)+len(m.Dirs))
+	for p := range m.Files {
+		paths = append(paths, p)
+	}
+	for p := range m.Dirs {
+		paths = append(paths, p)
+	}
+	return paths
+}
+
+// dirPrefix returns the prefix shared by every path inside the directory.
+func dirPrefix(dir string) string {
+	switch dir {
+	case "", ".":
+		return ""
+	case "/":
+		return "/"
+	}
+	return strings.TrimSuffix(dir, "/") + "/"
+}
+
+// mockFile is a file opened from a MockMedium.
+type mockFile struct {
+	*strings.Reader
+	info fs.FileInfo
+}
+
+func (f *mockFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+func (f *mockFile) Close() error               { return nil }
+
+// mockWriter buffers a file created in a MockMedium until it is closed.
+type mockWriter struct {
+	medium *MockMedium
+	path   string
+	buf    strings.Builder
+	closed bool
+}
+
+func (w *mockWriter) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, fs.ErrClosed
+	}
+	return w.buf.Write(p)
+}
+
+func (w *mockWriter) Close() error {
+	if w.closed {
+		return fs.ErrClosed
+	}
+	w.closed = true
+	return w.medium.Write(w.path, w.buf.String())
+}
@@ -3,19 +3,65 @@ package local
 
 import (
 	"errors"
+	"io"
+	"io/fs"
+	"math/rand/v2"
 	"os"
 	"path/filepath"
-	"strings"
+	"strconv"
+)
+
+// Default permissions for files and directories a Medium creates.
+const (
+	DefaultFileMode fs.FileMode = 0644
+	DefaultDirMode  fs.FileMode = 0755
 )
 
 // Medium is a local filesystem storage backend.
+//
+// Every path is confined to the root directory: ".." cannot climb out of it,
+// and neither can symlinks, which are only followed while they stay inside
+// the root. On Linux, files are opened with openat2(RESOLVE_BENEATH), so the
+// kernel enforces this even if a symlink is swapped in concurrently.
+//
+// Files are written atomically: content goes to a temporary file in the same
+// directory, which is synced and then renamed over the target, so a crash
+// leaves either the old or the new content, never a truncated file.
 type Medium struct {
-	root string
+	root     string
+	fileMode fs.FileMode
+	dirMode  fs.FileMode
+}
+
+// Option configures a Medium.
+type Option func(*Medium)
+
+// WithFileMode sets the permissions of files the medium creates, before the
+// umask is applied. Files it replaces keep their own permissions. The default
+// is DefaultFileMode.
+func WithFileMode(mode fs.FileMode) Option {
+	return func(m *Medium) {
+		m.fileMode = mode.Perm()
+	}
+}
+
+// WithDirMode sets the permissions of directories the medium creates,
+// including the root, before the umask is applied. The default is
+// DefaultDirMode.
+func WithDirMode(mode fs.FileMode) Option {
+	return func(m *Medium) {
+		m.dirMode = mode.Perm()
+	}
 }
 
 // New creates a new local Medium with the specified root directory.
 // The root directory will be created if it doesn't exist.
-func New(root string) (*Medium, error) {
+func New(root string, opts ...Option) (*Medium, error) {
+	m := &Medium{fileMode: DefaultFileMode, dirMode: DefaultDirMode}
+	for _, opt := range opts {
+		opt(m)
+	}
+
 	// Ensure root is an absolute path
 	absRoot, err := filepath.Abs(root)
 	if err != nil {
@@ -23,42 +69,47 @@ func New(root string) (*Medium, error) {
 	}
 
 	// Create root directory if it doesn't exist
-	if err := os.MkdirAll(absRoot, 0755); err != nil {
+	if err := os.MkdirAll(absRoot, m.dirMode); err != nil {
 		return nil, err
 	}
 
-	return &Medium{root: absRoot}, nil
+	// Resolve symlinks in the root itself, so paths below it can be compared
+	// with their resolved form.
+	m.root, err = filepath.EvalSymlinks(absRoot)
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
 }
 
-// path sanitizes and joins the relative path with the root directory.
-// Returns an error if a path traversal attempt is detected.
+// path sanitizes and joins the relative path with the root directory,
+// following symlinks. Returns ErrTraversal if the path leads out of the root.
 func (m *Medium) path(relativePath string) (string, error) {
-	// Clean the path to remove any .. or . components
-	cleanPath := filepath.Clean(relativePath)
-
-	// Check for path traversal attempts
-	if strings.HasPrefix(cleanPath, "..") || strings.Contains(cleanPath, string(filepath.Separator)+"..") {
-		return "", errors.New("path traversal attempt detected")
+	rel, err := clean(relativePath)
+	if err != nil {
+		return "", err
 	}
+	return m.resolve(rel, true)
+}
 
-	fullPath := filepath.Join(m.root, cleanPath)
-
-	// Verify the resulting path is still within root
-	if !strings.HasPrefix(fullPath, m.root) {
-		return "", errors.New("path traversal attempt detected")
+// pathNoFollow is like path, but leaves a symlink in the last element alone.
+func (m *Medium) pathNoFollow(relativePath string) (string, error) {
+	rel, err := clean(relativePath)
+	if err != nil {
+		return "", err
 	}
-
-	return fullPath, nil
+	return m.resolve(rel, false)
 }
 
 // Read retrieves the content of a file as a string.
 func (m *Medium) Read(relativePath string) (string, error) {
-	fullPath, err := m.path(relativePath)
+	f, err := m.Open(relativePath)
 	if err != nil {
 		return "", err
 	}
+	defer f.Close()
 
-	content, err := os.ReadFile(fullPath)
+	content, err := io.ReadAll(f)
 	if err != nil {
 		return "", err
 	}
@@ -69,18 +120,15 @@ func (m *Medium) Read(relativePath string) (string, error) {
 // Write saves the given content to a file, overwriting it if it exists.
 // Parent directories are created automatically.
 func (m *Medium) Write(relativePath, content string) error {
-	fullPath, err := m.path(relativePath)
+	w, err := m.Create(relativePath)
 	if err != nil {
 		return err
 	}
-
-	// Ensure parent directory exists
-	parentDir := filepath.Dir(fullPath)
-	if err := os.MkdirAll(parentDir, 0755); err != nil {
+	if _, err := io.WriteString(w, content); err != nil {
+		w.(*atomicFile).abort()
 		return err
 	}
-
-	return os.WriteFile(fullPath, []byte(content), 0644)
+	return w.Close()
 }
 
 // EnsureDir makes sure a directory exists, creating it if necessary.
@@ -90,7 +138,7 @@ func (m *Medium) EnsureDir(relativePath string) error {
 		return err
 	}
 
-	return os.MkdirAll(fullPath, 0755)
+	return os.MkdirAll(fullPath, m.dirMode)
 }
 
 // IsFile checks if a path exists and is a regular file.
@@ -117,3 +165,234 @@ func (m *Medium) FileGet(relativePath string) (string, error) {
 func (m *Medium) FileSet(relativePath, content string) error {
 	return m.Write(relativePath, content)
 }
+
+// Open opens a file for reading.
+func (m *Medium) Open(relativePath string) (fs.File, error) {
+	rel, err := clean(relativePath)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.openFile(rel, os.O_RDONLY, 0)
+}
+
+// Create creates or truncates a file for writing.
+// Parent directories are created automatically. The file only replaces any
+// existing one when the writer is closed, keeping its permissions, and a
+// symlink at the path is replaced rather than written through.
+func (m *Medium) Create(relativePath string) (io.WriteCloser, error) {
+	rel, err := clean(relativePath)
+	if err != nil {
+		return nil, err
+	}
+	if rel == "." {
+		return nil, &fs.PathError{Op: "create", Path: relativePath, Err: fs.ErrInvalid}
+	}
+
+	dir, err := m.openDir(filepath.Dir(rel))
+	if err != nil {
+		return nil, err
+	}
+	name := filepath.Base(rel)
+	// Replacing a file keeps its permissions; only new files get fileMode.
+	perm, exists := existingMode(dir, name)
+	if !exists {
+		perm = m.fileMode
+	}
+	tmp, tmpName, err := createTemp(dir, name, perm)
+	if err != nil {
+		dir.Close()
+		return nil, err
+	}
+	if exists {
+		// The mode createTemp was given is masked by the umask.
+		if err := tmp.Chmod(perm); err != nil {
+			tmp.Close()
+			removeAt(dir, tmpName)
+			dir.Close()
+			return nil, err
+		}
+	}
+
+	return &atomicFile{tmp: tmp, dir: dir, tmpName: tmpName, name: name}, nil
+}
+
+// openDir opens the directory at the clean relative path rel, creating it and
+// its parents if needed.
+func (m *Medium) openDir(rel string) (*os.File, error) {
+	fullPath, err := m.resolve(rel, true)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(fullPath, m.dirMode); err != nil {
+		return nil, err
+	}
+
+	dir, err := m.openFile(rel, os.O_RDONLY|oDirectory, 0)
+	if err != nil {
+		return nil, err
+	}
+	if info, err := dir.Stat(); err != nil || !info.IsDir() {
+		dir.Close()
+		return nil, &fs.PathError{Op: "open", Path: rel, Err: errors.New("not a directory")}
+	}
+	return dir, nil
+}
+
+// List returns the entries of a directory, sorted by name.
+func (m *Medium) List(relativePath string) ([]fs.DirEntry, error) {
+	fullPath, err := m.path(relativePath)
+	if err != nil {
+		return nil, err
+	}
+
+	return os.ReadDir(fullPath)
+}
+
+// Stat returns information about a file or directory.
+func (m *Medium) Stat(relativePath string) (fs.FileInfo, error) {
+	fullPath, err := m.path(relativePath)
+	if err != nil {
+		return nil, err
+	}
+
+	return os.Stat(fullPath)
+}
+
+// Exists checks if a path exists, as either a file or a directory.
+func (m *Medium) Exists(relativePath string) bool {
+	_, err := m.Stat(relativePath)
+	return err == nil
+}
+
+// IsDir checks if a path exists and is a directory.
+func (m *Medium) IsDir(relativePath string) bool {
+	info, err := m.Stat(relativePath)
+	if err != nil {
+		return false
+	}
+
+	return info.IsDir()
+}
+
+// Delete removes a file or an empty directory. A symlink is removed itself,
+// not its target.
+func (m *Medium) Delete(relativePath string) error {
+	fullPath, err := m.pathNoFollow(relativePath)
+	if err != nil {
+		return err
+	}
+	if fullPath == m.root {
+		return errors.New("refusing to delete the medium root")
+	}
+
+	return os.Remove(fullPath)
+}
+
+// DeleteAll removes a path and everything below it.
+// It returns nil if the path does not exist.
+func (m *Medium) DeleteAll(relativePath string) error {
+	fullPath, err := m.pathNoFollow(relativePath)
+	if err != nil {
+		return err
+	}
+	if fullPath == m.root {
+		return errors.New("refusing to delete the medium root")
+	}
+
+	return os.RemoveAll(fullPath)
+}
+
+// Rename moves a file or directory. A symlink is moved itself, not its
+// target. Parent directories of the destination are created automatically.
+func (m *Medium) Rename(oldRelativePath, newRelativePath string) error {
+	oldPath, err := m.pathNoFollow(oldRelativePath)
+	if err != nil {
+		return err
+	}
+	newPath, err := m.pathNoFollow(newRelativePath)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(newPath), m.dirMode); err != nil {
+		return err
+	}
+
+	return os.Rename(oldPath, newPath)
+}
+
+// WalkDir walks the tree rooted at root, calling fn for each file and
+// directory. Paths passed to fn are relative to the medium, in the same form
+// as root.
+func (m *Medium) WalkDir(root string, fn fs.WalkDirFunc) error {
+	fullRoot, err := m.path(root)
+	if err != nil {
+		return err
+	}
+
+	return filepath.WalkDir(fullRoot, func(fullPath string, d fs.DirEntry, err error) error {
+		rel, relErr := filepath.Rel(fullRoot, fullPath)
+		if relErr != nil {
+			return relErr
+		}
+		if rel == "." {
+			return fn(root, d, err)
+		}
+		return fn(filepath.Join(root, rel), d, err)
+	})
+}
+
+// atomicFile is a file being written by Create. Writes go to a temporary file
+// that replaces the target on Close.
+type atomicFile struct {
+	tmp     *os.File
+	dir     *os.File
+	tmpName string
+	name    string
+	done    bool
+}
+
+func (f *atomicFile) Write(p []byte) (int, error) {
+	return f.tmp.Write(p)
+}
+
+// Close syncs the temporary file and renames it over the target.
+func (f *atomicFile) Close() error {
+	if f.done {
+		return os.ErrClosed
+	}
+	f.done = true
+	defer f.dir.Close()
+
+	err := f.tmp.Sync()
+	if closeErr := f.tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = renameAt(f.dir, f.tmpName, f.name)
+	}
+	if err != nil {
+		removeAt(f.dir, f.tmpName)
+		return err
+	}
+	return syncDir(f.dir)
+}
+
+// abort discards the temporary file, leaving the target untouched.
+func (f *atomicFile) abort() {
+	if f.done {
+		return
+	}
+	f.done = true
+	f.tmp.Close()
+	removeAt(f.dir, f.tmpName)
+	f.dir.Close()
+}
+
+// tempName returns a random name for a temporary file next to name. The name
+// starts with a dot, so it is hidden from most directory listings while the
+// write is in progress.
+func tempName(name string) string {
+	return "." + name + ".tmp" + strconv.FormatUint(rand.Uint64(), 36)
+}
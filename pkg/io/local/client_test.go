@@ -1,10 +1,14 @@
 package local
 
 import (
+	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
+	"github.com/host-uk/core/pkg/io/notify"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -192,3 +196,276 @@ func TestFileGetFileSet(t *testing.T) {
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "path traversal attempt detected")
 }
+
+func TestStreaming(t *testing.T) {
+	medium, err := New(t.TempDir())
+	assert.NoError(t, err)
+
+	w, err := medium.Create("nested/stream.txt")
+	assert.NoError(t, err)
+	_, err = io.WriteString(w, "streamed")
+	assert.NoError(t, err)
+	assert.NoError(t, w.Close())
+
+	f, err := medium.Open("nested/stream.txt")
+	assert.NoError(t, err)
+	defer f.Close()
+	data, err := io.ReadAll(f)
+	assert.NoError(t, err)
+	assert.Equal(t, "streamed", string(data))
+
+	_, err = medium.Open("../outside.txt")
+	assert.Contains(t, err.Error(), "path traversal attempt detected")
+	_, err = medium.Create("../outside.txt")
+	assert.Contains(t, err.Error(), "path traversal attempt detected")
+}
+
+func TestListStat(t *testing.T) {
+	medium, err := New(t.TempDir())
+	assert.NoError(t, err)
+	assert.NoError(t, medium.Write("b.txt", "bb"))
+	assert.NoError(t, medium.Write("a/c.txt", "c"))
+
+	entries, err := medium.List(".")
+	assert.NoError(t, err)
+	assert.Len(t, entries, 2)
+	assert.Equal(t, "a", entries[0].Name())
+	assert.True(t, entries[0].IsDir())
+
+	info, err := medium.Stat("b.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), info.Size())
+
+	assert.True(t, medium.Exists("a"))
+	assert.True(t, medium.IsDir("a"))
+	assert.False(t, medium.IsDir("b.txt"))
+	assert.False(t, medium.Exists("missing"))
+
+	_, err = medium.Stat("missing")
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+}
+
+func TestDeleteRename(t *testing.T) {
+	testRoot := t.TempDir()
+	medium, err := New(testRoot)
+	assert.NoError(t, err)
+	assert.NoError(t, medium.Write("a/one.txt", "1"))
+	assert.NoError(t, medium.Write("a/two.txt", "2"))
+
+	assert.NoError(t, medium.Rename("a/one.txt", "b/one.txt"))
+	assert.True(t, medium.IsFile("b/one.txt"))
+	assert.False(t, medium.Exists("a/one.txt"))
+
+	assert.Error(t, medium.Delete("a"), "non-empty directories are kept")
+	assert.NoError(t, medium.Delete("a/two.txt"))
+	assert.NoError(t, medium.Delete("a"))
+
+	assert.NoError(t, medium.DeleteAll("b"))
+	assert.NoError(t, medium.DeleteAll("b"))
+	assert.False(t, medium.Exists("b"))
+
+	assert.Error(t, medium.DeleteAll("."))
+	assert.Error(t, medium.Rename("../x", "y"))
+	_, err = os.Stat(testRoot)
+	assert.NoError(t, err)
+}
+
+func TestWalkDir(t *testing.T) {
+	medium, err := New(t.TempDir())
+	assert.NoError(t, err)
+	assert.NoError(t, medium.Write("root/a.txt", "a"))
+	assert.NoError(t, medium.Write("root/skip/x.txt", "x"))
+	assert.NoError(t, medium.Write("root/sub/b.txt", "b"))
+
+	var visited []string
+	err = medium.WalkDir("root", func(path string, d fs.DirEntry, err error) error {
+		assert.NoError(t, err)
+		visited = append(visited, filepath.ToSlash(path))
+		if d.IsDir() && d.Name() == "skip" {
+			return fs.SkipDir
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"root", "root/a.txt", "root/skip", "root/sub", "root/sub/b.txt"}, visited)
+}
+
+func TestSymlinkSandbox(t *testing.T) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "data")
+	outside := filepath.Join(dir, "data-other")
+	assert.NoError(t, os.MkdirAll(outside, 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(outside, "secret.txt"), []byte("secret"), 0644))
+
+	medium, err := New(root)
+	assert.NoError(t, err)
+	assert.NoError(t, medium.Write("real/file.txt", "inside"))
+
+	// Symlinks that leave the root are refused, including into a sibling
+	// directory that shares the root's name as a prefix.
+	assert.NoError(t, os.Symlink(outside, filepath.Join(root, "abs")))
+	assert.NoError(t, os.Symlink("../data-other", filepath.Join(root, "rel")))
+	assert.NoError(t, os.Symlink("../../data-other/secret.txt", filepath.Join(root, "real", "file-link")))
+	for _, p := range []string{"abs/secret.txt", "rel/secret.txt", "real/file-link"} {
+		_, err := medium.Read(p)
+		assert.ErrorIs(t, err, ErrTraversal, p)
+		_, err = medium.Stat(p)
+		assert.ErrorIs(t, err, ErrTraversal, p)
+		assert.ErrorIs(t, medium.Write(p+"/new.txt", "x"), ErrTraversal, p)
+
+		// The portable resolver, used where openat2 isn't available,
+		// agrees.
+		_, err = medium.resolve(p, true)
+		assert.ErrorIs(t, err, ErrTraversal, p)
+	}
+	assert.NoFileExists(t, filepath.Join(outside, "new.txt"))
+
+	// Symlinks that stay inside the root work, whether relative or absolute.
+	assert.NoError(t, os.Symlink("real", filepath.Join(root, "alias")))
+	assert.NoError(t, os.Symlink(filepath.Join(root, "real", "file.txt"), filepath.Join(root, "abs-inside")))
+	for _, p := range []string{"alias/file.txt", "abs-inside"} {
+		content, err := medium.Read(p)
+		assert.NoError(t, err, p)
+		assert.Equal(t, "inside", content, p)
+	}
+
+	// Deleting a symlink removes the link, even one that leads outside.
+	assert.NoError(t, medium.Delete("abs"))
+	assert.FileExists(t, filepath.Join(outside, "secret.txt"))
+	assert.NoError(t, medium.Rename("rel", "moved"))
+	_, err = os.Lstat(filepath.Join(root, "moved"))
+	assert.NoError(t, err)
+
+	// Loops fail instead of hanging.
+	assert.NoError(t, os.Symlink("loop", filepath.Join(root, "loop")))
+	_, err = medium.Read("loop")
+	assert.Error(t, err)
+}
+
+func TestAtomicWrite(t *testing.T) {
+	root := t.TempDir()
+	medium, err := New(root)
+	assert.NoError(t, err)
+	assert.NoError(t, medium.Write("config.yaml", "old"))
+
+	w, err := medium.Create("config.yaml")
+	assert.NoError(t, err)
+	_, err = io.WriteString(w, "new")
+	assert.NoError(t, err)
+
+	content, err := medium.Read("config.yaml")
+	assert.NoError(t, err)
+	assert.Equal(t, "old", content, "the file is only replaced on Close")
+
+	assert.NoError(t, w.Close())
+	assert.Error(t, w.Close())
+	content, err = medium.Read("config.yaml")
+	assert.NoError(t, err)
+	assert.Equal(t, "new", content)
+
+	entries, err := os.ReadDir(root)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1, "no temporary files are left behind")
+
+	// A symlink at the target is replaced, not written through.
+	assert.NoError(t, medium.Write("target.txt", "target"))
+	assert.NoError(t, os.Symlink("target.txt", filepath.Join(root, "link.txt")))
+	assert.NoError(t, medium.Write("link.txt", "replaced"))
+	content, err = medium.Read("target.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, "target", content)
+
+	_, err = medium.Create("")
+	assert.Error(t, err)
+}
+
+func TestModes(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "private")
+	medium, err := New(root, WithFileMode(0600), WithDirMode(0700))
+	assert.NoError(t, err)
+	assert.NoError(t, medium.Write("sub/secret.txt", "x"))
+	assert.NoError(t, medium.EnsureDir("other"))
+
+	for path, want := range map[string]fs.FileMode{
+		root:                                     0700,
+		filepath.Join(root, "sub"):               0700,
+		filepath.Join(root, "other"):             0700,
+		filepath.Join(root, "sub", "secret.txt"): 0600,
+	} {
+		info, err := os.Stat(path)
+		assert.NoError(t, err)
+		assert.Equal(t, want, info.Mode().Perm(), path)
+	}
+
+	// By default files get the same permissions as os.WriteFile(0644).
+	root = t.TempDir()
+	medium, err = New(root)
+	assert.NoError(t, err)
+	assert.NoError(t, medium.Write("default.txt", "x"))
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "plain.txt"), []byte("x"), 0644))
+	info, err := medium.Stat("default.txt")
+	assert.NoError(t, err)
+	plain, err := medium.Stat("plain.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, plain.Mode(), info.Mode())
+
+	// Replacing a file keeps its permissions.
+	for name, mode := range map[string]fs.FileMode{"secret.txt": 0600, "run.sh": 0700, "open.txt": 0666} {
+		path := filepath.Join(root, name)
+		assert.NoError(t, os.WriteFile(path, []byte("old"), mode))
+		assert.NoError(t, os.Chmod(path, mode))
+		assert.NoError(t, medium.Write(name, "new"))
+		info, err := os.Stat(path)
+		assert.NoError(t, err)
+		assert.Equal(t, mode, info.Mode().Perm(), name)
+		content, err := medium.Read(name)
+		assert.NoError(t, err)
+		assert.Equal(t, "new", content)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	medium, err := New(t.TempDir())
+	assert.NoError(t, err)
+	assert.NoError(t, medium.Write("project/main.go", "package main"))
+
+	next := func(w interface{ Events() <-chan []notify.Event }) []notify.Event {
+		t.Helper()
+		select {
+		case batch := <-w.Events():
+			return batch
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for events")
+			return nil
+		}
+	}
+
+	w, err := medium.Watch("project", true)
+	assert.NoError(t, err)
+	defer w.Close()
+
+	// Directories created after the watch started are watched too.
+	assert.NoError(t, medium.EnsureDir("project/pkg"))
+	assert.Equal(t, []notify.Event{{Path: filepath.Join("project", "pkg"), Op: notify.OpCreate}}, next(w))
+	assert.NoError(t, medium.Write("project/pkg/util.go", "package pkg"))
+	assert.Contains(t, next(w), notify.Event{Path: filepath.Join("project", "pkg", "util.go"), Op: notify.OpCreate})
+
+	assert.NoError(t, medium.Delete("project/main.go"))
+	assert.Equal(t, []notify.Event{{Path: filepath.Join("project", "main.go"), Op: notify.OpRemove}}, next(w))
+
+	// Single files can be watched, and are still seen after being replaced.
+	assert.NoError(t, medium.Write("config.yaml", "a: 1"))
+	fw, err := medium.Watch("config.yaml", false)
+	assert.NoError(t, err)
+	defer fw.Close()
+	for i := 0; i < 2; i++ {
+		assert.NoError(t, medium.Write("other.yaml", "ignored"))
+		assert.NoError(t, medium.Write("config.yaml", "a: 2"))
+		assert.Equal(t, []notify.Event{{Path: "config.yaml", Op: notify.OpCreate}}, next(fw))
+	}
+
+	_, err = medium.Watch("missing", false)
+	assert.Error(t, err)
+	_, err = medium.Watch("../outside", false)
+	assert.ErrorIs(t, err, ErrTraversal)
+}
@@ -0,0 +1,115 @@
+//go:build linux
+
+package local
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+
+	"golang.org/x/sys/unix"
+)
+
+// oDirectory makes openFile fail unless the path is a directory.
+const oDirectory = unix.O_DIRECTORY
+
+// noOpenat2 is set once openat2 fails with ENOSYS, as it does before Linux
+// 5.6, so later calls go straight to the portable resolver.
+var noOpenat2 atomic.Bool
+
+// openFile opens the file at the clean relative path rel. The kernel resolves
+// the path beneath a handle on the root with openat2(RESOLVE_BENEATH), so a
+// symlink swapped in after any earlier check cannot redirect the open out of
+// the root. RESOLVE_BENEATH also refuses absolute symlinks, even ones that
+// point inside the root; those, and kernels without openat2, fall back to the
+// portable resolver.
+func (m *Medium) openFile(rel string, flag int, perm fs.FileMode) (*os.File, error) {
+	if !noOpenat2.Load() {
+		f, err := m.openat2(rel, flag, perm)
+		switch {
+		case err == nil:
+			return f, nil
+		case errors.Is(err, unix.ENOSYS):
+			noOpenat2.Store(true)
+		case errors.Is(err, unix.EXDEV), errors.Is(err, unix.EAGAIN), errors.Is(err, unix.ELOOP):
+			// Let the portable resolver decide whether the path escapes.
+		default:
+			return nil, &fs.PathError{Op: "open", Path: rel, Err: err}
+		}
+	}
+
+	fullPath, err := m.resolve(rel, true)
+	if err != nil {
+		return nil, err
+	}
+	return os.OpenFile(fullPath, flag, perm)
+}
+
+func (m *Medium) openat2(rel string, flag int, perm fs.FileMode) (*os.File, error) {
+	root, err := unix.Open(m.root, unix.O_PATH|unix.O_DIRECTORY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer unix.Close(root)
+
+	for {
+		fd, err := unix.Openat2(root, rel, &unix.OpenHow{
+			Flags:   uint64(flag) | unix.O_CLOEXEC,
+			Mode:    uint64(perm.Perm()),
+			Resolve: unix.RESOLVE_BENEATH | unix.RESOLVE_NO_MAGICLINKS,
+		})
+		if err == unix.EINTR {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return os.NewFile(uintptr(fd), filepath.Join(m.root, rel)), nil
+	}
+}
+
+// existingMode returns the permission bits of the regular file name in dir,
+// if there is one. A symlink is not followed.
+func existingMode(dir *os.File, name string) (fs.FileMode, bool) {
+	var st unix.Stat_t
+	if err := unix.Fstatat(int(dir.Fd()), name, &st, unix.AT_SYMLINK_NOFOLLOW); err != nil || st.Mode&unix.S_IFMT != unix.S_IFREG {
+		return 0, false
+	}
+	return fs.FileMode(st.Mode).Perm(), true
+}
+
+// createTemp creates a new, empty file in dir to be renamed over name.
+func createTemp(dir *os.File, name string, perm fs.FileMode) (*os.File, string, error) {
+	for {
+		tmpName := tempName(name)
+		fd, err := unix.Openat(int(dir.Fd()), tmpName, unix.O_WRONLY|unix.O_CREAT|unix.O_EXCL|unix.O_CLOEXEC, uint32(perm.Perm()))
+		if err == unix.EEXIST || err == unix.EINTR {
+			continue
+		}
+		if err != nil {
+			return nil, "", &fs.PathError{Op: "create", Path: filepath.Join(dir.Name(), tmpName), Err: err}
+		}
+		return os.NewFile(uintptr(fd), filepath.Join(dir.Name(), tmpName)), tmpName, nil
+	}
+}
+
+// renameAt renames oldName to newName, both in dir.
+func renameAt(dir *os.File, oldName, newName string) error {
+	fd := int(dir.Fd())
+	if err := unix.Renameat(fd, oldName, fd, newName); err != nil {
+		return &os.LinkError{Op: "rename", Old: filepath.Join(dir.Name(), oldName), New: filepath.Join(dir.Name(), newName), Err: err}
+	}
+	return nil
+}
+
+// removeAt removes the file name from dir.
+func removeAt(dir *os.File, name string) error {
+	return unix.Unlinkat(int(dir.Fd()), name, 0)
+}
+
+// syncDir flushes dir, so a rename into it survives a crash.
+func syncDir(dir *os.File) error {
+	return dir.Sync()
+}
@@ -0,0 +1,67 @@
+//go:build !linux
+
+package local
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// oDirectory is not needed outside Linux; openDir checks the result instead.
+const oDirectory = 0
+
+// openFile opens the file at the clean relative path rel, after checking that
+// no symlink along it leads out of the root.
+func (m *Medium) openFile(rel string, flag int, perm fs.FileMode) (*os.File, error) {
+	fullPath, err := m.resolve(rel, true)
+	if err != nil {
+		return nil, err
+	}
+	return os.OpenFile(fullPath, flag, perm)
+}
+
+// existingMode returns the permission bits of the regular file name in dir,
+// if there is one. A symlink is not followed.
+func existingMode(dir *os.File, name string) (fs.FileMode, bool) {
+	info, err := os.Lstat(filepath.Join(dir.Name(), name))
+	if err != nil || !info.Mode().IsRegular() {
+		return 0, false
+	}
+	return info.Mode().Perm(), true
+}
+
+// createTemp creates a new, empty file in dir to be renamed over name.
+func createTemp(dir *os.File, name string, perm fs.FileMode) (*os.File, string, error) {
+	for {
+		tmpName := tempName(name)
+		f, err := os.OpenFile(filepath.Join(dir.Name(), tmpName), os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+		if os.IsExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, "", err
+		}
+		return f, tmpName, nil
+	}
+}
+
+// renameAt renames oldName to newName, both in dir.
+func renameAt(dir *os.File, oldName, newName string) error {
+	return os.Rename(filepath.Join(dir.Name(), oldName), filepath.Join(dir.Name(), newName))
+}
+
+// removeAt removes the file name from dir.
+func removeAt(dir *os.File, name string) error {
+	return os.Remove(filepath.Join(dir.Name(), name))
+}
+
+// syncDir flushes dir, so a rename into it survives a crash. Windows cannot
+// sync directories, and its renames are already durable.
+func syncDir(dir *os.File) error {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	return dir.Sync()
+}
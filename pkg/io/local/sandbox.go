@@ -0,0 +1,117 @@
+package local
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrTraversal is returned for paths that would leave the medium root, either
+// through ".." or through a symlink.
+var ErrTraversal = errors.New("path traversal attempt detected")
+
+// maxSymlinks limits how many symlinks resolve follows for one path, so
+// symlink loops fail instead of spinning.
+const maxSymlinks = 255
+
+// clean returns relativePath cleaned and made relative to the root, with "."
+// for the root itself. Returns ErrTraversal if the path uses ".." to climb
+// above the root.
+func clean(relativePath string) (string, error) {
+	// Clean the path to remove any .. or . components
+	cleanPath := filepath.Clean(relativePath)
+
+	// Check for path traversal attempts
+	if strings.HasPrefix(cleanPath, "..") || strings.Contains(cleanPath, string(filepath.Separator)+"..") {
+		return "", ErrTraversal
+	}
+
+	cleanPath = strings.TrimLeft(cleanPath, string(filepath.Separator))
+	if cleanPath == "" {
+		return ".", nil
+	}
+	return cleanPath, nil
+}
+
+// within reports whether fullPath is the root or below it.
+func (m *Medium) within(fullPath string) bool {
+	if fullPath == m.root {
+		return true
+	}
+	prefix := m.root
+	if !strings.HasSuffix(prefix, string(filepath.Separator)) {
+		prefix += string(filepath.Separator)
+	}
+	return strings.HasPrefix(fullPath, prefix)
+}
+
+// resolve joins the clean relative path rel with the root, following any
+// symlinks along the way, and returns ErrTraversal if one of them leads out
+// of the root. Symlinks that stay inside the root are allowed. Components that
+// don't exist yet are joined as they are. If followLast is false, a symlink in
+// the last component is left alone, so that it can be removed or renamed
+// rather than its target.
+func (m *Medium) resolve(rel string, followLast bool) (string, error) {
+	current := m.root
+	parts := split(rel)
+	links := 0
+	for len(parts) > 0 {
+		name := parts[0]
+		parts = parts[1:]
+
+		if name == ".." {
+			current = filepath.Dir(current)
+			if !m.within(current) {
+				return "", ErrTraversal
+			}
+			continue
+		}
+
+		next := filepath.Join(current, name)
+		if len(parts) == 0 && !followLast {
+			current = next
+			break
+		}
+		info, err := os.Lstat(next)
+		if err != nil || info.Mode()&os.ModeSymlink == 0 {
+			current = next
+			continue
+		}
+
+		links++
+		if links > maxSymlinks {
+			return "", &os.PathError{Op: "resolve", Path: rel, Err: errors.New("too many levels of symbolic links")}
+		}
+		target, err := os.Readlink(next)
+		if err != nil {
+			return "", err
+		}
+		if filepath.IsAbs(target) {
+			target = filepath.Clean(target)
+			if !m.within(target) {
+				return "", ErrTraversal
+			}
+			inside, err := filepath.Rel(m.root, target)
+			if err != nil {
+				return "", err
+			}
+			current, parts = m.root, append(split(inside), parts...)
+		} else {
+			parts = append(split(target), parts...)
+		}
+	}
+	return current, nil
+}
+
+// split returns the elements of a relative path, without empty or "."
+// elements.
+func split(p string) []string {
+	var parts []string
+	for _, part := range strings.Split(filepath.ToSlash(p), "/") {
+		if part != "" && part != "." {
+			parts = append(parts, part)
+		}
+	}
+	return parts
+}
@@ -0,0 +1,138 @@
+package local
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/host-uk/core/pkg/io/notify"
+)
+
+// Watch reports changes to a file or directory using the operating system's
+// file notifications (inotify, kqueue or ReadDirectoryChangesW). For a
+// directory, changes to its entries are reported, and with recursive also
+// changes anywhere below it, including in directories created later.
+//
+// A file replaced by a rename, as Write does, is reported as created.
+func (m *Medium) Watch(relativePath string, recursive bool) (*notify.Watch, error) {
+	fullPath, err := m.path(relativePath)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(fullPath)
+	if err != nil {
+		return nil, err
+	}
+
+	fw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	lw := &localWatch{
+		fw:        fw,
+		fullPath:  fullPath,
+		path:      relativePath,
+		dir:       info.IsDir(),
+		recursive: recursive && info.IsDir(),
+	}
+
+	// Files are watched through their directory, so that they are still
+	// seen after being replaced.
+	if !lw.dir {
+		err = fw.Add(filepath.Dir(fullPath))
+	} else {
+		err = lw.add(fullPath, nil)
+	}
+	if err != nil {
+		fw.Close()
+		return nil, err
+	}
+
+	w, emit := notify.New(0, fw.Close)
+	go lw.run(emit)
+	return w, nil
+}
+
+// localWatch translates fsnotify events for one call to Watch.
+type localWatch struct {
+	fw        *fsnotify.Watcher
+	fullPath  string
+	path      string
+	dir       bool
+	recursive bool
+}
+
+// add watches dir and, for a recursive watch, every directory below it. If
+// emit is set, everything found below dir is reported as created, since it
+// may have appeared before the watch was in place.
+func (lw *localWatch) add(dir string, emit func(notify.Event)) error {
+	if !lw.recursive {
+		return lw.fw.Add(dir)
+	}
+	return filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// The directory may already be gone again.
+			return nil
+		}
+		if emit != nil && p != dir {
+			emit(notify.Event{Path: lw.relative(p), Op: notify.OpCreate})
+		}
+		if d.IsDir() {
+			return lw.fw.Add(p)
+		}
+		return nil
+	})
+}
+
+// relative converts a full path to the form Watch was called with.
+func (lw *localWatch) relative(fullPath string) string {
+	rel, err := filepath.Rel(lw.fullPath, fullPath)
+	if err != nil || rel == "." {
+		return lw.path
+	}
+	return filepath.Join(lw.path, rel)
+}
+
+func (lw *localWatch) run(emit func(notify.Event)) {
+	for {
+		select {
+		case ev, ok := <-lw.fw.Events:
+			if !ok {
+				return
+			}
+			lw.handle(ev, emit)
+		case _, ok := <-lw.fw.Errors:
+			// Errors such as a queue overflow can't be reported through
+			// the event stream; keep watching.
+			if !ok {
+				return
+			}
+		}
+	}
+}
+
+func (lw *localWatch) handle(ev fsnotify.Event, emit func(notify.Event)) {
+	if !lw.dir && ev.Name != lw.fullPath {
+		return
+	}
+	event := notify.Event{Path: lw.relative(ev.Name)}
+	switch {
+	case ev.Has(fsnotify.Create):
+		event.Op = notify.OpCreate
+	case ev.Has(fsnotify.Write):
+		event.Op = notify.OpWrite
+	case ev.Has(fsnotify.Remove), ev.Has(fsnotify.Rename):
+		event.Op = notify.OpRemove
+	default:
+		return
+	}
+	emit(event)
+
+	if event.Op == notify.OpCreate && lw.recursive {
+		if info, err := os.Lstat(ev.Name); err == nil && info.IsDir() {
+			lw.add(ev.Name, emit)
+		}
+	}
+}
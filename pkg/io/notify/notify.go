@@ -0,0 +1,209 @@
+// Package notify provides the debounced change stream behind io.Watch.
+//
+// Medium implementations report each raw change as it happens; a Watch merges
+// changes to the same path and delivers them in batches once they settle.
+// Most code should use the aliases in the io package rather than importing
+// this package directly.
+package notify
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultDebounce is how long a Watch waits for changes to settle before
+// delivering them.
+const DefaultDebounce = 100 * time.Millisecond
+
+// maxDelayFactor caps how long a stream of changes can hold back delivery,
+// as a multiple of the debounce period.
+const maxDelayFactor = 10
+
+// Op describes what happened to a path.
+type Op int
+
+// Change operations. A rename is reported as a remove of the old path and a
+// create of the new one.
+const (
+	OpCreate Op = iota + 1
+	OpWrite
+	OpRemove
+)
+
+// String returns "create", "write" or "remove".
+func (op Op) String() string {
+	switch op {
+	case OpCreate:
+		return "create"
+	case OpWrite:
+		return "write"
+	case OpRemove:
+		return "remove"
+	}
+	return fmt.Sprintf("Op(%d)", int(op))
+}
+
+// MarshalText encodes op as its name, so events read well in JSON.
+func (op Op) MarshalText() ([]byte, error) {
+	return []byte(op.String()), nil
+}
+
+// UnmarshalText decodes an op from its name.
+func (op *Op) UnmarshalText(text []byte) error {
+	for _, o := range []Op{OpCreate, OpWrite, OpRemove} {
+		if string(text) == o.String() {
+			*op = o
+			return nil
+		}
+	}
+	return fmt.Errorf("notify: unknown op %q", text)
+}
+
+// Event is a change to a file or directory.
+type Event struct {
+	// Path is the changed path, in the same form as the watched path: the
+	// watched path joined with the path below it.
+	Path string `json:"path"`
+	Op   Op     `json:"op"`
+}
+
+// Watch delivers batches of changes to a watched path. Create one with New in
+// a Medium implementation, and close it when done.
+type Watch struct {
+	events   chan []Event
+	raw      chan Event
+	done     chan struct{}
+	finished chan struct{}
+	debounce time.Duration
+	stop     func() error
+
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// New creates a Watch for a Medium implementation, which reports each raw
+// change by calling emit. Changes are merged per path and delivered as one
+// batch once none has arrived for debounce, or at the latest after ten
+// debounce periods. A zero debounce means DefaultDebounce.
+//
+// stop is called once when the Watch is closed, to release the medium's
+// resources. emit may safely be called after the Watch is closed; the change
+// is dropped.
+func New(debounce time.Duration, stop func() error) (*Watch, func(Event)) {
+	if debounce <= 0 {
+		debounce = DefaultDebounce
+	}
+	w := &Watch{
+		events:   make(chan []Event, 16),
+		raw:      make(chan Event, 256),
+		done:     make(chan struct{}),
+		finished: make(chan struct{}),
+		debounce: debounce,
+		stop:     stop,
+	}
+	go w.run()
+	return w, w.emit
+}
+
+// Events returns the channel batches of changes are delivered on, sorted by
+// path. It is closed when the Watch is closed.
+func (w *Watch) Events() <-chan []Event {
+	return w.events
+}
+
+// Close stops the watch and closes the Events channel. Changes that have not
+// been delivered yet are dropped.
+func (w *Watch) Close() error {
+	w.closeOnce.Do(func() {
+		close(w.done)
+		if w.stop != nil {
+			w.closeErr = w.stop()
+		}
+		<-w.finished
+	})
+	return w.closeErr
+}
+
+func (w *Watch) emit(e Event) {
+	select {
+	case w.raw <- e:
+	case <-w.done:
+	}
+}
+
+// run merges raw changes and delivers them once they settle.
+func (w *Watch) run() {
+	defer close(w.finished)
+	defer close(w.events)
+
+	pending := make(map[string]Op)
+	var timer *time.Timer
+	var fire <-chan time.Time
+	var deadline time.Time
+
+	for {
+		select {
+		case e := <-w.raw:
+			if op, ok := merge(pending[e.Path], e.Op); ok {
+				pending[e.Path] = op
+			} else {
+				delete(pending, e.Path)
+			}
+
+			now := time.Now()
+			if fire == nil {
+				deadline = now.Add(w.debounce * maxDelayFactor)
+			}
+			wait := min(w.debounce, deadline.Sub(now))
+			if timer == nil {
+				timer = time.NewTimer(wait)
+			} else {
+				timer.Reset(wait)
+			}
+			fire = timer.C
+
+		case <-fire:
+			fire = nil
+			if len(pending) == 0 {
+				continue
+			}
+			batch := make([]Event, 0, len(pending))
+			for p, op := range pending {
+				batch = append(batch, Event{Path: p, Op: op})
+			}
+			sort.Slice(batch, func(i, j int) bool { return batch[i].Path < batch[j].Path })
+			clear(pending)
+
+			select {
+			case w.events <- batch:
+			case <-w.done:
+				return
+			}
+
+		case <-w.done:
+			if timer != nil {
+				timer.Stop()
+			}
+			return
+		}
+	}
+}
+
+// merge combines a pending change to a path with a new one. It returns false
+// if the two cancel out, as when a file is created and removed again before
+// the batch is delivered.
+func merge(prev, next Op) (Op, bool) {
+	switch {
+	case prev == 0:
+		return next, true
+	case prev == OpCreate && next == OpRemove:
+		return 0, false
+	case prev == OpCreate:
+		return OpCreate, true
+	case prev == OpRemove && next == OpCreate, prev == OpWrite && next == OpCreate:
+		return OpWrite, true
+	}
+	return next, true
+}
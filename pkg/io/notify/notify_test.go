@@ -0,0 +1,84 @@
+package notify
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func next(t *testing.T, w *Watch) []Event {
+	t.Helper()
+	select {
+	case batch, ok := <-w.Events():
+		require.True(t, ok, "events channel closed")
+		return batch
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for events")
+		return nil
+	}
+}
+
+func TestWatch_Coalesce(t *testing.T) {
+	stopped := false
+	w, emit := New(20*time.Millisecond, func() error {
+		stopped = true
+		return nil
+	})
+
+	emit(Event{Path: "b.txt", Op: OpCreate})
+	emit(Event{Path: "b.txt", Op: OpWrite})
+	emit(Event{Path: "a.txt", Op: OpWrite})
+	emit(Event{Path: "a.txt", Op: OpWrite})
+	emit(Event{Path: "tmp", Op: OpCreate})
+	emit(Event{Path: "tmp", Op: OpRemove})
+	emit(Event{Path: "c.txt", Op: OpRemove})
+	emit(Event{Path: "c.txt", Op: OpCreate})
+	assert.Equal(t, []Event{
+		{Path: "a.txt", Op: OpWrite},
+		{Path: "b.txt", Op: OpCreate},
+		{Path: "c.txt", Op: OpWrite},
+	}, next(t, w))
+
+	emit(Event{Path: "a.txt", Op: OpRemove})
+	assert.Equal(t, []Event{{Path: "a.txt", Op: OpRemove}}, next(t, w))
+
+	require.NoError(t, w.Close())
+	require.NoError(t, w.Close())
+	assert.True(t, stopped)
+	_, ok := <-w.Events()
+	assert.False(t, ok)
+	emit(Event{Path: "late", Op: OpWrite}) // dropped, does not block
+}
+
+func TestWatch_MaxDelay(t *testing.T) {
+	w, emit := New(20*time.Millisecond, nil)
+	defer w.Close()
+
+	// A steady stream of changes is still delivered.
+	start := time.Now()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for time.Since(start) < 500*time.Millisecond {
+			emit(Event{Path: "busy.log", Op: OpWrite})
+			time.Sleep(5 * time.Millisecond)
+		}
+	}()
+	assert.Equal(t, []Event{{Path: "busy.log", Op: OpWrite}}, next(t, w))
+	assert.Less(t, time.Since(start), 400*time.Millisecond)
+	<-done
+}
+
+func TestOp_JSON(t *testing.T) {
+	data, err := json.Marshal(Event{Path: "a", Op: OpRemove})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"path":"a","op":"remove"}`, string(data))
+
+	var e Event
+	require.NoError(t, json.Unmarshal([]byte(`{"path":"b","op":"create"}`), &e))
+	assert.Equal(t, Event{Path: "b", Op: OpCreate}, e)
+	assert.Error(t, json.Unmarshal([]byte(`{"op":"explode"}`), &e))
+}
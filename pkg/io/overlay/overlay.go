@@ -0,0 +1,401 @@
+// Package overlay provides an io.Medium that stacks read-only layers under a
+// writable one, so installs can customise files an app ships with.
+//
+// Reads look in the writable top layer first, then in each lower layer in
+// turn, and directory listings merge every layer. Writes always go to the top
+// layer; lower layers are never modified. Deleting a path that exists in a
+// lower layer leaves a whiteout in the top layer that hides it.
+//
+// Whiteouts follow the convention used by container image layers: deleting
+// "dir/name" creates an empty "dir/.wh.name" file, and a directory holding a
+// ".wh..wh..opq" file hides every lower-layer entry below it. Whiteout files
+// never appear in listings, and paths whose last element starts with ".wh."
+// cannot be written.
+package overlay
+
+import (
+	"errors"
+	goio "io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/host-uk/core/pkg/io"
+)
+
+const (
+	// WhiteoutPrefix marks a file in the top layer that hides the lower-layer
+	// path named by the rest of its name.
+	WhiteoutPrefix = ".wh."
+	// OpaqueMarker is the name of a file in a top-layer directory that hides
+	// the contents of the same directory in every lower layer.
+	OpaqueMarker = WhiteoutPrefix + WhiteoutPrefix + ".opq"
+)
+
+// Medium is a union of a writable top layer and read-only lower layers.
+type Medium struct {
+	top    io.Medium
+	lowers []io.Medium
+}
+
+// Ensure Medium implements io.Medium.
+var _ io.Medium = (*Medium)(nil)
+
+// New creates a Medium that writes to top and reads from top and then each
+// of lowers, in order. Use io.FromFS to add an embed.FS or os.DirFS as a
+// lower layer:
+//
+//	union := overlay.New(userDir, io.FromFS(defaults))
+func New(top io.Medium, lowers ...io.Medium) *Medium {
+	return &Medium{top: top, lowers: lowers}
+}
+
+// Top returns the writable layer.
+func (m *Medium) Top() io.Medium {
+	return m.top
+}
+
+// clean makes p relative to the root of every layer, so ".." cannot escape.
+// The root itself is "".
+func clean(p string) string {
+	return strings.TrimPrefix(path.Clean("/"+p), "/")
+}
+
+// whiteout returns the path of the whiteout file that hides p.
+func whiteout(p string) string {
+	return path.Join(path.Dir(p), WhiteoutPrefix+path.Base(p))
+}
+
+// isWhiteout reports whether name is the name of a whiteout file.
+func isWhiteout(name string) bool {
+	return strings.HasPrefix(name, WhiteoutPrefix)
+}
+
+// hidden reports whether the lower-layer entry at the clean path p is hidden
+// by a whiteout of p or one of its parents, or by an opaque parent.
+func (m *Medium) hidden(p string) bool {
+	if p == "" {
+		return false
+	}
+	if m.top.Exists(OpaqueMarker) {
+		return true
+	}
+	parts := strings.Split(p, "/")
+	for i := range parts {
+		q := strings.Join(parts[:i+1], "/")
+		if m.top.Exists(whiteout(q)) {
+			return true
+		}
+		if i < len(parts)-1 && m.top.Exists(path.Join(q, OpaqueMarker)) {
+			return true
+		}
+	}
+	return false
+}
+
+// inLower reports whether the clean path p is visible in a lower layer.
+func (m *Medium) inLower(p string) bool {
+	if len(m.lowers) == 0 || m.hidden(p) {
+		return false
+	}
+	for _, l := range m.lowers {
+		if l.Exists(p) {
+			return true
+		}
+	}
+	return false
+}
+
+// layer returns the layer the clean path p is read from.
+func (m *Medium) layer(op, p string) (io.Medium, error) {
+	if isWhiteout(path.Base(p)) {
+		return nil, &fs.PathError{Op: op, Path: p, Err: fs.ErrNotExist}
+	}
+	if m.top.Exists(p) {
+		return m.top, nil
+	}
+	if !m.hidden(p) {
+		for _, l := range m.lowers {
+			if l.Exists(p) {
+				return l, nil
+			}
+		}
+	}
+	return nil, &fs.PathError{Op: op, Path: p, Err: fs.ErrNotExist}
+}
+
+// unhide prepares the top layer for writing the clean path p. Parents that
+// were deleted are recreated in the top layer as opaque directories, so their
+// old lower-layer contents stay hidden. If p itself was deleted, its whiteout
+// is removed, and if dir is set it is also recreated as an opaque directory.
+func (m *Medium) unhide(p string, dir bool) error {
+	if isWhiteout(path.Base(p)) {
+		return &fs.PathError{Op: "write", Path: p, Err: fs.ErrInvalid}
+	}
+	parts := strings.Split(p, "/")
+	for i := range parts {
+		q := strings.Join(parts[:i+1], "/")
+		if !m.top.Exists(whiteout(q)) {
+			continue
+		}
+		if err := m.top.Delete(whiteout(q)); err != nil {
+			return err
+		}
+		if i < len(parts)-1 || dir {
+			if err := m.top.Write(path.Join(q, OpaqueMarker), ""); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Read retrieves the content of a file from the first layer that has it.
+func (m *Medium) Read(p string) (string, error) {
+	p = clean(p)
+	l, err := m.layer("read", p)
+	if err != nil {
+		return "", err
+	}
+	return l.Read(p)
+}
+
+// Write saves the given content to a file in the top layer.
+func (m *Medium) Write(p, content string) error {
+	p = clean(p)
+	if err := m.unhide(p, false); err != nil {
+		return err
+	}
+	return m.top.Write(p, content)
+}
+
+// EnsureDir makes sure a directory exists, creating it in the top layer if
+// necessary.
+func (m *Medium) EnsureDir(p string) error {
+	p = clean(p)
+	if m.IsFile(p) {
+		return &fs.PathError{Op: "mkdir", Path: p, Err: fs.ErrExist}
+	}
+	if err := m.unhide(p, true); err != nil {
+		return err
+	}
+	return m.top.EnsureDir(p)
+}
+
+// IsFile checks if a path exists and is a regular file.
+func (m *Medium) IsFile(p string) bool {
+	info, err := m.Stat(p)
+	return err == nil && info.Mode().IsRegular()
+}
+
+// FileGet is a convenience function that reads a file from the medium.
+func (m *Medium) FileGet(p string) (string, error) {
+	return m.Read(p)
+}
+
+// FileSet is a convenience function that writes a file to the medium.
+func (m *Medium) FileSet(p, content string) error {
+	return m.Write(p, content)
+}
+
+// Open opens a file for reading from the first layer that has it.
+func (m *Medium) Open(p string) (fs.File, error) {
+	p = clean(p)
+	l, err := m.layer("open", p)
+	if err != nil {
+		return nil, err
+	}
+	return l.Open(p)
+}
+
+// Create creates or truncates a file for writing in the top layer.
+func (m *Medium) Create(p string) (goio.WriteCloser, error) {
+	p = clean(p)
+	if err := m.unhide(p, false); err != nil {
+		return nil, err
+	}
+	return m.top.Create(p)
+}
+
+// List returns the entries of a directory merged across every layer, sorted
+// by name. Where layers have an entry of the same name, the highest one wins.
+func (m *Medium) List(p string) ([]fs.DirEntry, error) {
+	p = clean(p)
+	info, err := m.Stat(p)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, &fs.PathError{Op: "readdir", Path: p, Err: fs.ErrInvalid}
+	}
+
+	seen := make(map[string]bool)
+	var entries []fs.DirEntry
+	lowers := m.lowers
+	if m.top.IsDir(p) {
+		topEntries, err := m.top.List(p)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range topEntries {
+			name := e.Name()
+			if name == OpaqueMarker {
+				lowers = nil
+			}
+			if isWhiteout(name) {
+				seen[strings.TrimPrefix(name, WhiteoutPrefix)] = true
+				continue
+			}
+			seen[name] = true
+			entries = append(entries, e)
+		}
+	}
+	if m.hidden(p) {
+		lowers = nil
+	}
+	for _, l := range lowers {
+		if !l.IsDir(p) {
+			continue
+		}
+		lowerEntries, err := l.List(p)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range lowerEntries {
+			if !seen[e.Name()] {
+				seen[e.Name()] = true
+				entries = append(entries, e)
+			}
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
+
+// Stat returns information about a file or directory from the first layer
+// that has it.
+func (m *Medium) Stat(p string) (fs.FileInfo, error) {
+	p = clean(p)
+	l, err := m.layer("stat", p)
+	if err != nil {
+		return nil, err
+	}
+	return l.Stat(p)
+}
+
+// Exists checks if a path exists, as either a file or a directory.
+func (m *Medium) Exists(p string) bool {
+	_, err := m.Stat(p)
+	return err == nil
+}
+
+// IsDir checks if a path exists and is a directory.
+func (m *Medium) IsDir(p string) bool {
+	info, err := m.Stat(p)
+	return err == nil && info.IsDir()
+}
+
+// Delete removes a file or an empty directory. If the path exists in a lower
+// layer, a whiteout is left in the top layer to hide it.
+func (m *Medium) Delete(p string) error {
+	p = clean(p)
+	if p == "" {
+		return errors.New("refusing to delete the medium root")
+	}
+	info, err := m.Stat(p)
+	if err != nil {
+		return &fs.PathError{Op: "remove", Path: p, Err: fs.ErrNotExist}
+	}
+	if info.IsDir() {
+		entries, err := m.List(p)
+		if err != nil {
+			return err
+		}
+		if len(entries) > 0 {
+			return &fs.PathError{Op: "remove", Path: p, Err: fs.ErrExist}
+		}
+	}
+	return m.remove(p)
+}
+
+// DeleteAll removes a path and everything below it, in every layer. It
+// returns nil if the path does not exist.
+func (m *Medium) DeleteAll(p string) error {
+	p = clean(p)
+	if p == "" {
+		return errors.New("refusing to delete the medium root")
+	}
+	if !m.Exists(p) {
+		return nil
+	}
+	return m.remove(p)
+}
+
+// remove deletes the clean path p from the top layer, including any
+// whiteouts below it, and hides it in the lower layers.
+func (m *Medium) remove(p string) error {
+	inLower := m.inLower(p)
+	if err := m.top.DeleteAll(p); err != nil {
+		return err
+	}
+	if inLower {
+		return m.top.Write(whiteout(p), "")
+	}
+	return nil
+}
+
+// Rename moves a file or directory, replacing the destination. Paths that
+// only exist in the top layer are renamed there; otherwise the tree is copied
+// into the top layer and the original is deleted.
+func (m *Medium) Rename(oldPath, newPath string) error {
+	oldPath, newPath = clean(oldPath), clean(newPath)
+	info, err := m.Stat(oldPath)
+	if err != nil {
+		return &fs.PathError{Op: "rename", Path: oldPath, Err: fs.ErrNotExist}
+	}
+	if oldPath == "" || newPath == "" || newPath == oldPath || strings.HasPrefix(newPath, oldPath+"/") {
+		return &fs.PathError{Op: "rename", Path: oldPath, Err: fs.ErrInvalid}
+	}
+	if err := m.DeleteAll(newPath); err != nil {
+		return err
+	}
+
+	if !m.inLower(oldPath) {
+		// A directory replacing a deleted one must hide its old contents,
+		// but the marker can only be added once the rename is done.
+		opaque := info.IsDir() && m.top.Exists(whiteout(newPath))
+		if err := m.unhide(newPath, false); err != nil {
+			return err
+		}
+		if err := m.top.Rename(oldPath, newPath); err != nil {
+			return err
+		}
+		if opaque {
+			return m.top.Write(path.Join(newPath, OpaqueMarker), "")
+		}
+		return nil
+	}
+
+	err = m.WalkDir(oldPath, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		target := path.Join(newPath, strings.TrimPrefix(p, oldPath))
+		if d.IsDir() {
+			return m.EnsureDir(target)
+		}
+		return io.Copy(m, p, m, target)
+	})
+	if err != nil {
+		return err
+	}
+	return m.DeleteAll(oldPath)
+}
+
+// WalkDir walks the merged tree rooted at root, calling fn for each file and
+// directory.
+func (m *Medium) WalkDir(root string, fn fs.WalkDirFunc) error {
+	return io.WalkDir(m, root, fn)
+}
@@ -0,0 +1,184 @@
+package overlay
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/host-uk/core/pkg/io"
+	"github.com/host-uk/core/pkg/io/local"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestMedium stacks a local top layer over a directory layer and an
+// in-memory "embedded" layer.
+func newTestMedium(t *testing.T) (*Medium, string) {
+	t.Helper()
+	topDir := t.TempDir()
+	top, err := local.New(topDir)
+	require.NoError(t, err)
+
+	dirLayer, err := local.New(t.TempDir())
+	require.NoError(t, err)
+	require.NoError(t, dirLayer.Write("config/app.yaml", "site"))
+	require.NoError(t, dirLayer.Write("locales/de.json", "site de"))
+
+	embedded := io.FromFS(fstest.MapFS{
+		"config/app.yaml":   {Data: []byte("default")},
+		"config/db.yaml":    {Data: []byte("db")},
+		"locales/en.json":   {Data: []byte("en")},
+		"locales/de.json":   {Data: []byte("de")},
+		"help/index.html":   {Data: []byte("help")},
+		"help/img/logo.png": {Data: []byte("png")},
+	})
+	return New(top, dirLayer, embedded), topDir
+}
+
+func TestMedium_Read(t *testing.T) {
+	m, _ := newTestMedium(t)
+
+	content, err := m.Read("config/app.yaml")
+	require.NoError(t, err)
+	assert.Equal(t, "site", content, "higher layers win")
+
+	content, err = m.Read("/config/../config/db.yaml")
+	require.NoError(t, err)
+	assert.Equal(t, "db", content)
+
+	require.NoError(t, m.Write("config/db.yaml", "mine"))
+	content, err = m.Read("config/db.yaml")
+	require.NoError(t, err)
+	assert.Equal(t, "mine", content, "the top layer wins")
+
+	_, err = m.Read("missing")
+	assert.True(t, errors.Is(err, fs.ErrNotExist))
+
+	assert.True(t, m.IsDir("help/img"))
+	assert.True(t, m.IsFile("help/index.html"))
+	assert.False(t, m.IsFile("help"))
+}
+
+func TestMedium_List(t *testing.T) {
+	m, topDir := newTestMedium(t)
+	require.NoError(t, m.Write("config/local.yaml", "local"))
+
+	entries, err := m.List("config")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"app.yaml", "db.yaml", "local.yaml"}, names(entries))
+
+	entries, err = m.List("")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"config", "help", "locales"}, names(entries))
+
+	var walked []string
+	err = m.WalkDir("help", func(p string, d fs.DirEntry, err error) error {
+		require.NoError(t, err)
+		walked = append(walked, p)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"help", "help/img", "help/img/logo.png", "help/index.html"}, walked)
+
+	_, err = m.List("config/app.yaml")
+	assert.Error(t, err)
+	_, err = os.Stat(filepath.Join(topDir, "help"))
+	assert.True(t, os.IsNotExist(err), "reads do not touch the top layer")
+}
+
+func TestMedium_Delete(t *testing.T) {
+	m, topDir := newTestMedium(t)
+
+	require.NoError(t, m.Delete("locales/de.json"))
+	assert.False(t, m.Exists("locales/de.json"), "both lower copies are hidden")
+	assert.FileExists(t, filepath.Join(topDir, "locales", ".wh.de.json"))
+	entries, err := m.List("locales")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"en.json"}, names(entries))
+
+	// Writing a deleted file brings it back without the whiteout.
+	require.NoError(t, m.Write("locales/de.json", "new"))
+	content, err := m.Read("locales/de.json")
+	require.NoError(t, err)
+	assert.Equal(t, "new", content)
+	assert.NoFileExists(t, filepath.Join(topDir, "locales", ".wh.de.json"))
+
+	assert.ErrorIs(t, m.Delete("help"), fs.ErrExist, "the merged directory is not empty")
+	assert.ErrorIs(t, m.Delete("missing"), fs.ErrNotExist)
+	assert.Error(t, m.Delete(""))
+	assert.Error(t, m.DeleteAll("/"))
+	require.NoError(t, m.DeleteAll("missing"))
+
+	// Recreating a deleted directory does not bring back its lower contents.
+	require.NoError(t, m.DeleteAll("help"))
+	assert.False(t, m.Exists("help/index.html"))
+	require.NoError(t, m.Write("help/new.html", "new"))
+	entries, err = m.List("help")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"new.html"}, names(entries))
+	assert.False(t, m.Exists("help/img"))
+
+	require.NoError(t, m.DeleteAll("config"))
+	require.NoError(t, m.EnsureDir("config"))
+	entries, err = m.List("config")
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+
+	// Whiteout files cannot be written or read through the medium.
+	assert.ErrorIs(t, m.Write("locales/.wh.en.json", ""), fs.ErrInvalid)
+	assert.False(t, m.Exists("locales/.wh.de.json"))
+}
+
+func TestMedium_Rename(t *testing.T) {
+	m, _ := newTestMedium(t)
+
+	// A lower-layer tree is copied up.
+	require.NoError(t, m.Rename("help", "docs"))
+	assert.False(t, m.Exists("help"))
+	content, err := m.Read("docs/img/logo.png")
+	require.NoError(t, err)
+	assert.Equal(t, "png", content)
+
+	// Top-layer files are renamed in place, replacing the destination.
+	require.NoError(t, m.Write("notes.txt", "notes"))
+	require.NoError(t, m.Rename("notes.txt", "config/app.yaml"))
+	content, err = m.Read("config/app.yaml")
+	require.NoError(t, err)
+	assert.Equal(t, "notes", content)
+	assert.False(t, m.Exists("notes.txt"))
+
+	// A top-layer directory replacing a lower one hides its old contents.
+	require.NoError(t, m.Write("new/only.txt", "x"))
+	require.NoError(t, m.Rename("new", "locales"))
+	entries, err := m.List("locales")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"only.txt"}, names(entries))
+
+	assert.ErrorIs(t, m.Rename("docs", "docs/inside"), fs.ErrInvalid)
+	assert.ErrorIs(t, m.Rename("missing", "x"), fs.ErrNotExist)
+}
+
+func TestToFS(t *testing.T) {
+	m, _ := newTestMedium(t)
+	require.NoError(t, m.Write("help/extra.html", "extra"))
+
+	fsys := io.ToFS(m)
+	require.NoError(t, fstest.TestFS(fsys, "help/index.html", "help/extra.html", "config/app.yaml"))
+
+	data, err := fs.ReadFile(fsys, "config/app.yaml")
+	require.NoError(t, err)
+	assert.Equal(t, "site", string(data))
+	_, err = fsys.Open("../etc/passwd")
+	assert.ErrorIs(t, err, fs.ErrInvalid)
+}
+
+func names(entries []fs.DirEntry) []string {
+	out := make([]string, len(entries))
+	for i, e := range entries {
+		out[i] = e.Name()
+	}
+	return out
+}
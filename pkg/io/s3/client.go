@@ -0,0 +1,266 @@
+package s3
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	goio "io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Error is an error response from the S3 API that has no io/fs equivalent.
+type Error struct {
+	StatusCode int
+	Code       string
+	Message    string
+}
+
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("s3: %s (HTTP %d)", e.Code, e.StatusCode)
+	}
+	return fmt.Sprintf("s3: %s: %s (HTTP %d)", e.Code, e.Message, e.StatusCode)
+}
+
+// request describes a call to the S3 API. key is the full object key,
+// including the medium prefix; an empty key addresses the bucket.
+type request struct {
+	method string
+	key    string
+	query  url.Values
+	header http.Header
+	body   []byte
+}
+
+// do signs and sends r. Responses with a status of 300 or above are turned
+// into errors: 404 wraps fs.ErrNotExist and 403 wraps fs.ErrPermission, so
+// callers can wrap them in an fs.PathError. The caller must close the body of
+// a successful response.
+func (m *Medium) do(r request) (*http.Response, error) {
+	req, err := m.newRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+
+	apiErr := &Error{StatusCode: resp.StatusCode, Code: http.StatusText(resp.StatusCode)}
+	var body struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	if data, _ := goio.ReadAll(resp.Body); xml.Unmarshal(data, &body) == nil && body.Code != "" {
+		apiErr.Code, apiErr.Message = body.Code, body.Message
+	}
+	switch resp.StatusCode {
+	case http.StatusNotFound:
+		return nil, fmt.Errorf("%w: %w", fs.ErrNotExist, apiErr)
+	case http.StatusForbidden:
+		return nil, fmt.Errorf("%w: %w", fs.ErrPermission, apiErr)
+	}
+	return nil, apiErr
+}
+
+// doXML sends r and decodes an XML response body into out.
+func (m *Medium) doXML(r request, out any) error {
+	resp, err := m.do(r)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := goio.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	// CopyObject and CompleteMultipartUpload can fail after sending a 200.
+	var apiErr struct {
+		XMLName xml.Name `xml:"Error"`
+		Code    string   `xml:"Code"`
+		Message string   `xml:"Message"`
+	}
+	if xml.Unmarshal(data, &apiErr) == nil {
+		return &Error{StatusCode: resp.StatusCode, Code: apiErr.Code, Message: apiErr.Message}
+	}
+	if out == nil {
+		return nil
+	}
+	return xml.Unmarshal(data, out)
+}
+
+// newRequest builds the HTTP request for r and signs it with AWS Signature
+// Version 4.
+func (m *Medium) newRequest(r request) (*http.Request, error) {
+	host := m.endpoint.Host
+	escapedPath := "/"
+	if m.cfg.PathStyle {
+		escapedPath += escapePath(m.cfg.Bucket)
+		if r.key != "" {
+			escapedPath += "/" + escapePath(r.key)
+		}
+	} else {
+		host = m.cfg.Bucket + "." + host
+		escapedPath += escapePath(r.key)
+	}
+	rawURL := m.endpoint.Scheme + "://" + host + escapedPath
+	if len(r.query) > 0 {
+		rawURL += "?" + canonicalQuery(r.query)
+	}
+
+	req, err := http.NewRequest(r.method, rawURL, bytes.NewReader(r.body))
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range r.header {
+		req.Header[k] = v
+	}
+	req.ContentLength = int64(len(r.body))
+
+	now := m.now().UTC()
+	payloadHash := sha256.Sum256(r.body)
+	req.Header.Set("X-Amz-Date", now.Format("20060102T150405Z"))
+	req.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(payloadHash[:]))
+	if m.cfg.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", m.cfg.SessionToken)
+	}
+	if m.cfg.AccessKey != "" {
+		m.sign(req, host, escapedPath, canonicalQuery(r.query), now)
+	}
+	return req, nil
+}
+
+// sign adds an AWS Signature Version 4 Authorization header to req. Only the
+// host and x-amz-* headers are signed.
+func (m *Medium) sign(req *http.Request, host, escapedPath, query string, now time.Time) {
+	headers := map[string]string{"host": host}
+	for k, v := range req.Header {
+		if lk := strings.ToLower(k); strings.HasPrefix(lk, "x-amz-") {
+			headers[lk] = strings.TrimSpace(strings.Join(v, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, k := range names {
+		canonicalHeaders.WriteString(k + ":" + headers[k] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		escapedPath,
+		query,
+		canonicalHeaders.String(),
+		signedHeaders,
+		headers["x-amz-content-sha256"],
+	}, "\n")
+
+	date := now.Format("20060102")
+	scope := date + "/" + m.cfg.Region + "/s3/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + now.Format("20060102T150405Z") + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+m.cfg.SecretKey), date)
+	key = hmacSHA256(key, m.cfg.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		m.cfg.AccessKey, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// escapePath percent-encodes everything in p except unreserved characters and
+// slashes, as S3 expects in canonical requests.
+func escapePath(p string) string {
+	return escape(p, true)
+}
+
+// escape percent-encodes everything in s except unreserved characters and,
+// if keepSlash is set, slashes.
+func escape(s string, keepSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (keepSlash && c == '/') || isUnreserved(c) {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// canonicalQuery encodes q sorted by key, as S3 expects in canonical
+// requests.
+func canonicalQuery(q url.Values) string {
+	keys := make([]string, 0, len(q))
+	for k := range q {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var parts []string
+	for _, k := range keys {
+		for _, v := range q[k] {
+			parts = append(parts, escape(k, false)+"="+escape(v, false))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+func isUnreserved(c byte) bool {
+	return 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' ||
+		c == '-' || c == '_' || c == '.' || c == '~'
+}
+
+// --- XML bodies ---
+
+type listBucketResult struct {
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+	Contents              []struct {
+		Key          string    `xml:"Key"`
+		LastModified time.Time `xml:"LastModified"`
+		Size         int64     `xml:"Size"`
+	} `xml:"Contents"`
+	CommonPrefixes []struct {
+		Prefix string `xml:"Prefix"`
+	} `xml:"CommonPrefixes"`
+}
+
+type initiateMultipartUploadResult struct {
+	UploadID string `xml:"UploadId"`
+}
+
+type completedPart struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+}
+
+type completeMultipartUpload struct {
+	XMLName xml.Name        `xml:"CompleteMultipartUpload"`
+	Parts   []completedPart `xml:"Part"`
+}
@@ -0,0 +1,598 @@
+// Package s3 provides an io.Medium backed by an S3-compatible object store,
+// such as AWS S3 or MinIO.
+//
+// Object stores have no directories, so they are emulated with key prefixes:
+// a directory exists while any key starts with its path and a slash.
+// EnsureDir writes an empty marker object ending in a slash so that empty
+// directories survive.
+package s3
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	goio "io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/host-uk/core/pkg/io"
+)
+
+const (
+	// DefaultRegion is used when Config.Region is empty.
+	DefaultRegion = "us-east-1"
+	// DefaultPartSize is used when Config.PartSize is zero.
+	DefaultPartSize = 16 << 20
+	// MinPartSize is the smallest part S3 accepts in a multipart upload,
+	// other than the last.
+	MinPartSize = 5 << 20
+)
+
+// Config describes the bucket a Medium stores its files in.
+type Config struct {
+	// Endpoint is the base URL of the S3 API, for example
+	// "http://minio.local:9000". If empty, the AWS endpoint for Region is
+	// used.
+	Endpoint string `json:"endpoint,omitempty"`
+	// Region is the bucket's region. Defaults to DefaultRegion.
+	Region string `json:"region,omitempty"`
+	// Bucket is the name of the bucket.
+	Bucket string `json:"bucket"`
+	// Prefix is prepended to every key, so a Medium can be rooted at a
+	// "directory" of a shared bucket.
+	Prefix string `json:"prefix,omitempty"`
+	// AccessKey and SecretKey are the credentials to sign requests with. If
+	// both are empty, AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY are used,
+	// and requests are sent unsigned if those are empty too.
+	AccessKey string `json:"accessKey,omitempty"`
+	SecretKey string `json:"secretKey,omitempty"`
+	// SessionToken is sent with temporary credentials. If empty and the
+	// credentials come from the environment, AWS_SESSION_TOKEN is used.
+	SessionToken string `json:"sessionToken,omitempty"`
+	// PathStyle addresses the bucket as a path of the endpoint rather than a
+	// subdomain. MinIO and most self-hosted stores need it.
+	PathStyle bool `json:"pathStyle,omitempty"`
+	// PartSize is the size of each part of a multipart upload. Files larger
+	// than this are uploaded in parts. Defaults to DefaultPartSize; S3 rejects
+	// parts smaller than MinPartSize.
+	PartSize int64 `json:"partSize,omitempty"`
+	// HTTPClient sends the requests. Defaults to http.DefaultClient.
+	HTTPClient *http.Client `json:"-"`
+}
+
+// ParseURL parses a storage URL of the form
+//
+//	s3://[accessKey:secretKey@]bucket[/prefix][?endpoint=URL&region=R&pathStyle=true&partSize=N]
+//
+// into a Config. It lets a single config string point a service at a bucket.
+func ParseURL(raw string) (Config, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return Config{}, err
+	}
+	if u.Scheme != "s3" {
+		return Config{}, fmt.Errorf("s3: unsupported storage URL scheme %q", u.Scheme)
+	}
+	cfg := Config{
+		Bucket:   u.Host,
+		Prefix:   strings.TrimPrefix(u.Path, "/"),
+		Endpoint: u.Query().Get("endpoint"),
+		Region:   u.Query().Get("region"),
+	}
+	if u.User != nil {
+		cfg.AccessKey = u.User.Username()
+		cfg.SecretKey, _ = u.User.Password()
+	}
+	if v := u.Query().Get("pathStyle"); v != "" {
+		if cfg.PathStyle, err = strconv.ParseBool(v); err != nil {
+			return Config{}, fmt.Errorf("s3: invalid pathStyle %q: %w", v, err)
+		}
+	}
+	if v := u.Query().Get("partSize"); v != "" {
+		if cfg.PartSize, err = strconv.ParseInt(v, 10, 64); err != nil {
+			return Config{}, fmt.Errorf("s3: invalid partSize %q: %w", v, err)
+		}
+	}
+	return cfg, nil
+}
+
+// Medium is an io.Medium that stores files as objects in a bucket.
+type Medium struct {
+	cfg      Config
+	endpoint *url.URL
+	prefix   string
+	client   *http.Client
+	now      func() time.Time
+}
+
+// Ensure Medium implements io.Medium.
+var _ io.Medium = (*Medium)(nil)
+
+// New creates a Medium for the bucket described by cfg. It does not contact
+// the server.
+func New(cfg Config) (*Medium, error) {
+	if cfg.Bucket == "" {
+		return nil, errors.New("s3: bucket is required")
+	}
+	if cfg.Region == "" {
+		cfg.Region = DefaultRegion
+	}
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = "https://s3." + cfg.Region + ".amazonaws.com"
+	}
+	if cfg.PartSize <= 0 {
+		cfg.PartSize = DefaultPartSize
+	}
+	if cfg.AccessKey == "" && cfg.SecretKey == "" {
+		cfg.AccessKey = os.Getenv("AWS_ACCESS_KEY_ID")
+		cfg.SecretKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+		if cfg.SessionToken == "" {
+			cfg.SessionToken = os.Getenv("AWS_SESSION_TOKEN")
+		}
+	}
+
+	endpoint, err := url.Parse(strings.TrimSuffix(cfg.Endpoint, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("s3: invalid endpoint: %w", err)
+	}
+	if endpoint.Scheme != "http" && endpoint.Scheme != "https" {
+		return nil, fmt.Errorf("s3: endpoint %q must be an http or https URL", cfg.Endpoint)
+	}
+
+	client := cfg.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	prefix := cleanKey(cfg.Prefix)
+	if prefix != "" {
+		prefix += "/"
+	}
+
+	return &Medium{cfg: cfg, endpoint: endpoint, prefix: prefix, client: client, now: time.Now}, nil
+}
+
+// cleanKey turns a medium path into a key relative to the prefix. The root is
+// the empty string, and ".." cannot climb above it.
+func cleanKey(p string) string {
+	return strings.TrimPrefix(path.Clean("/"+strings.ReplaceAll(p, "\\", "/")), "/")
+}
+
+// key returns the object key for a path.
+func (m *Medium) key(p string) string {
+	return m.prefix + cleanKey(p)
+}
+
+// dirKey returns the key prefix shared by everything inside a directory,
+// which is also the key of its marker object.
+func (m *Medium) dirKey(p string) string {
+	if rel := cleanKey(p); rel != "" {
+		return m.prefix + rel + "/"
+	}
+	return m.prefix
+}
+
+// Read retrieves the content of a file as a string.
+func (m *Medium) Read(p string) (string, error) {
+	f, err := m.Open(p)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	data, err := goio.ReadAll(f)
+	if err != nil {
+		return "", &fs.PathError{Op: "read", Path: p, Err: err}
+	}
+	return string(data), nil
+}
+
+// Write saves the given content to a file, overwriting it if it exists.
+func (m *Medium) Write(p, content string) error {
+	w, err := m.Create(p)
+	if err != nil {
+		return err
+	}
+	if _, err := goio.WriteString(w, content); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+// EnsureDir makes sure a directory exists by writing its marker object.
+func (m *Medium) EnsureDir(p string) error {
+	if cleanKey(p) == "" || m.IsDir(p) {
+		return nil
+	}
+	if m.IsFile(p) {
+		return &fs.PathError{Op: "mkdir", Path: p, Err: fs.ErrExist}
+	}
+	if err := m.put(m.dirKey(p), nil); err != nil {
+		return &fs.PathError{Op: "mkdir", Path: p, Err: err}
+	}
+	return nil
+}
+
+// IsFile checks if a path exists and is a file.
+func (m *Medium) IsFile(p string) bool {
+	info, err := m.Stat(p)
+	return err == nil && !info.IsDir()
+}
+
+// FileGet is a convenience function that reads a file from the bucket.
+func (m *Medium) FileGet(p string) (string, error) {
+	return m.Read(p)
+}
+
+// FileSet is a convenience function that writes a file to the bucket.
+func (m *Medium) FileSet(p, content string) error {
+	return m.Write(p, content)
+}
+
+// Open opens a file for streaming reads. The content is streamed from the
+// server as it is read.
+func (m *Medium) Open(p string) (fs.File, error) {
+	if cleanKey(p) == "" {
+		return nil, &fs.PathError{Op: "open", Path: p, Err: fs.ErrInvalid}
+	}
+	resp, err := m.do(request{method: http.MethodGet, key: m.key(p)})
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: p, Err: err}
+	}
+	return &file{ReadCloser: resp.Body, info: fileInfo(path.Base(cleanKey(p)), resp.Header)}, nil
+}
+
+// Create returns a writer for a file. Content is buffered until it reaches
+// the part size, after which it is sent as a multipart upload; smaller files
+// are sent in one request when the writer is closed.
+func (m *Medium) Create(p string) (goio.WriteCloser, error) {
+	if cleanKey(p) == "" {
+		return nil, &fs.PathError{Op: "create", Path: p, Err: fs.ErrInvalid}
+	}
+	return &writer{medium: m, path: p, key: m.key(p)}, nil
+}
+
+// List returns the files and directories directly inside a directory.
+func (m *Medium) List(p string) ([]fs.DirEntry, error) {
+	prefix := m.dirKey(p)
+	var entries []fs.DirEntry
+	marker := false
+	err := m.list(prefix, "/", 0, func(r *listBucketResult) {
+		for _, cp := range r.CommonPrefixes {
+			name := strings.TrimSuffix(strings.TrimPrefix(cp.Prefix, prefix), "/")
+			entries = append(entries, fs.FileInfoToDirEntry(io.NewFileInfo(name, 0, fs.ModeDir|0755, time.Time{})))
+		}
+		for _, obj := range r.Contents {
+			if obj.Key == prefix {
+				marker = true
+				continue
+			}
+			name := strings.TrimPrefix(obj.Key, prefix)
+			entries = append(entries, fs.FileInfoToDirEntry(io.NewFileInfo(name, obj.Size, 0644, obj.LastModified)))
+		}
+	})
+	if err != nil {
+		return nil, &fs.PathError{Op: "readdir", Path: p, Err: err}
+	}
+	if len(entries) == 0 && !marker && cleanKey(p) != "" {
+		if m.IsFile(p) {
+			return nil, &fs.PathError{Op: "readdir", Path: p, Err: fs.ErrInvalid}
+		}
+		return nil, &fs.PathError{Op: "readdir", Path: p, Err: fs.ErrNotExist}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
+
+// Stat returns information about a file or directory.
+func (m *Medium) Stat(p string) (fs.FileInfo, error) {
+	name := path.Base(cleanKey(p))
+	if cleanKey(p) == "" {
+		return io.NewFileInfo(".", 0, fs.ModeDir|0755, time.Time{}), nil
+	}
+
+	resp, err := m.do(request{method: http.MethodHead, key: m.key(p)})
+	if err == nil {
+		resp.Body.Close()
+		return fileInfo(name, resp.Header), nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return nil, &fs.PathError{Op: "stat", Path: p, Err: err}
+	}
+
+	found := false
+	err = m.list(m.dirKey(p), "", 1, func(r *listBucketResult) {
+		found = found || len(r.Contents) > 0
+	})
+	if err != nil {
+		return nil, &fs.PathError{Op: "stat", Path: p, Err: err}
+	}
+	if !found {
+		return nil, &fs.PathError{Op: "stat", Path: p, Err: fs.ErrNotExist}
+	}
+	return io.NewFileInfo(name, 0, fs.ModeDir|0755, time.Time{}), nil
+}
+
+// Exists checks if a path exists, as either a file or a directory.
+func (m *Medium) Exists(p string) bool {
+	_, err := m.Stat(p)
+	return err == nil
+}
+
+// IsDir checks if a path exists and is a directory.
+func (m *Medium) IsDir(p string) bool {
+	info, err := m.Stat(p)
+	return err == nil && info.IsDir()
+}
+
+// Delete removes a file or an empty directory.
+func (m *Medium) Delete(p string) error {
+	if cleanKey(p) == "" {
+		return errors.New("refusing to delete the medium root")
+	}
+	info, err := m.Stat(p)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return m.deleteKey(p, m.key(p))
+	}
+
+	prefix := m.dirKey(p)
+	empty := true
+	err = m.list(prefix, "", 2, func(r *listBucketResult) {
+		for _, obj := range r.Contents {
+			empty = empty && obj.Key == prefix
+		}
+	})
+	if err != nil {
+		return &fs.PathError{Op: "remove", Path: p, Err: err}
+	}
+	if !empty {
+		return &fs.PathError{Op: "remove", Path: p, Err: fs.ErrExist}
+	}
+	return m.deleteKey(p, prefix)
+}
+
+// DeleteAll removes a path and every key below it. It returns nil if the path
+// does not exist.
+func (m *Medium) DeleteAll(p string) error {
+	if cleanKey(p) == "" {
+		return errors.New("refusing to delete the medium root")
+	}
+	keys, err := m.keysUnder(m.dirKey(p))
+	if err != nil {
+		return &fs.PathError{Op: "remove", Path: p, Err: err}
+	}
+	for _, key := range append(keys, m.key(p)) {
+		if err := m.deleteKey(p, key); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// Rename moves a file or directory. Object stores cannot rename, so every key
+// is copied to its new name and then deleted.
+func (m *Medium) Rename(oldPath, newPath string) error {
+	info, err := m.Stat(oldPath)
+	if err != nil {
+		return &fs.PathError{Op: "rename", Path: oldPath, Err: err}
+	}
+	if !info.IsDir() {
+		if err := m.copyKey(m.key(oldPath), m.key(newPath)); err != nil {
+			return &fs.PathError{Op: "rename", Path: oldPath, Err: err}
+		}
+		return m.deleteKey(oldPath, m.key(oldPath))
+	}
+
+	oldPrefix, newPrefix := m.dirKey(oldPath), m.dirKey(newPath)
+	if oldPrefix == m.prefix || strings.HasPrefix(newPrefix, oldPrefix) {
+		return &fs.PathError{Op: "rename", Path: oldPath, Err: fs.ErrInvalid}
+	}
+	keys, err := m.keysUnder(oldPrefix)
+	if err != nil {
+		return &fs.PathError{Op: "rename", Path: oldPath, Err: err}
+	}
+	for _, key := range keys {
+		if err := m.copyKey(key, newPrefix+strings.TrimPrefix(key, oldPrefix)); err != nil {
+			return &fs.PathError{Op: "rename", Path: oldPath, Err: err}
+		}
+	}
+	for _, key := range keys {
+		if err := m.deleteKey(oldPath, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WalkDir walks the tree rooted at root, listing one directory at a time.
+func (m *Medium) WalkDir(root string, fn fs.WalkDirFunc) error {
+	return io.WalkDir(m, root, fn)
+}
+
+// --- Object operations ---
+
+// put stores body under key in a single request.
+func (m *Medium) put(key string, body []byte) error {
+	resp, err := m.do(request{method: http.MethodPut, key: key, body: body})
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// deleteKey deletes an object. S3 reports success for missing keys.
+func (m *Medium) deleteKey(p, key string) error {
+	resp, err := m.do(request{method: http.MethodDelete, key: key})
+	if err != nil {
+		return &fs.PathError{Op: "remove", Path: p, Err: err}
+	}
+	return resp.Body.Close()
+}
+
+// copyKey copies an object within the bucket.
+func (m *Medium) copyKey(src, dst string) error {
+	header := http.Header{}
+	header.Set("X-Amz-Copy-Source", "/"+escapePath(m.cfg.Bucket+"/"+src))
+	return m.doXML(request{method: http.MethodPut, key: dst, header: header}, nil)
+}
+
+// list calls fn with each page of the keys starting with prefix. With a
+// delimiter, keys below the next delimiter are grouped into CommonPrefixes.
+// If limit is positive only the first page is fetched, with at most limit
+// keys.
+func (m *Medium) list(prefix, delimiter string, limit int, fn func(*listBucketResult)) error {
+	query := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+	if delimiter != "" {
+		query.Set("delimiter", delimiter)
+	}
+	if limit > 0 {
+		query.Set("max-keys", strconv.Itoa(limit))
+	}
+	for {
+		var result listBucketResult
+		if err := m.doXML(request{method: http.MethodGet, query: query}, &result); err != nil {
+			return err
+		}
+		fn(&result)
+		if limit > 0 || !result.IsTruncated || result.NextContinuationToken == "" {
+			return nil
+		}
+		query.Set("continuation-token", result.NextContinuationToken)
+	}
+}
+
+// keysUnder returns every key starting with prefix.
+func (m *Medium) keysUnder(prefix string) ([]string, error) {
+	var keys []string
+	err := m.list(prefix, "", 0, func(r *listBucketResult) {
+		for _, obj := range r.Contents {
+			keys = append(keys, obj.Key)
+		}
+	})
+	return keys, err
+}
+
+// fileInfo describes an object from the headers of a GET or HEAD response.
+func fileInfo(name string, header http.Header) fs.FileInfo {
+	size, _ := strconv.ParseInt(header.Get("Content-Length"), 10, 64)
+	modTime, _ := http.ParseTime(header.Get("Last-Modified"))
+	return io.NewFileInfo(name, size, 0644, modTime)
+}
+
+// file is an object being streamed from the server.
+type file struct {
+	goio.ReadCloser
+	info fs.FileInfo
+}
+
+func (f *file) Stat() (fs.FileInfo, error) { return f.info, nil }
+
+// writer buffers a file until it is closed or a part is full.
+type writer struct {
+	medium   *Medium
+	path     string
+	key      string
+	buf      bytes.Buffer
+	uploadID string
+	parts    []completedPart
+	closed   bool
+}
+
+func (w *writer) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, fs.ErrClosed
+	}
+	n, _ := w.buf.Write(p)
+	for int64(w.buf.Len()) >= w.medium.cfg.PartSize {
+		if err := w.uploadPart(w.buf.Next(int(w.medium.cfg.PartSize))); err != nil {
+			w.abort()
+			return n, &fs.PathError{Op: "write", Path: w.path, Err: err}
+		}
+	}
+	return n, nil
+}
+
+func (w *writer) Close() error {
+	if w.closed {
+		return fs.ErrClosed
+	}
+	w.closed = true
+	if w.uploadID == "" {
+		if err := w.medium.put(w.key, w.buf.Bytes()); err != nil {
+			return &fs.PathError{Op: "write", Path: w.path, Err: err}
+		}
+		return nil
+	}
+
+	if w.buf.Len() > 0 {
+		if err := w.uploadPart(w.buf.Bytes()); err != nil {
+			w.abort()
+			return &fs.PathError{Op: "write", Path: w.path, Err: err}
+		}
+	}
+	body, err := xml.Marshal(completeMultipartUpload{Parts: w.parts})
+	if err == nil {
+		err = w.medium.doXML(request{
+			method: http.MethodPost,
+			key:    w.key,
+			query:  url.Values{"uploadId": {w.uploadID}},
+			body:   body,
+		}, nil)
+	}
+	if err != nil {
+		w.abort()
+		return &fs.PathError{Op: "write", Path: w.path, Err: err}
+	}
+	return nil
+}
+
+// uploadPart sends the next part of a multipart upload, starting the upload
+// first if needed.
+func (w *writer) uploadPart(data []byte) error {
+	if w.uploadID == "" {
+		var result initiateMultipartUploadResult
+		err := w.medium.doXML(request{method: http.MethodPost, key: w.key, query: url.Values{"uploads": {""}}}, &result)
+		if err != nil {
+			return err
+		}
+		w.uploadID = result.UploadID
+	}
+
+	number := len(w.parts) + 1
+	resp, err := w.medium.do(request{
+		method: http.MethodPut,
+		key:    w.key,
+		query:  url.Values{"partNumber": {strconv.Itoa(number)}, "uploadId": {w.uploadID}},
+		body:   data,
+	})
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	w.parts = append(w.parts, completedPart{PartNumber: number, ETag: resp.Header.Get("ETag")})
+	return nil
+}
+
+// abort cancels a multipart upload so its parts are not stored.
+func (w *writer) abort() {
+	if w.uploadID == "" {
+		return
+	}
+	w.closed = true
+	resp, err := w.medium.do(request{method: http.MethodDelete, key: w.key, query: url.Values{"uploadId": {w.uploadID}}})
+	if err == nil {
+		resp.Body.Close()
+	}
+	w.uploadID = ""
+}
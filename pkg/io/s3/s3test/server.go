@@ -0,0 +1,374 @@
+// Package s3test provides an in-process fake of the S3 API for tests.
+//
+// The server keeps objects in memory and supports the calls the s3 medium
+// makes: GetObject, HeadObject, PutObject, CopyObject, DeleteObject,
+// ListObjectsV2 and multipart uploads. Buckets are addressed path-style, so
+// clients must set PathStyle.
+package s3test
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Default credentials accepted by a Server.
+const (
+	AccessKey = "test-access-key"
+	SecretKey = "test-secret-key"
+)
+
+// object is a stored object.
+type object struct {
+	data    []byte
+	modTime time.Time
+}
+
+// upload is a multipart upload in progress.
+type upload struct {
+	bucket string
+	key    string
+	parts  map[int][]byte
+}
+
+// Server is a fake S3 server. Create one with NewServer and close it when the
+// test is done.
+type Server struct {
+	*httptest.Server
+
+	// AccessKey is the only access key the server accepts.
+	AccessKey string
+	// Now returns the modification time given to new objects.
+	Now func() time.Time
+	// MaxKeys caps the keys in each ListObjectsV2 page, so tests can
+	// exercise pagination.
+	MaxKeys int
+
+	mu         sync.Mutex
+	buckets    map[string]map[string]*object
+	uploads    map[string]*upload
+	nextUpload int
+	completed  int
+}
+
+// NewServer starts a server with the named buckets.
+func NewServer(buckets ...string) *Server {
+	s := &Server{
+		AccessKey: AccessKey,
+		Now:       time.Now,
+		buckets:   make(map[string]map[string]*object),
+		uploads:   make(map[string]*upload),
+		MaxKeys:   1000,
+	}
+	for _, b := range buckets {
+		s.buckets[b] = make(map[string]*object)
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// Object returns the content stored under key in bucket.
+func (s *Server) Object(bucket, key string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	obj, ok := s.buckets[bucket][key]
+	if !ok {
+		return nil, false
+	}
+	return append([]byte(nil), obj.data...), true
+}
+
+// Keys returns the keys stored in bucket, sorted.
+func (s *Server) Keys(bucket string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return sortedKeys(s.buckets[bucket])
+}
+
+// CompletedUploads returns the number of multipart uploads completed.
+func (s *Server) CompletedUploads() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.completed
+}
+
+// PendingUploads returns the number of multipart uploads neither completed
+// nor aborted.
+func (s *Server) PendingUploads() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.uploads)
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "IncompleteBody", err.Error())
+		return
+	}
+	if !s.authorized(r) {
+		writeError(w, http.StatusForbidden, "AccessDenied", "Access Denied.")
+		return
+	}
+	if hash := r.Header.Get("X-Amz-Content-Sha256"); hash != "" && hash != "UNSIGNED-PAYLOAD" {
+		sum := sha256.Sum256(body)
+		if hash != hex.EncodeToString(sum[:]) {
+			writeError(w, http.StatusBadRequest, "XAmzContentSHA256Mismatch", "The provided 'x-amz-content-sha256' header does not match what was computed.")
+			return
+		}
+	}
+
+	bucketName, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	bucket, ok := s.buckets[bucketName]
+	if !ok {
+		writeError(w, http.StatusNotFound, "NoSuchBucket", "The specified bucket does not exist")
+		return
+	}
+	query := r.URL.Query()
+
+	switch {
+	case key == "" && r.Method == http.MethodGet:
+		s.list(w, bucket, query)
+	case key == "":
+		writeError(w, http.StatusMethodNotAllowed, "MethodNotAllowed", "")
+	case r.Method == http.MethodPost && query.Has("uploads"):
+		s.nextUpload++
+		id := strconv.Itoa(s.nextUpload)
+		s.uploads[id] = &upload{bucket: bucketName, key: key, parts: make(map[int][]byte)}
+		writeXML(w, struct {
+			XMLName  xml.Name `xml:"InitiateMultipartUploadResult"`
+			Bucket   string
+			Key      string
+			UploadID string `xml:"UploadId"`
+		}{Bucket: bucketName, Key: key, UploadID: id})
+	case r.Method == http.MethodPut && query.Has("uploadId"):
+		up, ok := s.upload(w, query.Get("uploadId"), bucketName, key)
+		if !ok {
+			return
+		}
+		n, err := strconv.Atoi(query.Get("partNumber"))
+		if err != nil || n < 1 {
+			writeError(w, http.StatusBadRequest, "InvalidArgument", "Part number must be a positive integer.")
+			return
+		}
+		up.parts[n] = body
+		w.Header().Set("ETag", etag(body))
+	case r.Method == http.MethodPost && query.Has("uploadId"):
+		s.complete(w, bucket, bucketName, key, query.Get("uploadId"), body)
+	case r.Method == http.MethodDelete && query.Has("uploadId"):
+		if _, ok := s.upload(w, query.Get("uploadId"), bucketName, key); ok {
+			delete(s.uploads, query.Get("uploadId"))
+			w.WriteHeader(http.StatusNoContent)
+		}
+	case r.Method == http.MethodPut && r.Header.Get("X-Amz-Copy-Source") != "":
+		s.copy(w, bucket, key, r.Header.Get("X-Amz-Copy-Source"))
+	case r.Method == http.MethodPut:
+		bucket[key] = &object{data: body, modTime: s.Now().UTC().Truncate(time.Second)}
+		w.Header().Set("ETag", etag(body))
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		obj, ok := bucket[key]
+		if !ok {
+			writeError(w, http.StatusNotFound, "NoSuchKey", "The specified key does not exist.")
+			return
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(obj.data)))
+		w.Header().Set("Last-Modified", obj.modTime.Format(http.TimeFormat))
+		w.Header().Set("ETag", etag(obj.data))
+		if r.Method == http.MethodGet {
+			w.Write(obj.data)
+		}
+	case r.Method == http.MethodDelete:
+		delete(bucket, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "MethodNotAllowed", "")
+	}
+}
+
+// authorized checks that a request is signed with the server's access key.
+// Signatures themselves are not verified.
+func (s *Server) authorized(r *http.Request) bool {
+	return strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential="+s.AccessKey+"/")
+}
+
+func (s *Server) list(w http.ResponseWriter, bucket map[string]*object, query url.Values) {
+	prefix, delimiter := query.Get("prefix"), query.Get("delimiter")
+	maxKeys := s.MaxKeys
+	if v, err := strconv.Atoi(query.Get("max-keys")); err == nil && v < maxKeys {
+		maxKeys = v
+	}
+
+	type content struct {
+		Key          string
+		LastModified time.Time
+		Size         int
+		ETag         string
+	}
+	type commonPrefix struct {
+		Prefix string
+	}
+	result := struct {
+		XMLName               xml.Name `xml:"ListBucketResult"`
+		Prefix                string
+		KeyCount              int
+		MaxKeys               int
+		IsTruncated           bool
+		NextContinuationToken string         `xml:",omitempty"`
+		Contents              []content      `xml:"Contents"`
+		CommonPrefixes        []commonPrefix `xml:"CommonPrefixes"`
+	}{Prefix: prefix, MaxKeys: maxKeys}
+
+	// The continuation token is the last key or prefix already returned.
+	after := query.Get("continuation-token")
+	seen := make(map[string]bool)
+	lastGrouped := false
+	for _, key := range sortedKeys(bucket) {
+		if !strings.HasPrefix(key, prefix) || key <= after {
+			continue
+		}
+		name, grouped := key, false
+		if delimiter != "" {
+			if i := strings.Index(key[len(prefix):], delimiter); i >= 0 {
+				name, grouped = key[:len(prefix)+i+len(delimiter)], true
+				if seen[name] || name <= after {
+					continue
+				}
+			}
+		}
+		if result.KeyCount == maxKeys {
+			result.IsTruncated = true
+			break
+		}
+		result.KeyCount++
+		result.NextContinuationToken, lastGrouped = name, grouped
+		if grouped {
+			seen[name] = true
+			result.CommonPrefixes = append(result.CommonPrefixes, commonPrefix{Prefix: name})
+			continue
+		}
+		obj := bucket[key]
+		result.Contents = append(result.Contents, content{Key: key, LastModified: obj.modTime, Size: len(obj.data), ETag: etag(obj.data)})
+	}
+	if !result.IsTruncated {
+		result.NextContinuationToken = ""
+	} else if lastGrouped {
+		// Skip everything inside the last common prefix on the next page.
+		result.NextContinuationToken += "\U0010FFFF"
+	}
+	writeXML(w, result)
+}
+
+func (s *Server) copy(w http.ResponseWriter, bucket map[string]*object, key, source string) {
+	source, err := url.PathUnescape(strings.TrimPrefix(source, "/"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "InvalidArgument", "Copy Source must mention the source bucket and key: sourcebucket/sourcekey.")
+		return
+	}
+	srcBucket, srcKey, _ := strings.Cut(source, "/")
+	obj, ok := s.buckets[srcBucket][srcKey]
+	if !ok {
+		writeError(w, http.StatusNotFound, "NoSuchKey", "The specified key does not exist.")
+		return
+	}
+	now := s.Now().UTC().Truncate(time.Second)
+	bucket[key] = &object{data: append([]byte(nil), obj.data...), modTime: now}
+	writeXML(w, struct {
+		XMLName      xml.Name `xml:"CopyObjectResult"`
+		LastModified time.Time
+		ETag         string
+	}{LastModified: now, ETag: etag(obj.data)})
+}
+
+func (s *Server) complete(w http.ResponseWriter, bucket map[string]*object, bucketName, key, id string, body []byte) {
+	up, ok := s.upload(w, id, bucketName, key)
+	if !ok {
+		return
+	}
+	var req struct {
+		Parts []struct {
+			PartNumber int
+			ETag       string
+		} `xml:"Part"`
+	}
+	if err := xml.Unmarshal(body, &req); err != nil || len(req.Parts) == 0 {
+		writeError(w, http.StatusBadRequest, "MalformedXML", "The XML you provided was not well-formed.")
+		return
+	}
+	var data []byte
+	for i, part := range req.Parts {
+		content, ok := up.parts[part.PartNumber]
+		if !ok || part.ETag != etag(content) || (i > 0 && part.PartNumber <= req.Parts[i-1].PartNumber) {
+			writeError(w, http.StatusBadRequest, "InvalidPart", "One or more of the specified parts could not be found.")
+			return
+		}
+		data = append(data, content...)
+	}
+	bucket[key] = &object{data: data, modTime: s.Now().UTC().Truncate(time.Second)}
+	delete(s.uploads, id)
+	s.completed++
+	writeXML(w, struct {
+		XMLName xml.Name `xml:"CompleteMultipartUploadResult"`
+		Bucket  string
+		Key     string
+	}{Bucket: bucketName, Key: key})
+}
+
+// upload finds a multipart upload, writing an error if it does not exist.
+func (s *Server) upload(w http.ResponseWriter, id, bucket, key string) (*upload, bool) {
+	up, ok := s.uploads[id]
+	if !ok || up.bucket != bucket || up.key != key {
+		writeError(w, http.StatusNotFound, "NoSuchUpload", "The specified multipart upload does not exist.")
+		return nil, false
+	}
+	return up, true
+}
+
+func sortedKeys(bucket map[string]*object) []string {
+	keys := make([]string, 0, len(bucket))
+	for k := range bucket {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func etag(data []byte) string {
+	sum := md5.Sum(data)
+	return `"` + hex.EncodeToString(sum[:]) + `"`
+}
+
+func writeXML(w http.ResponseWriter, v any) {
+	data, err := xml.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/xml")
+	fmt.Fprint(w, xml.Header)
+	w.Write(data)
+}
+
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	data, _ := xml.Marshal(struct {
+		XMLName xml.Name `xml:"Error"`
+		Code    string
+		Message string
+	}{Code: code, Message: message})
+	fmt.Fprint(w, xml.Header)
+	w.Write(data)
+}
@@ -0,0 +1,150 @@
+package sftp
+
+import (
+	"errors"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"strings"
+
+	gosftp "github.com/pkg/sftp"
+)
+
+// ErrTraversal is returned for paths that would leave the medium root, either
+// through ".." or through a symlink on the remote host.
+var ErrTraversal = errors.New("path traversal attempt detected")
+
+// maxSymlinks limits how many symlinks resolve follows for one path, so
+// symlink loops fail instead of spinning.
+const maxSymlinks = 255
+
+// path connects if needed, then joins the relative path with the root
+// directory, following symlinks. Returns ErrTraversal if the path leads out
+// of the root.
+func (m *Medium) path(relativePath string) (*gosftp.Client, string, error) {
+	return m.resolvePath(relativePath, true)
+}
+
+// pathNoFollow is like path, but leaves a symlink in the last element alone.
+func (m *Medium) pathNoFollow(relativePath string) (*gosftp.Client, string, error) {
+	return m.resolvePath(relativePath, false)
+}
+
+func (m *Medium) resolvePath(relativePath string, followLast bool) (*gosftp.Client, string, error) {
+	rel, err := clean(relativePath)
+	if err != nil {
+		return nil, "", err
+	}
+	client, err := m.conn()
+	if err != nil {
+		return nil, "", err
+	}
+	fullPath, err := m.resolve(client, rel, followLast)
+	if err != nil {
+		return nil, "", err
+	}
+	return client, fullPath, nil
+}
+
+// clean returns relativePath cleaned and made relative to the root, with "."
+// for the root itself. Returns ErrTraversal if the path uses ".." to climb
+// above the root; names that merely start with dots, such as "..foo", are
+// fine.
+func clean(relativePath string) (string, error) {
+	cleanPath := path.Clean(filepath.ToSlash(relativePath))
+	if cleanPath == ".." || strings.HasPrefix(cleanPath, "../") {
+		return "", ErrTraversal
+	}
+	cleanPath = strings.TrimLeft(cleanPath, "/")
+	if cleanPath == "" {
+		return ".", nil
+	}
+	return cleanPath, nil
+}
+
+// within reports whether fullPath is the root or below it.
+func (m *Medium) within(fullPath string) bool {
+	return under(fullPath, m.root)
+}
+
+// under reports whether p is dir or below it.
+func under(p, dir string) bool {
+	return p == dir || strings.HasPrefix(p, strings.TrimSuffix(dir, "/")+"/")
+}
+
+// relative returns the absolute path p relative to the root, accepting the
+// root by either of its names. ok is false if p is outside the root.
+func (m *Medium) relative(p string) (rel string, ok bool) {
+	for _, root := range []string{m.root, m.realRoot} {
+		if root != "" && under(p, root) {
+			return strings.TrimPrefix(p, root), true
+		}
+	}
+	return "", false
+}
+
+// resolve joins the clean relative path rel with the root, following any
+// symlinks along the way on the remote host, and returns ErrTraversal if one
+// of them leads out of the root. Symlinks that stay inside the root are
+// allowed. Components that don't exist yet are joined as they are. If
+// followLast is false, a symlink in the last component is left alone, so
+// that it can be removed or renamed rather than its target.
+func (m *Medium) resolve(client *gosftp.Client, rel string, followLast bool) (string, error) {
+	current := m.root
+	parts := split(rel)
+	links := 0
+	for len(parts) > 0 {
+		name := parts[0]
+		parts = parts[1:]
+
+		if name == ".." {
+			current = path.Dir(current)
+			if !m.within(current) {
+				return "", ErrTraversal
+			}
+			continue
+		}
+
+		next := path.Join(current, name)
+		if len(parts) == 0 && !followLast {
+			current = next
+			break
+		}
+		info, err := client.Lstat(next)
+		if err != nil || info.Mode()&fs.ModeSymlink == 0 {
+			current = next
+			continue
+		}
+
+		links++
+		if links > maxSymlinks {
+			return "", &fs.PathError{Op: "resolve", Path: rel, Err: errors.New("too many levels of symbolic links")}
+		}
+		target, err := client.ReadLink(next)
+		if err != nil {
+			return "", pathError("readlink", rel, err)
+		}
+		if path.IsAbs(target) {
+			rest, ok := m.relative(path.Clean(target))
+			if !ok {
+				return "", ErrTraversal
+			}
+			current, parts = m.root, append(split(rest), parts...)
+		} else {
+			parts = append(split(target), parts...)
+		}
+	}
+	return current, nil
+}
+
+// split returns the elements of a relative path, without empty or "."
+// elements.
+func split(p string) []string {
+	var parts []string
+	for _, part := range strings.Split(p, "/") {
+		if part != "" && part != "." {
+			parts = append(parts, part)
+		}
+	}
+	return parts
+}
@@ -0,0 +1,489 @@
+// Package sftp provides an io.Medium for files on a remote host, accessed
+// over SFTP.
+//
+// Paths are sandboxed to a root directory on the remote host in the same way
+// as local.Medium: symlinks on the host are followed, one element at a time,
+// and any path that leads out of the root fails with ErrTraversal. The SSH
+// connection is opened on first use and shared by every later call; if it
+// drops, the next call reconnects.
+package sftp
+
+import (
+	"errors"
+	"fmt"
+	goio "io"
+	"io/fs"
+	"net"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	gosftp "github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+
+	"github.com/host-uk/core/pkg/io"
+)
+
+// DefaultTimeout is used when Config.Timeout is zero.
+const DefaultTimeout = 30 * time.Second
+
+// Config describes the remote host and directory a Medium works in.
+type Config struct {
+	// Addr is the host to connect to, with an optional port (default 22).
+	Addr string `json:"addr"`
+	// User is the user to log in as.
+	User string `json:"user"`
+	// Root is the remote directory every path is confined to. A relative
+	// root is resolved against the login directory. Defaults to the login
+	// directory.
+	Root string `json:"root,omitempty"`
+
+	// KeyFile is a private key file to authenticate with.
+	KeyFile string `json:"keyFile,omitempty"`
+	// Key is a PEM-encoded private key to authenticate with, used instead of
+	// KeyFile.
+	Key []byte `json:"-"`
+	// KeyPassphrase decrypts an encrypted private key.
+	KeyPassphrase string `json:"-"`
+	// Agent authenticates with the keys held by the SSH agent listening on
+	// AgentSocket, or SSH_AUTH_SOCK if that is empty.
+	Agent       bool   `json:"agent,omitempty"`
+	AgentSocket string `json:"agentSocket,omitempty"`
+
+	// KnownHostsFile lists the host keys that are trusted. Defaults to
+	// ~/.ssh/known_hosts. Connections to hosts whose key is missing or
+	// different are refused.
+	KnownHostsFile string `json:"knownHostsFile,omitempty"`
+	// HostKeyCallback, if set, verifies host keys instead of KnownHostsFile.
+	HostKeyCallback ssh.HostKeyCallback `json:"-"`
+
+	// Timeout limits how long connecting may take. Defaults to
+	// DefaultTimeout.
+	Timeout time.Duration `json:"timeout,omitempty"`
+}
+
+// ParseURL parses a storage URL of the form
+//
+//	sftp://user@host[:port][/root][?key=PATH&agent=true&knownHosts=PATH]
+//
+// into a Config. A root of "/~/" is relative to the login directory, so
+// "sftp://dev@build1/~/src" is the src directory in dev's home.
+func ParseURL(raw string) (Config, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return Config{}, err
+	}
+	if u.Scheme != "sftp" {
+		return Config{}, fmt.Errorf("sftp: unsupported storage URL scheme %q", u.Scheme)
+	}
+	if u.User == nil || u.User.Username() == "" {
+		return Config{}, errors.New("sftp: storage URL must include a user")
+	}
+	cfg := Config{
+		Addr:           u.Host,
+		User:           u.User.Username(),
+		Root:           u.Path,
+		KeyFile:        u.Query().Get("key"),
+		KnownHostsFile: u.Query().Get("knownHosts"),
+		Agent:          u.Query().Get("agent") == "true",
+	}
+	if rest, ok := strings.CutPrefix(cfg.Root, "/~"); ok {
+		cfg.Root = strings.TrimPrefix(rest, "/")
+	}
+	return cfg, nil
+}
+
+// Medium is an io.Medium for a directory on a remote host.
+type Medium struct {
+	cfg Config
+
+	mu     sync.Mutex
+	ssh    *ssh.Client
+	client *gosftp.Client
+	root   string
+	// realRoot is root as the server resolves it, which differs from root
+	// when root itself goes through a symlink.
+	realRoot string
+}
+
+// Ensure Medium implements io.Medium.
+var _ io.Medium = (*Medium)(nil)
+
+// New creates a Medium for the host described by cfg. The connection is made
+// on first use.
+func New(cfg Config) (*Medium, error) {
+	if cfg.Addr == "" || cfg.User == "" {
+		return nil, errors.New("sftp: addr and user are required")
+	}
+	if cfg.KeyFile == "" && cfg.Key == nil && !cfg.Agent {
+		return nil, errors.New("sftp: a key or the SSH agent is required to authenticate")
+	}
+	if _, _, err := net.SplitHostPort(cfg.Addr); err != nil {
+		cfg.Addr = net.JoinHostPort(cfg.Addr, "22")
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+	return &Medium{cfg: cfg}, nil
+}
+
+// Close closes the connection, if one is open. The Medium can still be used;
+// the next call reconnects.
+func (m *Medium) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.ssh == nil {
+		return nil
+	}
+	m.client.Close()
+	err := m.ssh.Close()
+	m.ssh, m.client = nil, nil
+	return err
+}
+
+// conn returns the SFTP client, connecting if there is no open connection.
+func (m *Medium) conn() (*gosftp.Client, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.client != nil {
+		return m.client, nil
+	}
+
+	config, closeAgent, err := m.clientConfig()
+	if err != nil {
+		return nil, err
+	}
+	sshClient, err := ssh.Dial("tcp", m.cfg.Addr, config)
+	closeAgent()
+	if err != nil {
+		return nil, fmt.Errorf("sftp: failed to connect to %s: %w", m.cfg.Addr, err)
+	}
+	client, err := gosftp.NewClient(sshClient)
+	if err != nil {
+		sshClient.Close()
+		return nil, fmt.Errorf("sftp: failed to start SFTP session: %w", err)
+	}
+
+	if m.root == "" {
+		root := m.cfg.Root
+		if root == "" {
+			root = "."
+		}
+		if !path.IsAbs(root) {
+			if root, err = client.RealPath(root); err != nil {
+				client.Close()
+				sshClient.Close()
+				return nil, fmt.Errorf("sftp: failed to resolve root %q: %w", m.cfg.Root, err)
+			}
+		}
+		m.root = path.Clean(root)
+		// Symlinks on the host may point at the root by its real path.
+		m.realRoot = m.root
+		if real, err := client.RealPath(m.root); err == nil {
+			m.realRoot = path.Clean(real)
+		}
+	}
+
+	m.ssh, m.client = sshClient, client
+	go func() {
+		// Forget the connection once it drops, so the next call reconnects.
+		sshClient.Wait()
+		m.mu.Lock()
+		if m.ssh == sshClient {
+			m.ssh, m.client = nil, nil
+		}
+		m.mu.Unlock()
+	}()
+	return client, nil
+}
+
+// clientConfig builds the SSH client configuration from m.cfg. The returned
+// function closes the agent connection, if any, once authentication is done.
+func (m *Medium) clientConfig() (*ssh.ClientConfig, func(), error) {
+	var auth []ssh.AuthMethod
+	closeAgent := func() {}
+
+	key := m.cfg.Key
+	if key == nil && m.cfg.KeyFile != "" {
+		data, err := os.ReadFile(m.cfg.KeyFile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("sftp: failed to read key file: %w", err)
+		}
+		key = data
+	}
+	if key != nil {
+		var signer ssh.Signer
+		var err error
+		if m.cfg.KeyPassphrase != "" {
+			signer, err = ssh.ParsePrivateKeyWithPassphrase(key, []byte(m.cfg.KeyPassphrase))
+		} else {
+			signer, err = ssh.ParsePrivateKey(key)
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("sftp: failed to parse private key: %w", err)
+		}
+		auth = append(auth, ssh.PublicKeys(signer))
+	}
+
+	if m.cfg.Agent {
+		socket := m.cfg.AgentSocket
+		if socket == "" {
+			socket = os.Getenv("SSH_AUTH_SOCK")
+		}
+		if socket == "" {
+			return nil, nil, errors.New("sftp: SSH agent requested but SSH_AUTH_SOCK is not set")
+		}
+		conn, err := net.Dial("unix", socket)
+		if err != nil {
+			return nil, nil, fmt.Errorf("sftp: failed to connect to SSH agent: %w", err)
+		}
+		closeAgent = func() { conn.Close() }
+		auth = append(auth, ssh.PublicKeysCallback(agent.NewClient(conn).Signers))
+	}
+
+	hostKeyCallback := m.cfg.HostKeyCallback
+	if hostKeyCallback == nil {
+		file := m.cfg.KnownHostsFile
+		if file == "" {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				closeAgent()
+				return nil, nil, fmt.Errorf("sftp: failed to find known_hosts: %w", err)
+			}
+			file = filepath.Join(home, ".ssh", "known_hosts")
+		}
+		callback, err := knownhosts.New(file)
+		if err != nil {
+			closeAgent()
+			return nil, nil, fmt.Errorf("sftp: failed to read known hosts: %w", err)
+		}
+		hostKeyCallback = callback
+	}
+
+	return &ssh.ClientConfig{
+		User:            m.cfg.User,
+		Auth:            auth,
+		HostKeyCallback: hostKeyCallback,
+		Timeout:         m.cfg.Timeout,
+	}, closeAgent, nil
+}
+
+// Read retrieves the content of a file as a string.
+func (m *Medium) Read(relativePath string) (string, error) {
+	f, err := m.Open(relativePath)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	content, err := goio.ReadAll(f)
+	if err != nil {
+		return "", err
+	}
+	return string(content), nil
+}
+
+// Write saves the given content to a file, overwriting it if it exists.
+// Parent directories are created automatically.
+func (m *Medium) Write(relativePath, content string) error {
+	w, err := m.Create(relativePath)
+	if err != nil {
+		return err
+	}
+	if _, err := goio.WriteString(w, content); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+// EnsureDir makes sure a directory exists, creating it if necessary.
+func (m *Medium) EnsureDir(relativePath string) error {
+	client, fullPath, err := m.path(relativePath)
+	if err != nil {
+		return err
+	}
+	return client.MkdirAll(fullPath)
+}
+
+// IsFile checks if a path exists and is a regular file.
+func (m *Medium) IsFile(relativePath string) bool {
+	info, err := m.Stat(relativePath)
+	return err == nil && info.Mode().IsRegular()
+}
+
+// FileGet is a convenience function that reads a file from the medium.
+func (m *Medium) FileGet(relativePath string) (string, error) {
+	return m.Read(relativePath)
+}
+
+// FileSet is a convenience function that writes a file to the medium.
+func (m *Medium) FileSet(relativePath, content string) error {
+	return m.Write(relativePath, content)
+}
+
+// Open opens a file for reading.
+func (m *Medium) Open(relativePath string) (fs.File, error) {
+	client, fullPath, err := m.path(relativePath)
+	if err != nil {
+		return nil, err
+	}
+	f, err := client.Open(fullPath)
+	if err != nil {
+		return nil, pathError("open", relativePath, err)
+	}
+	return f, nil
+}
+
+// Create creates or truncates a file for writing.
+// Parent directories are created automatically.
+func (m *Medium) Create(relativePath string) (goio.WriteCloser, error) {
+	client, fullPath, err := m.path(relativePath)
+	if err != nil {
+		return nil, err
+	}
+	if err := client.MkdirAll(path.Dir(fullPath)); err != nil {
+		return nil, err
+	}
+	f, err := client.Create(fullPath)
+	if err != nil {
+		return nil, pathError("create", relativePath, err)
+	}
+	return f, nil
+}
+
+// List returns the entries of a directory, sorted by name.
+func (m *Medium) List(relativePath string) ([]fs.DirEntry, error) {
+	client, fullPath, err := m.path(relativePath)
+	if err != nil {
+		return nil, err
+	}
+	infos, err := client.ReadDir(fullPath)
+	if err != nil {
+		return nil, pathError("readdir", relativePath, err)
+	}
+	entries := make([]fs.DirEntry, len(infos))
+	for i, info := range infos {
+		entries[i] = fs.FileInfoToDirEntry(info)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
+
+// Stat returns information about a file or directory.
+func (m *Medium) Stat(relativePath string) (fs.FileInfo, error) {
+	client, fullPath, err := m.path(relativePath)
+	if err != nil {
+		return nil, err
+	}
+	info, err := client.Stat(fullPath)
+	if err != nil {
+		return nil, pathError("stat", relativePath, err)
+	}
+	return info, nil
+}
+
+// Exists checks if a path exists, as either a file or a directory.
+func (m *Medium) Exists(relativePath string) bool {
+	_, err := m.Stat(relativePath)
+	return err == nil
+}
+
+// IsDir checks if a path exists and is a directory.
+func (m *Medium) IsDir(relativePath string) bool {
+	info, err := m.Stat(relativePath)
+	return err == nil && info.IsDir()
+}
+
+// Delete removes a file or an empty directory.
+func (m *Medium) Delete(relativePath string) error {
+	client, fullPath, err := m.pathNoFollow(relativePath)
+	if err != nil {
+		return err
+	}
+	if fullPath == m.root {
+		return errors.New("refusing to delete the medium root")
+	}
+	if err := client.Remove(fullPath); err != nil {
+		return pathError("remove", relativePath, err)
+	}
+	return nil
+}
+
+// DeleteAll removes a path and everything below it.
+// It returns nil if the path does not exist.
+func (m *Medium) DeleteAll(relativePath string) error {
+	client, fullPath, err := m.pathNoFollow(relativePath)
+	if err != nil {
+		return err
+	}
+	if fullPath == m.root {
+		return errors.New("refusing to delete the medium root")
+	}
+	info, err := client.Lstat(fullPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err == nil && !info.IsDir() {
+		// RemoveAll follows a symlink at the top, so remove links and files
+		// directly; entries below a real directory are not followed.
+		err = client.Remove(fullPath)
+	} else {
+		err = client.RemoveAll(fullPath)
+	}
+	if err != nil {
+		return pathError("remove", relativePath, err)
+	}
+	return nil
+}
+
+// Rename moves a file or directory, replacing the destination if the server
+// supports it. Parent directories of the destination are created
+// automatically.
+func (m *Medium) Rename(oldRelativePath, newRelativePath string) error {
+	client, oldPath, err := m.pathNoFollow(oldRelativePath)
+	if err != nil {
+		return err
+	}
+	_, newPath, err := m.pathNoFollow(newRelativePath)
+	if err != nil {
+		return err
+	}
+	if err := client.MkdirAll(path.Dir(newPath)); err != nil {
+		return err
+	}
+
+	if _, ok := client.HasExtension("posix-rename@openssh.com"); ok {
+		err = client.PosixRename(oldPath, newPath)
+	} else {
+		err = client.Rename(oldPath, newPath)
+	}
+	if err != nil {
+		return pathError("rename", oldRelativePath, err)
+	}
+	return nil
+}
+
+// WalkDir walks the tree rooted at root, calling fn for each file and
+// directory. Paths passed to fn are relative to the medium, in the same form
+// as root.
+func (m *Medium) WalkDir(root string, fn fs.WalkDirFunc) error {
+	return io.WalkDir(m, root, fn)
+}
+
+// pathError reports err against the medium path rather than the remote one,
+// unless it already names a path.
+func pathError(op, relativePath string, err error) error {
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		return &fs.PathError{Op: op, Path: relativePath, Err: pathErr.Err}
+	}
+	return &fs.PathError{Op: op, Path: relativePath, Err: err}
+}
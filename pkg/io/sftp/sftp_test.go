@@ -0,0 +1,363 @@
+package sftp
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"io/fs"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/host-uk/core/pkg/io/sftp/sftptest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+func newTestMedium(t *testing.T, mutate ...func(*Config)) (*Medium, *sftptest.Server, string) {
+	t.Helper()
+	dir := t.TempDir()
+	root := filepath.Join(dir, "root")
+	require.NoError(t, os.Mkdir(root, 0755))
+	srv := sftptest.NewServer(root)
+	t.Cleanup(srv.Close)
+
+	knownHosts := filepath.Join(dir, "known_hosts")
+	require.NoError(t, os.WriteFile(knownHosts, []byte(srv.KnownHostsLine()+"\n"), 0600))
+
+	cfg := Config{
+		Addr:           srv.Addr,
+		User:           sftptest.User,
+		Key:            srv.ClientKey,
+		KnownHostsFile: knownHosts,
+		Timeout:        5 * time.Second,
+	}
+	for _, fn := range mutate {
+		fn(&cfg)
+	}
+	m, err := New(cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() { m.Close() })
+	return m, srv, root
+}
+
+func TestMedium_ReadWrite(t *testing.T) {
+	m, _, root := newTestMedium(t)
+
+	require.NoError(t, m.Write("notes/hello.txt", "hello"))
+	data, err := os.ReadFile(filepath.Join(root, "notes", "hello.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(data))
+
+	content, err := m.Read("notes/hello.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "hello", content)
+
+	content, err = m.FileGet("/notes/../notes/hello.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "hello", content, "paths are cleaned")
+
+	require.NoError(t, m.FileSet("notes/hello.txt", "bye"))
+	content, err = m.Read("notes/hello.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "bye", content, "writes truncate")
+
+	_, err = m.Read("missing.txt")
+	assert.True(t, errors.Is(err, fs.ErrNotExist))
+	var pathErr *fs.PathError
+	require.ErrorAs(t, err, &pathErr)
+	assert.Equal(t, "missing.txt", pathErr.Path)
+}
+
+func TestMedium_Sandbox(t *testing.T) {
+	m, _, root := newTestMedium(t)
+
+	for _, p := range []string{"../escape.txt", "a/../../escape.txt", ".."} {
+		assert.Error(t, m.Write(p, "x"), p)
+		_, err := m.Read(p)
+		assert.Error(t, err, p)
+	}
+	_, err := os.Stat(filepath.Join(filepath.Dir(root), "escape.txt"))
+	assert.True(t, os.IsNotExist(err))
+
+	// Absolute paths are relative to the root.
+	require.NoError(t, m.Write("/abs.txt", "x"))
+	assert.FileExists(t, filepath.Join(root, "abs.txt"))
+}
+
+func TestMedium_SymlinkSandbox(t *testing.T) {
+	m, _, root := newTestMedium(t)
+	outside := root + "-other"
+	require.NoError(t, os.Mkdir(outside, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(outside, "secret.txt"), []byte("secret"), 0644))
+
+	require.NoError(t, os.Symlink(outside, filepath.Join(root, "abs")))
+	require.NoError(t, os.Symlink("../root-other", filepath.Join(root, "rel")))
+	require.NoError(t, os.Symlink("../root-other/secret.txt", filepath.Join(root, "file")))
+
+	t.Run("Links out of the root fail", func(t *testing.T) {
+		for _, p := range []string{"abs/secret.txt", "rel/secret.txt", "file"} {
+			_, err := m.Read(p)
+			assert.ErrorIs(t, err, ErrTraversal, p)
+			_, err = m.Stat(p)
+			assert.ErrorIs(t, err, ErrTraversal, p)
+		}
+		assert.ErrorIs(t, m.Write("abs/new.txt", "x"), ErrTraversal)
+		assert.ErrorIs(t, m.Write("rel/sub/new.txt", "x"), ErrTraversal)
+		assert.ErrorIs(t, m.Write("file", "x"), ErrTraversal)
+		assert.ErrorIs(t, m.EnsureDir("rel/sub"), ErrTraversal)
+		_, err := m.List("abs")
+		assert.ErrorIs(t, err, ErrTraversal)
+		assert.ErrorIs(t, m.Rename("abs/secret.txt", "stolen.txt"), ErrTraversal)
+
+		_, err = os.Stat(filepath.Join(outside, "new.txt"))
+		assert.True(t, os.IsNotExist(err))
+		_, err = os.Stat(filepath.Join(outside, "sub"))
+		assert.True(t, os.IsNotExist(err))
+		data, err := os.ReadFile(filepath.Join(outside, "secret.txt"))
+		require.NoError(t, err)
+		assert.Equal(t, "secret", string(data))
+	})
+
+	t.Run("Links inside the root work", func(t *testing.T) {
+		require.NoError(t, m.Write("dir/real.txt", "hello"))
+		require.NoError(t, os.Symlink("dir", filepath.Join(root, "inner")))
+		require.NoError(t, os.Symlink(filepath.Join(root, "dir", "real.txt"), filepath.Join(root, "inner-abs")))
+
+		content, err := m.Read("inner/real.txt")
+		require.NoError(t, err)
+		assert.Equal(t, "hello", content)
+		content, err = m.Read("inner-abs")
+		require.NoError(t, err)
+		assert.Equal(t, "hello", content)
+		require.NoError(t, m.Write("inner/other.txt", "x"))
+		assert.FileExists(t, filepath.Join(root, "dir", "other.txt"))
+	})
+
+	t.Run("Deleting a link removes the link", func(t *testing.T) {
+		require.NoError(t, m.Delete("file"))
+		require.NoError(t, m.DeleteAll("abs"))
+		_, err := os.Lstat(filepath.Join(root, "abs"))
+		assert.True(t, os.IsNotExist(err))
+		require.NoError(t, m.EnsureDir("tree"))
+		require.NoError(t, os.Symlink(outside, filepath.Join(root, "tree", "link")))
+		require.NoError(t, m.DeleteAll("tree"))
+		assert.NoDirExists(t, filepath.Join(root, "tree"))
+		assert.FileExists(t, filepath.Join(outside, "secret.txt"))
+	})
+
+	t.Run("Loops fail", func(t *testing.T) {
+		require.NoError(t, os.Symlink("loop-b", filepath.Join(root, "loop-a")))
+		require.NoError(t, os.Symlink("loop-a", filepath.Join(root, "loop-b")))
+		_, err := m.Read("loop-a")
+		assert.Error(t, err)
+	})
+
+	t.Run("Names starting with dots are allowed", func(t *testing.T) {
+		require.NoError(t, m.Write("..foo", "x"))
+		assert.FileExists(t, filepath.Join(root, "..foo"))
+		require.NoError(t, m.Write("a/..b/c.txt", "x"))
+		assert.FileExists(t, filepath.Join(root, "a", "..b", "c.txt"))
+	})
+}
+
+func TestMedium_Directories(t *testing.T) {
+	m, _, _ := newTestMedium(t)
+
+	require.NoError(t, m.EnsureDir("empty"))
+	require.NoError(t, m.Write("docs/b.md", "bb"))
+	require.NoError(t, m.Write("docs/a.md", "a"))
+	require.NoError(t, m.EnsureDir("docs/sub"))
+
+	assert.True(t, m.IsDir("empty"))
+	assert.True(t, m.IsDir("docs"))
+	assert.True(t, m.IsFile("docs/a.md"))
+	assert.False(t, m.IsFile("docs"))
+	assert.False(t, m.Exists("nope"))
+
+	entries, err := m.List("docs")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a.md", "b.md", "sub"}, names(entries))
+	assert.True(t, entries[2].IsDir())
+
+	info, err := m.Stat("docs/b.md")
+	require.NoError(t, err)
+	assert.Equal(t, "b.md", info.Name())
+	assert.Equal(t, int64(2), info.Size())
+
+	var walked []string
+	err = m.WalkDir("docs", func(p string, d fs.DirEntry, err error) error {
+		require.NoError(t, err)
+		walked = append(walked, p)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"docs", "docs/a.md", "docs/b.md", "docs/sub"}, walked)
+
+	_, err = m.List("nope")
+	assert.True(t, errors.Is(err, fs.ErrNotExist))
+}
+
+func TestMedium_DeleteRename(t *testing.T) {
+	m, _, _ := newTestMedium(t)
+	require.NoError(t, m.Write("dir/a.txt", "a"))
+	require.NoError(t, m.Write("dir/sub/b.txt", "b"))
+	require.NoError(t, m.Write("other.txt", "other"))
+
+	assert.Error(t, m.Delete(""))
+	assert.Error(t, m.DeleteAll("/"))
+	assert.Error(t, m.Delete("dir"), "non-empty directories are not deleted")
+	assert.True(t, errors.Is(m.Delete("missing"), fs.ErrNotExist))
+
+	require.NoError(t, m.Rename("dir/a.txt", "moved/a.txt"))
+	assert.False(t, m.Exists("dir/a.txt"))
+	content, err := m.Read("moved/a.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "a", content)
+
+	require.NoError(t, m.Rename("other.txt", "moved/a.txt"))
+	content, err = m.Read("moved/a.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "other", content, "rename replaces the destination")
+
+	require.NoError(t, m.DeleteAll("dir"))
+	assert.False(t, m.Exists("dir"))
+	require.NoError(t, m.DeleteAll("missing"))
+	require.NoError(t, m.Delete("moved/a.txt"))
+	require.NoError(t, m.Delete("moved"))
+	assert.False(t, m.Exists("moved"))
+}
+
+func TestMedium_ConnectionReuse(t *testing.T) {
+	m, srv, _ := newTestMedium(t)
+
+	for i := 0; i < 5; i++ {
+		require.NoError(t, m.Write("a.txt", "a"))
+		assert.True(t, m.IsFile("a.txt"))
+	}
+	assert.Equal(t, 1, srv.Connections())
+
+	// A dropped connection is replaced on the next call.
+	srv.DropConnections()
+	assert.Eventually(t, func() bool {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		return m.client == nil
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.True(t, m.IsFile("a.txt"))
+	assert.Equal(t, 2, srv.Connections())
+
+	require.NoError(t, m.Close())
+	assert.True(t, m.IsFile("a.txt"))
+	assert.Equal(t, 3, srv.Connections())
+}
+
+func TestMedium_Agent(t *testing.T) {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	keyring := agent.NewKeyring()
+	require.NoError(t, keyring.Add(agent.AddedKey{PrivateKey: priv}))
+
+	socket := filepath.Join(t.TempDir(), "agent.sock")
+	listener, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go agent.ServeAgent(keyring, conn)
+		}
+	}()
+
+	m, srv, _ := newTestMedium(t, func(c *Config) {
+		c.Key = nil
+		c.Agent = true
+		c.AgentSocket = socket
+	})
+	assert.Error(t, m.Write("a.txt", "a"), "the agent key is not authorized yet")
+
+	signer, err := ssh.NewSignerFromKey(priv)
+	require.NoError(t, err)
+	srv.Authorize(signer.PublicKey())
+	require.NoError(t, m.Write("a.txt", "a"))
+}
+
+func TestMedium_Bad(t *testing.T) {
+	// A host key that differs from known_hosts is refused, as is an
+	// unknown host.
+	m, _, _ := newTestMedium(t, func(c *Config) {
+		file := filepath.Join(t.TempDir(), "known_hosts")
+		other := sftptest.NewServer(t.TempDir())
+		defer other.Close()
+		line := knownhosts.Line([]string{knownhosts.Normalize(c.Addr)}, other.HostKey)
+		require.NoError(t, os.WriteFile(file, []byte(line+"\n"), 0600))
+		c.KnownHostsFile = file
+	})
+	err := m.Write("a.txt", "a")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "key mismatch")
+
+	m, _, _ = newTestMedium(t, func(c *Config) {
+		file := filepath.Join(t.TempDir(), "known_hosts")
+		require.NoError(t, os.WriteFile(file, nil, 0600))
+		c.KnownHostsFile = file
+	})
+	err = m.Write("a.txt", "a")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "key is unknown")
+
+	// So is a key the server does not accept.
+	m, _, _ = newTestMedium(t, func(c *Config) {
+		other := sftptest.NewServer(t.TempDir())
+		defer other.Close()
+		c.Key = other.ClientKey
+	})
+	assert.Error(t, m.Write("a.txt", "a"))
+
+	_, err = New(Config{Addr: "host"})
+	assert.Error(t, err)
+	_, err = New(Config{Addr: "host", User: "dev"})
+	assert.Error(t, err, "a key or agent is required")
+
+	m, err = New(Config{Addr: "host", User: "dev", Agent: true})
+	require.NoError(t, err)
+	assert.Equal(t, "host:22", m.cfg.Addr)
+}
+
+func TestParseURL(t *testing.T) {
+	cfg, err := ParseURL("sftp://dev@build1:2222/srv/app?key=/home/dev/.ssh/id_ed25519&knownHosts=/tmp/kh")
+	require.NoError(t, err)
+	assert.Equal(t, Config{
+		Addr:           "build1:2222",
+		User:           "dev",
+		Root:           "/srv/app",
+		KeyFile:        "/home/dev/.ssh/id_ed25519",
+		KnownHostsFile: "/tmp/kh",
+	}, cfg)
+
+	cfg, err = ParseURL("sftp://dev@build1/~/src?agent=true")
+	require.NoError(t, err)
+	assert.Equal(t, "src", cfg.Root)
+	assert.True(t, cfg.Agent)
+
+	_, err = ParseURL("s3://bucket")
+	assert.Error(t, err)
+	_, err = ParseURL("sftp://build1/")
+	assert.Error(t, err)
+}
+
+func names(entries []fs.DirEntry) []string {
+	out := make([]string, len(entries))
+	for i, e := range entries {
+		out[i] = e.Name()
+	}
+	return out
+}
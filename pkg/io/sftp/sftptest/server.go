@@ -0,0 +1,193 @@
+// Package sftptest provides an in-process SSH server with the SFTP subsystem
+// for tests.
+//
+// The server serves the local filesystem, with relative paths resolved against
+// the directory it was started with. It accepts public-key authentication
+// only, for ClientKey and any keys added with Authorize.
+package sftptest
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"fmt"
+	"net"
+	"sync"
+
+	gosftp "github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// User is the only user name the server accepts.
+const User = "test"
+
+// Server is a fake SFTP server. Create one with NewServer and close it when
+// the test is done.
+type Server struct {
+	// Addr is the host:port the server listens on.
+	Addr string
+	// HostKey is the server's public host key.
+	HostKey ssh.PublicKey
+	// ClientKey is a PEM-encoded private key the server accepts.
+	ClientKey []byte
+
+	root     string
+	listener net.Listener
+
+	mu          sync.Mutex
+	authorized  map[string]bool
+	conns       []*ssh.ServerConn
+	connections int
+	wg          sync.WaitGroup
+}
+
+// NewServer starts a server whose working directory is root.
+func NewServer(root string) *Server {
+	_, hostPriv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		panic(fmt.Sprintf("sftptest: failed to generate host key: %v", err))
+	}
+	hostSigner, err := ssh.NewSignerFromKey(hostPriv)
+	if err != nil {
+		panic(fmt.Sprintf("sftptest: failed to create host signer: %v", err))
+	}
+	clientPub, clientPriv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		panic(fmt.Sprintf("sftptest: failed to generate client key: %v", err))
+	}
+	block, err := ssh.MarshalPrivateKey(clientPriv, "")
+	if err != nil {
+		panic(fmt.Sprintf("sftptest: failed to encode client key: %v", err))
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic(fmt.Sprintf("sftptest: failed to listen: %v", err))
+	}
+
+	s := &Server{
+		Addr:       listener.Addr().String(),
+		HostKey:    hostSigner.PublicKey(),
+		ClientKey:  pem.EncodeToMemory(block),
+		root:       root,
+		listener:   listener,
+		authorized: make(map[string]bool),
+	}
+	sshClientPub, err := ssh.NewPublicKey(clientPub)
+	if err != nil {
+		panic(fmt.Sprintf("sftptest: failed to encode client key: %v", err))
+	}
+	s.Authorize(sshClientPub)
+
+	config := &ssh.ServerConfig{
+		PublicKeyCallback: func(meta ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			if meta.User() == User && s.authorized[string(key.Marshal())] {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("sftptest: key not authorized for %q", meta.User())
+		},
+	}
+	config.AddHostKey(hostSigner)
+
+	s.wg.Add(1)
+	go s.serve(config)
+	return s
+}
+
+// Authorize adds key to the keys the server accepts.
+func (s *Server) Authorize(key ssh.PublicKey) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.authorized[string(key.Marshal())] = true
+}
+
+// KnownHostsLine returns a known_hosts line that trusts the server.
+func (s *Server) KnownHostsLine() string {
+	return knownhosts.Line([]string{knownhosts.Normalize(s.Addr)}, s.HostKey)
+}
+
+// Connections returns the number of SSH connections the server has accepted.
+func (s *Server) Connections() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.connections
+}
+
+// DropConnections closes every open connection, as if the network failed.
+func (s *Server) DropConnections() {
+	s.mu.Lock()
+	conns := s.conns
+	s.conns = nil
+	s.mu.Unlock()
+	for _, c := range conns {
+		c.Close()
+	}
+}
+
+// Close stops the server and closes every open connection.
+func (s *Server) Close() {
+	s.listener.Close()
+	s.DropConnections()
+	s.wg.Wait()
+}
+
+func (s *Server) serve(config *ssh.ServerConfig) {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.handle(conn, config)
+		}()
+	}
+}
+
+func (s *Server) handle(conn net.Conn, config *ssh.ServerConfig) {
+	serverConn, chans, reqs, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		conn.Close()
+		return
+	}
+	s.mu.Lock()
+	s.conns = append(s.conns, serverConn)
+	s.connections++
+	s.mu.Unlock()
+
+	go ssh.DiscardRequests(reqs)
+	for newChannel := range chans {
+		if newChannel.ChannelType() != "session" {
+			newChannel.Reject(ssh.UnknownChannelType, "unknown channel type")
+			continue
+		}
+		channel, requests, err := newChannel.Accept()
+		if err != nil {
+			continue
+		}
+		go s.session(channel, requests)
+	}
+}
+
+// session starts the SFTP subsystem when the client asks for it.
+func (s *Server) session(channel ssh.Channel, requests <-chan *ssh.Request) {
+	defer channel.Close()
+	for req := range requests {
+		ok := req.Type == "subsystem" && len(req.Payload) > 4 && string(req.Payload[4:]) == "sftp"
+		req.Reply(ok, nil)
+		if !ok {
+			continue
+		}
+		server, err := gosftp.NewServer(channel, gosftp.WithServerWorkingDirectory(s.root))
+		if err != nil {
+			return
+		}
+		server.Serve()
+		server.Close()
+		return
+	}
+}
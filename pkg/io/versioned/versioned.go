@@ -0,0 +1,720 @@
+// Package versioned provides an io.Medium that keeps the history of every file
+// written through it, so earlier versions can be listed, compared and
+// restored.
+//
+// Each file has a log of its versions. The content of each version is stored
+// once under its SHA-256, so writing content a file has had before takes no
+// extra space. By default history is kept in a HistoryDir directory in the
+// wrapped medium, which is hidden from listings and cannot be written through
+// the versioned medium; WithStore keeps it in another medium instead.
+//
+// Content a file had before it was first changed through the medium is saved
+// as a version too, so the first bad write can be undone. Deleting a file
+// keeps its history, so it can be restored later.
+package versioned
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	goio "io"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/host-uk/core/pkg/crypt"
+	"github.com/host-uk/core/pkg/io"
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// HistoryDir is the directory in the wrapped medium that history is kept in,
+// unless WithStore is used.
+const HistoryDir = ".versions"
+
+const (
+	// historyName names the directory a file's history is kept in, below
+	// the file's escaped path. Escaped names of files never take this name.
+	historyName = ".history"
+	logName     = "log.json"
+)
+
+var (
+	// ErrUnknownVersion is returned for a version ID that doesn't match any
+	// version of the file. It wraps fs.ErrNotExist.
+	ErrUnknownVersion = fmt.Errorf("unknown version: %w", fs.ErrNotExist)
+	// ErrAmbiguousVersion is returned for an abbreviated version ID that
+	// matches more than one version of the file.
+	ErrAmbiguousVersion = errors.New("ambiguous version")
+)
+
+// sums computes version IDs. crypt.New cannot fail.
+var sums, _ = crypt.New()
+
+// Version is one saved version of a file.
+type Version struct {
+	// ID is the hex-encoded SHA-256 of the content. Any unambiguous prefix
+	// of it can be used to refer to the version.
+	ID   string    `json:"id"`
+	Size int64     `json:"size"`
+	Time time.Time `json:"time"`
+}
+
+// Retention limits how much history is kept for each file. The newest version
+// is always kept.
+type Retention struct {
+	// MaxVersions is the number of versions kept per file. Zero keeps every
+	// version.
+	MaxVersions int
+	// MaxAge is how long versions are kept for. Zero keeps versions forever.
+	MaxAge time.Duration
+}
+
+// Option configures a Medium.
+type Option func(*Medium)
+
+// WithRetention limits how much history is kept. Limits are applied whenever
+// a file changes, and to every file by Prune.
+func WithRetention(r Retention) Option {
+	return func(m *Medium) {
+		m.retention = r
+	}
+}
+
+// WithStore keeps history in store rather than in a HistoryDir directory of the
+// wrapped medium. The wrapped medium is then left exactly as it would be
+// without versioning.
+func WithStore(store io.Medium) Option {
+	return func(m *Medium) {
+		m.store, m.root, m.shared = store, "", false
+	}
+}
+
+// Medium wraps another io.Medium and records every change to its files.
+type Medium struct {
+	base      io.Medium
+	store     io.Medium
+	root      string // directory in store holding history
+	shared    bool   // history is kept in base, under HistoryDir
+	retention Retention
+	now       func() time.Time
+
+	// mu makes each change and its history entry atomic with respect to
+	// other changes through the same Medium.
+	mu sync.Mutex
+}
+
+// Ensure Medium implements io.Medium.
+var _ io.Medium = (*Medium)(nil)
+
+// New creates a Medium that stores files in base and records their history.
+func New(base io.Medium, opts ...Option) *Medium {
+	m := &Medium{base: base, store: base, root: HistoryDir, shared: true, now: time.Now}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// Base returns the medium the current files are stored in.
+func (m *Medium) Base() io.Medium {
+	return m.base
+}
+
+// clean makes p relative to the root of the medium, so ".." cannot escape.
+// The root itself is "".
+func clean(p string) string {
+	return strings.TrimPrefix(path.Clean("/"+p), "/")
+}
+
+// internal reports whether the clean path p is part of the history kept in
+// the wrapped medium.
+func (m *Medium) internal(p string) bool {
+	return m.shared && (p == HistoryDir || strings.HasPrefix(p, HistoryDir+"/"))
+}
+
+// checkWrite returns the clean form of p, or an error if p is part of the
+// history.
+func (m *Medium) checkWrite(op, p string) (string, error) {
+	p = clean(p)
+	if p == "" || m.internal(p) {
+		return "", &fs.PathError{Op: op, Path: p, Err: fs.ErrPermission}
+	}
+	return p, nil
+}
+
+// Read retrieves the current content of a file.
+func (m *Medium) Read(p string) (string, error) {
+	p = clean(p)
+	if m.internal(p) {
+		return "", &fs.PathError{Op: "read", Path: p, Err: fs.ErrNotExist}
+	}
+	return m.base.Read(p)
+}
+
+// Write saves content to a file, overwriting it if it exists, and records it
+// as a new version.
+func (m *Medium) Write(p, content string) error {
+	p, err := m.checkWrite("write", p)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.write(p, content)
+}
+
+func (m *Medium) write(p, content string) error {
+	if err := m.snapshot(p); err != nil {
+		return err
+	}
+	if err := m.base.Write(p, content); err != nil {
+		return err
+	}
+	return m.record(p, content, m.now())
+}
+
+// EnsureDir makes sure a directory exists.
+func (m *Medium) EnsureDir(p string) error {
+	p, err := m.checkWrite("mkdir", p)
+	if err != nil {
+		return err
+	}
+	return m.base.EnsureDir(p)
+}
+
+// IsFile checks if a path exists and is a regular file.
+func (m *Medium) IsFile(p string) bool {
+	p = clean(p)
+	return !m.internal(p) && m.base.IsFile(p)
+}
+
+// FileGet is a convenience function that reads a file from the medium.
+func (m *Medium) FileGet(p string) (string, error) {
+	return m.Read(p)
+}
+
+// FileSet is a convenience function that writes a file to the medium.
+func (m *Medium) FileSet(p, content string) error {
+	return m.Write(p, content)
+}
+
+// Open opens a file for reading.
+func (m *Medium) Open(p string) (fs.File, error) {
+	p = clean(p)
+	if m.internal(p) {
+		return nil, &fs.PathError{Op: "open", Path: p, Err: fs.ErrNotExist}
+	}
+	return m.base.Open(p)
+}
+
+// Create returns a writer whose content is written, and recorded as a new
+// version, when it is closed. Nothing is written before then.
+func (m *Medium) Create(p string) (goio.WriteCloser, error) {
+	p, err := m.checkWrite("create", p)
+	if err != nil {
+		return nil, err
+	}
+	if m.base.IsDir(p) {
+		return nil, &fs.PathError{Op: "create", Path: p, Err: fs.ErrExist}
+	}
+	return &writer{medium: m, path: p}, nil
+}
+
+// List returns the entries of a directory, sorted by name. HistoryDir is left
+// out.
+func (m *Medium) List(p string) ([]fs.DirEntry, error) {
+	p = clean(p)
+	if m.internal(p) {
+		return nil, &fs.PathError{Op: "readdir", Path: p, Err: fs.ErrNotExist}
+	}
+	entries, err := m.base.List(p)
+	if err != nil || p != "" || !m.shared {
+		return entries, err
+	}
+	return slices.DeleteFunc(entries, func(e fs.DirEntry) bool {
+		return e.Name() == HistoryDir
+	}), nil
+}
+
+// Stat returns information about a file or directory.
+func (m *Medium) Stat(p string) (fs.FileInfo, error) {
+	p = clean(p)
+	if m.internal(p) {
+		return nil, &fs.PathError{Op: "stat", Path: p, Err: fs.ErrNotExist}
+	}
+	return m.base.Stat(p)
+}
+
+// Exists checks if a path exists, as either a file or a directory.
+func (m *Medium) Exists(p string) bool {
+	p = clean(p)
+	return !m.internal(p) && m.base.Exists(p)
+}
+
+// IsDir checks if a path exists and is a directory.
+func (m *Medium) IsDir(p string) bool {
+	p = clean(p)
+	return !m.internal(p) && m.base.IsDir(p)
+}
+
+// Delete removes a file or an empty directory. The history of a file is kept,
+// so it can be restored.
+func (m *Medium) Delete(p string) error {
+	p, err := m.checkWrite("remove", p)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.snapshot(p); err != nil {
+		return err
+	}
+	return m.base.Delete(p)
+}
+
+// DeleteAll removes a path and everything below it. The history of every file
+// removed is kept.
+func (m *Medium) DeleteAll(p string) error {
+	p = clean(p)
+	if m.internal(p) {
+		return &fs.PathError{Op: "remove", Path: p, Err: fs.ErrPermission}
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.snapshotAll(p); err != nil {
+		return err
+	}
+	if p != "" || !m.shared {
+		return m.base.DeleteAll(p)
+	}
+
+	// Empty the root without removing the history.
+	entries, err := m.List("")
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if err := m.base.DeleteAll(e.Name()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Rename moves a file or directory. History moves with it, after the history
+// of any file it replaces.
+func (m *Medium) Rename(oldPath, newPath string) error {
+	oldPath, err := m.checkWrite("rename", oldPath)
+	if err != nil {
+		return err
+	}
+	newPath, err = m.checkWrite("rename", newPath)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.snapshotAll(oldPath); err != nil {
+		return err
+	}
+	if err := m.snapshotAll(newPath); err != nil {
+		return err
+	}
+	if err := m.base.Rename(oldPath, newPath); err != nil {
+		return err
+	}
+	return m.moveHistory(oldPath, newPath)
+}
+
+// WalkDir walks the tree rooted at root, calling fn for each file and
+// directory. HistoryDir is skipped.
+func (m *Medium) WalkDir(root string, fn fs.WalkDirFunc) error {
+	return io.WalkDir(m, root, fn)
+}
+
+// Versions returns the saved versions of a file, newest first. The newest
+// version is the file's current content, unless it has been deleted since.
+func (m *Medium) Versions(p string) ([]Version, error) {
+	p = clean(p)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.snapshot(p); err != nil {
+		return nil, err
+	}
+	versions, err := m.readLog(p)
+	if err != nil {
+		return nil, err
+	}
+	if len(versions) == 0 {
+		return nil, &fs.PathError{Op: "versions", Path: p, Err: fs.ErrNotExist}
+	}
+	slices.Reverse(versions)
+	return versions, nil
+}
+
+// ReadVersion returns the content of a file at the version with the given
+// ID, or an unambiguous prefix of it.
+func (m *Medium) ReadVersion(p, id string) (string, error) {
+	p = clean(p)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.readVersion(p, id)
+}
+
+func (m *Medium) readVersion(p, id string) (string, error) {
+	versions, err := m.readLog(p)
+	if err != nil {
+		return "", err
+	}
+	v, err := find(versions, id)
+	if err != nil {
+		return "", &fs.PathError{Op: "version", Path: p, Err: err}
+	}
+	return m.store.Read(path.Join(m.historyDir(p), v.ID))
+}
+
+// Diff returns a unified diff from one version of a file to another.
+func (m *Medium) Diff(p, fromID, toID string) (string, error) {
+	p = clean(p)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	from, err := m.readVersion(p, fromID)
+	if err != nil {
+		return "", err
+	}
+	to, err := m.readVersion(p, toID)
+	if err != nil {
+		return "", err
+	}
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(from),
+		B:        difflib.SplitLines(to),
+		FromFile: p + "@" + short(fromID),
+		ToFile:   p + "@" + short(toID),
+		Context:  3,
+	})
+}
+
+// Restore writes the content of an earlier version back to a file, as a new
+// version. The file is recreated if it has been deleted.
+func (m *Medium) Restore(p, id string) error {
+	p, err := m.checkWrite("restore", p)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	content, err := m.readVersion(p, id)
+	if err != nil {
+		return err
+	}
+	return m.write(p, content)
+}
+
+// Prune applies the retention limits to the history of every file, including
+// deleted ones. Changing a file only applies them to that file, so call Prune
+// from time to time to expire the history of files that no longer change.
+func (m *Medium) Prune() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.store.IsDir(m.root) {
+		return nil
+	}
+
+	var files []string
+	err := m.store.WalkDir(m.root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && d.Name() == historyName {
+			files = append(files, m.fileOf(p))
+			return fs.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		versions, err := m.readLog(f)
+		if err != nil {
+			return err
+		}
+		if err := m.writeLog(f, versions); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// historyDir returns the directory in the store that holds the history of
+// the file at the clean path p.
+func (m *Medium) historyDir(p string) string {
+	return path.Join(m.root, escape(p), historyName)
+}
+
+// fileOf returns the path of the file whose history is kept in dir.
+func (m *Medium) fileOf(dir string) string {
+	p := strings.TrimSuffix(strings.TrimSuffix(dir, historyName), "/")
+	if m.root != "" {
+		p = strings.TrimPrefix(strings.TrimPrefix(p, m.root), "/")
+	}
+	return unescape(p)
+}
+
+// escape returns the clean path p with a dot added to the front of every
+// name that starts with one, so no name in it is historyName. Files named
+// ".history" or "foo.history" therefore keep histories of their own.
+func escape(p string) string {
+	names := strings.Split(p, "/")
+	for i, name := range names {
+		if strings.HasPrefix(name, ".") {
+			names[i] = "." + name
+		}
+	}
+	return strings.Join(names, "/")
+}
+
+// unescape reverses escape.
+func unescape(p string) string {
+	names := strings.Split(p, "/")
+	for i, name := range names {
+		if strings.HasPrefix(name, "..") {
+			names[i] = name[1:]
+		}
+	}
+	return strings.Join(names, "/")
+}
+
+// readLog returns the versions of the file at p, oldest first.
+func (m *Medium) readLog(p string) ([]Version, error) {
+	data, err := m.store.Read(path.Join(m.historyDir(p), logName))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var versions []Version
+	if err := json.Unmarshal([]byte(data), &versions); err != nil {
+		return nil, &fs.PathError{Op: "versions", Path: p, Err: err}
+	}
+	return versions, nil
+}
+
+// writeLog applies the retention limits to versions and saves them as the log
+// of the file at p. Content no longer used by any version is removed.
+func (m *Medium) writeLog(p string, versions []Version) error {
+	kept := m.retain(versions)
+	data, err := json.MarshalIndent(kept, "", "  ")
+	if err != nil {
+		return err
+	}
+	dir := m.historyDir(p)
+	if err := m.store.Write(path.Join(dir, logName), string(data)); err != nil {
+		return err
+	}
+	for _, v := range versions {
+		if !slices.ContainsFunc(kept, func(k Version) bool { return k.ID == v.ID }) {
+			if err := m.store.DeleteAll(path.Join(dir, v.ID)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// retain returns the versions kept by the retention limits.
+func (m *Medium) retain(versions []Version) []Version {
+	if n := m.retention.MaxVersions; n > 0 && len(versions) > n {
+		versions = versions[len(versions)-n:]
+	}
+	if m.retention.MaxAge > 0 && len(versions) > 0 {
+		cutoff := m.now().Add(-m.retention.MaxAge)
+		newest := versions[len(versions)-1]
+		versions = slices.DeleteFunc(versions[:len(versions)-1], func(v Version) bool {
+			return v.Time.Before(cutoff)
+		})
+		versions = append(versions, newest)
+	}
+	return versions
+}
+
+// record saves content as the newest version of the file at p, unless it
+// already is.
+func (m *Medium) record(p, content string, t time.Time) error {
+	versions, err := m.readLog(p)
+	if err != nil {
+		return err
+	}
+	id := sums.Hash(crypt.SHA256, content)
+	if len(versions) > 0 && versions[len(versions)-1].ID == id {
+		return nil
+	}
+
+	object := path.Join(m.historyDir(p), id)
+	if !m.store.IsFile(object) {
+		if err := m.store.Write(object, content); err != nil {
+			return err
+		}
+	}
+	return m.writeLog(p, append(versions, Version{ID: id, Size: int64(len(content)), Time: t}))
+}
+
+// snapshot saves the current content of the file at p as a version if it has
+// no history yet, so that content written before the file was versioned can
+// be restored.
+func (m *Medium) snapshot(p string) error {
+	if !m.base.IsFile(p) {
+		return nil
+	}
+	versions, err := m.readLog(p)
+	if err != nil || len(versions) > 0 {
+		return err
+	}
+	info, err := m.base.Stat(p)
+	if err != nil {
+		return err
+	}
+	content, err := m.base.Read(p)
+	if err != nil {
+		return err
+	}
+	return m.record(p, content, info.ModTime())
+}
+
+// snapshotAll takes a snapshot of every file at or below p.
+func (m *Medium) snapshotAll(p string) error {
+	if !m.Exists(p) {
+		return nil
+	}
+	return m.WalkDir(p, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		return m.snapshot(p)
+	})
+}
+
+// moveHistory moves the history of the file or directory at oldPath to
+// newPath, appending it to any history already there.
+func (m *Medium) moveHistory(oldPath, newPath string) error {
+	if m.base.IsFile(newPath) {
+		if !m.store.IsDir(m.historyDir(oldPath)) {
+			return nil
+		}
+		return m.mergeHistory(oldPath, newPath)
+	}
+
+	dir := path.Join(m.root, escape(oldPath))
+	if !m.store.IsDir(dir) {
+		return nil
+	}
+	var files []string
+	err := m.store.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && d.Name() == historyName {
+			files = append(files, m.fileOf(p))
+			return fs.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		if err := m.mergeHistory(f, newPath+strings.TrimPrefix(f, oldPath)); err != nil {
+			return err
+		}
+	}
+	return m.store.DeleteAll(dir)
+}
+
+// mergeHistory appends the history of the file at from to that of the file
+// at to, and removes it from from.
+func (m *Medium) mergeHistory(from, to string) error {
+	moved, err := m.readLog(from)
+	if err != nil {
+		return err
+	}
+	versions, err := m.readLog(to)
+	if err != nil {
+		return err
+	}
+	fromDir, toDir := m.historyDir(from), m.historyDir(to)
+	for _, v := range moved {
+		object := path.Join(toDir, v.ID)
+		if m.store.IsFile(object) {
+			continue
+		}
+		content, err := m.store.Read(path.Join(fromDir, v.ID))
+		if err != nil {
+			return err
+		}
+		if err := m.store.Write(object, content); err != nil {
+			return err
+		}
+	}
+	if err := m.writeLog(to, append(versions, moved...)); err != nil {
+		return err
+	}
+	return m.store.DeleteAll(fromDir)
+}
+
+// find returns the newest version whose ID starts with id.
+func find(versions []Version, id string) (Version, error) {
+	var found Version
+	for i := len(versions) - 1; i >= 0; i-- {
+		v := versions[i]
+		if id == "" || !strings.HasPrefix(v.ID, id) {
+			continue
+		}
+		if found.ID != "" && found.ID != v.ID {
+			return Version{}, ErrAmbiguousVersion
+		}
+		if found.ID == "" {
+			found = v
+		}
+	}
+	if found.ID == "" {
+		return Version{}, ErrUnknownVersion
+	}
+	return found, nil
+}
+
+// short abbreviates a version ID for display.
+func short(id string) string {
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
+
+// writer buffers a file created in a Medium until it is closed.
+type writer struct {
+	medium *Medium
+	path   string
+	buf    strings.Builder
+	closed bool
+}
+
+func (w *writer) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, fs.ErrClosed
+	}
+	return w.buf.Write(p)
+}
+
+func (w *writer) Close() error {
+	if w.closed {
+		return fs.ErrClosed
+	}
+	w.closed = true
+	return w.medium.Write(w.path, w.buf.String())
+}
@@ -0,0 +1,259 @@
+package versioned
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+	"time"
+
+	"github.com/host-uk/core/pkg/io"
+	"github.com/host-uk/core/pkg/io/local"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// clock sets a fixed time for m and returns it, to be moved on by the test.
+func clock(m *Medium) *time.Time {
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	m.now = func() time.Time { return now }
+	return &now
+}
+
+func TestMedium_History(t *testing.T) {
+	base := io.NewMockMedium()
+	m := New(base)
+	now := clock(m)
+
+	for _, content := range []string{"one\n", "one\ntwo\n", "one\ntwo\n", "bad\n"} {
+		require.NoError(t, m.Write("notes.txt", content))
+		*now = now.Add(time.Minute)
+	}
+
+	versions, err := m.Versions("notes.txt")
+	require.NoError(t, err)
+	require.Len(t, versions, 3, "unchanged writes are not new versions")
+	assert.Equal(t, int64(4), versions[0].Size)
+	assert.True(t, versions[0].Time.After(versions[1].Time), "newest first")
+
+	good := versions[1].ID
+	content, err := m.ReadVersion("notes.txt", good[:6])
+	require.NoError(t, err)
+	assert.Equal(t, "one\ntwo\n", content)
+
+	diff, err := m.Diff("notes.txt", good, versions[0].ID)
+	require.NoError(t, err)
+	assert.Contains(t, diff, "--- notes.txt@"+good[:12])
+	assert.Contains(t, diff, "-one\n-two\n+bad\n")
+
+	require.NoError(t, m.Restore("notes.txt", good))
+	content, err = m.Read("notes.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "one\ntwo\n", content)
+
+	versions, err = m.Versions("notes.txt")
+	require.NoError(t, err)
+	assert.Len(t, versions, 4, "restoring adds a version")
+	assert.Equal(t, good, versions[0].ID)
+
+	t.Run("Unknown version", func(t *testing.T) {
+		_, err := m.ReadVersion("notes.txt", "zzz")
+		assert.ErrorIs(t, err, ErrUnknownVersion)
+		assert.ErrorIs(t, err, fs.ErrNotExist)
+
+		_, err = m.Versions("missing.txt")
+		assert.ErrorIs(t, err, fs.ErrNotExist)
+	})
+
+	t.Run("Ambiguous version", func(t *testing.T) {
+		_, err := m.ReadVersion("notes.txt", "")
+		assert.ErrorIs(t, err, ErrUnknownVersion)
+
+		versions := []Version{{ID: "abc1"}, {ID: "abc2"}, {ID: "abc1"}}
+		_, err = find(versions, "abc")
+		assert.ErrorIs(t, err, ErrAmbiguousVersion)
+		v, err := find(versions, "abc1")
+		require.NoError(t, err)
+		assert.Equal(t, "abc1", v.ID)
+	})
+
+	t.Run("Content is stored once", func(t *testing.T) {
+		entries, err := base.List(".versions/notes.txt/.history")
+		require.NoError(t, err)
+		assert.Len(t, entries, 4, "log and three distinct contents")
+	})
+}
+
+func TestMedium_ExistingContent(t *testing.T) {
+	base := io.NewMockMedium()
+	base.Files["config.yaml"] = "original"
+	m := New(base)
+
+	require.NoError(t, m.Write("config.yaml", "broken"))
+
+	versions, err := m.Versions("config.yaml")
+	require.NoError(t, err)
+	require.Len(t, versions, 2)
+	content, err := m.ReadVersion("config.yaml", versions[1].ID)
+	require.NoError(t, err)
+	assert.Equal(t, "original", content, "content from before versioning is kept")
+
+	base.Files["untouched.yaml"] = "as is"
+	versions, err = m.Versions("untouched.yaml")
+	require.NoError(t, err)
+	assert.Len(t, versions, 1)
+}
+
+func TestMedium_Delete(t *testing.T) {
+	m := New(io.NewMockMedium())
+	require.NoError(t, m.Write("docs/a.md", "a"))
+	require.NoError(t, m.Write("docs/b.md", "b"))
+
+	require.NoError(t, m.Delete("docs/a.md"))
+	assert.False(t, m.Exists("docs/a.md"))
+	versions, err := m.Versions("docs/a.md")
+	require.NoError(t, err)
+	require.NoError(t, m.Restore("docs/a.md", versions[0].ID))
+	content, err := m.Read("docs/a.md")
+	require.NoError(t, err)
+	assert.Equal(t, "a", content)
+
+	require.NoError(t, m.DeleteAll(""))
+	entries, err := m.List("")
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+	versions, err = m.Versions("docs/b.md")
+	require.NoError(t, err)
+	assert.Len(t, versions, 1, "history survives deleting everything")
+}
+
+func TestMedium_Rename(t *testing.T) {
+	m := New(io.NewMockMedium())
+	require.NoError(t, m.Write("draft.md", "v1"))
+	require.NoError(t, m.Write("draft.md", "v2"))
+	require.NoError(t, m.Write("final.md", "old final"))
+
+	require.NoError(t, m.Rename("draft.md", "final.md"))
+	versions, err := m.Versions("final.md")
+	require.NoError(t, err)
+	require.Len(t, versions, 3, "history follows the file, after that of the file it replaced")
+	content, err := m.ReadVersion("final.md", versions[2].ID)
+	require.NoError(t, err)
+	assert.Equal(t, "old final", content)
+	_, err = m.Versions("draft.md")
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+
+	require.NoError(t, m.Write("src/main.go", "package main"))
+	require.NoError(t, m.Rename("src", "cmd"))
+	versions, err = m.Versions("cmd/main.go")
+	require.NoError(t, err)
+	assert.Len(t, versions, 1)
+	_, err = m.Versions("src/main.go")
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+}
+
+func TestMedium_HistoryNames(t *testing.T) {
+	m := New(io.NewMockMedium(), WithRetention(Retention{MaxVersions: 1}))
+	names := []string{"foo", "foo.history/a.txt", ".history", "dir/.history/log.json"}
+	for _, name := range names {
+		require.NoError(t, m.Write(name, name+" v1"))
+		require.NoError(t, m.Write(name, name+" v2"))
+	}
+
+	require.NoError(t, m.Rename("foo", "bar"))
+	require.NoError(t, m.Prune())
+	for _, name := range []string{"bar", "foo.history/a.txt", ".history", "dir/.history/log.json"} {
+		versions, err := m.Versions(name)
+		require.NoError(t, err, name)
+		require.Len(t, versions, 1, "%s keeps its own history, pruned", name)
+		content, err := m.ReadVersion(name, versions[0].ID)
+		require.NoError(t, err)
+		assert.Contains(t, content, "v2")
+	}
+	assert.Equal(t, "foo.history/a.txt", unescape(escape("foo.history/a.txt")))
+	assert.Equal(t, "..history/...x", escape(".history/..x"))
+}
+
+func TestMedium_Retention(t *testing.T) {
+	t.Run("MaxVersions", func(t *testing.T) {
+		m := New(io.NewMockMedium(), WithRetention(Retention{MaxVersions: 2}))
+		for _, content := range []string{"1", "2", "3", "4"} {
+			require.NoError(t, m.Write("f", content))
+		}
+		versions, err := m.Versions("f")
+		require.NoError(t, err)
+		require.Len(t, versions, 2)
+		content, err := m.ReadVersion("f", versions[1].ID)
+		require.NoError(t, err)
+		assert.Equal(t, "3", content)
+
+		entries, err := m.Base().List(".versions/f/.history")
+		require.NoError(t, err)
+		assert.Len(t, entries, 3, "dropped content is removed")
+	})
+
+	t.Run("MaxAge", func(t *testing.T) {
+		m := New(io.NewMockMedium(), WithRetention(Retention{MaxAge: 90 * time.Second}))
+		now := clock(m)
+		for _, content := range []string{"1", "2", "3"} {
+			require.NoError(t, m.Write("a", content))
+			*now = now.Add(time.Minute)
+		}
+		require.NoError(t, m.Write("b", "1"))
+
+		versions, err := m.Versions("a")
+		require.NoError(t, err)
+		assert.Len(t, versions, 2)
+
+		*now = now.Add(time.Hour)
+		require.NoError(t, m.Prune())
+		for _, p := range []string{"a", "b"} {
+			versions, err := m.Versions(p)
+			require.NoError(t, err)
+			assert.Len(t, versions, 1, "the newest version is always kept")
+		}
+	})
+}
+
+func TestMedium_Hidden(t *testing.T) {
+	m := New(io.NewMockMedium())
+	require.NoError(t, m.Write("a.txt", "a"))
+
+	entries, err := m.List("")
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "a.txt", entries[0].Name())
+
+	var walked []string
+	require.NoError(t, m.WalkDir("", func(p string, d fs.DirEntry, err error) error {
+		walked = append(walked, p)
+		return err
+	}))
+	assert.Equal(t, []string{"", "a.txt"}, walked)
+
+	assert.False(t, m.Exists(HistoryDir))
+	err = m.Write(HistoryDir+"/a.txt/.history/log.json", "[]")
+	assert.True(t, errors.Is(err, fs.ErrPermission))
+	assert.True(t, errors.Is(m.DeleteAll(HistoryDir), fs.ErrPermission))
+	assert.True(t, errors.Is(m.Rename("a.txt", HistoryDir+"/x"), fs.ErrPermission))
+}
+
+func TestMedium_WithStore(t *testing.T) {
+	data, err := local.New(t.TempDir())
+	require.NoError(t, err)
+	history := io.NewMockMedium()
+	m := New(data, WithStore(history))
+
+	w, err := m.Create("report.csv")
+	require.NoError(t, err)
+	_, err = w.Write([]byte("a,b\n"))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	require.NoError(t, m.Write("report.csv", "a,b\n1,2\n"))
+	assert.False(t, data.Exists(HistoryDir))
+	assert.True(t, history.IsFile("report.csv/.history/log.json"))
+
+	versions, err := m.Versions("report.csv")
+	require.NoError(t, err)
+	assert.Len(t, versions, 2)
+}
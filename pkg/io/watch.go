@@ -0,0 +1,196 @@
+package io
+
+import (
+	"io/fs"
+	"strings"
+	"time"
+
+	"github.com/host-uk/core/pkg/io/notify"
+)
+
+// --- Watching ---
+
+// Event is a change to a file or directory in a medium.
+type Event = notify.Event
+
+// Op describes what happened to a path.
+type Op = notify.Op
+
+// Change operations.
+const (
+	OpCreate = notify.OpCreate
+	OpWrite  = notify.OpWrite
+	OpRemove = notify.OpRemove
+)
+
+// Watch delivers debounced batches of changes to a watched path. Changes to
+// the same path are merged, so a file written many times in quick succession
+// is reported once.
+type Watch = notify.Watch
+
+// DefaultPollInterval is how often StartWatch polls mediums that cannot
+// watch for changes themselves.
+const DefaultPollInterval = 2 * time.Second
+
+// Watcher is implemented by mediums that can watch for changes themselves,
+// such as local.Medium, which uses the operating system's file notifications.
+type Watcher interface {
+	// Watch starts watching a file or directory. For a directory, changes to
+	// its entries are reported, and with recursive also changes anywhere
+	// below it.
+	Watch(path string, recursive bool) (*Watch, error)
+}
+
+// StartWatch watches a file or directory on m. Mediums that implement Watcher
+// watch natively; any other medium is polled every DefaultPollInterval.
+func StartWatch(m Medium, path string, recursive bool) (*Watch, error) {
+	if w, ok := m.(Watcher); ok {
+		return w.Watch(path, recursive)
+	}
+	return Poll(m, path, recursive, DefaultPollInterval)
+}
+
+// Poll watches a file or directory on m by listing it every interval and
+// comparing sizes and modification times. It works with any medium, but a
+// change is only noticed on the next poll, and a file rewritten with the same
+// size within the timestamp resolution of the medium is missed.
+func Poll(m Medium, path string, recursive bool, interval time.Duration) (*Watch, error) {
+	prev, err := snapshot(m, path, recursive)
+	if err != nil {
+		return nil, err
+	}
+
+	quit := make(chan struct{})
+	stopped := make(chan struct{})
+	w, emit := notify.New(0, func() error {
+		close(quit)
+		<-stopped
+		return nil
+	})
+
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-quit:
+				return
+			case <-ticker.C:
+			}
+			next, err := snapshot(m, path, recursive)
+			if err != nil {
+				// The watched path itself may have been removed.
+				next = map[string]fileState{}
+			}
+			for p, state := range next {
+				old, ok := prev[p]
+				switch {
+				case !ok:
+					emit(Event{Path: p, Op: OpCreate})
+				case !state.dir && (old.size != state.size || !old.modTime.Equal(state.modTime)):
+					emit(Event{Path: p, Op: OpWrite})
+				}
+			}
+			for p := range prev {
+				if _, ok := next[p]; !ok {
+					emit(Event{Path: p, Op: OpRemove})
+				}
+			}
+			prev = next
+		}
+	}()
+	return w, nil
+}
+
+// fileState is what Poll compares between polls.
+type fileState struct {
+	size    int64
+	modTime time.Time
+	dir     bool
+}
+
+// snapshot records the state of root and the paths below it.
+func snapshot(m Medium, root string, recursive bool) (map[string]fileState, error) {
+	info, err := m.Stat(root)
+	if err != nil {
+		return nil, err
+	}
+	states := map[string]fileState{
+		root: {size: info.Size(), modTime: info.ModTime(), dir: info.IsDir()},
+	}
+	if !info.IsDir() {
+		return states, nil
+	}
+
+	err = m.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// Entries may disappear while walking.
+			return nil
+		}
+		if p == root {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		states[p] = fileState{size: info.Size(), modTime: info.ModTime(), dir: d.IsDir()}
+		if d.IsDir() && !recursive {
+			return fs.SkipDir
+		}
+		return nil
+	})
+	return states, err
+}
+
+// --- MockMedium watching ---
+
+// mockWatch is a Watch on a MockMedium.
+type mockWatch struct {
+	path      string
+	recursive bool
+	emit      func(Event)
+}
+
+// covers reports whether a change to p should be reported to the watch.
+func (w *mockWatch) covers(p string) bool {
+	if p == w.path {
+		return true
+	}
+	rest, ok := strings.CutPrefix(p, dirPrefix(w.path))
+	return ok && rest != "" && (w.recursive || !strings.Contains(rest, "/"))
+}
+
+// Watch reports changes made through the mock's methods. Changes made by
+// editing its maps directly are not seen.
+func (m *MockMedium) Watch(p string, recursive bool) (*Watch, error) {
+	if !m.Exists(p) {
+		return nil, &fs.PathError{Op: "watch", Path: p, Err: fs.ErrNotExist}
+	}
+	mw := &mockWatch{path: p, recursive: recursive}
+	w, emit := notify.New(0, func() error {
+		for i, other := range m.watches {
+			if other == mw {
+				m.watches = append(m.watches[:i], m.watches[i+1:]...)
+				break
+			}
+		}
+		return nil
+	})
+	mw.emit = emit
+	m.watches = append(m.watches, mw)
+	return w, nil
+}
+
+// notify reports a change to every watch that covers p.
+func (m *MockMedium) notify(p string, op Op) {
+	for _, w := range m.watches {
+		if w.covers(p) {
+			w.emit(Event{Path: p, Op: op})
+		}
+	}
+}
+
+// Ensure MockMedium implements Watcher.
+var _ Watcher = (*MockMedium)(nil)
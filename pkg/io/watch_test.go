@@ -0,0 +1,107 @@
+package io
+
+import (
+	"testing"
+	"time"
+
+	"github.com/host-uk/core/pkg/io/local"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func nextEvents(t *testing.T, w *Watch) []Event {
+	t.Helper()
+	select {
+	case batch, ok := <-w.Events():
+		require.True(t, ok, "events channel closed")
+		return batch
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for events")
+		return nil
+	}
+}
+
+func TestMockMedium_Watch(t *testing.T) {
+	m := NewMockMedium()
+	require.NoError(t, m.Write("docs/a.md", "a"))
+	require.NoError(t, m.Write("docs/sub/b.md", "b"))
+
+	recursive, err := m.Watch("docs", true)
+	require.NoError(t, err)
+	defer recursive.Close()
+	shallow, err := m.Watch("docs", false)
+	require.NoError(t, err)
+
+	require.NoError(t, m.Write("docs/a.md", "changed"))
+	require.NoError(t, m.Write("docs/sub/new.md", "new"))
+	require.NoError(t, m.Rename("docs/sub/b.md", "docs/c.md"))
+	require.NoError(t, m.Write("other.md", "ignored"))
+
+	assert.Equal(t, []Event{
+		{Path: "docs/a.md", Op: OpWrite},
+		{Path: "docs/c.md", Op: OpCreate},
+		{Path: "docs/sub/b.md", Op: OpRemove},
+		{Path: "docs/sub/new.md", Op: OpCreate},
+	}, nextEvents(t, recursive))
+	assert.Equal(t, []Event{
+		{Path: "docs/a.md", Op: OpWrite},
+		{Path: "docs/c.md", Op: OpCreate},
+	}, nextEvents(t, shallow))
+
+	require.NoError(t, shallow.Close())
+	assert.Len(t, m.watches, 1)
+
+	require.NoError(t, m.DeleteAll("docs/sub"))
+	assert.Equal(t, []Event{
+		{Path: "docs/sub", Op: OpRemove},
+		{Path: "docs/sub/new.md", Op: OpRemove},
+	}, nextEvents(t, recursive))
+
+	_, err = m.Watch("missing", false)
+	assert.Error(t, err)
+}
+
+func TestPoll(t *testing.T) {
+	m, err := local.New(t.TempDir())
+	require.NoError(t, err)
+	require.NoError(t, m.Write("a.txt", "a"))
+	require.NoError(t, m.Write("sub/b.txt", "b"))
+
+	// Hide the native watcher so the medium is polled.
+	var medium Medium = struct{ Medium }{m}
+	_, native := medium.(Watcher)
+	require.False(t, native)
+
+	w, err := Poll(medium, "", true, 20*time.Millisecond)
+	require.NoError(t, err)
+	defer w.Close()
+
+	require.NoError(t, m.Write("a.txt", "changed"))
+	require.NoError(t, m.Write("sub/c.txt", "c"))
+	require.NoError(t, m.Delete("sub/b.txt"))
+	assert.Equal(t, []Event{
+		{Path: "a.txt", Op: OpWrite},
+		{Path: "sub/b.txt", Op: OpRemove},
+		{Path: "sub/c.txt", Op: OpCreate},
+	}, nextEvents(t, w))
+
+	shallow, err := Poll(medium, "", false, 20*time.Millisecond)
+	require.NoError(t, err)
+	defer shallow.Close()
+	require.NoError(t, m.Write("sub/d.txt", "d"))
+	require.NoError(t, m.Write("e.txt", "e"))
+	assert.Equal(t, []Event{{Path: "e.txt", Op: OpCreate}}, nextEvents(t, shallow))
+
+	_, err = Poll(medium, "missing", false, time.Second)
+	assert.Error(t, err)
+}
+
+func TestStartWatch(t *testing.T) {
+	m := NewMockMedium()
+	require.NoError(t, m.EnsureDir("dir"))
+	w, err := StartWatch(m, "dir", false)
+	require.NoError(t, err)
+	defer w.Close()
+	require.NoError(t, m.Write("dir/x", "x"))
+	assert.Equal(t, []Event{{Path: "dir/x", Op: OpCreate}}, nextEvents(t, w))
+}
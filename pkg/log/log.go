@@ -0,0 +1,286 @@
+// Package log provides the central logging service for the Core application.
+// It hands out slog loggers that are attributed to the service using them,
+// filters records by level, and fans them out to pluggable sinks such as the
+// console, a rotating log file in the data directory, or a ws.Hub channel for
+// the frontend.
+//
+// Services do not normally use this package directly. They call Logger on
+// their core.ServiceRuntime, which routes through the registered log service:
+//
+//	s.Logger().Warn("could not parse workspace list", "err", err)
+package log
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/host-uk/core/pkg/core"
+)
+
+// Level is the severity of a log record. It is the slog level, so the two can
+// be used interchangeably.
+type Level = slog.Level
+
+// Log levels, from least to most severe.
+const (
+	LevelDebug Level = slog.LevelDebug
+	LevelInfo  Level = slog.LevelInfo
+	LevelWarn  Level = slog.LevelWarn
+	LevelError Level = slog.LevelError
+)
+
+// Options holds configuration for the log service.
+type Options struct {
+	// Level is the minimum level that is logged. Defaults to LevelInfo.
+	Level Level
+	// Sinks receive every record at or above Level. If nil, New logs to the
+	// console on stderr.
+	Sinks []Sink
+}
+
+// Entry is a single log record as delivered to a sink.
+type Entry struct {
+	Time    time.Time      `json:"time"`
+	Level   Level          `json:"level"`
+	Service string         `json:"service,omitempty"`
+	Message string         `json:"message"`
+	Attrs   map[string]any `json:"attrs,omitempty"`
+}
+
+// Sink receives log entries. Write may be called from several goroutines at
+// once. If a sink also implements io.Closer, it is closed when the service
+// shuts down.
+type Sink interface {
+	Write(Entry) error
+}
+
+// Service is the central log service.
+type Service struct {
+	*core.ServiceRuntime[Options]
+	level    slog.LevelVar
+	mu       sync.RWMutex
+	sinks    []Sink
+	disabled bool
+}
+
+// New is the constructor for static dependency injection.
+// It creates a Service instance without initializing the core.ServiceRuntime field.
+func New(opts Options) (*Service, error) {
+	s := &Service{sinks: opts.Sinks}
+	if s.sinks == nil {
+		s.sinks = []Sink{NewConsoleSink(os.Stderr)}
+	}
+	s.level.Set(opts.Level)
+	return s, nil
+}
+
+// Register is the constructor for dynamic dependency injection (used with core.WithService).
+// It logs to the console and, if a config service is registered, to a rotating
+// file in the logs directory under the configured dataDir. If the Core's
+// contract sets DisableLogging, every record is dropped.
+func Register(c *core.Core) (any, error) {
+	s, err := New(Options{})
+	if err != nil {
+		return nil, err
+	}
+	s.ServiceRuntime = core.NewServiceRuntime(c, Options{})
+	s.disabled = c.Contract().DisableLogging
+	if s.disabled {
+		return s, nil
+	}
+
+	if cfg, ok := c.Service("config").(core.Config); ok {
+		var dataDir string
+		if err := cfg.Get("dataDir", &dataDir); err == nil && dataDir != "" {
+			file, err := NewFileSink(filepath.Join(dataDir, "logs"), FileOptions{})
+			if err != nil {
+				return nil, core.E("log.Register", "failed to open log file", err)
+			}
+			s.AddSink(file)
+		}
+	}
+	return s, nil
+}
+
+// Logger returns a logger whose records are attributed to the named service.
+// It implements core.Log.
+func (s *Service) Logger(service string) *slog.Logger {
+	logger := slog.New(s.Handler())
+	if service != "" {
+		logger = logger.With(serviceKey, service)
+	}
+	return logger
+}
+
+// Handler returns an slog.Handler that writes to the service's sinks. Use it
+// to route third-party slog output through the log service:
+//
+//	slog.SetDefault(slog.New(logSvc.Handler()))
+func (s *Service) Handler() slog.Handler {
+	return &handler{svc: s}
+}
+
+// SetLevel changes the minimum level that is logged.
+func (s *Service) SetLevel(level Level) {
+	s.level.Set(level)
+}
+
+// Level returns the minimum level that is logged.
+func (s *Service) Level() Level {
+	return s.level.Level()
+}
+
+// AddSink adds a sink that receives every subsequent record.
+func (s *Service) AddSink(sink Sink) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sinks = append(s.sinks, sink)
+}
+
+// OnShutdown closes every sink that implements io.Closer.
+func (s *Service) OnShutdown(context.Context) error {
+	s.mu.Lock()
+	sinks := s.sinks
+	s.sinks = nil
+	s.mu.Unlock()
+
+	var errs []error
+	for _, sink := range sinks {
+		if closer, ok := sink.(io.Closer); ok {
+			if err := closer.Close(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// enabled reports whether records at level are logged.
+func (s *Service) enabled(level Level) bool {
+	return !s.disabled && level >= s.level.Level()
+}
+
+// write delivers entry to every sink and returns their joined errors.
+func (s *Service) write(entry Entry) error {
+	s.mu.RLock()
+	sinks := s.sinks
+	s.mu.RUnlock()
+
+	var errs []error
+	for _, sink := range sinks {
+		if err := sink.Write(entry); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// serviceKey is the attribute that carries the name of the logging service.
+const serviceKey = "service"
+
+// handler adapts the Service to slog.Handler.
+type handler struct {
+	svc    *Service
+	attrs  []slog.Attr
+	groups []string
+}
+
+func (h *handler) Enabled(_ context.Context, level slog.Level) bool {
+	return h.svc.enabled(level)
+}
+
+func (h *handler) Handle(_ context.Context, r slog.Record) error {
+	entry := Entry{Time: r.Time, Level: r.Level, Message: r.Message}
+	attrs := make(map[string]any)
+	for _, a := range h.attrs {
+		if a.Key == serviceKey && entry.Service == "" {
+			entry.Service = a.Value.String()
+			continue
+		}
+		addAttr(attrs, "", a)
+	}
+	prefix := groupPrefix(h.groups)
+	r.Attrs(func(a slog.Attr) bool {
+		addAttr(attrs, prefix, a)
+		return true
+	})
+	if len(attrs) > 0 {
+		entry.Attrs = attrs
+	}
+	return h.svc.write(entry)
+}
+
+func (h *handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	prefix := groupPrefix(h.groups)
+	next := &handler{svc: h.svc, groups: h.groups}
+	next.attrs = append(next.attrs, h.attrs...)
+	for _, a := range attrs {
+		if prefix != "" {
+			a.Key = prefix + a.Key
+		}
+		next.attrs = append(next.attrs, a)
+	}
+	return next
+}
+
+func (h *handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	next := &handler{svc: h.svc, attrs: h.attrs}
+	next.groups = append(append(next.groups, h.groups...), name)
+	return next
+}
+
+// addAttr flattens a into attrs, joining group names with dots.
+func addAttr(attrs map[string]any, prefix string, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			prefix += a.Key + "."
+		}
+		for _, ga := range a.Value.Group() {
+			addAttr(attrs, prefix, ga)
+		}
+		return
+	}
+	value := a.Value.Any()
+	if err, ok := value.(error); ok {
+		value = err.Error()
+	}
+	attrs[prefix+a.Key] = value
+}
+
+// groupPrefix returns the key prefix for attributes inside groups.
+func groupPrefix(groups []string) string {
+	prefix := ""
+	for _, g := range groups {
+		prefix += g + "."
+	}
+	return prefix
+}
+
+// formatAttrs renders attrs as space-separated key=value pairs sorted by key.
+func formatAttrs(attrs map[string]any) string {
+	keys := make([]string, 0, len(attrs))
+	for k := range attrs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	out := ""
+	for _, k := range keys {
+		out += fmt.Sprintf(" %s=%v", k, attrs[k])
+	}
+	return out
+}
@@ -0,0 +1,181 @@
+package log
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/host-uk/core/pkg/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memorySink records entries for inspection.
+type memorySink struct {
+	mu      sync.Mutex
+	entries []Entry
+	closed  bool
+}
+
+func (m *memorySink) Write(entry Entry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entries = append(m.entries, entry)
+	return nil
+}
+
+func (m *memorySink) Close() error {
+	m.closed = true
+	return nil
+}
+
+// failingSink always returns an error.
+type failingSink struct{}
+
+func (failingSink) Write(Entry) error { return errors.New("sink failed") }
+
+// mockConfig is a core.Config backed by a map.
+type mockConfig struct {
+	values map[string]any
+}
+
+func (m *mockConfig) Get(key string, out any) error {
+	v, ok := m.values[key]
+	if !ok {
+		return errors.New("key not found")
+	}
+	*(out.(*string)) = v.(string)
+	return nil
+}
+
+func (m *mockConfig) Set(key string, v any) error {
+	m.values[key] = v
+	return nil
+}
+
+func TestNew(t *testing.T) {
+	s, err := New(Options{})
+	require.NoError(t, err)
+	assert.Equal(t, LevelInfo, s.Level())
+	assert.Len(t, s.sinks, 1, "logs to the console by default")
+}
+
+func TestLogger(t *testing.T) {
+	sink := &memorySink{}
+	s, err := New(Options{Sinks: []Sink{sink}})
+	require.NoError(t, err)
+
+	t.Run("attributes records to the service", func(t *testing.T) {
+		s.Logger("workspace").Info("switched", "name", "default")
+		require.Len(t, sink.entries, 1)
+		entry := sink.entries[0]
+		assert.Equal(t, "workspace", entry.Service)
+		assert.Equal(t, "switched", entry.Message)
+		assert.Equal(t, LevelInfo, entry.Level)
+		assert.Equal(t, map[string]any{"name": "default"}, entry.Attrs)
+		assert.False(t, entry.Time.IsZero())
+	})
+
+	t.Run("filters by level", func(t *testing.T) {
+		sink.entries = nil
+		logger := s.Logger("config")
+		logger.Debug("hidden")
+		s.SetLevel(LevelDebug)
+		logger.Debug("shown")
+		s.SetLevel(LevelInfo)
+		require.Len(t, sink.entries, 1)
+		assert.Equal(t, "shown", sink.entries[0].Message)
+	})
+
+	t.Run("flattens groups and errors", func(t *testing.T) {
+		sink.entries = nil
+		s.Logger("io").WithGroup("req").With("id", 7).Error("failed", "err", errors.New("boom"), slog.Group("file", "path", "a.txt"))
+		require.Len(t, sink.entries, 1)
+		assert.Equal(t, "io", sink.entries[0].Service)
+		assert.Equal(t, map[string]any{
+			"req.id":        int64(7),
+			"req.err":       "boom",
+			"req.file.path": "a.txt",
+		}, sink.entries[0].Attrs)
+	})
+}
+
+func TestHandler(t *testing.T) {
+	sink := &memorySink{}
+	s, err := New(Options{Sinks: []Sink{sink}})
+	require.NoError(t, err)
+
+	logger := slog.New(s.Handler())
+	logger.Warn("from slog")
+	require.Len(t, sink.entries, 1)
+	assert.Equal(t, LevelWarn, sink.entries[0].Level)
+	assert.Empty(t, sink.entries[0].Service)
+}
+
+func TestSinkErrors(t *testing.T) {
+	sink := &memorySink{}
+	s, err := New(Options{Sinks: []Sink{failingSink{}, sink}})
+	require.NoError(t, err)
+
+	err = s.Handler().Handle(context.Background(), slog.NewRecord(time.Now(), LevelError, "msg", 0))
+	assert.EqualError(t, err, "sink failed")
+	assert.Len(t, sink.entries, 1, "other sinks still receive the entry")
+}
+
+func TestOnShutdown(t *testing.T) {
+	sink := &memorySink{}
+	s, err := New(Options{Sinks: []Sink{sink}})
+	require.NoError(t, err)
+
+	assert.NoError(t, s.OnShutdown(context.Background()))
+	assert.True(t, sink.closed)
+}
+
+func TestRegister(t *testing.T) {
+	t.Run("writes to a log file in the data directory", func(t *testing.T) {
+		dataDir := t.TempDir()
+		c, err := core.New(
+			core.WithName("config", func(c *core.Core) (any, error) {
+				return &mockConfig{values: map[string]any{"dataDir": dataDir}}, nil
+			}),
+			core.WithService(Register),
+		)
+		require.NoError(t, err)
+
+		c.Logger("workspace").Warn("hello file")
+		require.NoError(t, c.Stop(context.Background()))
+
+		data, err := os.ReadFile(filepath.Join(dataDir, "logs", "core.log"))
+		require.NoError(t, err)
+		assert.Contains(t, string(data), `"service":"workspace"`)
+		assert.Contains(t, string(data), `"message":"hello file"`)
+	})
+
+	t.Run("honours DisableLogging", func(t *testing.T) {
+		dataDir := t.TempDir()
+		c, err := core.New(
+			core.WithContract(core.Contract{DisableLogging: true}),
+			core.WithName("config", func(c *core.Core) (any, error) {
+				return &mockConfig{values: map[string]any{"dataDir": dataDir}}, nil
+			}),
+			core.WithService(Register),
+		)
+		require.NoError(t, err)
+
+		svc := core.MustServiceFor[*Service](c, "log")
+		assert.False(t, svc.Logger("workspace").Enabled(context.Background(), LevelError))
+		assert.NoFileExists(t, filepath.Join(dataDir, "logs", "core.log"))
+	})
+
+	t.Run("works without a config service", func(t *testing.T) {
+		c, err := core.New(core.WithService(Register))
+		require.NoError(t, err)
+		svc := core.MustServiceFor[*Service](c, "log")
+		assert.Len(t, svc.sinks, 1)
+	})
+}
@@ -0,0 +1,192 @@
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/host-uk/core/pkg/core"
+	"github.com/host-uk/core/pkg/ws"
+)
+
+// ConsoleSink writes entries as human-readable lines.
+type ConsoleSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewConsoleSink creates a sink that writes one line per entry to w, e.g.
+//
+//	2024-05-01T10:00:00Z WARN [workspace] could not parse workspace list err=EOF
+func NewConsoleSink(w io.Writer) *ConsoleSink {
+	return &ConsoleSink{w: w}
+}
+
+// Write formats entry and writes it to the underlying writer.
+func (s *ConsoleSink) Write(entry Entry) error {
+	service := ""
+	if entry.Service != "" {
+		service = " [" + entry.Service + "]"
+	}
+	line := fmt.Sprintf("%s %s%s %s%s\n",
+		entry.Time.Format(time.RFC3339), entry.Level, service, entry.Message, formatAttrs(entry.Attrs))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err := io.WriteString(s.w, line)
+	return err
+}
+
+// Default limits for FileSink.
+const (
+	defaultMaxSize    = 10 << 20
+	defaultMaxBackups = 5
+	logFileName       = "core.log"
+)
+
+// FileOptions configures a FileSink.
+type FileOptions struct {
+	// MaxSize is the size in bytes at which the log file is rotated.
+	// Defaults to 10 MiB.
+	MaxSize int64
+	// MaxBackups is the number of rotated files to keep. Defaults to 5.
+	MaxBackups int
+}
+
+// FileSink writes entries as JSON lines to core.log in a directory, rotating
+// the file when it grows past MaxSize. Rotated files are named core.log.1
+// (newest) through core.log.N (oldest).
+type FileSink struct {
+	mu   sync.Mutex
+	dir  string
+	opts FileOptions
+	file *os.File
+	size int64
+}
+
+// NewFileSink opens, or creates, core.log in dir.
+func NewFileSink(dir string, opts FileOptions) (*FileSink, error) {
+	if opts.MaxSize <= 0 {
+		opts.MaxSize = defaultMaxSize
+	}
+	if opts.MaxBackups <= 0 {
+		opts.MaxBackups = defaultMaxBackups
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, core.E("log.NewFileSink", "failed to create log directory", err)
+	}
+	s := &FileSink{dir: dir, opts: opts}
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Path returns the path of the current log file.
+func (s *FileSink) Path() string {
+	return filepath.Join(s.dir, logFileName)
+}
+
+// Write appends entry to the log file, rotating it first if it is full.
+func (s *FileSink) Write(entry Entry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return core.E("log.FileSink.Write", "failed to encode entry", err)
+	}
+	data = append(data, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return core.E("log.FileSink.Write", "log file is closed", nil)
+	}
+	if s.size > 0 && s.size+int64(len(data)) > s.opts.MaxSize {
+		if err := s.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := s.file.Write(data)
+	s.size += int64(n)
+	if err != nil {
+		return core.E("log.FileSink.Write", "failed to write log file", err)
+	}
+	return nil
+}
+
+// Close closes the log file.
+func (s *FileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
+
+// open opens the current log file for appending.
+func (s *FileSink) open() error {
+	f, err := os.OpenFile(s.Path(), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return core.E("log.FileSink", "failed to open log file", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return core.E("log.FileSink", "failed to stat log file", err)
+	}
+	s.file = f
+	s.size = info.Size()
+	return nil
+}
+
+// rotate shifts the backups along by one, moves the current file to
+// core.log.1 and opens a new, empty core.log.
+func (s *FileSink) rotate() error {
+	if err := s.file.Close(); err != nil {
+		return core.E("log.FileSink", "failed to close log file", err)
+	}
+	s.file = nil
+
+	base := s.Path()
+	os.Remove(fmt.Sprintf("%s.%d", base, s.opts.MaxBackups))
+	for i := s.opts.MaxBackups - 1; i >= 1; i-- {
+		os.Rename(fmt.Sprintf("%s.%d", base, i), fmt.Sprintf("%s.%d", base, i+1))
+	}
+	if err := os.Rename(base, base+".1"); err != nil {
+		return core.E("log.FileSink", "failed to rotate log file", err)
+	}
+	return s.open()
+}
+
+// HubChannel is the ws.Hub channel that HubSink publishes to by default.
+const HubChannel = "logs"
+
+// HubSink publishes entries to subscribers of a ws.Hub channel, so the
+// frontend can show a live log view.
+type HubSink struct {
+	hub     *ws.Hub
+	channel string
+}
+
+// NewHubSink creates a sink that sends each entry to channel on hub as a
+// ws.TypeEvent message. An empty channel means HubChannel.
+func NewHubSink(hub *ws.Hub, channel string) *HubSink {
+	if channel == "" {
+		channel = HubChannel
+	}
+	return &HubSink{hub: hub, channel: channel}
+}
+
+// Write sends entry to the hub channel.
+func (s *HubSink) Write(entry Entry) error {
+	return s.hub.SendToChannel(s.channel, ws.Message{
+		Type: ws.TypeEvent,
+		Data: entry,
+	})
+}
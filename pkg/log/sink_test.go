@@ -0,0 +1,107 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/host-uk/core/pkg/ws"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConsoleSink(t *testing.T) {
+	var buf bytes.Buffer
+	s, err := New(Options{Sinks: []Sink{NewConsoleSink(&buf)}})
+	require.NoError(t, err)
+
+	s.Logger("workspace").Warn("could not parse", "b", 2, "a", "x")
+	assert.Regexp(t, `^\S+ WARN \[workspace\] could not parse a=x b=2\n$`, buf.String())
+}
+
+func TestFileSink(t *testing.T) {
+	t.Run("writes JSON lines", func(t *testing.T) {
+		dir := t.TempDir()
+		sink, err := NewFileSink(dir, FileOptions{})
+		require.NoError(t, err)
+
+		require.NoError(t, sink.Write(Entry{Level: LevelInfo, Service: "config", Message: "loaded"}))
+		require.NoError(t, sink.Close())
+
+		data, err := os.ReadFile(filepath.Join(dir, "core.log"))
+		require.NoError(t, err)
+		var entry Entry
+		require.NoError(t, json.Unmarshal(bytes.TrimSpace(data), &entry))
+		assert.Equal(t, "config", entry.Service)
+		assert.Equal(t, "loaded", entry.Message)
+		assert.Equal(t, LevelInfo, entry.Level)
+	})
+
+	t.Run("rotates when full", func(t *testing.T) {
+		dir := t.TempDir()
+		sink, err := NewFileSink(dir, FileOptions{MaxSize: 200, MaxBackups: 2})
+		require.NoError(t, err)
+		defer sink.Close()
+
+		for i := 0; i < 20; i++ {
+			require.NoError(t, sink.Write(Entry{Message: strings.Repeat("x", 50)}))
+		}
+
+		assert.FileExists(t, filepath.Join(dir, "core.log"))
+		assert.FileExists(t, filepath.Join(dir, "core.log.1"))
+		assert.FileExists(t, filepath.Join(dir, "core.log.2"))
+		assert.NoFileExists(t, filepath.Join(dir, "core.log.3"))
+
+		info, err := os.Stat(sink.Path())
+		require.NoError(t, err)
+		assert.LessOrEqual(t, info.Size(), int64(200))
+	})
+
+	t.Run("refuses writes after close", func(t *testing.T) {
+		sink, err := NewFileSink(t.TempDir(), FileOptions{})
+		require.NoError(t, err)
+		require.NoError(t, sink.Close())
+		assert.Error(t, sink.Write(Entry{Message: "late"}))
+	})
+}
+
+func TestHubSink(t *testing.T) {
+	hub := ws.NewHub()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go hub.Run(ctx)
+
+	server := httptest.NewServer(hub.Handler())
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	require.NoError(t, conn.WriteJSON(ws.Message{Type: ws.TypeSubscribe, Data: HubChannel}))
+	require.Eventually(t, func() bool { return hub.Stats().Channels == 1 }, time.Second, 10*time.Millisecond)
+
+	s, err := New(Options{Sinks: []Sink{NewHubSink(hub, "")}})
+	require.NoError(t, err)
+	s.Logger("process").Error("exited", "code", 1)
+
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+	var msg struct {
+		Type    ws.MessageType `json:"type"`
+		Channel string         `json:"channel"`
+		Data    Entry          `json:"data"`
+	}
+	require.NoError(t, conn.ReadJSON(&msg))
+	assert.Equal(t, ws.TypeEvent, msg.Type)
+	assert.Equal(t, HubChannel, msg.Channel)
+	assert.Equal(t, "process", msg.Data.Service)
+	assert.Equal(t, "exited", msg.Data.Message)
+	assert.Equal(t, LevelError, msg.Data.Level)
+}
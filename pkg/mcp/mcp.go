@@ -6,7 +6,9 @@ package mcp
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io/fs"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -18,6 +20,7 @@ import (
 	"github.com/host-uk/core/pkg/core"
 	"github.com/host-uk/core/pkg/display"
 	"github.com/host-uk/core/pkg/ide"
+	"github.com/host-uk/core/pkg/io"
 	"github.com/host-uk/core/pkg/process"
 	"github.com/host-uk/core/pkg/webview"
 	"github.com/host-uk/core/pkg/ws"
@@ -34,6 +37,9 @@ type Service struct {
 	wsHub     *ws.Hub
 	wsPort    int
 	wsRunning bool
+	recorder  *core.Recorder
+	metrics   *core.Metrics
+	medium    io.Medium
 }
 
 // New creates a new MCP service.
@@ -54,6 +60,12 @@ func New(c *core.Core) *Service {
 	if c != nil {
 		ideSvc, _ := core.ServiceFor[*ide.Service](c, "github.com/host-uk/core/ide")
 		s.ide = ideSvc
+
+		// Capture IPC activity for the ipc_records and ipc_metrics tools
+		s.recorder = core.NewRecorder(0)
+		s.metrics = core.NewMetrics()
+		c.Use(s.metrics.Intercept)
+		c.Use(s.recorder.Intercept)
 	}
 
 	s.registerTools()
@@ -287,6 +299,17 @@ func (s *Service) registerTools() {
 		Name:        "screen_list",
 		Description: "List all available screens/monitors with their dimensions",
 	}, s.screenList)
+
+	// IPC debugging
+	mcp.AddTool(s.server, &mcp.Tool{
+		Name:        "ipc_records",
+		Description: "List the most recent IPC dispatches and handler invocations captured by the debug recorder",
+	}, s.ipcRecords)
+
+	mcp.AddTool(s.server, &mcp.Tool{
+		Name:        "ipc_metrics",
+		Description: "Get IPC message counts and per-service handler timings",
+	}, s.ipcMetrics)
 }
 
 // SetWebView sets the WebView service for GUI interaction.
@@ -301,6 +324,17 @@ func (s *Service) SetDisplay(d *display.Service) {
 	s.display = d
 }
 
+// SetMedium sets the storage backend used by the file_* and dir_* tools, and
+// by the IDE service if there is one. Paths are then interpreted by the
+// medium. By default the local filesystem is used, with relative paths
+// resolved against the working directory.
+func (s *Service) SetMedium(m io.Medium) {
+	s.medium = m
+	if s.ide != nil {
+		s.ide.SetMedium(m)
+	}
+}
+
 // Tool input/output types
 
 // ReadFileInput contains parameters for reading a file.
@@ -459,13 +493,13 @@ func (s *Service) readFile(ctx context.Context, req *mcp.CallToolRequest, input
 		}, nil
 	}
 
-	// Fallback to direct file read
-	content, err := os.ReadFile(input.Path)
+	m, path := io.Resolve(s.medium, input.Path)
+	content, err := m.Read(path)
 	if err != nil {
 		return nil, ReadFileOutput{}, fmt.Errorf("failed to read file: %w", err)
 	}
 	return nil, ReadFileOutput{
-		Content:  string(content),
+		Content:  content,
 		Language: detectLanguage(input.Path),
 		Path:     input.Path,
 	}, nil
@@ -480,13 +514,8 @@ func (s *Service) writeFile(ctx context.Context, req *mcp.CallToolRequest, input
 		return nil, WriteFileOutput{Success: true, Path: input.Path}, nil
 	}
 
-	// Fallback to direct file write
-	dir := filepath.Dir(input.Path)
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return nil, WriteFileOutput{}, fmt.Errorf("failed to create directory: %w", err)
-	}
-	err := os.WriteFile(input.Path, []byte(input.Content), 0644)
-	if err != nil {
+	m, path := io.Resolve(s.medium, input.Path)
+	if err := m.Write(path, input.Content); err != nil {
 		return nil, WriteFileOutput{}, fmt.Errorf("failed to write file: %w", err)
 	}
 	return nil, WriteFileOutput{Success: true, Path: input.Path}, nil
@@ -510,8 +539,8 @@ func (s *Service) listDirectory(ctx context.Context, req *mcp.CallToolRequest, i
 		return nil, ListDirectoryOutput{Entries: result, Path: input.Path}, nil
 	}
 
-	// Fallback to direct directory listing
-	entries, err := os.ReadDir(input.Path)
+	m, path := io.Resolve(s.medium, input.Path)
+	entries, err := m.List(path)
 	if err != nil {
 		return nil, ListDirectoryOutput{}, fmt.Errorf("failed to list directory: %w", err)
 	}
@@ -541,8 +570,8 @@ func (s *Service) createDirectory(ctx context.Context, req *mcp.CallToolRequest,
 		return nil, CreateDirectoryOutput{Success: true, Path: input.Path}, nil
 	}
 
-	err := os.MkdirAll(input.Path, 0755)
-	if err != nil {
+	m, path := io.Resolve(s.medium, input.Path)
+	if err := m.EnsureDir(path); err != nil {
 		return nil, CreateDirectoryOutput{}, fmt.Errorf("failed to create directory: %w", err)
 	}
 	return nil, CreateDirectoryOutput{Success: true, Path: input.Path}, nil
@@ -557,8 +586,8 @@ func (s *Service) deleteFile(ctx context.Context, req *mcp.CallToolRequest, inpu
 		return nil, DeleteFileOutput{Success: true, Path: input.Path}, nil
 	}
 
-	err := os.Remove(input.Path)
-	if err != nil {
+	m, path := io.Resolve(s.medium, input.Path)
+	if err := m.Delete(path); err != nil {
 		return nil, DeleteFileOutput{}, fmt.Errorf("failed to delete file: %w", err)
 	}
 	return nil, DeleteFileOutput{Success: true, Path: input.Path}, nil
@@ -573,16 +602,18 @@ func (s *Service) renameFile(ctx context.Context, req *mcp.CallToolRequest, inpu
 		return nil, RenameFileOutput{Success: true, OldPath: input.OldPath, NewPath: input.NewPath}, nil
 	}
 
-	err := os.Rename(input.OldPath, input.NewPath)
-	if err != nil {
+	m, oldPath := io.Resolve(s.medium, input.OldPath)
+	_, newPath := io.Resolve(s.medium, input.NewPath)
+	if err := m.Rename(oldPath, newPath); err != nil {
 		return nil, RenameFileOutput{}, fmt.Errorf("failed to rename file: %w", err)
 	}
 	return nil, RenameFileOutput{Success: true, OldPath: input.OldPath, NewPath: input.NewPath}, nil
 }
 
 func (s *Service) fileExists(ctx context.Context, req *mcp.CallToolRequest, input FileExistsInput) (*mcp.CallToolResult, FileExistsOutput, error) {
-	info, err := os.Stat(input.Path)
-	if os.IsNotExist(err) {
+	m, path := io.Resolve(s.medium, input.Path)
+	info, err := m.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
 		return nil, FileExistsOutput{Exists: false, IsDir: false, Path: input.Path}, nil
 	}
 	if err != nil {
@@ -626,12 +657,12 @@ func (s *Service) getSupportedLanguages(ctx context.Context, req *mcp.CallToolRe
 
 func (s *Service) editDiff(ctx context.Context, req *mcp.CallToolRequest, input EditDiffInput) (*mcp.CallToolResult, EditDiffOutput, error) {
 	// Read the file
-	content, err := os.ReadFile(input.Path)
+	m, path := io.Resolve(s.medium, input.Path)
+	fileContent, err := m.Read(path)
 	if err != nil {
 		return nil, EditDiffOutput{}, fmt.Errorf("failed to read file: %w", err)
 	}
 
-	fileContent := string(content)
 	count := 0
 
 	if input.ReplaceAll {
@@ -651,8 +682,7 @@ func (s *Service) editDiff(ctx context.Context, req *mcp.CallToolRequest, input
 	}
 
 	// Write the file back
-	err = os.WriteFile(input.Path, []byte(fileContent), 0644)
-	if err != nil {
+	if err := m.Write(path, fileContent); err != nil {
 		return nil, EditDiffOutput{}, fmt.Errorf("failed to write file: %w", err)
 	}
 
@@ -1338,6 +1368,25 @@ type WindowActionOutput struct {
 	Action  string `json:"action"`
 }
 
+// IPCRecordsInput contains parameters for listing recorded IPC activity.
+type IPCRecordsInput struct {
+	// Maximum number of records to return, newest last. Zero returns all.
+	Limit int `json:"limit,omitempty"`
+}
+
+// IPCRecordsOutput contains the recorded IPC activity, oldest first.
+type IPCRecordsOutput struct {
+	Records []core.Record `json:"records"`
+}
+
+// IPCMetricsInput is empty.
+type IPCMetricsInput struct{}
+
+// IPCMetricsOutput contains the IPC counters.
+type IPCMetricsOutput struct {
+	Metrics core.MetricsSnapshot `json:"metrics"`
+}
+
 // ScreenListInput is empty.
 type ScreenListInput struct{}
 
@@ -1547,3 +1596,21 @@ func (s *Service) screenList(ctx context.Context, req *mcp.CallToolRequest, inpu
 
 	return nil, ScreenListOutput{Screens: result}, nil
 }
+
+func (s *Service) ipcRecords(ctx context.Context, req *mcp.CallToolRequest, input IPCRecordsInput) (*mcp.CallToolResult, IPCRecordsOutput, error) {
+	if s.recorder == nil {
+		return nil, IPCRecordsOutput{}, fmt.Errorf("IPC recorder not available")
+	}
+	records := s.recorder.Records()
+	if input.Limit > 0 && len(records) > input.Limit {
+		records = records[len(records)-input.Limit:]
+	}
+	return nil, IPCRecordsOutput{Records: records}, nil
+}
+
+func (s *Service) ipcMetrics(ctx context.Context, req *mcp.CallToolRequest, input IPCMetricsInput) (*mcp.CallToolResult, IPCMetricsOutput, error) {
+	if s.metrics == nil {
+		return nil, IPCMetricsOutput{}, fmt.Errorf("IPC metrics not available")
+	}
+	return nil, IPCMetricsOutput{Metrics: s.metrics.Snapshot()}, nil
+}
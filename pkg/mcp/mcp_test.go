@@ -0,0 +1,59 @@
+package mcp
+
+import (
+	"context"
+	"testing"
+
+	"github.com/host-uk/core/pkg/core"
+)
+
+type testPing struct{}
+
+func TestIPCTools(t *testing.T) {
+	c, err := core.New()
+	if err != nil {
+		t.Fatalf("core.New() failed: %v", err)
+	}
+	s := New(c)
+	core.Subscribe(c, func(testPing) error { return nil })
+
+	for i := 0; i < 3; i++ {
+		if err := c.ACTION(testPing{}); err != nil {
+			t.Fatalf("ACTION() failed: %v", err)
+		}
+	}
+
+	_, metrics, err := s.ipcMetrics(context.Background(), nil, IPCMetricsInput{})
+	if err != nil {
+		t.Fatalf("ipcMetrics() failed: %v", err)
+	}
+	if got := metrics.Metrics.Dispatches["mcp.testPing"]; got != 3 {
+		t.Errorf("Expected 3 dispatches, got %d", got)
+	}
+	if got := metrics.Metrics.Handlers[""].Calls; got != 3 {
+		t.Errorf("Expected 3 handler calls, got %d", got)
+	}
+
+	_, records, err := s.ipcRecords(context.Background(), nil, IPCRecordsInput{Limit: 2})
+	if err != nil {
+		t.Fatalf("ipcRecords() failed: %v", err)
+	}
+	if len(records.Records) != 2 {
+		t.Fatalf("Expected 2 records, got %d", len(records.Records))
+	}
+	for _, r := range records.Records {
+		if r.MessageType != "mcp.testPing" {
+			t.Errorf("Expected a testPing record, got %s", r.MessageType)
+		}
+	}
+}
+
+func TestIPCTools_Standalone(t *testing.T) {
+	s := NewStandalone()
+	if _, _, err := s.ipcRecords(context.Background(), nil, IPCRecordsInput{}); err == nil {
+		t.Error("Expected ipc_records to fail without a Core")
+	}
+	if _, _, err := s.ipcMetrics(context.Background(), nil, IPCMetricsInput{}); err == nil {
+		t.Error("Expected ipc_metrics to fail without a Core")
+	}
+}
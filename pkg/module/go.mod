@@ -3,6 +3,7 @@ module github.com/host-uk/core/pkg/module
 go 1.24
 
 require (
+	github.com/host-uk/core/pkg/config v0.0.0
 	github.com/host-uk/core/pkg/core v0.0.0
 	github.com/gin-gonic/gin v1.11.0
 	github.com/wailsapp/wails/v3 v3.0.0-alpha.41
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -12,8 +13,23 @@ import (
 	"sync"
 
 	"github.com/gin-gonic/gin"
+	"github.com/host-uk/core/pkg/io"
+	"github.com/host-uk/core/pkg/io/local"
 )
 
+// appConfigSuffix is the file name suffix of dynamic module configs.
+const appConfigSuffix = ".itw3.json"
+
+// ConfigNamespace is the config namespace module settings live under, as
+// "modules.<code>".
+const ConfigNamespace = "modules"
+
+// ConfigDefaults receives the default settings of registered modules.
+// *config.Service implements it.
+type ConfigDefaults interface {
+	SetDefaults(namespace string, defaults map[string]any) error
+}
+
 // Registry manages module registration and provides unified API routing + UI assembly.
 type Registry struct {
 	mu            sync.RWMutex
@@ -22,7 +38,10 @@ type Registry struct {
 	engine        *gin.Engine
 	api           *gin.RouterGroup
 	assetHandler  http.Handler
-	appsDir       string // Directory to scan for dynamic modules
+	appsDir       string            // Directory to scan for dynamic modules
+	sources       map[string]string // module config file -> module code
+	config        ConfigDefaults
+	logger        *slog.Logger
 }
 
 // NewRegistry creates a new module registry.
@@ -32,10 +51,12 @@ func NewRegistry() *Registry {
 
 	r := &Registry{
 		modules:       make(map[string]*Module),
+		sources:       make(map[string]string),
 		activeContext: ContextDefault,
 		engine:        engine,
 		api:           engine.Group("/api"),
 		appsDir:       "apps",
+		logger:        slog.Default(),
 	}
 
 	// Root API endpoint lists modules
@@ -45,6 +66,49 @@ func NewRegistry() *Registry {
 	return r
 }
 
+// SetConfig makes the defaults in each module's Config map the defaults of
+// its keys in config, under "modules.<code>", including for modules already
+// registered.
+func (r *Registry) SetConfig(config ConfigDefaults) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.config = config
+	for _, mod := range r.modules {
+		if err := r.setDefaults(mod.Config); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetLogger sets the logger that problems loading module configs from the
+// apps directory are reported to. The default is slog.Default().
+func (r *Registry) SetLogger(logger *slog.Logger) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.logger = logger
+}
+
+// warnLoad reports a module config in the apps directory that failed to load.
+func (r *Registry) warnLoad(path string, err error) {
+	r.mu.RLock()
+	logger := r.logger
+	r.mu.RUnlock()
+	logger.Warn("failed to load module", "path", path, "err", err)
+}
+
+// setDefaults passes the defaults of a module to the config. The caller
+// must hold r.mu.
+func (r *Registry) setDefaults(cfg Config) error {
+	if r.config == nil || len(cfg.Config) == 0 {
+		return nil
+	}
+	if err := r.config.SetDefaults(ConfigNamespace+"."+cfg.Code, cfg.Config); err != nil {
+		return fmt.Errorf("setting config defaults for %s: %w", cfg.Code, err)
+	}
+	return nil
+}
+
 // SetAppsDir sets the directory to scan for dynamic modules.
 func (r *Registry) SetAppsDir(dir string) {
 	r.appsDir = dir
@@ -80,7 +144,7 @@ func (r *Registry) Register(cfg Config) error {
 	mod := &Module{Config: cfg}
 	r.modules[cfg.Code] = mod
 
-	return nil
+	return r.setDefaults(cfg)
 }
 
 // RegisterWithHandler registers a module with an HTTP handler for API routes.
@@ -90,6 +154,9 @@ func (r *Registry) RegisterWithHandler(cfg Config, handler http.Handler) error {
 
 	mod := &Module{Config: cfg, Handler: handler}
 	r.modules[cfg.Code] = mod
+	if err := r.setDefaults(cfg); err != nil {
+		return err
+	}
 
 	// Register API routes
 	basePath := "/" + cfg.Namespace + "/" + cfg.Code
@@ -106,6 +173,9 @@ func (r *Registry) RegisterGinModule(cfg Config, gm GinModule) error {
 
 	mod := &Module{Config: cfg}
 	r.modules[cfg.Code] = mod
+	if err := r.setDefaults(cfg); err != nil {
+		return err
+	}
 
 	// Let the module register its routes
 	group := r.api.Group("/" + cfg.Namespace + "/" + cfg.Code)
@@ -123,13 +193,37 @@ func (r *Registry) RegisterFromJSON(data []byte) error {
 	return r.Register(cfg)
 }
 
-// RegisterFromFile registers a module from a .itw3.json file.
+// RegisterFromFile registers a module from a .itw3.json file. If the file
+// previously registered a module under a different code, that module is
+// unregistered.
 func (r *Registry) RegisterFromFile(path string) error {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return fmt.Errorf("reading module file: %w", err)
 	}
-	return r.RegisterFromJSON(data)
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return fmt.Errorf("invalid module config: %w", err)
+	}
+
+	r.mu.Lock()
+	if old, ok := r.sources[path]; ok && old != cfg.Code {
+		delete(r.modules, old)
+	}
+	r.sources[path] = cfg.Code
+	r.mu.Unlock()
+
+	return r.Register(cfg)
+}
+
+// unregisterFile removes the module registered from the config file at path.
+func (r *Registry) unregisterFile(path string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if code, ok := r.sources[path]; ok {
+		delete(r.modules, code)
+		delete(r.sources, path)
+	}
 }
 
 // LoadApps scans the apps directory and loads all .itw3.json configs.
@@ -150,16 +244,61 @@ func (r *Registry) LoadApps(ctx context.Context) error {
 		if info.IsDir() {
 			return nil
 		}
-		if strings.HasSuffix(path, ".itw3.json") {
+		if strings.HasSuffix(path, appConfigSuffix) {
 			if err := r.RegisterFromFile(path); err != nil {
 				// Log but don't fail on individual module errors
-				fmt.Printf("Warning: failed to load module %s: %v\n", path, err)
+				r.warnLoad(path, err)
 			}
 		}
 		return nil
 	})
 }
 
+// WatchApps watches the apps directory and keeps the registry in step with it
+// until ctx is done: module configs that are added or changed are registered,
+// and modules whose config is removed are unregistered. Call LoadApps first to
+// register the modules that are already there.
+func (r *Registry) WatchApps(ctx context.Context) error {
+	if r.appsDir == "" {
+		return nil
+	}
+	if _, err := os.Stat(r.appsDir); os.IsNotExist(err) {
+		return nil // No apps directory, nothing to watch
+	}
+
+	m, err := local.New(r.appsDir)
+	if err != nil {
+		return fmt.Errorf("opening apps directory: %w", err)
+	}
+	w, err := io.StartWatch(m, "", true)
+	if err != nil {
+		return fmt.Errorf("watching apps directory: %w", err)
+	}
+
+	go func() {
+		<-ctx.Done()
+		w.Close()
+	}()
+	go func() {
+		for events := range w.Events() {
+			for _, e := range events {
+				if !strings.HasSuffix(e.Path, appConfigSuffix) {
+					continue
+				}
+				path := filepath.Join(r.appsDir, e.Path)
+				if e.Op == io.OpRemove {
+					r.unregisterFile(path)
+					continue
+				}
+				if err := r.RegisterFromFile(path); err != nil {
+					r.warnLoad(path, err)
+				}
+			}
+		}
+	}()
+	return nil
+}
+
 // Unregister removes a module.
 func (r *Registry) Unregister(code string) {
 	r.mu.Lock()
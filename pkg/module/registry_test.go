@@ -0,0 +1,43 @@
+package module
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/host-uk/core/pkg/config"
+)
+
+func TestRegistryConfigDefaults(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	cfg, err := config.New(config.Options{
+		SystemFile:    filepath.Join(home, "system.json"),
+		WorkspaceFile: filepath.Join(home, "workspace.json"),
+	})
+	if err != nil {
+		t.Fatalf("config.New() failed: %v", err)
+	}
+
+	r := NewRegistry()
+	err = r.RegisterFromJSON([]byte(`{"code": "existing", "name": "Existing", "config": {"enabled": true}}`))
+	if err != nil {
+		t.Fatalf("RegisterFromJSON() failed: %v", err)
+	}
+	if err := r.SetConfig(cfg); err != nil {
+		t.Fatalf("SetConfig() failed: %v", err)
+	}
+	err = r.RegisterFromJSON([]byte(`{"code": "miner", "name": "Miner", "config": {"pool": {"url": "stratum+tcp://pool.lthn.io"}, "threads": 2}}`))
+	if err != nil {
+		t.Fatalf("RegisterFromJSON() failed: %v", err)
+	}
+
+	if !cfg.GetBool("modules.existing.enabled") {
+		t.Error("Expected the defaults of modules registered earlier")
+	}
+	if got := cfg.GetString("modules.miner.pool.url"); got != "stratum+tcp://pool.lthn.io" {
+		t.Errorf("Expected the default pool url, got '%s'", got)
+	}
+	if got := cfg.GetInt("modules.miner.threads"); got != 2 {
+		t.Errorf("Expected the default threads, got %d", got)
+	}
+}
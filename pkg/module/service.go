@@ -2,6 +2,7 @@ package module
 
 import (
 	"context"
+	"sync"
 
 	"github.com/host-uk/core/pkg/core"
 	"github.com/wailsapp/wails/v3/pkg/application"
@@ -17,6 +18,9 @@ type Service struct {
 	*core.ServiceRuntime[Options]
 	registry *Registry
 	config   Options
+
+	mu        sync.Mutex
+	stopWatch context.CancelFunc
 }
 
 // NewService creates a new module service.
@@ -36,10 +40,39 @@ func (s *Service) ServiceName() string {
 	return "github.com/host-uk/core/module"
 }
 
-// ServiceStartup is called by Wails on app start.
+// ServiceStartup is called by Wails on app start. It loads the apps in the
+// apps directory and keeps the registry in step with changes to them until
+// ctx is done or the service shuts down.
 func (s *Service) ServiceStartup(ctx context.Context, options application.ServiceOptions) error {
+	if s.ServiceRuntime != nil {
+		s.registry.SetLogger(s.Logger())
+	}
+
 	// Load any apps from the apps directory
-	return s.registry.LoadApps(ctx)
+	if err := s.registry.LoadApps(ctx); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	if err := s.registry.WatchApps(ctx); err != nil {
+		cancel()
+		return err
+	}
+	s.mu.Lock()
+	s.stopWatch = cancel
+	s.mu.Unlock()
+	return nil
+}
+
+// OnShutdown stops watching the apps directory.
+func (s *Service) OnShutdown(context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stopWatch != nil {
+		s.stopWatch()
+		s.stopWatch = nil
+	}
+	return nil
 }
 
 // Registry returns the underlying registry for direct access.
@@ -0,0 +1,67 @@
+package module
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/wailsapp/wails/v3/pkg/application"
+)
+
+// waitFor polls cond until it holds or a few seconds have passed.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %s", what)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestServiceWatchesApps(t *testing.T) {
+	appsDir := t.TempDir()
+	writeApp := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(appsDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	writeApp("existing.itw3.json", `{"code": "existing", "name": "Existing"}`)
+
+	s, err := NewService(Options{AppsDir: appsDir})
+	if err != nil {
+		t.Fatalf("NewService() failed: %v", err)
+	}
+	if err := s.ServiceStartup(context.Background(), application.ServiceOptions{}); err != nil {
+		t.Fatalf("ServiceStartup() failed: %v", err)
+	}
+	defer s.OnShutdown(context.Background())
+
+	if _, ok := s.GetModule("existing"); !ok {
+		t.Fatal("Expected the existing app to be loaded on startup")
+	}
+
+	writeApp("miner.itw3.json", `{"code": "miner", "name": "Miner"}`)
+	waitFor(t, "the added app", func() bool {
+		_, ok := s.GetModule("miner")
+		return ok
+	})
+
+	writeApp("miner.itw3.json", `{"code": "miner", "name": "Miner 2"}`)
+	waitFor(t, "the edited app", func() bool {
+		cfg, _ := s.GetModule("miner")
+		return cfg.Name == "Miner 2"
+	})
+
+	if err := os.Remove(filepath.Join(appsDir, "miner.itw3.json")); err != nil {
+		t.Fatalf("Failed to remove app: %v", err)
+	}
+	waitFor(t, "the removed app", func() bool {
+		_, ok := s.GetModule("miner")
+		return !ok
+	})
+}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 
+	"github.com/host-uk/core/pkg/core"
 	"github.com/host-uk/core/pkg/plugin"
 )
 
@@ -16,6 +17,7 @@ import (
 type Plugin struct {
 	*plugin.BasePlugin
 	startTime time.Time
+	core      *core.Core
 }
 
 // New creates a new system plugin.
@@ -29,6 +31,13 @@ func New() *Plugin {
 	return p
 }
 
+// WithCore makes the health route report the health of the services
+// registered with c.
+func (p *Plugin) WithCore(c *core.Core) *Plugin {
+	p.core = c
+	return p
+}
+
 // RegisterRoutes registers the plugin's Gin routes.
 func (p *Plugin) RegisterRoutes(group *gin.RouterGroup) {
 	group.GET("/info", p.handleInfo)
@@ -57,15 +66,28 @@ func (p *Plugin) handleInfo(c *gin.Context) {
 
 // HealthResponse contains health check information.
 type HealthResponse struct {
-	Status string `json:"status"`
-	Uptime string `json:"uptime"`
+	Status   string               `json:"status"`
+	Uptime   string               `json:"uptime"`
+	Services []core.ServiceHealth `json:"services,omitempty"`
 }
 
+// handleHealth reports the aggregate service health when a Core is attached.
+// It responds with 503 Service Unavailable if any service is unhealthy.
 func (p *Plugin) handleHealth(c *gin.Context) {
-	c.JSON(http.StatusOK, HealthResponse{
-		Status: "healthy",
+	resp := HealthResponse{
+		Status: string(core.HealthHealthy),
 		Uptime: time.Since(p.startTime).String(),
-	})
+	}
+	code := http.StatusOK
+	if p.core != nil {
+		report := p.core.Health(c.Request.Context())
+		resp.Status = string(report.Status)
+		resp.Services = report.Services
+		if report.Status == core.HealthUnhealthy {
+			code = http.StatusServiceUnavailable
+		}
+	}
+	c.JSON(code, resp)
 }
 
 // RuntimeResponse contains Go runtime statistics.
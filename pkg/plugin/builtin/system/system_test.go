@@ -3,6 +3,7 @@ package system
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -11,6 +12,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/host-uk/core/pkg/core"
 	"github.com/host-uk/core/pkg/plugin"
 )
 
@@ -60,6 +62,45 @@ func TestSystemPlugin_Health(t *testing.T) {
 	assert.NotEmpty(t, resp.Uptime)
 }
 
+// healthCheck is a service that reports a fixed health check result.
+type healthCheck struct {
+	err error
+}
+
+func (h *healthCheck) CheckHealth(ctx context.Context) error { return h.err }
+
+func TestSystemPlugin_HealthWithCore(t *testing.T) {
+	c, err := core.New(
+		core.WithName("ws", func(c *core.Core) (any, error) { return &healthCheck{}, nil }),
+		core.WithName("updater", func(c *core.Core) (any, error) {
+			return &healthCheck{err: core.E("updater.CheckHealth", "source unreachable", core.ErrDegraded)}, nil
+		}),
+	)
+	require.NoError(t, err)
+
+	router := plugin.NewRouter()
+	require.NoError(t, router.Register(context.Background(), New().WithCore(c)))
+
+	req := httptest.NewRequest("GET", "/api/core/system/health", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var resp HealthResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "degraded", resp.Status)
+	require.Len(t, resp.Services, 2)
+	assert.Equal(t, "ws", resp.Services[0].Name)
+	assert.Equal(t, core.HealthDegraded, resp.Services[1].Status)
+
+	require.NoError(t, c.RegisterService("workspace", &healthCheck{err: errors.New("workspace locked")}))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/core/system/health", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "unhealthy", resp.Status)
+}
+
 func TestSystemPlugin_Runtime(t *testing.T) {
 	router := plugin.NewRouter()
 	ctx := context.Background()
@@ -2,10 +2,8 @@ module github.com/host-uk/core/pkg/repos
 
 go 1.25
 
-require gopkg.in/yaml.v3 v3.0.1
+require github.com/host-uk/core/pkg/config/schema v0.0.0
 
-require (
-	github.com/kr/pretty v0.3.1 // indirect
-	github.com/rogpeppe/go-internal v1.14.1 // indirect
-	gopkg.in/check.v1 v1.0.0-20201130134442-10cb98267c6c // indirect
-)
+require gopkg.in/yaml.v3 v3.0.1 // indirect
+
+replace github.com/host-uk/core/pkg/config/schema => ../config/schema
@@ -5,20 +5,28 @@ package repos
 
 import (
 	"fmt"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"strings"
 
-	"gopkg.in/yaml.v3"
+	"github.com/host-uk/core/pkg/config/schema"
 )
 
+// RegistryVersion is the current repos.yaml format version. Files with an
+// older version are upgraded by registryMigrations when they are loaded.
+const RegistryVersion = 1
+
+// registryMigrations upgrade repos.yaml files to RegistryVersion.
+var registryMigrations = schema.NewMigrator(RegistryVersion)
+
 // Registry represents a collection of repositories defined in repos.yaml.
 type Registry struct {
-	Version  int                `yaml:"version"`
-	Org      string             `yaml:"org"`
-	BasePath string             `yaml:"base_path"`
-	Repos    map[string]*Repo   `yaml:"repos"`
-	Defaults RegistryDefaults   `yaml:"defaults"`
+	Version  int              `yaml:"version"`
+	Org      string           `yaml:"org"`
+	BasePath string           `yaml:"base_path"`
+	Repos    map[string]*Repo `yaml:"repos"`
+	Defaults RegistryDefaults `yaml:"defaults"`
 }
 
 // RegistryDefaults contains default values applied to all repos.
@@ -41,7 +49,7 @@ const (
 // Repo represents a single repository in the registry.
 type Repo struct {
 	Name        string   `yaml:"-"` // Set from map key
-	Type        string   `yaml:"type"`
+	Type        string   `yaml:"type" validate:"omitempty,oneof=foundation module product template"`
 	DependsOn   []string `yaml:"depends_on"`
 	Description string   `yaml:"description"`
 	Docs        bool     `yaml:"docs"`
@@ -53,17 +61,32 @@ type Repo struct {
 	Path string `yaml:"-"` // Full path to repo directory
 }
 
-// LoadRegistry reads and parses a repos.yaml file.
+// LoadRegistry reads and parses a repos.yaml file. Files from older versions
+// are upgraded in place, keeping a backup. Versions newer than
+// RegistryVersion and unknown repo types are errors; unknown fields are
+// logged as a warning and skipped.
 func LoadRegistry(path string) (*Registry, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read registry file: %w", err)
 	}
 
+	data, err = registryMigrations.Upgrade(path, data)
+	if err != nil {
+		return nil, err
+	}
+
 	var reg Registry
-	if err := yaml.Unmarshal(data, &reg); err != nil {
+	warning, err := schema.UnmarshalLenient(path, data, &reg)
+	if err != nil {
 		return nil, fmt.Errorf("failed to parse registry file: %w", err)
 	}
+	if warning != nil {
+		slog.Warn("ignoring unknown fields in registry file", "path", path, "err", warning)
+	}
+	if err := schema.Validate(&reg); err != nil {
+		return nil, fmt.Errorf("invalid registry file %s: %w", path, err)
+	}
 
 	// Expand base path
 	reg.BasePath = expandPath(reg.BasePath)
@@ -308,4 +331,4 @@ func expandPath(path string) string {
 		return filepath.Join(home, path[2:])
 	}
 	return path
-}
\ No newline at end of file
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/host-uk/core/pkg/ide"
 	"github.com/host-uk/core/pkg/io"
 	"github.com/host-uk/core/pkg/module"
+	"github.com/host-uk/core/pkg/watch"
 	"github.com/host-uk/core/pkg/workspace"
 	// Import the ABSTRACT contracts (interfaces).
 	"github.com/host-uk/core/pkg/core"
@@ -31,17 +32,32 @@ type Runtime struct {
 	IDE       *ide.Service
 	Module    *module.Service
 	Workspace *workspace.Service
+	Watch     *watch.Service
 }
 
 // ServiceFactory defines a function that creates a service instance.
 type ServiceFactory func() (any, error)
 
+// serviceNames lists every service wired by New, in registration order.
+var serviceNames = []string{"config", "display", "docs", "help", "crypt", "i18n", "ide", "module", "workspace", "watch"}
+
+// headlessServiceNames lists the services wired by NewHeadless. It leaves out
+// the services that need a Wails application window to do anything useful.
+var headlessServiceNames = []string{"config", "crypt", "i18n", "ide", "module", "workspace", "watch"}
+
 // newWithFactories creates a new Runtime instance using the provided service factories.
 func newWithFactories(factories map[string]ServiceFactory) (*Runtime, error) {
+	return newWithServices(serviceNames, factories)
+}
+
+// newWithServices creates a new Runtime instance containing the named
+// services, each built by its factory. Runtime fields for services that are
+// not named are left nil.
+func newWithServices(names []string, factories map[string]ServiceFactory) (*Runtime, error) {
 	services := make(map[string]any)
 	coreOpts := []core.Option{}
 
-	for _, name := range []string{"config", "display", "docs", "help", "crypt", "i18n", "ide", "module", "workspace"} {
+	for _, name := range names {
 		factory, ok := factories[name]
 		if !ok {
 			return nil, fmt.Errorf("service %s factory not provided", name)
@@ -60,55 +76,107 @@ func newWithFactories(factories map[string]ServiceFactory) (*Runtime, error) {
 		return nil, err
 	}
 
-	configSvc, ok := services["config"].(*config.Service)
-	if !ok {
-		return nil, fmt.Errorf("config service has unexpected type")
+	configSvc, err := serviceAs[*config.Service](services, "config")
+	if err != nil {
+		return nil, err
 	}
-	displaySvc, ok := services["display"].(*display.Service)
-	if !ok {
-		return nil, fmt.Errorf("display service has unexpected type")
+	displaySvc, err := serviceAs[*display.Service](services, "display")
+	if err != nil {
+		return nil, err
 	}
-	docsSvc, ok := services["docs"].(*docs.Service)
-	if !ok {
-		return nil, fmt.Errorf("docs service has unexpected type")
+	docsSvc, err := serviceAs[*docs.Service](services, "docs")
+	if err != nil {
+		return nil, err
 	}
-	helpSvc, ok := services["help"].(*help.Service)
-	if !ok {
-		return nil, fmt.Errorf("help service has unexpected type")
+	helpSvc, err := serviceAs[*help.Service](services, "help")
+	if err != nil {
+		return nil, err
 	}
-	cryptSvc, ok := services["crypt"].(*crypt.Service)
-	if !ok {
-		return nil, fmt.Errorf("crypt service has unexpected type")
+	cryptSvc, err := serviceAs[*crypt.Service](services, "crypt")
+	if err != nil {
+		return nil, err
 	}
-	i18nSvc, ok := services["i18n"].(*i18n.Service)
-	if !ok {
-		return nil, fmt.Errorf("i18n service has unexpected type")
+	i18nSvc, err := serviceAs[*i18n.Service](services, "i18n")
+	if err != nil {
+		return nil, err
 	}
-	ideSvc, ok := services["ide"].(*ide.Service)
-	if !ok {
-		return nil, fmt.Errorf("ide service has unexpected type")
+	ideSvc, err := serviceAs[*ide.Service](services, "ide")
+	if err != nil {
+		return nil, err
 	}
-	moduleSvc, ok := services["module"].(*module.Service)
-	if !ok {
-		return nil, fmt.Errorf("module service has unexpected type")
+	moduleSvc, err := serviceAs[*module.Service](services, "module")
+	if err != nil {
+		return nil, err
 	}
-	workspaceSvc, ok := services["workspace"].(*workspace.Service)
-	if !ok {
-		return nil, fmt.Errorf("workspace service has unexpected type")
+	workspaceSvc, err := serviceAs[*workspace.Service](services, "workspace")
+	if err != nil {
+		return nil, err
+	}
+	watchSvc, err := serviceAs[*watch.Service](services, "watch")
+	if err != nil {
+		return nil, err
+	}
+
+	// Set up ServiceRuntime for Config so changes are sent as actions, and
+	// keep the i18n language in step with it
+	if configSvc != nil {
+		configSvc.ServiceRuntime = core.NewServiceRuntime(coreInstance, config.Options{})
+		if i18nSvc != nil {
+			configSvc.Subscribe("language", func(msg config.ActionConfigChanged) {
+				lang, _ := msg.New.(string)
+				if err := i18nSvc.SetLanguage(lang); err != nil {
+					configSvc.Logger().Warn("failed to apply language", "language", lang, "err", err)
+				}
+			})
+		}
+		// Keep the display's default route and theme in step with it too
+		if displaySvc != nil {
+			displaySvc.SetDefaultRoute(configSvc.GetString("default_route"))
+			displaySvc.SetTheme(configSvc.GetString("theme"))
+			configSvc.Subscribe("default_route", func(msg config.ActionConfigChanged) {
+				route, _ := msg.New.(string)
+				displaySvc.SetDefaultRoute(route)
+			})
+			configSvc.Subscribe("theme", func(msg config.ActionConfigChanged) {
+				theme, _ := msg.New.(string)
+				displaySvc.SetTheme(theme)
+			})
+		}
 	}
 
 	// Set core reference for services that need it
-	docsSvc.SetCore(coreInstance)
+	if docsSvc != nil {
+		docsSvc.SetCore(coreInstance)
+	}
 
 	// Set up ServiceRuntime for workspace (needs Config access)
-	workspaceSvc.ServiceRuntime = core.NewServiceRuntime(coreInstance, workspace.Options{})
+	if workspaceSvc != nil {
+		workspaceSvc.ServiceRuntime = core.NewServiceRuntime(coreInstance, workspace.Options{})
+	}
 
 	// Set up ServiceRuntime for IDE
-	ideSvc.ServiceRuntime = core.NewServiceRuntime(coreInstance, ide.Options{})
+	if ideSvc != nil {
+		ideSvc.ServiceRuntime = core.NewServiceRuntime(coreInstance, ide.Options{})
+	}
+
+	// Set up ServiceRuntime for Module, pass module defaults to Config and
+	// register builtins
+	if moduleSvc != nil {
+		moduleSvc.ServiceRuntime = core.NewServiceRuntime(coreInstance, module.Options{})
+		if configSvc != nil {
+			if err := moduleSvc.Registry().SetConfig(configSvc); err != nil {
+				return nil, err
+			}
+		}
+		if err := module.RegisterBuiltins(moduleSvc.Registry()); err != nil {
+			return nil, err
+		}
+	}
 
-	// Set up ServiceRuntime for Module and register builtins
-	moduleSvc.ServiceRuntime = core.NewServiceRuntime(coreInstance, module.Options{})
-	module.RegisterBuiltins(moduleSvc.Registry())
+	// Set up ServiceRuntime for Watch so changes are sent as actions
+	if watchSvc != nil {
+		watchSvc.ServiceRuntime = core.NewServiceRuntime(coreInstance, watch.Options{})
+	}
 
 	app := &Runtime{
 		Core:      coreInstance,
@@ -121,6 +189,7 @@ func newWithFactories(factories map[string]ServiceFactory) (*Runtime, error) {
 		IDE:       ideSvc,
 		Module:    moduleSvc,
 		Workspace: workspaceSvc,
+		Watch:     watchSvc,
 	}
 
 	return app, nil
@@ -129,7 +198,7 @@ func newWithFactories(factories map[string]ServiceFactory) (*Runtime, error) {
 // New creates and wires together all application services using static dependency injection.
 func New() (*Runtime, error) {
 	return newWithFactories(map[string]ServiceFactory{
-		"config":    func() (any, error) { return config.New() },
+		"config":    func() (any, error) { return config.New(config.Options{}) },
 		"display":   func() (any, error) { return display.New() },
 		"docs":      func() (any, error) { return docs.New(docs.Options{BaseURL: "https://docs.lethean.io"}) },
 		"help":      func() (any, error) { return help.New(help.Options{}) },
@@ -138,5 +207,37 @@ func New() (*Runtime, error) {
 		"ide":       func() (any, error) { return ide.New() },
 		"module":    func() (any, error) { return module.NewService(module.Options{AppsDir: "apps"}) },
 		"workspace": func() (any, error) { return workspace.New(io.Local) },
+		"watch":     func() (any, error) { return watch.New(watch.Options{}) },
 	})
 }
+
+// NewHeadless creates and wires together the services that work without a
+// Wails application: config, crypt, i18n, ide, module, workspace and watch. The
+// Display, Docs and Help fields of the returned Runtime are nil. Drive the
+// lifecycle with Runtime.Core.Start and Runtime.Core.Stop.
+func NewHeadless() (*Runtime, error) {
+	return newWithServices(headlessServiceNames, map[string]ServiceFactory{
+		"config":    func() (any, error) { return config.New(config.Options{}) },
+		"crypt":     func() (any, error) { return crypt.New() },
+		"i18n":      func() (any, error) { return i18n.New() },
+		"ide":       func() (any, error) { return ide.New() },
+		"module":    func() (any, error) { return module.NewService(module.Options{AppsDir: "apps"}) },
+		"workspace": func() (any, error) { return workspace.New(io.Local) },
+		"watch":     func() (any, error) { return watch.New(watch.Options{}) },
+	})
+}
+
+// serviceAs returns the named service as type T. A service that was not
+// created returns T's zero value and no error.
+func serviceAs[T any](services map[string]any, name string) (T, error) {
+	var zero T
+	raw, ok := services[name]
+	if !ok {
+		return zero, nil
+	}
+	typed, ok := raw.(T)
+	if !ok {
+		return zero, fmt.Errorf("%s service has unexpected type", name)
+	}
+	return typed, nil
+}
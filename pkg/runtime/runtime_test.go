@@ -14,6 +14,7 @@ import (
 	"github.com/host-uk/core/pkg/ide"
 	"github.com/host-uk/core/pkg/io"
 	"github.com/host-uk/core/pkg/module"
+	"github.com/host-uk/core/pkg/watch"
 	"github.com/host-uk/core/pkg/workspace"
 )
 
@@ -31,6 +32,7 @@ func TestNew(t *testing.T) {
 	assert.NotNil(t, runtime.Crypt, "Crypt service should be initialized")
 	assert.NotNil(t, runtime.I18n, "I18n service should be initialized")
 	assert.NotNil(t, runtime.Workspace, "Workspace service should be initialized")
+	assert.NotNil(t, runtime.Watch, "Watch service should be initialized")
 
 	// Verify services are properly wired through Core
 	configFromCore := runtime.Core.Service("config")
@@ -56,12 +58,34 @@ func TestNew(t *testing.T) {
 	workspaceFromCore := runtime.Core.Service("workspace")
 	assert.NotNil(t, workspaceFromCore, "Workspace should be registered in Core")
 	assert.Equal(t, runtime.Workspace, workspaceFromCore, "Workspace from Core should match direct reference")
+
+	watchFromCore := runtime.Core.Service("watch")
+	assert.NotNil(t, watchFromCore, "Watch should be registered in Core")
+	assert.Equal(t, runtime.Watch, watchFromCore, "Watch from Core should match direct reference")
+}
+
+// TestNewHeadless ensures that NewHeadless wires only the services that do not need Wails.
+func TestNewHeadless(t *testing.T) {
+	runtime, err := NewHeadless()
+	assert.NoError(t, err)
+	assert.NotNil(t, runtime)
+
+	assert.NotNil(t, runtime.Core)
+	assert.Nil(t, runtime.Core.App)
+	assert.NotNil(t, runtime.Config)
+	assert.NotNil(t, runtime.Crypt)
+	assert.NotNil(t, runtime.Workspace)
+	assert.NotNil(t, runtime.Watch)
+	assert.Nil(t, runtime.Display, "Display should not be created in headless mode")
+	assert.Nil(t, runtime.Docs, "Docs should not be created in headless mode")
+	assert.Nil(t, runtime.Help, "Help should not be created in headless mode")
+	assert.Nil(t, runtime.Core.Service("display"))
 }
 
 // TestNewServiceInitializationError tests the error path in New.
 func TestNewServiceInitializationError(t *testing.T) {
 	factories := map[string]ServiceFactory{
-		"config":    func() (any, error) { return config.New() },
+		"config":    func() (any, error) { return config.New(config.Options{}) },
 		"display":   func() (any, error) { return display.New() },
 		"docs":      func() (any, error) { return docs.New(docs.Options{BaseURL: "https://docs.lethean.io"}) },
 		"help":      func() (any, error) { return help.New(help.Options{}) },
@@ -70,6 +94,7 @@ func TestNewServiceInitializationError(t *testing.T) {
 		"ide":       func() (any, error) { return ide.New() },
 		"module":    func() (any, error) { return module.NewService(module.Options{AppsDir: "apps"}) },
 		"workspace": func() (any, error) { return workspace.New(io.Local) },
+		"watch":     func() (any, error) { return watch.New(watch.Options{}) },
 	}
 
 	runtime, err := newWithFactories(factories)
@@ -92,6 +117,7 @@ func TestMissingFactory(t *testing.T) {
 		"ide":       func() (any, error) { return ide.New() },
 		"module":    func() (any, error) { return module.NewService(module.Options{AppsDir: "apps"}) },
 		"workspace": func() (any, error) { return workspace.New(io.Local) },
+		"watch":     func() (any, error) { return watch.New(watch.Options{}) },
 	}
 
 	runtime, err := newWithFactories(factories)
@@ -0,0 +1,170 @@
+// Package watch provides the file watching service for the Core application.
+// It watches paths on any io.Medium and reports each debounced batch of
+// changes as an ActionFilesChanged IPC message and, if a ws.Hub is set, on a
+// hub channel for the frontend.
+//
+// Services react to changes by handling the action:
+//
+//	func (s *Service) HandleIPCEvents(c *core.Core, msg core.Message) error {
+//		if changed, ok := msg.(watch.ActionFilesChanged); ok && changed.Name == "config" {
+//			return s.reload()
+//		}
+//		return nil
+//	}
+package watch
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/host-uk/core/pkg/core"
+	"github.com/host-uk/core/pkg/io"
+	"github.com/host-uk/core/pkg/ws"
+)
+
+// HubChannel is the ws.Hub channel that changes are published to by default.
+const HubChannel = "files"
+
+// ActionFilesChanged is sent with each batch of changes to a watched path.
+type ActionFilesChanged struct {
+	// Name is the name the watch was added under.
+	Name string `json:"name"`
+	// Events are the changes, sorted by path.
+	Events []io.Event `json:"events"`
+}
+
+// Options holds configuration for the watch service.
+type Options struct {
+	// Hub, if set, receives every batch of changes as a ws.TypeEvent message
+	// whose data is an ActionFilesChanged.
+	Hub *ws.Hub
+	// Channel is the hub channel to publish to. Defaults to HubChannel.
+	Channel string
+}
+
+// Service manages named watches.
+type Service struct {
+	*core.ServiceRuntime[Options]
+	opts Options
+
+	mu      sync.Mutex
+	watches map[string]*io.Watch
+	wg      sync.WaitGroup
+}
+
+// New is the constructor for static dependency injection.
+// It creates a Service instance without initializing the core.ServiceRuntime field.
+func New(opts Options) (*Service, error) {
+	if opts.Channel == "" {
+		opts.Channel = HubChannel
+	}
+	return &Service{opts: opts, watches: make(map[string]*io.Watch)}, nil
+}
+
+// Register is the constructor for dynamic dependency injection (used with core.WithService).
+func Register(c *core.Core) (any, error) {
+	s, err := New(Options{})
+	if err != nil {
+		return nil, err
+	}
+	s.ServiceRuntime = core.NewServiceRuntime(c, Options{})
+	return s, nil
+}
+
+// SetHub sets the ws.Hub that changes are published to, on channel, or on
+// HubChannel if channel is empty. A nil hub stops publishing.
+func (s *Service) SetHub(hub *ws.Hub, channel string) {
+	if channel == "" {
+		channel = HubChannel
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.opts.Hub, s.opts.Channel = hub, channel
+}
+
+// Add starts watching path on m under name, replacing any watch already
+// added under that name. Mediums that cannot watch natively are polled; see
+// io.StartWatch.
+func (s *Service) Add(name string, m io.Medium, path string, recursive bool) error {
+	w, err := io.StartWatch(m, path, recursive)
+	if err != nil {
+		return core.E("watch.Add", "failed to watch "+path, err)
+	}
+
+	s.mu.Lock()
+	old := s.watches[name]
+	s.watches[name] = w
+	s.mu.Unlock()
+	if old != nil {
+		old.Close()
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		for events := range w.Events() {
+			s.dispatch(ActionFilesChanged{Name: name, Events: events})
+		}
+	}()
+	return nil
+}
+
+// Remove stops the watch added under name.
+func (s *Service) Remove(name string) error {
+	s.mu.Lock()
+	w, ok := s.watches[name]
+	delete(s.watches, name)
+	s.mu.Unlock()
+	if !ok {
+		return core.E("watch.Remove", "no watch named "+name, nil, core.WithKind(core.KindNotFound))
+	}
+	return w.Close()
+}
+
+// Names returns the names of the active watches, sorted.
+func (s *Service) Names() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	names := make([]string, 0, len(s.watches))
+	for name := range s.watches {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// OnShutdown stops every watch.
+func (s *Service) OnShutdown(context.Context) error {
+	s.mu.Lock()
+	watches := s.watches
+	s.watches = make(map[string]*io.Watch)
+	s.mu.Unlock()
+
+	var errs []error
+	for _, w := range watches {
+		errs = append(errs, w.Close())
+	}
+	s.wg.Wait()
+	return errors.Join(errs...)
+}
+
+// dispatch reports a batch of changes over IPC and to the hub.
+func (s *Service) dispatch(msg ActionFilesChanged) {
+	if s.ServiceRuntime != nil {
+		if err := s.Core().ACTION(msg); err != nil {
+			s.Logger().Warn("file change handler failed", "watch", msg.Name, "err", err)
+		}
+	}
+
+	s.mu.Lock()
+	hub, channel := s.opts.Hub, s.opts.Channel
+	s.mu.Unlock()
+	if hub == nil {
+		return
+	}
+	if err := hub.SendToChannel(channel, ws.Message{Type: ws.TypeEvent, Data: msg}); err != nil && s.ServiceRuntime != nil {
+		s.Logger().Warn("failed to publish file changes", "watch", msg.Name, "err", err)
+	}
+}
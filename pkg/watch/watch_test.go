@@ -0,0 +1,103 @@
+package watch
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/host-uk/core/pkg/core"
+	"github.com/host-uk/core/pkg/io"
+	"github.com/host-uk/core/pkg/ws"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestService(t *testing.T) {
+	c, err := core.New(core.WithService(Register))
+	require.NoError(t, err)
+	svc := core.MustServiceFor[*Service](c, "watch")
+
+	changes := make(chan ActionFilesChanged, 4)
+	c.RegisterAction(func(_ *core.Core, msg core.Message) error {
+		if changed, ok := msg.(ActionFilesChanged); ok {
+			changes <- changed
+		}
+		return nil
+	})
+
+	m := io.NewMockMedium()
+	require.NoError(t, m.EnsureDir("config"))
+	require.NoError(t, svc.Add("config", m, "config", true))
+	assert.Equal(t, []string{"config"}, svc.Names())
+
+	require.NoError(t, m.Write("config/app.yaml", "a: 1"))
+	require.NoError(t, m.Write("config/app.yaml", "a: 2"))
+	require.NoError(t, m.Write("other.txt", "ignored"))
+
+	select {
+	case changed := <-changes:
+		assert.Equal(t, "config", changed.Name)
+		assert.Equal(t, []io.Event{{Path: "config/app.yaml", Op: io.OpCreate}}, changed.Events)
+	case <-time.After(2 * time.Second):
+		t.Fatal("no ActionFilesChanged")
+	}
+
+	t.Run("Remove", func(t *testing.T) {
+		require.NoError(t, svc.Remove("config"))
+		assert.Empty(t, svc.Names())
+
+		err := svc.Remove("config")
+		assert.Equal(t, core.KindNotFound, core.KindOf(err))
+	})
+
+	t.Run("Missing", func(t *testing.T) {
+		assert.Error(t, svc.Add("missing", m, "missing", false))
+	})
+
+	t.Run("Shutdown", func(t *testing.T) {
+		require.NoError(t, svc.Add("a", m, "config", false))
+		require.NoError(t, svc.Add("b", m, "", true))
+		require.NoError(t, svc.OnShutdown(context.Background()))
+		assert.Empty(t, svc.Names())
+	})
+}
+
+func TestHub(t *testing.T) {
+	hub := ws.NewHub()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go hub.Run(ctx)
+
+	server := httptest.NewServer(hub.Handler())
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	require.NoError(t, conn.WriteJSON(ws.Message{Type: ws.TypeSubscribe, Data: HubChannel}))
+	require.Eventually(t, func() bool { return hub.Stats().Channels == 1 }, time.Second, 10*time.Millisecond)
+
+	svc, err := New(Options{Hub: hub})
+	require.NoError(t, err)
+	defer svc.OnShutdown(context.Background())
+
+	m := io.NewMockMedium()
+	require.NoError(t, svc.Add("workspace", m, "", true))
+	require.NoError(t, m.Write("notes.md", "hello"))
+
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(2*time.Second)))
+	var msg struct {
+		Type    ws.MessageType     `json:"type"`
+		Channel string             `json:"channel"`
+		Data    ActionFilesChanged `json:"data"`
+	}
+	require.NoError(t, conn.ReadJSON(&msg))
+	assert.Equal(t, ws.TypeEvent, msg.Type)
+	assert.Equal(t, HubChannel, msg.Channel)
+	assert.Equal(t, "workspace", msg.Data.Name)
+	assert.Equal(t, []io.Event{{Path: "notes.md", Op: io.OpCreate}}, msg.Data.Events)
+}
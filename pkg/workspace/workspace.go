@@ -4,24 +4,44 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"path"
 	"path/filepath"
+	"strings"
+	"sync"
 
 	"github.com/host-uk/core/pkg/core"
 	"github.com/host-uk/core/pkg/crypt/lthn"
 	"github.com/host-uk/core/pkg/crypt/openpgp"
 	"github.com/host-uk/core/pkg/io"
+	"github.com/host-uk/core/pkg/io/encrypted"
 	"github.com/host-uk/core/pkg/io/local"
+	"github.com/host-uk/core/pkg/io/s3"
 	"github.com/wailsapp/wails/v3/pkg/application"
 )
 
 const (
 	defaultWorkspace = "default"
 	listFile         = "list.json"
+	publicKeyFile    = "keys/key.pub"
+	privateKeyFile   = "keys/key.priv"
 )
 
+// encryptedDirs are the workspace directories whose files are encrypted with
+// the workspace key. They can only be read or written while the workspace is
+// unlocked.
+var encryptedDirs = []string{"files", "data"}
+
 // Options holds configuration for the workspace service.
 type Options struct{}
 
+// errNoActiveWorkspace reports that an operation needs an active workspace
+// but none has been selected.
+func errNoActiveWorkspace(op string) error {
+	return core.E(op, "no active workspace", nil,
+		core.WithKind(core.KindNotFound),
+		core.WithCode("workspace.no_active"))
+}
+
 // Workspace represents a user's workspace.
 type Workspace struct {
 	Name string
@@ -31,9 +51,19 @@ type Workspace struct {
 // Service manages user workspaces.
 type Service struct {
 	*core.ServiceRuntime[Options]
+	// mu guards activeWorkspace, workspaceList, unlocked and cipher.
+	mu              sync.RWMutex
 	activeWorkspace *Workspace
 	workspaceList   map[string]string // Maps Workspace ID to Public Key
 	medium          io.Medium
+	// unlocked encrypts the active workspace's files/ and data/ directories
+	// with cipher, the workspace key. Both are nil while the workspace is
+	// locked.
+	unlocked io.Medium
+	cipher   encrypted.Cipher
+	// workspaceDir, if set, is used instead of the configured workspaceDir.
+	// It is "/" when the medium is rooted at the workspaces themselves.
+	workspaceDir string
 }
 
 // newWorkspaceService contains the common logic for initializing a Service struct.
@@ -59,7 +89,8 @@ func New(medium io.Medium) (*Service, error) {
 
 // Register is the constructor for dynamic dependency injection (used with core.WithService).
 // It creates a Service instance and initializes its core.ServiceRuntime field.
-// Dependencies are injected during ServiceStartup.
+// Dependencies are injected during ServiceStartup. Workspaces are stored in
+// the configured workspaceDir, or at the workspaceStorage URL if one is set.
 func Register(c *core.Core) (any, error) {
 	s, err := newWorkspaceService()
 	if err != nil {
@@ -67,20 +98,45 @@ func Register(c *core.Core) (any, error) {
 	}
 	s.ServiceRuntime = core.NewServiceRuntime(c, Options{})
 
+	// Remote storage, if configured, replaces the workspace directory. The
+	// key is optional, so a config without it is not an error.
+	var storage string
+	if err := c.Config().Get("workspaceStorage", &storage); err == nil && storage != "" {
+		cfg, err := s3.ParseURL(storage)
+		if err != nil {
+			return nil, core.E("workspace.Register", "invalid workspaceStorage", err,
+				core.WithKind(core.KindInvalid),
+				core.WithCode("workspace.invalid_storage"))
+		}
+		medium, err := s3.New(cfg)
+		if err != nil {
+			return nil, core.E("workspace.Register", "failed to create storage medium", err)
+		}
+		s.medium = medium
+		s.workspaceDir = "/"
+		return s, nil
+	}
+
 	// Initialize the local medium for file operations
 	var workspaceDir string
 	if err := c.Config().Get("workspaceDir", &workspaceDir); err != nil {
-		return nil, fmt.Errorf("workspace: failed to get workspaceDir from config: %w", err)
+		return nil, core.E("workspace.Register", "failed to get workspaceDir from config", err)
 	}
 	medium, err := local.New(workspaceDir)
 	if err != nil {
-		return nil, fmt.Errorf("workspace: failed to create local medium: %w", err)
+		return nil, core.E("workspace.Register", "failed to create local medium", err)
 	}
 	s.medium = medium
 
 	return s, nil
 }
 
+// Dependencies returns the names of the services the workspace service needs
+// to be started before it. The workspace directory is read from config.
+func (s *Service) Dependencies() []string {
+	return []string{"config"}
+}
+
 // HandleIPCEvents processes IPC messages, including injecting dependencies on startup.
 func (s *Service) HandleIPCEvents(c *core.Core, msg core.Message) error {
 	switch m := msg.(type) {
@@ -91,16 +147,20 @@ func (s *Service) HandleIPCEvents(c *core.Core, msg core.Message) error {
 	case core.ActionServiceStartup:
 		return s.ServiceStartup(context.Background(), application.ServiceOptions{})
 	default:
-		c.App.Logger.Error("Workspace: Unknown message type", "type", fmt.Sprintf("%T", m))
+		c.Logger("workspace").Error("Unknown message type", "type", fmt.Sprintf("%T", m))
 	}
 	return nil
 }
 
-// getWorkspaceDir retrieves the WorkspaceDir from the config service.
+// getWorkspaceDir retrieves the WorkspaceDir from the config service, unless
+// workspaces are kept in remote storage.
 func (s *Service) getWorkspaceDir() (string, error) {
+	if s.workspaceDir != "" {
+		return s.workspaceDir, nil
+	}
 	var workspaceDir string
 	if err := s.Config().Get("workspaceDir", &workspaceDir); err != nil {
-		return "", fmt.Errorf("failed to get WorkspaceDir from config: %w", err)
+		return "", core.E("workspace.getWorkspaceDir", "failed to get WorkspaceDir from config", err)
 	}
 	return workspaceDir, nil
 }
@@ -117,13 +177,17 @@ func (s *Service) ServiceStartup(context.Context, application.ServiceOptions) er
 	if s.medium.IsFile(listPath) {
 		content, err := s.medium.FileGet(listPath)
 		if err != nil {
-			return fmt.Errorf("failed to read workspace list: %w", err)
+			return core.E("workspace.ServiceStartup", "failed to read workspace list", err)
 		}
-		if err := json.Unmarshal([]byte(content), &s.workspaceList); err != nil {
+		list := make(map[string]string)
+		if err := json.Unmarshal([]byte(content), &list); err != nil {
 			// Log warning but continue with empty list
-			fmt.Printf("Warning: could not parse workspace list: %v\n", err)
-			s.workspaceList = make(map[string]string)
+			s.Logger().Warn("could not parse workspace list", "err", err)
+			list = make(map[string]string)
 		}
+		s.mu.Lock()
+		s.workspaceList = list
+		s.mu.Unlock()
 	}
 
 	return s.SwitchWorkspace(defaultWorkspace)
@@ -140,92 +204,284 @@ func (s *Service) CreateWorkspace(identifier, password string) (string, error) {
 	workspaceID := lthn.Hash(fmt.Sprintf("workspace/%s", realName))
 	workspacePath := filepath.Join(workspaceDir, workspaceID)
 
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	if _, exists := s.workspaceList[workspaceID]; exists {
-		return "", fmt.Errorf("workspace for this identifier already exists")
+		return "", core.E("workspace.CreateWorkspace", "workspace for this identifier already exists", nil,
+			core.WithKind(core.KindConflict),
+			core.WithCode("workspace.exists"))
 	}
 
 	dirsToCreate := []string{"config", "log", "data", "files", "keys"}
 	for _, dir := range dirsToCreate {
 		if err := s.medium.EnsureDir(filepath.Join(workspacePath, dir)); err != nil {
-			return "", fmt.Errorf("failed to create workspace directory '%s': %w", dir, err)
+			return "", core.E("workspace.CreateWorkspace", fmt.Sprintf("failed to create workspace directory '%s'", dir), err)
 		}
 	}
 
 	keyPair, err := openpgp.CreateKeyPair(workspaceID, password)
 	if err != nil {
-		return "", fmt.Errorf("failed to create workspace key pair: %w", err)
+		return "", core.E("workspace.CreateWorkspace", "failed to create workspace key pair", err)
+	}
+
+	// The private key is stored encrypted with the workspace password and
+	// only decrypted by UnlockWorkspace.
+	privateKey, err := encrypted.Password(password).Encrypt([]byte(keyPair.PrivateKey))
+	if err != nil {
+		return "", core.E("workspace.CreateWorkspace", "failed to encrypt workspace private key", err)
 	}
 
 	keyFiles := map[string]string{
-		filepath.Join(workspacePath, "keys", "key.pub"):  keyPair.PublicKey,
-		filepath.Join(workspacePath, "keys", "key.priv"): keyPair.PrivateKey,
+		filepath.Join(workspacePath, publicKeyFile):  keyPair.PublicKey,
+		filepath.Join(workspacePath, privateKeyFile): string(privateKey),
 	}
 	for path, content := range keyFiles {
 		if err := s.medium.FileSet(path, content); err != nil {
-			return "", fmt.Errorf("failed to write key file %s: %w", path, err)
+			return "", core.E("workspace.CreateWorkspace", "failed to write key file "+path, err)
 		}
 	}
 
 	s.workspaceList[workspaceID] = keyPair.PublicKey
 	listData, err := json.MarshalIndent(s.workspaceList, "", "  ")
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal workspace list: %w", err)
+		return "", core.E("workspace.CreateWorkspace", "failed to marshal workspace list", err)
 	}
 
 	listPath := filepath.Join(workspaceDir, listFile)
 	if err := s.medium.FileSet(listPath, string(listData)); err != nil {
-		return "", fmt.Errorf("failed to write workspace list file: %w", err)
+		return "", core.E("workspace.CreateWorkspace", "failed to write workspace list file", err)
 	}
 
 	return workspaceID, nil
 }
 
-// SwitchWorkspace changes the active workspace.
+// SwitchWorkspace changes the active workspace. The workspace starts locked;
+// call UnlockWorkspace to use its files/ and data/ directories.
 func (s *Service) SwitchWorkspace(name string) error {
 	workspaceDir, err := s.getWorkspaceDir()
 	if err != nil {
 		return err
 	}
 
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	if name != defaultWorkspace {
 		if _, exists := s.workspaceList[name]; !exists {
-			return fmt.Errorf("workspace '%s' does not exist", name)
+			return core.E("workspace.SwitchWorkspace", fmt.Sprintf("workspace '%s' does not exist", name), nil,
+				core.WithKind(core.KindNotFound),
+				core.WithCode("workspace.not_found"),
+				core.WithMeta("name", name))
 		}
 	}
 
 	path := filepath.Join(workspaceDir, name)
 	if err := s.medium.EnsureDir(path); err != nil {
-		return fmt.Errorf("failed to ensure workspace directory exists: %w", err)
+		return core.E("workspace.SwitchWorkspace", "failed to ensure workspace directory exists", err)
 	}
 
 	s.activeWorkspace = &Workspace{
 		Name: name,
 		Path: path,
 	}
+	s.unlocked, s.cipher = nil, nil
+
+	return nil
+}
+
+// UnlockWorkspace decrypts the active workspace's private key with password,
+// after which files in its files/ and data/ directories are transparently
+// encrypted and decrypted. A private key written before keys were encrypted
+// is refused with a workspace.unencrypted_key error until it is encrypted
+// with MigrateWorkspaceKey.
+func (s *Service) UnlockWorkspace(password string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored, err := s.readPrivateKey("workspace.UnlockWorkspace")
+	if err != nil {
+		return err
+	}
+	if !encrypted.IsPasswordEncrypted([]byte(stored)) {
+		return core.E("workspace.UnlockWorkspace", fmt.Sprintf("workspace '%s' has an unencrypted private key", s.activeWorkspace.Name), nil,
+			core.WithKind(core.KindInvalid),
+			core.WithCode("workspace.unencrypted_key"))
+	}
+	privateKey, err := encrypted.Password(password).Decrypt([]byte(stored))
+	if err != nil {
+		return core.E("workspace.UnlockWorkspace", "incorrect workspace password", err,
+			core.WithKind(core.KindPermission),
+			core.WithCode("workspace.wrong_password"))
+	}
+
+	publicKey, err := s.medium.FileGet(filepath.Join(s.activeWorkspace.Path, publicKeyFile))
+	if err != nil {
+		return core.E("workspace.UnlockWorkspace", "failed to read workspace public key", err)
+	}
+	s.cipher = encrypted.PGP(publicKey, string(privateKey))
+	s.unlocked = encrypted.New(s.medium, s.cipher, encrypted.WithBase(s.activeWorkspace.Path))
+	return nil
+}
 
+// MigrateWorkspaceKey encrypts the private key of a workspace created before
+// keys were encrypted, so that password is needed to unlock it from then on.
+// The workspace stays locked. It fails with a workspace.key_encrypted error
+// if the key is already encrypted.
+func (s *Service) MigrateWorkspaceKey(password string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored, err := s.readPrivateKey("workspace.MigrateWorkspaceKey")
+	if err != nil {
+		return err
+	}
+	if encrypted.IsPasswordEncrypted([]byte(stored)) {
+		return core.E("workspace.MigrateWorkspaceKey", fmt.Sprintf("workspace '%s' private key is already encrypted", s.activeWorkspace.Name), nil,
+			core.WithKind(core.KindConflict),
+			core.WithCode("workspace.key_encrypted"))
+	}
+	ciphertext, err := encrypted.Password(password).Encrypt([]byte(stored))
+	if err != nil {
+		return core.E("workspace.MigrateWorkspaceKey", "failed to encrypt workspace private key", err)
+	}
+	if err := s.medium.FileSet(filepath.Join(s.activeWorkspace.Path, privateKeyFile), string(ciphertext)); err != nil {
+		return core.E("workspace.MigrateWorkspaceKey", "failed to write workspace private key", err)
+	}
 	return nil
 }
 
-// WorkspaceFileGet retrieves a file from the active workspace.
+// readPrivateKey returns the active workspace's private key as it is stored.
+// The caller must hold s.mu.
+func (s *Service) readPrivateKey(op string) (string, error) {
+	if s.activeWorkspace == nil {
+		return "", errNoActiveWorkspace(op)
+	}
+	if !s.hasKeys() {
+		return "", core.E(op, fmt.Sprintf("workspace '%s' has no keys", s.activeWorkspace.Name), nil,
+			core.WithKind(core.KindInvalid),
+			core.WithCode("workspace.no_keys"))
+	}
+	stored, err := s.medium.FileGet(filepath.Join(s.activeWorkspace.Path, privateKeyFile))
+	if err != nil {
+		return "", core.E(op, "failed to read workspace private key", err)
+	}
+	return stored, nil
+}
+
+// LockWorkspace forgets the active workspace's decrypted key. Its files/ and
+// data/ directories can't be used until it is unlocked again.
+func (s *Service) LockWorkspace() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.unlocked, s.cipher = nil, nil
+}
+
+// Cipher returns the active workspace's key, for encrypting other data with
+// it, such as config secrets. The workspace must be unlocked.
+func (s *Service) Cipher() (encrypted.Cipher, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.activeWorkspace == nil {
+		return nil, errNoActiveWorkspace("workspace.Cipher")
+	}
+	if s.cipher == nil {
+		return nil, core.E("workspace.Cipher", "workspace is locked", nil,
+			core.WithKind(core.KindPermission),
+			core.WithCode("workspace.locked"))
+	}
+	return s.cipher, nil
+}
+
+// IsLocked reports whether the active workspace has encrypted directories
+// that can't be used until UnlockWorkspace is called.
+func (s *Service) IsLocked() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.isLocked()
+}
+
+// isLocked is IsLocked for callers that hold s.mu.
+func (s *Service) isLocked() bool {
+	return s.hasKeys() && s.unlocked == nil
+}
+
+// hasKeys reports whether the active workspace was created with a key pair.
+// The default workspace has none, so nothing in it is encrypted. The caller
+// must hold s.mu.
+func (s *Service) hasKeys() bool {
+	if s.activeWorkspace == nil {
+		return false
+	}
+	_, ok := s.workspaceList[s.activeWorkspace.Name]
+	return ok
+}
+
+// workspaceFile cleans filename into a slash-separated path relative to the
+// workspace, so that "/files/x", "./files/x" and "files/x" all name the same
+// file and ".." can't leave the workspace.
+func workspaceFile(filename string) string {
+	return strings.TrimPrefix(path.Clean("/"+filepath.ToSlash(filename)), "/")
+}
+
+// fileMedium returns the medium for a file in the active workspace: the
+// encrypted medium for files under files/ and data/, otherwise the plain one.
+// filename must have been cleaned by workspaceFile. The caller must hold
+// s.mu.
+func (s *Service) fileMedium(op, filename string) (io.Medium, error) {
+	if !s.hasKeys() {
+		return s.medium, nil
+	}
+	first, _, _ := strings.Cut(filename, "/")
+	for _, dir := range encryptedDirs {
+		if first != dir {
+			continue
+		}
+		if s.unlocked == nil {
+			return nil, core.E(op, "workspace is locked", nil,
+				core.WithKind(core.KindPermission),
+				core.WithCode("workspace.locked"),
+				core.WithMeta("path", filename))
+		}
+		return s.unlocked, nil
+	}
+	return s.medium, nil
+}
+
+// WorkspaceFileGet retrieves a file from the active workspace. Files under
+// files/ and data/ are decrypted and need the workspace to be unlocked.
 func (s *Service) WorkspaceFileGet(filename string) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 	if s.activeWorkspace == nil {
-		return "", fmt.Errorf("no active workspace")
+		return "", errNoActiveWorkspace("workspace.WorkspaceFileGet")
+	}
+	filename = workspaceFile(filename)
+	medium, err := s.fileMedium("workspace.WorkspaceFileGet", filename)
+	if err != nil {
+		return "", err
 	}
 	path := filepath.Join(s.activeWorkspace.Path, filename)
-	return s.medium.FileGet(path)
+	return medium.FileGet(path)
 }
 
-// WorkspaceFileSet writes a file to the active workspace.
+// WorkspaceFileSet writes a file to the active workspace. Files under files/
+// and data/ are encrypted and need the workspace to be unlocked.
 func (s *Service) WorkspaceFileSet(filename, content string) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 	if s.activeWorkspace == nil {
-		return fmt.Errorf("no active workspace")
+		return errNoActiveWorkspace("workspace.WorkspaceFileSet")
+	}
+	filename = workspaceFile(filename)
+	medium, err := s.fileMedium("workspace.WorkspaceFileSet", filename)
+	if err != nil {
+		return err
 	}
 	path := filepath.Join(s.activeWorkspace.Path, filename)
-	return s.medium.FileSet(path, content)
+	return medium.FileSet(path, content)
 }
 
 // ListWorkspaces returns the list of workspace IDs.
 func (s *Service) ListWorkspaces() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 	workspaces := make([]string, 0, len(s.workspaceList))
 	for id := range s.workspaceList {
 		workspaces = append(workspaces, id)
@@ -233,7 +489,25 @@ func (s *Service) ListWorkspaces() []string {
 	return workspaces
 }
 
+// CheckHealth reports the workspace service as unhealthy until a workspace
+// is active, and as degraded while the active workspace is locked. It
+// implements core.HealthChecker.
+func (s *Service) CheckHealth(ctx context.Context) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.activeWorkspace == nil {
+		return errNoActiveWorkspace("workspace.CheckHealth")
+	}
+	if s.isLocked() {
+		return core.E("workspace.CheckHealth", "workspace is locked", core.ErrDegraded,
+			core.WithCode("workspace.locked"))
+	}
+	return nil
+}
+
 // ActiveWorkspace returns the currently active workspace, or nil if none is active.
 func (s *Service) ActiveWorkspace() *Workspace {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 	return s.activeWorkspace
 }
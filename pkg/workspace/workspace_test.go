@@ -5,10 +5,13 @@ import (
 	"encoding/json"
 	"fmt"
 	"path/filepath"
+	"sync"
 	"testing"
 
 	"github.com/host-uk/core/pkg/core"
 	"github.com/host-uk/core/pkg/io"
+	"github.com/host-uk/core/pkg/io/encrypted"
+	"github.com/host-uk/core/pkg/io/s3/s3test"
 	"github.com/stretchr/testify/assert"
 	"github.com/wailsapp/wails/v3/pkg/application"
 )
@@ -103,6 +106,7 @@ func TestWorkspaceFileOperations(t *testing.T) {
 		_, err := service.WorkspaceFileGet("test.txt")
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "no active workspace")
+		assert.True(t, core.Is(err, core.KindNotFound))
 	})
 
 	t.Run("FileSet returns error when no active workspace", func(t *testing.T) {
@@ -112,6 +116,7 @@ func TestWorkspaceFileOperations(t *testing.T) {
 		err := service.WorkspaceFileSet("test.txt", "content")
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "no active workspace")
+		assert.True(t, core.Is(err, core.KindNotFound))
 	})
 
 	t.Run("FileGet and FileSet work with active workspace", func(t *testing.T) {
@@ -192,6 +197,7 @@ func TestCreateWorkspaceErrors(t *testing.T) {
 		_, err = service.CreateWorkspace("duplicate-test", "password")
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "already exists")
+		assert.True(t, core.Is(err, core.KindConflict))
 	})
 }
 
@@ -203,6 +209,8 @@ func TestSwitchWorkspaceErrors(t *testing.T) {
 		err := service.SwitchWorkspace("non-existent-workspace")
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "does not exist")
+		assert.True(t, core.Is(err, core.KindNotFound))
+		assert.Equal(t, "workspace.not_found", core.CodeOf(err))
 	})
 
 	t.Run("default workspace is always accessible", func(t *testing.T) {
@@ -291,11 +299,28 @@ func TestHandleIPCEvents(t *testing.T) {
 		assert.NotNil(t, service.activeWorkspace)
 	})
 
-	// Skipping "logs error for unknown message type" test as it requires core.App.Logger to be initialized
-	// which requires Wails runtime
+	t.Run("ignores unknown message type without a Wails app", func(t *testing.T) {
+		coreInstance, err := core.New()
+		assert.NoError(t, err)
+		assert.Nil(t, coreInstance.App)
+
+		service, err := New(io.NewMockMedium())
+		assert.NoError(t, err)
+
+		err = service.HandleIPCEvents(coreInstance, struct{}{})
+		assert.NoError(t, err)
+	})
+
+	t.Run("ignores map message with non-workspace action", func(t *testing.T) {
+		coreInstance, err := core.New()
+		assert.NoError(t, err)
+
+		service, err := New(io.NewMockMedium())
+		assert.NoError(t, err)
 
-	// Skipping "handles map message with non-workspace action" test as it falls through to default
-	// case which requires core.App.Logger
+		err = service.HandleIPCEvents(coreInstance, map[string]any{"action": "display.open"})
+		assert.NoError(t, err)
+	})
 }
 
 func TestGetWorkspaceDirError(t *testing.T) {
@@ -318,3 +343,222 @@ func TestGetWorkspaceDirError(t *testing.T) {
 		assert.Contains(t, err.Error(), "workspaceDir")
 	})
 }
+
+func TestDependencies(t *testing.T) {
+	service, _ := newTestService(t, "/tmp/workspace")
+	assert.Equal(t, []string{"config"}, service.Dependencies())
+}
+
+func TestCheckHealth(t *testing.T) {
+	service, _ := newTestService(t, "/tmp/workspace")
+	err := service.CheckHealth(context.Background())
+	assert.True(t, core.Is(err, core.KindNotFound))
+
+	assert.NoError(t, service.ServiceStartup(context.Background(), application.ServiceOptions{}))
+	assert.NoError(t, service.CheckHealth(context.Background()))
+
+	t.Run("locked workspace is degraded", func(t *testing.T) {
+		wsID, err := service.CreateWorkspace("health", "password")
+		assert.NoError(t, err)
+		assert.NoError(t, service.SwitchWorkspace(wsID))
+
+		err = service.CheckHealth(context.Background())
+		assert.ErrorIs(t, err, core.ErrDegraded)
+		assert.Equal(t, "workspace.locked", core.CodeOf(err))
+
+		assert.NoError(t, service.UnlockWorkspace("password"))
+		assert.NoError(t, service.CheckHealth(context.Background()))
+	})
+
+	t.Run("concurrent with lock and unlock", func(t *testing.T) {
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 3; i++ {
+				service.LockWorkspace()
+				assert.NoError(t, service.UnlockWorkspace("password"))
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				if err := service.CheckHealth(context.Background()); err != nil {
+					assert.ErrorIs(t, err, core.ErrDegraded)
+				}
+			}
+		}()
+		wg.Wait()
+	})
+}
+
+func TestUnlockWorkspace(t *testing.T) {
+	workspaceDir := "/tmp/workspace"
+	service, mockMedium := newTestService(t, workspaceDir)
+
+	wsID, err := service.CreateWorkspace("secure", "password")
+	assert.NoError(t, err)
+	privPath := filepath.Join(workspaceDir, wsID, "keys", "key.priv")
+	assert.NotContains(t, mockMedium.Files[privPath], "PRIVATE KEY")
+
+	assert.NoError(t, service.SwitchWorkspace(wsID))
+	assert.True(t, service.IsLocked())
+
+	t.Run("encrypted directories need the workspace unlocked", func(t *testing.T) {
+		err := service.WorkspaceFileSet("files/notes.txt", "secret")
+		assert.True(t, core.Is(err, core.KindPermission))
+		assert.Equal(t, "workspace.locked", core.CodeOf(err))
+
+		_, err = service.WorkspaceFileGet("data/state.json")
+		assert.Equal(t, "workspace.locked", core.CodeOf(err))
+
+		// Other directories are not encrypted.
+		assert.NoError(t, service.WorkspaceFileSet("config/app.json", "{}"))
+	})
+
+	t.Run("wrong password", func(t *testing.T) {
+		err := service.UnlockWorkspace("wrong")
+		assert.True(t, core.Is(err, core.KindPermission))
+		assert.Equal(t, "workspace.wrong_password", core.CodeOf(err))
+		assert.True(t, service.IsLocked())
+	})
+
+	t.Run("unlocked files are encrypted at rest", func(t *testing.T) {
+		assert.NoError(t, service.UnlockWorkspace("password"))
+		assert.False(t, service.IsLocked())
+
+		assert.NoError(t, service.WorkspaceFileSet("files/notes.txt", "secret"))
+		stored := mockMedium.Files[filepath.Join(workspaceDir, wsID, "files", "notes.txt")]
+		assert.NotEmpty(t, stored)
+		assert.NotContains(t, stored, "secret")
+
+		content, err := service.WorkspaceFileGet("files/notes.txt")
+		assert.NoError(t, err)
+		assert.Equal(t, "secret", content)
+
+		content, err = service.WorkspaceFileGet("config/app.json")
+		assert.NoError(t, err)
+		assert.Equal(t, "{}", content)
+	})
+
+	t.Run("paths are cleaned before choosing the medium", func(t *testing.T) {
+		for _, name := range []string{"/files/abs.txt", "./files/dot.txt", "config/../files/up.txt", "../../files/out.txt"} {
+			assert.NoError(t, service.WorkspaceFileSet(name, "secret"), name)
+			content, err := service.WorkspaceFileGet(name)
+			assert.NoError(t, err, name)
+			assert.Equal(t, "secret", content, name)
+		}
+		for _, name := range []string{"abs.txt", "dot.txt", "up.txt", "out.txt"} {
+			stored, ok := mockMedium.Files[filepath.Join(workspaceDir, wsID, "files", name)]
+			assert.True(t, ok, name)
+			assert.NotContains(t, stored, "secret", name)
+		}
+	})
+
+	t.Run("cipher is the workspace key", func(t *testing.T) {
+		c, err := service.Cipher()
+		assert.NoError(t, err)
+		ciphertext, err := c.Encrypt([]byte("token"))
+		assert.NoError(t, err)
+		plaintext, err := c.Decrypt(ciphertext)
+		assert.NoError(t, err)
+		assert.Equal(t, "token", string(plaintext))
+	})
+
+	t.Run("lock and switch forget the key", func(t *testing.T) {
+		service.LockWorkspace()
+		_, err := service.WorkspaceFileGet("files/notes.txt")
+		assert.Equal(t, "workspace.locked", core.CodeOf(err))
+
+		_, err = service.Cipher()
+		assert.Equal(t, "workspace.locked", core.CodeOf(err))
+
+		assert.NoError(t, service.UnlockWorkspace("password"))
+		assert.NoError(t, service.SwitchWorkspace(wsID))
+		assert.True(t, service.IsLocked())
+		_, err = service.Cipher()
+		assert.Equal(t, "workspace.locked", core.CodeOf(err))
+	})
+}
+
+func TestUnlockWorkspaceErrors(t *testing.T) {
+	workspaceDir := "/tmp/workspace"
+	service, mockMedium := newTestService(t, workspaceDir)
+
+	t.Run("no active workspace", func(t *testing.T) {
+		err := service.UnlockWorkspace("password")
+		assert.Equal(t, "workspace.no_active", core.CodeOf(err))
+	})
+
+	t.Run("default workspace has no keys", func(t *testing.T) {
+		assert.NoError(t, service.SwitchWorkspace(defaultWorkspace))
+		assert.False(t, service.IsLocked())
+		err := service.UnlockWorkspace("password")
+		assert.Equal(t, "workspace.no_keys", core.CodeOf(err))
+
+		// Nothing in the default workspace is encrypted.
+		assert.NoError(t, service.WorkspaceFileSet("files/plain.txt", "plain"))
+		assert.Equal(t, "plain", mockMedium.Files[filepath.Join(workspaceDir, defaultWorkspace, "files", "plain.txt")])
+	})
+
+	t.Run("plaintext private key needs migrating", func(t *testing.T) {
+		wsID, err := service.CreateWorkspace("legacy", "password")
+		assert.NoError(t, err)
+		assert.NoError(t, service.SwitchWorkspace(wsID))
+		assert.NoError(t, service.UnlockWorkspace("password"))
+		assert.NoError(t, service.WorkspaceFileSet("data/a.txt", "a"))
+
+		// Store the private key in plaintext, as older versions did.
+		privPath := filepath.Join(workspaceDir, wsID, "keys", "key.priv")
+		plaintext, err := encrypted.Password("password").Decrypt([]byte(mockMedium.Files[privPath]))
+		assert.NoError(t, err)
+		mockMedium.Files[privPath] = string(plaintext)
+
+		// Unlocking doesn't adopt whatever password is given.
+		assert.NoError(t, service.SwitchWorkspace(wsID))
+		err = service.UnlockWorkspace("new-password")
+		assert.Equal(t, "workspace.unencrypted_key", core.CodeOf(err))
+		assert.True(t, service.IsLocked())
+		assert.Equal(t, string(plaintext), mockMedium.Files[privPath])
+
+		assert.NoError(t, service.MigrateWorkspaceKey("new-password"))
+		assert.True(t, encrypted.IsPasswordEncrypted([]byte(mockMedium.Files[privPath])))
+		assert.True(t, service.IsLocked())
+		assert.Equal(t, "workspace.key_encrypted", core.CodeOf(service.MigrateWorkspaceKey("other")))
+		assert.NoError(t, service.UnlockWorkspace("new-password"))
+
+		content, err := service.WorkspaceFileGet("data/a.txt")
+		assert.NoError(t, err)
+		assert.Equal(t, "a", content)
+
+		assert.NoError(t, service.SwitchWorkspace(wsID))
+		assert.Error(t, service.UnlockWorkspace("password"))
+	})
+}
+
+func TestRegisterWithStorage(t *testing.T) {
+	srv := s3test.NewServer("team")
+	defer srv.Close()
+
+	coreInstance, err := core.New()
+	assert.NoError(t, err)
+	storage := fmt.Sprintf("s3://%s:%s@team/workspaces?endpoint=%s&pathStyle=true", s3test.AccessKey, s3test.SecretKey, srv.URL)
+	mockCfg := &mockConfig{values: map[string]interface{}{"workspaceDir": "/unused", "workspaceStorage": storage}}
+	coreInstance.RegisterService("config", mockCfg)
+
+	svc, err := Register(coreInstance)
+	assert.NoError(t, err)
+	service := svc.(*Service)
+	assert.NoError(t, service.ServiceStartup(context.Background(), application.ServiceOptions{}))
+
+	wsID, err := service.CreateWorkspace("shared", "password")
+	assert.NoError(t, err)
+	_, ok := srv.Object("team", "workspaces/"+wsID+"/keys/key.pub")
+	assert.True(t, ok)
+	_, ok = srv.Object("team", "workspaces/list.json")
+	assert.True(t, ok)
+
+	mockCfg.values["workspaceStorage"] = "ftp://nowhere"
+	_, err = Register(coreInstance)
+	assert.Equal(t, "workspace.invalid_storage", core.CodeOf(err))
+}